@@ -0,0 +1,151 @@
+package log
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// AccessLogFormatter formats the entries of http requests in Common Log Format of
+// Apache/Nginx, or Combined Log Format with referer and user agent, e.g.
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://a.com/" "Mozilla/4.08"
+//
+// It reads the fields remote_addr, user, method, path, proto, status, bytes, referer,
+// user_agent and duration (in milliseconds), the absent fields are written as "-".
+type AccessLogFormatter struct {
+	// Combined determines if writes Combined Log Format.
+	Combined bool
+
+	// Duration determines if appends the duration in seconds, as $request_time of nginx.
+	Duration bool
+}
+
+// Formatter implements ConsoleWriter.Formatter.
+func (f AccessLogFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, err error) {
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	defer bbpool.Put(b)
+
+	field := func(key string) string {
+		if value := args.Get(key); value != "" {
+			return value
+		}
+		return "-"
+	}
+
+	// remote addr
+	addr := field("remote_addr")
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	b.B = append(b.B, addr...)
+	b.B = append(b.B, " - "...)
+	b.B = append(b.B, field("user")...)
+
+	// time
+	b.B = append(b.B, ' ', '[')
+	if ns, ok := parseEntryTime(args.Time); ok {
+		b.B = time.Unix(0, ns).AppendFormat(b.B, "02/Jan/2006:15:04:05 -0700")
+	} else {
+		b.B = append(b.B, args.Time...)
+	}
+	b.B = append(b.B, ']', ' ')
+
+	// request line
+	proto := args.Get("proto")
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	b.B = accessLogAppendQuote(b.B, field("method")+" "+field("path")+" "+proto)
+
+	// status and bytes
+	b.B = append(b.B, ' ')
+	b.B = append(b.B, field("status")...)
+	b.B = append(b.B, ' ')
+	if bytes := args.Get("bytes"); bytes != "" && bytes != "0" {
+		b.B = append(b.B, bytes...)
+	} else {
+		b.B = append(b.B, '-')
+	}
+
+	if f.Combined {
+		b.B = append(b.B, ' ')
+		b.B = accessLogAppendQuote(b.B, field("referer"))
+		b.B = append(b.B, ' ')
+		b.B = accessLogAppendQuote(b.B, field("user_agent"))
+	}
+
+	if f.Duration {
+		b.B = append(b.B, ' ')
+		if ms, err := strconv.ParseFloat(args.Get("duration"), 64); err == nil {
+			b.B = strconv.AppendFloat(b.B, ms/1000, 'f', 3, 64)
+		} else {
+			b.B = append(b.B, '-')
+		}
+	}
+
+	b.B = append(b.B, '\n')
+	return out.Write(b.B)
+}
+
+// accessLogAppendQuote appends the quoted s, the quotes, backslashes and control
+// characters are escaped as Apache does.
+func accessLogAppendQuote(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < ' ' || c == 0x7f:
+			dst = append(dst, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
+
+// AccessLogWriter is an Writer that writes the entries of http requests in
+// Common/Combined Log Format, see AccessLogFormatter.
+type AccessLogWriter struct {
+	// Combined determines if writes Combined Log Format.
+	Combined bool
+
+	// Duration determines if appends the duration in seconds, as $request_time of nginx.
+	Duration bool
+
+	// Writer is the output destination. using os.Stdout if empty.
+	Writer io.Writer
+}
+
+// Close implements io.Closer, will closes the underlying Writer if not empty.
+func (w *AccessLogWriter) Close() (err error) {
+	if w.Writer != nil {
+		if closer, ok := w.Writer.(io.Closer); ok {
+			err = closer.Close()
+		}
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *AccessLogWriter) WriteEntry(e *Entry) (int, error) {
+	out := w.Writer
+	if out == nil {
+		out = os.Stdout
+	}
+
+	b := bbpool.Get().(*bb)
+	b.B = append(b.B[:0], e.buf...)
+	defer bbpool.Put(b)
+
+	var args FormatterArgs
+	parseFormatterArgs(b.B, &args)
+
+	return AccessLogFormatter{Combined: w.Combined, Duration: w.Duration}.Formatter(out, &args)
+}
+
+var _ Writer = (*AccessLogWriter)(nil)
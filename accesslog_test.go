@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestAccessLogWriter(t *testing.T) {
+	ts := time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC)
+	json := `{"time":"` + ts.Format(time.RFC3339) + `","level":"info","remote_addr":"127.0.0.1:1234","user":"frank","method":"GET","path":"/a.gif?b=1","proto":"HTTP/1.0","status":200,"bytes":2326,"referer":"http://a.com/","user_agent":"Mozilla/4.08 \"x\"","duration":12.5,"message":"request"}` + "\n"
+	clf := "127.0.0.1 - frank [" + ts.Local().Format("02/Jan/2006:15:04:05 -0700") + `] "GET /a.gif?b=1 HTTP/1.0" 200 2326`
+
+	cases := []struct {
+		Writer AccessLogWriter
+		Output string
+	}{
+		{AccessLogWriter{}, clf + "\n"},
+		{AccessLogWriter{Combined: true}, clf + ` "http://a.com/" "Mozilla/4.08 \"x\""` + "\n"},
+		{AccessLogWriter{Combined: true, Duration: true}, clf + ` "http://a.com/" "Mozilla/4.08 \"x\"" 0.013` + "\n"},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		w := c.Writer
+		w.Writer = &buf
+		if _, err := wlprintf(&w, InfoLevel, json); err != nil {
+			t.Errorf("access log writer error: %+v", err)
+		}
+		if got := buf.String(); got != c.Output {
+			t.Errorf("access log writer:\ngot  %q\nwant %q", got, c.Output)
+		}
+	}
+}
+
+func TestAccessLogWriterAbsent(t *testing.T) {
+	var buf bytes.Buffer
+	w := &AccessLogWriter{Combined: true, Duration: true, Writer: &buf}
+	_, _ = wlprintf(w, InfoLevel, `{"time":"bad","status":404,"bytes":0,"user_agent":"a\tb"}`+"\n")
+
+	if got, want := buf.String(), `- - - [bad] "- - HTTP/1.1" 404 - "-" "a\x09b" -`+"\n"; got != want {
+		t.Errorf("access log writer:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestAccessLogFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Writer: &ConsoleWriter{
+			Formatter: AccessLogFormatter{Combined: true}.Formatter,
+			Writer:    &buf,
+		},
+	}
+	logger.Info().Str("remote_addr", "::1").Str("method", "POST").Str("path", "/").Int("status", 201).Msg("")
+
+	if got, want := buf.String()[:5], "::1 -"; got != want {
+		t.Errorf("access log formatter: got %q, want prefix %q", buf.String(), want)
+	}
+}
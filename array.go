@@ -0,0 +1,146 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Array is a builder of json arrays with mixed or object elements, it appends the elements
+// to the entry directly without allocations. It is instanced by Entry.Array.
+type Array Entry
+
+// Array adds the field key with the elements appended by f as an array, e.g.
+//
+//	log.Info().Array("items", func(a *log.Array) {
+//		a.Str("foo").Int(42).Object(func(e *log.Entry) {
+//			e.Str("id", "bar")
+//		})
+//	}).Msg("")
+//
+//	// Output: {"time":"2020-07-12T05:03:43.949Z","level":"info","items":["foo",42,{"id":"bar"}],"message":""}
+func (e *Entry) Array(key string, f func(a *Array)) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':', '[')
+	f((*Array)(e))
+	e.buf = append(e.buf, ']')
+	return e
+}
+
+// sep appends a comma if a is not empty.
+func (a *Array) sep() {
+	if a.buf[len(a.buf)-1] != '[' {
+		a.buf = append(a.buf, ',')
+	}
+}
+
+// Str appends a string to the array.
+func (a *Array) Str(val string) *Array {
+	a.sep()
+	a.buf = append(a.buf, '"')
+	(*Entry)(a).string(val)
+	a.buf = append(a.buf, '"')
+	return a
+}
+
+// Bytes appends a bytes as string to the array.
+func (a *Array) Bytes(val []byte) *Array {
+	a.sep()
+	a.buf = append(a.buf, '"')
+	(*Entry)(a).bytes(val)
+	a.buf = append(a.buf, '"')
+	return a
+}
+
+// Bool appends a bool to the array.
+func (a *Array) Bool(b bool) *Array {
+	a.sep()
+	a.buf = strconv.AppendBool(a.buf, b)
+	return a
+}
+
+// Int appends an int to the array.
+func (a *Array) Int(i int) *Array {
+	return a.Int64(int64(i))
+}
+
+// Int64 appends an int64 to the array.
+func (a *Array) Int64(i int64) *Array {
+	a.sep()
+	a.buf = strconv.AppendInt(a.buf, i, 10)
+	return a
+}
+
+// Uint64 appends an uint64 to the array.
+func (a *Array) Uint64(i uint64) *Array {
+	a.sep()
+	a.buf = strconv.AppendUint(a.buf, i, 10)
+	return a
+}
+
+// Float64 appends a float64 to the array.
+func (a *Array) Float64(f float64) *Array {
+	a.sep()
+	a.buf = strconv.AppendFloat(a.buf, f, 'f', -1, 64)
+	return a
+}
+
+// Time appends t formated as string using time.RFC3339Nano to the array.
+func (a *Array) Time(t time.Time) *Array {
+	a.sep()
+	a.buf = append(a.buf, '"')
+	a.buf = t.AppendFormat(a.buf, "2006-01-02T15:04:05.999Z07:00")
+	a.buf = append(a.buf, '"')
+	return a
+}
+
+// Err appends the message of err to the array, or null if err is nil.
+func (a *Array) Err(err error) *Array {
+	if err == nil {
+		return a.Null()
+	}
+	return a.Str(err.Error())
+}
+
+// Null appends a null to the array.
+func (a *Array) Null() *Array {
+	a.sep()
+	a.buf = append(a.buf, "null"...)
+	return a
+}
+
+// RawJSON appends an already encoded json to the array, or null if b is empty.
+func (a *Array) RawJSON(b []byte) *Array {
+	if len(b) == 0 {
+		return a.Null()
+	}
+	a.sep()
+	a.buf = append(a.buf, b...)
+	return a
+}
+
+// Object appends the fields appended by f as an object to the array.
+func (a *Array) Object(f func(e *Entry)) *Array {
+	a.sep()
+	n := len(a.buf)
+	f((*Entry)(a))
+	if n < len(a.buf) {
+		a.buf[n] = '{'
+		a.buf = append(a.buf, '}')
+	} else {
+		a.buf = append(a.buf, '{', '}')
+	}
+	return a
+}
+
+// Array appends the elements appended by f as a nested array to the array.
+func (a *Array) Array(f func(a *Array)) *Array {
+	a.sep()
+	a.buf = append(a.buf, '[')
+	f(a)
+	a.buf = append(a.buf, ']')
+	return a
+}
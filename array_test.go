@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryArray(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	logger.Info().Array("a", func(a *Array) {
+		a.Str("foo\"").Bytes([]byte("bar")).Int(-1).Int64(2).Uint64(3).Float64(0.5).Bool(true).Null().
+			Time(time.Date(2020, 7, 12, 5, 3, 43, 0, time.UTC)).Err(errors.New("e")).Err(nil).
+			RawJSON([]byte(`{"x":1}`)).RawJSON(nil).
+			Object(func(e *Entry) { e.Str("id", "baz").Int("n", 1) }).
+			Object(func(e *Entry) {}).
+			Array(func(a *Array) { a.Int(1).Int(2) }).
+			Array(func(a *Array) {})
+	}).Array("empty", func(a *Array) {}).Msg("array")
+
+	want := `"a":["foo\"","bar",-1,2,3,0.5,true,null,"2020-07-12T05:03:43Z","e",null,{"x":1},null,{"id":"baz","n":1},{},[1,2],[]],"empty":[],"message":"array"}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("array output got %s, want %s", got, want)
+	}
+
+	var entry *Entry
+	if entry.Array("nil", func(a *Array) { t.Errorf("array of nil entry must not be called") }) != nil {
+		t.Errorf("array of nil entry must return nil")
+	}
+}
+
+func BenchmarkEntryArray(b *testing.B) {
+	logger := Logger{Writer: IOWriter{io.Discard}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Array("a", func(a *Array) {
+			a.Str("foo").Int(i).Object(func(e *Entry) { e.Str("id", "bar") })
+		}).Msg("")
+	}
+}
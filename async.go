@@ -1,61 +1,383 @@
 package log
 
 import (
+	"context"
 	"io"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// QueuePolicy specifies the policy of AsyncWriter when the queue is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks the logging until the queue is available.
+	QueueBlock QueuePolicy = iota
+	// QueueDropNewest drops the entry being logged.
+	QueueDropNewest
+	// QueueDropOldest drops the oldest entry in the queue.
+	QueueDropOldest
+	// QueueDropBelowLevel drops the entry being logged if its level is below DropLevel,
+	// and blocks otherwise.
+	QueueDropBelowLevel
 )
 
 // AsyncWriter is an Writer that writes asynchronously.
+//
+// The entries are queued in a lock-free ring buffer, and written in batches by a single
+// background goroutine, so the producers barely contend with each other.
 type AsyncWriter struct {
-	// ChannelSize is the size of the data channel, the default size is 1.
+	// ChannelSize is the size of the queue, which is rounded up to a power of two, the
+	// default size is 2. It bounds the queued entries, see QueuePolicy for the entries exceeded.
 	ChannelSize uint
 
+	// QueuePolicy specifies the policy when the queue is full, uses QueueBlock if zero.
+	QueuePolicy QueuePolicy
+
+	// DropLevel specifies the level of QueueDropBelowLevel.
+	DropLevel Level
+
+	// OnDrop specifies an optional callback with the total number of dropped entries,
+	// which is called after an entry is dropped.
+	OnDrop func(dropped uint64)
+
 	// Writer specifies the writer of output.
 	Writer Writer
 
-	once    sync.Once
-	ch      chan *Entry
-	chClose chan error
+	dropped   uint64
+	enqueued  uint64
+	dequeued  uint64
+	abandon   uint32
+	closed    uint32
+	sleeping  uint32
+	waiters   int32
+	writing   int32
+	once      sync.Once
+	closeOnce sync.Once
+	abandoned int
+	closeErr  error
+	ring      *asyncRing
+	notify    chan struct{}
+	mu        sync.Mutex
+	cond      *sync.Cond
+	chClose   chan error
 }
 
+// asyncBatchSize is the max number of entries dequeued at once.
+const asyncBatchSize = 128
+
 // Close implements io.Closer, and closes the underlying Writer.
 func (w *AsyncWriter) Close() (err error) {
-	w.ch <- nil
-	err = <-w.chClose
-	if closer, ok := w.Writer.(io.Closer); ok {
-		if err1 := closer.Close(); err1 != nil {
-			err = err1
+	_, err = w.Shutdown(context.Background())
+	return
+}
+
+// Shutdown writes the queued entries until the deadline of ctx, and closes the underlying
+// Writer. It returns the number of abandoned entries and ctx.Err() if the deadline exceeds,
+// the in-flight write is waited before closing. The later calls return the results of
+// the first call, and the writes after Shutdown return ErrClosed.
+func (w *AsyncWriter) Shutdown(ctx context.Context) (abandoned int, err error) {
+	w.once.Do(w.start)
+
+	w.closeOnce.Do(func() {
+		atomic.StoreUint32(&w.closed, 1)
+		w.wakeup()
+
+		select {
+		case w.closeErr = <-w.chClose:
+		case <-ctx.Done():
+			// stops the consumer and waits for the in-flight write
+			atomic.StoreUint32(&w.abandon, 1)
+			w.wakeup()
+			<-w.chClose
+			if enqueued, dequeued := atomic.LoadUint64(&w.enqueued), atomic.LoadUint64(&w.dequeued); enqueued > dequeued {
+				w.abandoned = int(enqueued - dequeued)
+			}
+			w.closeErr = ctx.Err()
+		}
+
+		// wakes up the blocked producers
+		w.mu.Lock()
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if closer, ok := w.Writer.(io.Closer); ok {
+			if err1 := closer.Close(); err1 != nil {
+				w.closeErr = err1
+			}
 		}
+	})
+
+	return w.abandoned, w.closeErr
+}
+
+// Flush waits until the entries queued before are written, or the deadline of ctx exceeds.
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	enqueued := atomic.LoadUint64(&w.enqueued)
+	if atomic.LoadUint64(&w.dequeued) >= enqueued {
+		return nil
 	}
-	return
+
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadUint64(&w.dequeued) < enqueued {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
 }
 
-// WriteEntry implements Writer.
-func (w *AsyncWriter) WriteEntry(e *Entry) (int, error) {
-	w.once.Do(func() {
-		// channels
-		w.ch = make(chan *Entry, w.ChannelSize)
-		w.chClose = make(chan error)
-		go func() {
-			var err error
-			for entry := range w.ch {
-				if entry == nil {
+// Dropped returns the total number of dropped entries.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *AsyncWriter) start() {
+	w.ring = newAsyncRing(w.ChannelSize)
+	w.notify = make(chan struct{}, 1)
+	w.cond = sync.NewCond(&w.mu)
+	w.chClose = make(chan error, 1)
+	go w.consume()
+}
+
+// consume writes the queued entries in batches until closed or abandoned.
+func (w *AsyncWriter) consume() {
+	var err error
+	batch := make([]*Entry, 0, asyncBatchSize)
+	for atomic.LoadUint32(&w.abandon) == 0 {
+		batch = w.ring.dequeueBatch(batch[:0])
+		if len(batch) == 0 {
+			if atomic.LoadUint32(&w.closed) != 0 {
+				if atomic.LoadInt32(&w.writing) == 0 && w.ring.empty() {
 					break
 				}
+				// a producer passed the closed check before Shutdown, waits for it
+				runtime.Gosched()
+				continue
+			}
+			atomic.StoreUint32(&w.sleeping, 1)
+			if !w.ring.empty() || atomic.LoadUint32(&w.closed) != 0 {
+				// a producer is storing the entry, yields to it
+				atomic.StoreUint32(&w.sleeping, 0)
+				runtime.Gosched()
+				continue
+			}
+			<-w.notify
+			continue
+		}
+		if atomic.LoadInt32(&w.waiters) > 0 {
+			w.mu.Lock()
+			w.cond.Broadcast()
+			w.mu.Unlock()
+		}
+		for i, entry := range batch {
+			if atomic.LoadUint32(&w.abandon) == 0 {
 				_, err = w.Writer.WriteEntry(entry)
-				epool.Put(entry)
+				atomic.AddUint64(&w.dequeued, 1)
 			}
-			w.chClose <- err
-		}()
-	})
+			epool.Put(entry)
+			batch[i] = nil
+		}
+	}
+	w.chClose <- err
+}
+
+// wakeup wakes up the sleeping consumer.
+func (w *AsyncWriter) wakeup() {
+	if atomic.LoadUint32(&w.sleeping) != 0 && atomic.CompareAndSwapUint32(&w.sleeping, 1, 0) {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wait blocks until the entry is queued, it returns false if abandoned.
+func (w *AsyncWriter) wait(entry *Entry) bool {
+	for i := 0; i < 64; i++ {
+		if w.ring.enqueue(entry) {
+			return true
+		}
+		runtime.Gosched()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	atomic.AddInt32(&w.waiters, 1)
+	defer atomic.AddInt32(&w.waiters, -1)
+	for !w.ring.enqueue(entry) {
+		if atomic.LoadUint32(&w.abandon) != 0 {
+			return false
+		}
+		w.cond.Wait()
+	}
+	return true
+}
+
+// WriteEntry implements Writer.
+func (w *AsyncWriter) WriteEntry(e *Entry) (int, error) {
+	w.once.Do(w.start)
+
+	// the in-flight writes are counted before the closed check, the consumer of Shutdown
+	// waits for them to queue the entries.
+	atomic.AddInt32(&w.writing, 1)
+	defer atomic.AddInt32(&w.writing, -1)
+
+	if atomic.LoadUint32(&w.closed) != 0 {
+		return 0, ErrClosed
+	}
 
 	// cheating to logger pool
 	entry := epool.Get().(*Entry)
 	entry.Level = e.Level
+	entry.name = e.name
 	entry.buf, e.buf = e.buf, entry.buf
+	n := len(entry.buf)
+
+	queued := true
+	switch w.QueuePolicy {
+	case QueueDropNewest:
+		queued = w.ring.enqueue(entry)
+	case QueueDropOldest:
+		for !w.ring.enqueue(entry) {
+			if old := w.ring.dequeue(); old != nil {
+				atomic.AddUint64(&w.dequeued, 1)
+				w.drop(old)
+			} else {
+				runtime.Gosched()
+			}
+		}
+	case QueueDropBelowLevel:
+		if queued = w.ring.enqueue(entry); !queued && !levelLess(entry.Level, w.DropLevel) {
+			queued = w.wait(entry)
+		}
+	default:
+		if !w.ring.enqueue(entry) {
+			queued = w.wait(entry)
+		}
+	}
+
+	if queued {
+		atomic.AddUint64(&w.enqueued, 1)
+		w.wakeup()
+	} else {
+		w.drop(entry)
+	}
+
+	return n, nil
+}
+
+func (w *AsyncWriter) drop(entry *Entry) {
+	epool.Put(entry)
+	dropped := atomic.AddUint64(&w.dropped, 1)
+	if w.OnDrop != nil {
+		w.OnDrop(dropped)
+	}
+}
+
+// asyncRing is a bounded lock-free queue of entries, each slot has a sequence number to
+// tell whether it is ready for enqueue or dequeue, see
+// https://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+//
+// The consumer of AsyncWriter is single, and the producers of QueueDropOldest dequeue
+// the oldest entries as well.
+type asyncRing struct {
+	head  uint64
+	_     [56]byte
+	tail  uint64
+	_     [56]byte
+	mask  uint64
+	slots []asyncSlot
+}
+
+// asyncSlot is padded to 16 bytes, to keep seq 8-byte aligned for the 64-bit atomic
+// operations of 32-bit platforms.
+type asyncSlot struct {
+	seq   uint64
+	entry *Entry
+	_     [8 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// newAsyncRing returns a ring of size rounded up to a power of two, the minimum size
+// is 2 because the sequence numbers of a single slot can't tell full from empty.
+func newAsyncRing(size uint) *asyncRing {
+	n := uint64(2)
+	for n < uint64(size) {
+		n <<= 1
+	}
+	r := &asyncRing{
+		mask:  n - 1,
+		slots: make([]asyncSlot, n),
+	}
+	for i := range r.slots {
+		r.slots[i].seq = uint64(i)
+	}
+	return r
+}
+
+// enqueue appends the entry to the queue, it returns false if the queue is full.
+func (r *asyncRing) enqueue(entry *Entry) bool {
+	for {
+		pos := atomic.LoadUint64(&r.head)
+		slot := &r.slots[pos&r.mask]
+		switch seq := atomic.LoadUint64(&slot.seq); {
+		case seq == pos:
+			if atomic.CompareAndSwapUint64(&r.head, pos, pos+1) {
+				slot.entry = entry
+				atomic.StoreUint64(&slot.seq, pos+1)
+				return true
+			}
+		case seq < pos:
+			return false
+		default:
+			// head is advanced by another producer, reloads it
+			runtime.Gosched()
+		}
+	}
+}
+
+// dequeue removes the oldest entry of the queue, it returns nil if the queue is empty.
+func (r *asyncRing) dequeue() *Entry {
+	for {
+		pos := atomic.LoadUint64(&r.tail)
+		slot := &r.slots[pos&r.mask]
+		switch seq := atomic.LoadUint64(&slot.seq); {
+		case seq == pos+1:
+			if atomic.CompareAndSwapUint64(&r.tail, pos, pos+1) {
+				entry := slot.entry
+				slot.entry = nil
+				atomic.StoreUint64(&slot.seq, pos+r.mask+1)
+				return entry
+			}
+		case seq < pos+1:
+			return nil
+		default:
+			// tail is advanced by another consumer, reloads it
+			runtime.Gosched()
+		}
+	}
+}
+
+// dequeueBatch appends the oldest entries of the queue to batch up to its capacity.
+func (r *asyncRing) dequeueBatch(batch []*Entry) []*Entry {
+	for len(batch) < cap(batch) {
+		entry := r.dequeue()
+		if entry == nil {
+			break
+		}
+		batch = append(batch, entry)
+	}
+	return batch
+}
 
-	w.ch <- entry
-	return len(entry.buf), nil
+// empty reports whether the queue is empty.
+func (r *asyncRing) empty() bool {
+	return atomic.LoadUint64(&r.head) == atomic.LoadUint64(&r.tail)
 }
 
 var _ Writer = (*AsyncWriter)(nil)
@@ -1,11 +1,31 @@
 package log
 
 import (
+	"context"
 	"io"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+type asyncBlockingWriter struct {
+	mu      sync.Mutex
+	unblock chan struct{}
+	entries []string
+}
+
+func (w *asyncBlockingWriter) WriteEntry(e *Entry) (int, error) {
+	<-w.unblock
+	w.mu.Lock()
+	w.entries = append(w.entries, strings.TrimSpace(string(e.buf)))
+	w.mu.Unlock()
+	return len(e.buf), nil
+}
+
 func TestAsyncWriterZero(t *testing.T) {
 	w := &AsyncWriter{
 		ChannelSize: 0,
@@ -32,6 +52,165 @@ func TestAsyncWriterSmall(t *testing.T) {
 	}
 }
 
+func TestAsyncWriterQueuePolicy(t *testing.T) {
+	cases := []struct {
+		policy   QueuePolicy
+		dropped  uint64
+		expected string
+	}{
+		{QueueDropNewest, 3, "0,1,2"},
+		{QueueDropOldest, 3, "0,4,5"},
+		{QueueDropBelowLevel, 2, "0,1,2,5"},
+	}
+	for _, c := range cases {
+		var dropped uint64
+		bw := &asyncBlockingWriter{unblock: make(chan struct{})}
+		w := &AsyncWriter{
+			ChannelSize: 2,
+			QueuePolicy: c.policy,
+			DropLevel:   WarnLevel,
+			OnDrop:      func(n uint64) { dropped = n },
+			Writer:      bw,
+		}
+
+		// the consumer takes entry 0 and blocks
+		_, _ = wlprintf(w, InfoLevel, "0")
+		for !w.ring.empty() {
+		}
+		for i := 1; i <= 5; i++ {
+			level := InfoLevel
+			if c.policy == QueueDropBelowLevel && i == 5 {
+				level = WarnLevel
+				go close(bw.unblock)
+			}
+			_, _ = wlprintf(w, level, "%d", i)
+		}
+		if c.policy != QueueDropBelowLevel {
+			close(bw.unblock)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("async close error: %+v", err)
+		}
+
+		if got := strings.Join(bw.entries, ","); got != c.expected {
+			t.Errorf("async policy %d expected: %s, actual: %s", c.policy, c.expected, got)
+		}
+		if w.Dropped() != c.dropped || dropped != c.dropped {
+			t.Errorf("async policy %d dropped expected: %d, actual: %d %d", c.policy, c.dropped, w.Dropped(), dropped)
+		}
+	}
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	bw := &asyncBlockingWriter{unblock: make(chan struct{})}
+	w := &AsyncWriter{ChannelSize: 10, Writer: bw}
+	for i := 0; i < 3; i++ {
+		_, _ = wlprintf(w, InfoLevel, "%d", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("async flush must exceed the deadline, got %+v", err)
+	}
+
+	close(bw.unblock)
+	if err := w.Flush(context.Background()); err != nil {
+		t.Errorf("async flush error: %+v", err)
+	}
+	bw.mu.Lock()
+	if len(bw.entries) != 3 {
+		t.Errorf("async flush must write all entries, got %+v", bw.entries)
+	}
+	bw.mu.Unlock()
+
+	if err := w.Close(); err != nil {
+		t.Errorf("async close error: %+v", err)
+	}
+}
+
+func TestAsyncWriterShutdown(t *testing.T) {
+	bw := &asyncBlockingWriter{unblock: make(chan struct{})}
+	w := &AsyncWriter{ChannelSize: 10, Writer: bw}
+	for i := 0; i < 5; i++ {
+		_, _ = wlprintf(w, InfoLevel, "%d", i)
+	}
+
+	// unblocks the in-flight write after the deadline
+	time.AfterFunc(50*time.Millisecond, func() { close(bw.unblock) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	abandoned, err := w.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("async shutdown must exceed the deadline, got %+v", err)
+	}
+	if abandoned != 4 {
+		t.Errorf("async shutdown must abandon 4 entries, got %d", abandoned)
+	}
+	if len(bw.entries) != 1 {
+		t.Errorf("async shutdown must wait for the in-flight write, got %+v", bw.entries)
+	}
+
+	if n, err := w.Shutdown(context.Background()); n != abandoned || err != context.DeadlineExceeded {
+		t.Errorf("async shutdown again must return the first results, got %d %+v", n, err)
+	}
+	if _, err := wlprintf(w, InfoLevel, "after close"); err != ErrClosed {
+		t.Errorf("async write after close must return ErrClosed, got %+v", err)
+	}
+}
+
+func TestAsyncWriterCloseTwice(t *testing.T) {
+	w := &AsyncWriter{Writer: IOWriter{io.Discard}}
+	for i := 0; i < 3; i++ {
+		_, _ = wlprintf(w, InfoLevel, "%d", i)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.Close(); err != nil {
+			t.Errorf("async close error: %+v", err)
+		}
+	}
+}
+
+type asyncCountingWriter struct{ n int64 }
+
+func (w *asyncCountingWriter) WriteEntry(e *Entry) (int, error) {
+	atomic.AddInt64(&w.n, 1)
+	return len(e.buf), nil
+}
+
+func TestAsyncWriterCloseWriting(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		cw := &asyncCountingWriter{}
+		w := &AsyncWriter{ChannelSize: 4, Writer: cw}
+
+		var written int64
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					if _, err := wlprintf(w, InfoLevel, "entry"); err != nil {
+						return
+					}
+					atomic.AddInt64(&written, 1)
+					runtime.Gosched()
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		if err := w.Close(); err != nil {
+			t.Fatalf("async close error: %+v", err)
+		}
+		wg.Wait()
+
+		if n := atomic.LoadInt64(&cw.n); n != written {
+			t.Fatalf("async close must write the accepted entries, written=%d accepted=%d", n, written)
+		}
+	}
+}
+
 func BenchmarkAsyncWriter(b *testing.B) {
 	logger := Logger{
 		Writer: &AsyncWriter{
@@ -48,3 +227,58 @@ func BenchmarkAsyncWriter(b *testing.B) {
 		}
 	})
 }
+
+func TestAsyncRing(t *testing.T) {
+	for _, size := range []uint{0, 1, 2, 3, 100} {
+		r := newAsyncRing(size)
+		n := len(r.slots)
+		if n < 2 || n&(n-1) != 0 || n < int(size) {
+			t.Fatalf("async ring size %d got %d slots", size, n)
+		}
+		for i := 0; i < n; i++ {
+			if !r.enqueue(&Entry{}) {
+				t.Fatalf("async ring size %d enqueue %d failed", size, i)
+			}
+		}
+		if r.enqueue(&Entry{}) {
+			t.Fatalf("async ring size %d enqueue to full succeeded", size)
+		}
+		if batch := r.dequeueBatch(make([]*Entry, 0, n+1)); len(batch) != n {
+			t.Fatalf("async ring size %d dequeue %d entries", size, len(batch))
+		}
+		if !r.empty() || r.dequeue() != nil {
+			t.Fatalf("async ring size %d is not empty", size)
+		}
+	}
+
+	r := newAsyncRing(4)
+	var wg sync.WaitGroup
+	var total int64
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				for !r.enqueue(&Entry{}) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		for total < 8000 {
+			if r.dequeue() != nil {
+				total++
+			} else {
+				runtime.Gosched()
+			}
+		}
+		close(done)
+	}()
+	wg.Wait()
+	<-done
+	if !r.empty() {
+		t.Errorf("async ring is not empty after dequeuing %d entries", total)
+	}
+}
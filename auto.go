@@ -0,0 +1,64 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// AutoWriter is an Writer that writes in the colorized console format if the output is a
+// terminal, and in raw JSON otherwise, e.g. redirected to a file or collected by an agent.
+type AutoWriter struct {
+	// ConsoleWriter specifies the console writer of terminal, uses a ConsoleWriter with
+	// AutoColor if nil, its Writer is replaced by Writer.
+	ConsoleWriter *ConsoleWriter
+
+	// Writer specifies the output, uses os.Stdout if nil.
+	Writer io.Writer
+
+	once   sync.Once
+	writer Writer
+}
+
+// WriteEntry implements Writer.
+func (w *AutoWriter) WriteEntry(e *Entry) (int, error) {
+	w.once.Do(w.init)
+	return w.writer.WriteEntry(e)
+}
+
+// Terminal reports whether the output is a terminal and written in the console format.
+func (w *AutoWriter) Terminal() bool {
+	w.once.Do(w.init)
+	_, ok := w.writer.(*ConsoleWriter)
+	return ok
+}
+
+func (w *AutoWriter) init() {
+	out := w.Writer
+	if out == nil {
+		out = os.Stdout
+	}
+
+	if f, ok := out.(*os.File); !ok || !IsTerminal(f.Fd()) {
+		w.writer = IOWriter{out}
+		return
+	}
+
+	cw := &ConsoleWriter{AutoColor: true}
+	if w.ConsoleWriter != nil {
+		c := *w.ConsoleWriter
+		cw = &c
+	}
+	cw.Writer = out
+	w.writer = cw
+}
+
+// Close implements io.Closer, and closes the underlying Writer if it is set.
+func (w *AutoWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+var _ Writer = (*AutoWriter)(nil)
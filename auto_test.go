@@ -0,0 +1,36 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestAutoWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &AutoWriter{Writer: &b}
+	logger := Logger{Writer: w}
+
+	logger.Info().Str("foo", "bar").Msg("hello auto writer")
+	if w.Terminal() {
+		t.Errorf("auto writer must not treat a buffer as terminal")
+	}
+	if s := b.String(); s == "" || s[0] != '{' {
+		t.Errorf("auto writer must write raw json to a non-terminal: %q", s)
+	}
+
+	file, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("open %s error: %+v", os.DevNull, err)
+	}
+	w = &AutoWriter{ConsoleWriter: &ConsoleWriter{ColorOutput: true}, Writer: file}
+	if w.Terminal() {
+		t.Errorf("auto writer must not treat %s as terminal", os.DevNull)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("auto writer close error: %+v", err)
+	}
+
+	w = &AutoWriter{}
+	t.Logf("auto writer of stdout is terminal: %v", w.Terminal())
+}
@@ -0,0 +1,252 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials specifies the credentials to sign the requests of aws services.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Expires specifies the expiration of temporary credentials, zero means never expires.
+	Expires time.Time
+}
+
+// AWSEnvCredentials returns the credentials from the environment variables AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, as which Lambda provides, or from the container
+// credentials endpoint of ECS/EKS by AWS_CONTAINER_CREDENTIALS_RELATIVE_URI/FULL_URI.
+func AWSEnvCredentials() (creds AWSCredentials, err error) {
+	creds.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	creds.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	creds.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return
+	}
+
+	url := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		url = "http://169.254.170.2" + uri
+	}
+	if url == "" {
+		return creds, errors.New("log: aws credentials not found in environment")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	data, err := httpDo(&http.Client{Timeout: 5 * time.Second}, req, 64*1024)
+	if err != nil {
+		return
+	}
+
+	var resp struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return
+	}
+
+	return AWSCredentials{
+		AccessKeyID:     resp.AccessKeyID,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.Token,
+		Expires:         resp.Expiration,
+	}, nil
+}
+
+// awsSigner signs the requests of aws service with signature version 4.
+type awsSigner struct {
+	region      string
+	service     string
+	credentials func() (AWSCredentials, error)
+
+	mu    sync.Mutex
+	creds AWSCredentials
+}
+
+// sign signs the req with the sha256 of body, the credentials are cached until 5 minutes before expiration.
+func (s *awsSigner) sign(req *http.Request, body []byte) error {
+	now := timeNow().UTC()
+
+	s.mu.Lock()
+	creds := s.creds
+	if creds.AccessKeyID == "" || (!creds.Expires.IsZero() && now.Add(5*time.Minute).After(creds.Expires)) {
+		provider := s.credentials
+		if provider == nil {
+			provider = AWSEnvCredentials
+		}
+		var err error
+		if creds, err = provider(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.creds = creds
+	}
+	s.mu.Unlock()
+
+	awsSign(req, body, s.region, s.service, creds, now)
+	return nil
+}
+
+// awsSign signs the req in place with signature version 4.
+func awsSign(req *http.Request, body []byte, region, service string, creds AWSCredentials, now time.Time) {
+	amzdate := now.UTC().Format("20060102T150405Z")
+	date := amzdate[:8]
+
+	sum := sha256.Sum256(body)
+	payload := hexEncode(sum[:])
+
+	req.Header.Set("X-Amz-Date", amzdate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// canonical headers
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	signed := strings.Join(names, ";")
+
+	// canonical query
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(awsURIEncode(k, true))
+			sb.WriteByte('=')
+			sb.WriteString(awsURIEncode(v, true))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonical := make([]byte, 0, 512)
+	canonical = append(canonical, req.Method...)
+	canonical = append(canonical, '\n')
+	canonical = append(canonical, path...)
+	canonical = append(canonical, '\n')
+	canonical = append(canonical, sb.String()...)
+	canonical = append(canonical, '\n')
+	for _, k := range names {
+		canonical = append(canonical, k...)
+		canonical = append(canonical, ':')
+		canonical = append(canonical, headers[k]...)
+		canonical = append(canonical, '\n')
+	}
+	canonical = append(canonical, '\n')
+	canonical = append(canonical, signed...)
+	canonical = append(canonical, '\n')
+	canonical = append(canonical, payload...)
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	sum = sha256.Sum256(canonical)
+	tosign := "AWS4-HMAC-SHA256\n" + amzdate + "\n" + scope + "\n" + hexEncode(sum[:])
+
+	key := awsHmac([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = awsHmac(key, region)
+	key = awsHmac(key, service)
+	key = awsHmac(key, "aws4_request")
+	signature := hexEncode(awsHmac(key, tosign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+", SignedHeaders="+signed+", Signature="+signature)
+}
+
+func awsHmac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode encodes s as the canonical uri of aws, the '/' is kept if not slash.
+func awsURIEncode(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte("0123456789ABCDEF"[c>>4])
+			b.WriteByte("0123456789ABCDEF"[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+// awsErrorType returns the error type of the json error response of aws service.
+func awsErrorType(err error) string {
+	var e *httpStatusError
+	if !errors.As(err, &e) {
+		return ""
+	}
+	var resp struct {
+		Type string `json:"__type"`
+	}
+	if json.Unmarshal([]byte(e.Body), &resp) != nil {
+		return ""
+	}
+	typ := resp.Type
+	if i := strings.LastIndexByte(typ, '#'); i >= 0 {
+		typ = typ[i+1:]
+	}
+	return typ
+}
+
+func hexEncode(b []byte) string {
+	dst := make([]byte, 0, 2*len(b))
+	for _, v := range b {
+		dst = append(dst, hex[v>>4], hex[v&0x0f])
+	}
+	return string(dst)
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAWSSign(t *testing.T) {
+	// get-vanilla of aws signature version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	awsSign(req, nil, "us-east-1", "service", creds, now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("awsSign mismatch:\n got: %s\nwant: %s", got, want)
+	}
+	if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+		t.Errorf("awsSign date mismatch: %s", req.Header.Get("X-Amz-Date"))
+	}
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	if s := awsURIEncode("a b/c~d+e", true); s != "a%20b%2Fc~d%2Be" {
+		t.Errorf("awsURIEncode mismatch: %s", s)
+	}
+	if s := awsURIEncode("a b/c", false); s != "a%20b/c" {
+		t.Errorf("awsURIEncode mismatch: %s", s)
+	}
+}
+
+func TestAWSEnvCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token1" {
+			t.Errorf("aws container credentials should send authorization: %v", r.Header)
+		}
+		_, _ = rw.Write([]byte(`{"AccessKeyId":"AKID","SecretAccessKey":"SECRET","Token":"TOKEN","Expiration":"2030-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN"} {
+		if value, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, value)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Unsetenv(key)
+	}
+
+	if _, err := AWSEnvCredentials(); err == nil {
+		t.Errorf("AWSEnvCredentials should fail without environment")
+	}
+
+	os.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL)
+	os.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "token1")
+	creds, err := AWSEnvCredentials()
+	if err != nil || creds.AccessKeyID != "AKID" || creds.SecretAccessKey != "SECRET" || creds.SessionToken != "TOKEN" || creds.Expires.Year() != 2030 {
+		t.Errorf("AWSEnvCredentials container mismatch: %+v %+v", creds, err)
+	}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID2")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET2")
+	creds, err = AWSEnvCredentials()
+	if err != nil || creds.AccessKeyID != "AKID2" || creds.SecretAccessKey != "SECRET2" {
+		t.Errorf("AWSEnvCredentials env mismatch: %+v %+v", creds, err)
+	}
+}
@@ -0,0 +1,154 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AzureToken specifies the access token of microsoft entra id (azure active directory).
+type AzureToken struct {
+	AccessToken string
+
+	// Expires specifies the expiration of access token, zero means never expires.
+	Expires time.Time
+}
+
+// AzureEnvToken returns the access token of scope by the credentials from environment, it tries
+// the client secret of AZURE_TENANT_ID/AZURE_CLIENT_ID/AZURE_CLIENT_SECRET, the workload identity
+// of AKS by AZURE_FEDERATED_TOKEN_FILE, the managed identity of App Service by IDENTITY_ENDPOINT,
+// and the managed identity of virtual machines by instance metadata service in order.
+func AzureEnvToken(scope string) (token AzureToken, err error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	tenant, clientID := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = "https://login.microsoftonline.com/"
+	}
+	authority = strings.TrimRight(authority, "/") + "/" + tenant + "/oauth2/v2.0/token"
+
+	// the resource of managed identity is the scope without "/.default".
+	resource := strings.TrimSuffix(scope, "/.default")
+
+	var req *http.Request
+	switch {
+	case tenant != "" && clientID != "" && os.Getenv("AZURE_CLIENT_SECRET") != "":
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {os.Getenv("AZURE_CLIENT_SECRET")},
+			"scope":         {scope},
+		}
+		req, err = http.NewRequest(http.MethodPost, authority, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case tenant != "" && clientID != "" && os.Getenv("AZURE_FEDERATED_TOKEN_FILE") != "":
+		var assertion []byte
+		if assertion, err = os.ReadFile(os.Getenv("AZURE_FEDERATED_TOKEN_FILE")); err != nil {
+			return
+		}
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {clientID},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {string(bytes.TrimSpace(assertion))},
+			"scope":                 {scope},
+		}
+		req, err = http.NewRequest(http.MethodPost, authority, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case os.Getenv("IDENTITY_ENDPOINT") != "" && os.Getenv("IDENTITY_HEADER") != "":
+		query := url.Values{"api-version": {"2019-08-01"}, "resource": {resource}}
+		if clientID != "" {
+			query.Set("client_id", clientID)
+		}
+		req, err = http.NewRequest(http.MethodGet, os.Getenv("IDENTITY_ENDPOINT")+"?"+query.Encode(), nil)
+		if err == nil {
+			req.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+		}
+	default:
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}
+		if clientID != "" {
+			query.Set("client_id", clientID)
+		}
+		req, err = http.NewRequest(http.MethodGet, "http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(), nil)
+		if err == nil {
+			req.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	data, err := httpDo(client, req, 64*1024)
+	if err != nil {
+		return
+	}
+
+	// the expires_in is a number of entra id, and a string of managed identity.
+	var resp struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+		ExpiresOn   json.Number `json:"expires_on"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return
+	}
+	if resp.AccessToken == "" {
+		return token, errors.New("log: azure token response without access_token")
+	}
+
+	token.AccessToken = resp.AccessToken
+	if n, _ := resp.ExpiresIn.Int64(); n > 0 {
+		token.Expires = timeNow().Add(time.Duration(n) * time.Second)
+	} else if n, _ := resp.ExpiresOn.Int64(); n > 0 {
+		token.Expires = time.Unix(n, 0)
+	}
+	return
+}
+
+// azureTokenSource caches the access token of scope.
+type azureTokenSource struct {
+	scope string
+	token func() (AzureToken, error)
+
+	mu     sync.Mutex
+	cached AzureToken
+}
+
+// get returns the cached token until 5 minutes before expiration.
+func (s *azureTokenSource) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached.AccessToken == "" || (!s.cached.Expires.IsZero() && timeNow().Add(5*time.Minute).After(s.cached.Expires)) {
+		var token AzureToken
+		var err error
+		if s.token != nil {
+			token, err = s.token()
+		} else {
+			token, err = AzureEnvToken(s.scope)
+		}
+		if err != nil {
+			return "", err
+		}
+		s.cached = token
+	}
+	return s.cached.AccessToken, nil
+}
+
+// invalidate drops the cached token, e.g. the token is revoked.
+func (s *azureTokenSource) invalidate() {
+	s.mu.Lock()
+	s.cached = AzureToken{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAzureEnvTokenClientSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mytenant/oauth2/v2.0/token" {
+			t.Errorf("azure token path mismatch: %s", r.URL.Path)
+		}
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "myclient" ||
+			r.Form.Get("client_secret") != "mysecret" || r.Form.Get("scope") != "https://monitor.azure.com/.default" {
+			t.Errorf("azure token form mismatch: %v", r.Form)
+		}
+		_, _ = rw.Write([]byte(`{"token_type":"Bearer","expires_in":3599,"access_token":"TOKEN"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	t.Setenv("AZURE_TENANT_ID", "mytenant")
+	t.Setenv("AZURE_CLIENT_ID", "myclient")
+	t.Setenv("AZURE_CLIENT_SECRET", "mysecret")
+
+	token, err := AzureEnvToken("https://monitor.azure.com/.default")
+	if err != nil {
+		t.Fatalf("azure token error: %+v", err)
+	}
+	if token.AccessToken != "TOKEN" || time.Until(token.Expires) < 59*time.Minute {
+		t.Errorf("azure token mismatch: %+v", token)
+	}
+}
+
+func TestAzureEnvTokenWorkloadIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_assertion") != "JWT" || r.Form.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+			t.Errorf("azure token form mismatch: %v", r.Form)
+		}
+		_, _ = rw.Write([]byte(`{"token_type":"Bearer","expires_in":3599,"access_token":"TOKEN"}`))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("JWT\n"), 0644); err != nil {
+		t.Fatalf("write token file error: %+v", err)
+	}
+	t.Setenv("AZURE_AUTHORITY_HOST", server.URL)
+	t.Setenv("AZURE_TENANT_ID", "mytenant")
+	t.Setenv("AZURE_CLIENT_ID", "myclient")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", file)
+
+	if token, err := AzureEnvToken("https://monitor.azure.com/.default"); err != nil || token.AccessToken != "TOKEN" {
+		t.Errorf("azure token mismatch: %+v %+v", token, err)
+	}
+}
+
+func TestAzureEnvTokenAppService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-IDENTITY-HEADER") != "secret" || r.URL.Query().Get("resource") != "https://monitor.azure.com" {
+			t.Errorf("azure token request mismatch: %v %v", r.Header, r.URL)
+		}
+		_, _ = rw.Write([]byte(`{"access_token":"TOKEN","expires_on":"4102444800","resource":"https://monitor.azure.com","token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+	t.Setenv("IDENTITY_ENDPOINT", server.URL)
+	t.Setenv("IDENTITY_HEADER", "secret")
+
+	token, err := AzureEnvToken("https://monitor.azure.com/.default")
+	if err != nil {
+		t.Fatalf("azure token error: %+v", err)
+	}
+	if token.AccessToken != "TOKEN" || token.Expires.Unix() != 4102444800 {
+		t.Errorf("azure token mismatch: %+v", token)
+	}
+}
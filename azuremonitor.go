@@ -0,0 +1,157 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AzureMonitorWriter is a Writer that sends entries in batches to the Logs Ingestion API of
+// Azure Monitor by a data collection rule, the requests are authorized by the access token
+// of microsoft entra id. Each entry is sent as a record with TimeGenerated column, and the
+// fields of entry are renamed to the columns of table by Columns.
+type AzureMonitorWriter struct {
+	// Endpoint specifies the logs ingestion endpoint of data collection endpoint or rule,
+	// e.g. "https://my-dce-abcd.eastus-1.ingest.monitor.azure.com".
+	Endpoint string
+
+	// RuleID specifies the immutable id of data collection rule, e.g. "dcr-00000000000000000000000000000000".
+	RuleID string
+
+	// StreamName specifies the stream of data collection rule, e.g. "Custom-MyTable_CL".
+	StreamName string
+
+	// Columns specifies the column names of entry fields, the fields mapped to empty
+	// column are dropped, and the unmapped fields are kept as is.
+	Columns map[string]string
+
+	// Token specifies the access token provider, uses AzureEnvToken of "https://monitor.azure.com/.default" if nil.
+	Token func() (AzureToken, error)
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 500 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once    sync.Once
+	batcher batcher
+	tokens  azureTokenSource
+	url     string
+}
+
+// azureMonitorMaxBytes is the maximum bytes of a request of logs ingestion api with headroom
+// of the TimeGenerated columns.
+const azureMonitorMaxBytes = 1024*1024 - 128*1024
+
+func (w *AzureMonitorWriter) init() {
+	w.once.Do(func() {
+		w.url = w.Endpoint + "/dataCollectionRules/" + url.PathEscape(w.RuleID) +
+			"/streams/" + url.PathEscape(w.StreamName) + "?api-version=2023-01-01"
+
+		w.tokens.scope = "https://monitor.azure.com/.default"
+		w.tokens.token = w.Token
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 500
+		}
+		w.batcher.bytes = azureMonitorMaxBytes
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *AzureMonitorWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *AzureMonitorWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *AzureMonitorWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	dst := append(buf.B[:0], '[')
+	for i := 0; i < b.Len(); i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		entry := b.Entry(i)
+		ts, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+		if !ok {
+			ts = b.times[i]
+		}
+		dst = append(dst, "{\"TimeGenerated\":\""...)
+		dst = time.Unix(0, ts).UTC().AppendFormat(dst, time.RFC3339Nano)
+		dst = append(dst, '"')
+		jsonRange(entry, func(key, value []byte) bool {
+			if column, ok := w.Columns[b2s(key)]; ok {
+				if column == "" {
+					return true
+				}
+				dst = append(dst, ',')
+				dst = jsonAppendString(dst, column)
+			} else {
+				dst = append(dst, ",\""...)
+				dst = append(dst, key...)
+				dst = append(dst, '"')
+			}
+			dst = append(dst, ':')
+			dst = append(dst, value...)
+			return true
+		})
+		dst = append(dst, '}')
+	}
+	dst = append(dst, ']')
+	buf.B = dst
+
+	body := buf.B
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+		header.Set("Content-Encoding", "gzip")
+	}
+
+	token, err := w.tokens.get()
+	if err != nil {
+		return err
+	}
+	header.Set("Authorization", "Bearer "+token)
+
+	err = httpSend(w.Client, http.MethodPost, w.url, header, body)
+	var e *httpStatusError
+	if errors.As(err, &e) && e.StatusCode == http.StatusUnauthorized {
+		// the token is expired or revoked, retries with a new token.
+		w.tokens.invalidate()
+		err = e
+	}
+	return err
+}
+
+var _ Writer = (*AzureMonitorWriter)(nil)
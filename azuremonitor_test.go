@@ -0,0 +1,67 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAzureMonitorWriter(t *testing.T) {
+	var mu sync.Mutex
+	var records []map[string]interface{}
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dataCollectionRules/dcr-123/streams/Custom-AppLogs_CL" || r.URL.Query().Get("api-version") != "2023-01-01" {
+			t.Errorf("azure monitor writer url mismatch: %s", r.URL)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		auths = append(auths, r.Header.Get("Authorization"))
+		if len(auths) == 1 {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("azure monitor writer json error: %+v", err)
+		}
+		records = append(records, req...)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var n int
+	w := &AzureMonitorWriter{
+		Endpoint:   server.URL,
+		RuleID:     "dcr-123",
+		StreamName: "Custom-AppLogs_CL",
+		Columns:    map[string]string{"message": "Message", "level": "Level", "goid": ""},
+		Token: func() (AzureToken, error) {
+			n++
+			return AzureToken{AccessToken: "TOKEN" + string(rune('0'+n)), Expires: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Int("goid", 1).Msg("hello azure")
+	if err := w.Close(); err != nil {
+		t.Fatalf("azure monitor writer close error: %+v", err)
+	}
+
+	if len(auths) != 2 || auths[0] != "Bearer TOKEN1" || auths[1] != "Bearer TOKEN2" {
+		t.Errorf("azure monitor writer should refresh token after 401: %v", auths)
+	}
+	if len(records) != 1 {
+		t.Fatalf("azure monitor writer want 1 record got %d", len(records))
+	}
+	r := records[0]
+	if r["Message"] != "hello azure" || r["Level"] != "info" || r["foo"] != "bar" || r["goid"] != nil {
+		t.Errorf("azure monitor writer record mismatch: %v", r)
+	}
+	if ts, _ := r["TimeGenerated"].(string); ts == "" || ts[len(ts)-1] != 'Z' {
+		t.Errorf("azure monitor writer TimeGenerated mismatch: %v", r["TimeGenerated"])
+	}
+}
@@ -0,0 +1,382 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batch is a group of entries to be sent together.
+type batch struct {
+	buf    []byte
+	ends   []int
+	levels []Level
+	times  []int64
+}
+
+// Len returns the number of entries in batch.
+func (b *batch) Len() int {
+	return len(b.ends)
+}
+
+// Entry returns the i-th entry of batch.
+func (b *batch) Entry(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = b.ends[i-1]
+	}
+	return b.buf[start:b.ends[i]]
+}
+
+func (b *batch) append(e *Entry, now int64) {
+	b.buf = append(b.buf, e.buf...)
+	b.ends = append(b.ends, len(b.buf))
+	b.levels = append(b.levels, e.Level)
+	b.times = append(b.times, now)
+}
+
+// filter keeps the entries of batch which keep returns true in place,
+// the i is the original index of entry.
+func (b *batch) filter(keep func(i int, entry []byte) bool) {
+	n, j, start := 0, 0, 0
+	for i := 0; i < len(b.ends); i++ {
+		end := b.ends[i]
+		if keep(i, b.buf[start:end]) {
+			n += copy(b.buf[n:], b.buf[start:end])
+			b.ends[j], b.levels[j], b.times[j] = n, b.levels[i], b.times[i]
+			j++
+		}
+		start = end
+	}
+	b.buf, b.ends, b.levels, b.times = b.buf[:n], b.ends[:j], b.levels[:j], b.times[:j]
+}
+
+func (b *batch) reset() {
+	b.buf = b.buf[:0]
+	b.ends = b.ends[:0]
+	b.levels = b.levels[:0]
+	b.times = b.times[:0]
+}
+
+var batchpool = sync.Pool{
+	New: func() interface{} {
+		return new(batch)
+	},
+}
+
+// permanentError marks an error of batch which should not be retried.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+// batcher accumulates entries and sends them in batches by a background goroutine,
+// the batches are retried with exponential backoff on failure.
+type batcher struct {
+	// size specifies the maximum entries of a batch.
+	size int
+	// bytes specifies the maximum bytes of a batch, zero means no limit.
+	bytes int
+	// interval specifies the flush interval of the pending batch.
+	interval time.Duration
+	// retries specifies the maximum retries of a failed batch.
+	retries int
+	// backoff specifies the initial retry delay of a failed batch.
+	backoff time.Duration
+	// send sends the batch, the error wrapped in permanentError is not retried.
+	send func(b *batch) error
+	// fail is called when the batch is failed after all retries, optional.
+	fail func(b *batch, err error)
+
+	once      sync.Once
+	closeOnce sync.Once
+	mu        sync.Mutex
+	current   *batch
+	closed    bool
+	err       error
+	closeErr  error
+	ch        chan *batch
+	chClose   chan error
+	sending   sync.WaitGroup
+}
+
+// ErrClosed is returned by the writers after Close, it matches os.ErrClosed by errors.Is.
+var ErrClosed error = closedError{}
+
+type closedError struct{}
+
+func (closedError) Error() string { return "log: writer is closed" }
+
+func (closedError) Is(target error) bool { return target == os.ErrClosed }
+
+func (w *batcher) start() {
+	w.once.Do(func() {
+		if w.size <= 0 {
+			w.size = 100
+		}
+		if w.interval <= 0 {
+			w.interval = time.Second
+		}
+		if w.backoff <= 0 {
+			w.backoff = 100 * time.Millisecond
+		}
+		w.ch = make(chan *batch, 1)
+		w.chClose = make(chan error)
+		go w.run()
+	})
+}
+
+func (w *batcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case b := <-w.ch:
+			if b == nil {
+				if b = w.take(); b != nil {
+					w.flush(b)
+				}
+				w.chClose <- w.err
+				return
+			}
+			w.flush(b)
+		case <-ticker.C:
+			if b := w.take(); b != nil {
+				w.flush(b)
+			}
+		}
+	}
+}
+
+// take returns the pending batch if any.
+func (w *batcher) take() (b *batch) {
+	w.mu.Lock()
+	b, w.current = w.current, nil
+	w.mu.Unlock()
+	return
+}
+
+// write appends the entry to pending batch, it blocks if the background goroutine
+// is busy and the pending batch is full. It returns ErrClosed after close.
+func (w *batcher) write(e *Entry) error {
+	w.start()
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	b := w.current
+	if b == nil {
+		b = batchpool.Get().(*batch)
+		b.reset()
+		w.current = b
+	}
+	b.append(e, timeNow().UnixNano())
+	full := b.Len() >= w.size || (w.bytes > 0 && len(b.buf) >= w.bytes)
+	if full {
+		// the sending is counted under lock, close waits for it before the sentinel.
+		w.current = nil
+		w.sending.Add(1)
+	}
+	w.mu.Unlock()
+
+	if full {
+		w.ch <- b
+		w.sending.Done()
+	}
+	return nil
+}
+
+// close flushes the pending batch and stops the background goroutine, the later
+// calls return the error of first call.
+func (w *batcher) close() error {
+	w.start()
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		w.sending.Wait()
+		w.ch <- nil
+		w.closeErr = <-w.chClose
+	})
+	return w.closeErr
+}
+
+func (w *batcher) flush(b *batch) {
+	delay := w.backoff
+	for i := 0; ; i++ {
+		err := w.send(b)
+		if err == nil {
+			break
+		}
+		var perm permanentError
+		if i >= w.retries || errors.As(err, &perm) {
+			w.err = err
+			if w.fail != nil {
+				w.fail(b, err)
+			}
+			break
+		}
+		time.Sleep(delay + time.Duration(Fastrandn(uint32(delay/time.Millisecond)+1))*time.Millisecond)
+		if delay *= 2; delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+	}
+	if cap(b.buf) <= 8*bbcap {
+		batchpool.Put(b)
+	}
+}
+
+// batchRetries returns the retries of batcher, it uses 3 if zero, and disables retries if negative.
+func batchRetries(n int) int {
+	switch {
+	case n == 0:
+		return 3
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// httpStatusError is the error of http response with unexpected status code.
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return "log: unexpected http status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// httpSend sends the body to url, the non-2xx response is reported as error, and
+// it is wrapped in permanentError unless the status code is 429 or 5xx.
+func httpSend(client *http.Client, method, url string, header http.Header, body []byte) error {
+	_, err := httpRequest(client, method, url, header, body, 0)
+	return err
+}
+
+// httpRequest is like httpSend, and returns the response body of 2xx up to limit bytes.
+func httpRequest(client *http.Client, method, url string, header http.Header, body []byte, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return httpDo(client, req, limit)
+}
+
+// httpDo is like httpRequest with the prepared request.
+func httpDo(client *http.Client, req *http.Request, limit int64) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if limit > 0 {
+			return io.ReadAll(io.LimitReader(resp.Body, limit))
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return nil, nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = &httpStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(data))}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		err = permanentError{err}
+	}
+	return nil, err
+}
+
+var gzpool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipAppend appends the gzip compressed data to dst.
+func gzipAppend(dst, data []byte) []byte {
+	b := bb{B: dst}
+	zw := gzpool.Get().(*gzip.Writer)
+	zw.Reset(&b)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	gzpool.Put(zw)
+	return b.B
+}
+
+// parseEntryTime parses the time field of entry to unix nanoseconds, it supports
+// RFC3339 and the unix timestamps of TimeFormatUnix/TimeFormatUnixMs/TimeFormatUnixWithMs.
+func parseEntryTime(s string) (int64, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	if s[0] < '0' || s[0] > '9' || (len(s) > 4 && s[4] == '-') {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return 0, false
+		}
+		return t.UnixNano(), true
+	}
+	if strings.IndexByte(s, '.') > 0 {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false
+		}
+		return int64(f * 1e9), true
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case len(s) <= 10:
+		n *= 1e9
+	case len(s) <= 13:
+		n *= 1e6
+	case len(s) <= 16:
+		n *= 1e3
+	}
+	return n, true
+}
+
+// jsonAppendString appends the json quoted string s to dst.
+func jsonAppendString(dst []byte, s string) []byte {
+	e := Entry{buf: append(dst, '"')}
+	e.string(s)
+	return append(e.buf, '"')
+}
+
+// jsonAppendBytes appends the json quoted string b to dst.
+func jsonAppendBytes(dst []byte, b []byte) []byte {
+	e := Entry{buf: append(dst, '"')}
+	e.bytes(b)
+	return append(e.buf, '"')
+}
+
+// jsonFieldString returns the string value of the given top-level field of json input,
+// the non-string values are returned as raw json.
+func jsonFieldString(json []byte, field string) []byte {
+	value := jsonGetField(json, field)
+	if len(value) >= 2 && value[0] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,213 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	var sizes []int
+	w := &batcher{
+		size:     3,
+		interval: 10 * time.Millisecond,
+		send: func(b *batch) error {
+			sizes = append(sizes, b.Len())
+			for i := 0; i < b.Len(); i++ {
+				if string(b.Entry(i)) != "entry" {
+					t.Errorf("batcher entry mismatch: %q", b.Entry(i))
+				}
+			}
+			return nil
+		},
+	}
+
+	e := &Entry{buf: []byte("entry"), Level: InfoLevel}
+	for i := 0; i < 7; i++ {
+		w.write(e)
+	}
+	if err := w.close(); err != nil {
+		t.Errorf("batcher close error: %+v", err)
+	}
+
+	var total int
+	for _, n := range sizes {
+		if n > 3 {
+			t.Errorf("batcher batch size exceeded: %v", sizes)
+		}
+		total += n
+	}
+	if total != 7 {
+		t.Errorf("batcher should send 7 entries, got %d: %v", total, sizes)
+	}
+}
+
+func TestBatcherRetry(t *testing.T) {
+	var calls, failed int
+	w := &batcher{
+		size:    1,
+		retries: 2,
+		backoff: time.Millisecond,
+		send: func(b *batch) error {
+			calls++
+			return errors.New("temporary error")
+		},
+		fail: func(b *batch, err error) {
+			failed += b.Len()
+		},
+	}
+
+	w.write(&Entry{buf: []byte("entry")})
+	if err := w.close(); err == nil {
+		t.Errorf("batcher close should return last error")
+	}
+	if calls != 3 || failed != 1 {
+		t.Errorf("batcher should retry 2 times, calls=%d failed=%d", calls, failed)
+	}
+
+	calls, failed = 0, 0
+	w = &batcher{
+		size:    1,
+		retries: 2,
+		send: func(b *batch) error {
+			calls++
+			return permanentError{errors.New("permanent error")}
+		},
+	}
+	w.write(&Entry{buf: []byte("entry")})
+	_ = w.close()
+	if calls != 1 {
+		t.Errorf("batcher should not retry permanent error, calls=%d", calls)
+	}
+}
+
+func TestBatcherClose(t *testing.T) {
+	sendErr := permanentError{errors.New("permanent error")}
+	w := &batcher{
+		size: 1,
+		send: func(b *batch) error {
+			return sendErr
+		},
+	}
+
+	e := &Entry{buf: []byte("entry"), Level: InfoLevel}
+	if err := w.write(e); err != nil {
+		t.Errorf("batcher write error: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.close(); err != sendErr {
+			t.Errorf("batcher close #%d should return the send error, got %+v", i+1, err)
+		}
+	}
+	if err := w.write(e); err != ErrClosed || !errors.Is(err, os.ErrClosed) {
+		t.Errorf("batcher write after close should return ErrClosed, got %+v", err)
+	}
+}
+
+func TestBatcherCloseWriting(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		var sent, written int64
+		w := &batcher{
+			size: 1,
+			send: func(b *batch) error {
+				atomic.AddInt64(&sent, int64(b.Len()))
+				time.Sleep(100 * time.Microsecond)
+				return nil
+			},
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e := &Entry{buf: []byte("entry")}
+				for w.write(e) == nil {
+					atomic.AddInt64(&written, 1)
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		_ = w.close()
+		wg.Wait()
+
+		if sent != written {
+			t.Fatalf("batcher must send the written entries before close, sent=%d written=%d", sent, written)
+		}
+	}
+}
+
+func TestBatchFilter(t *testing.T) {
+	b := new(batch)
+	for _, s := range []string{"a", "bb", "ccc", "dddd"} {
+		b.append(&Entry{buf: []byte(s)}, 0)
+	}
+	b.filter(func(i int, entry []byte) bool {
+		return len(entry)%2 == 0
+	})
+	if b.Len() != 2 || string(b.Entry(0)) != "bb" || string(b.Entry(1)) != "dddd" {
+		t.Errorf("batch filter mismatch: %q", b.buf)
+	}
+}
+
+func TestHTTPSend(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" {
+			t.Errorf("httpSend should send headers: %v", r.Header)
+		}
+		rw.WriteHeader(int(atomic.LoadInt32(&status)))
+		_, _ = rw.Write([]byte("oops"))
+	}))
+	defer server.Close()
+
+	header := http.Header{"X-Test": []string{"1"}}
+	if err := httpSend(nil, http.MethodPost, server.URL, header, []byte("{}")); err != nil {
+		t.Errorf("httpSend error: %+v", err)
+	}
+
+	var perm permanentError
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	if err := httpSend(nil, http.MethodPost, server.URL, header, nil); !errors.As(err, &perm) {
+		t.Errorf("httpSend should return permanent error for 400: %+v", err)
+	}
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	if err := httpSend(nil, http.MethodPost, server.URL, header, nil); err == nil || errors.As(err, &perm) {
+		t.Errorf("httpSend should return temporary error for 503: %+v", err)
+	}
+}
+
+func TestParseEntryTime(t *testing.T) {
+	cases := []struct {
+		Time string
+		Nano int64
+	}{
+		{"2020-07-12T05:03:43.949Z", 1594530223949000000},
+		{"2020-07-12T05:03:43Z", 1594530223000000000},
+		{"1594530223", 1594530223000000000},
+		{"1594530223949", 1594530223949000000},
+		{"1594530223.949", 1594530223949000000},
+	}
+
+	for _, c := range cases {
+		n, ok := parseEntryTime(c.Time)
+		if !ok {
+			t.Errorf("parseEntryTime(%q) failed", c.Time)
+		}
+		if d := n - c.Nano; d > 1000 || d < -1000 {
+			t.Errorf("parseEntryTime(%q) = %d, want %d", c.Time, n, c.Nano)
+		}
+	}
+
+	for _, s := range []string{"", "abc", "12:34"} {
+		if _, ok := parseEntryTime(s); ok {
+			t.Errorf("parseEntryTime(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"math/big"
+	"unsafe"
+)
+
+// BigInt adds the field key with i as a string of decimal digits to the entry, or null
+// if i is nil, so the value keeps the precision beyond float64.
+func (e *Entry) BigInt(key string, i *big.Int) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if i == nil {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = i.Append(e.buf, 10)
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// BigFloat adds the field key with f as a string of decimal number to the entry, or null
+// if f is nil. The number has the smallest digits that represents f exactly at its precision.
+func (e *Entry) BigFloat(key string, f *big.Float) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if f == nil {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = f.Append(e.buf, 'f', -1)
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// BigRat adds the field key with r as a string of decimal number rounded to prec digits
+// after the decimal point to the entry, or null if r is nil.
+func (e *Entry) BigRat(key string, r *big.Rat, prec int) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if r == nil {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = append(e.buf, r.FloatString(prec)...)
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// Decimal adds the field key with the String of d as a string of decimal number to the
+// entry, or null if d is nil, e.g. the decimal.Decimal of shopspring.
+func (e *Entry) Decimal(key string, d fmt.Stringer) *Entry {
+	if e == nil {
+		return nil
+	}
+	if d == nil || (*[2]uintptr)(unsafe.Pointer(&d))[1] == 0 {
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, key...)
+		e.buf = append(e.buf, '"', ':')
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	return e.Str(key, d.String())
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type testDecimal struct {
+	s string
+}
+
+func (d testDecimal) String() string { return d.s }
+
+func TestEntryBig(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	i, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	f, _ := new(big.Float).SetPrec(200).SetString("12345678901234567890.125")
+	r := big.NewRat(1, 3)
+	var nilDecimal *big.Int
+
+	logger.Info().
+		BigInt("int", i).
+		BigInt("nil_int", nil).
+		BigFloat("float", f).
+		BigFloat("nil_float", nil).
+		BigRat("rat", r, 4).
+		BigRat("nil_rat", nil, 4).
+		Decimal("decimal", testDecimal{"0.10"}).
+		Decimal("nil_decimal", nilDecimal).
+		Decimal("nil_stringer", nil).
+		Msg("")
+
+	want := `"int":"-123456789012345678901234567890","nil_int":null,"float":"12345678901234567890.125","nil_float":null,"rat":"0.3333","nil_rat":null,"decimal":"0.10","nil_decimal":null,"nil_stringer":null}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("big output got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// BridgeWriter is an io.Writer that dispatches json lines produced by other structured
+// loggers to Writer, with the level parsed from the LevelField of each line.
+//
+// It allows the existing instrumented codebases funnel their output through the
+// writers of phuslog (FileWriter, AsyncWriter, SyslogWriter) without rewriting call sites,
+// e.g. for go.uber.org/zap, the fields and sampling decisions are handled by zap itself.
+//
+// Note that a ZapCore which implements zapcore.Core is not provided, because this module
+// has no dependencies and zapcore.Core refers to the types of zap. So the bridged entries
+// are the json output of zap re-parsed for the level, and the field types are those of
+// json. The Check, With and Sync of zap are done by its own JSON core.
+//
+//	core := zapcore.NewCore(
+//		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+//		zapcore.AddSync(&log.BridgeWriter{Writer: &log.FileWriter{Filename: "main.log"}}),
+//		zap.InfoLevel,
+//	)
+//	logger := zap.New(core)
+type BridgeWriter struct {
+	// LevelField specifies the level field name of json input, uses "level" if empty.
+	LevelField string
+
+	// Writer specifies the writer of output. It uses a wrapped os.Stderr Writer in if empty.
+	Writer Writer
+}
+
+// Write implements io.Writer.
+func (w *BridgeWriter) Write(p []byte) (n int, err error) {
+	field := w.LevelField
+	if field == "" {
+		field = "level"
+	}
+	return w.WriteLevel(bridgeLevel(jsonGetField(p, field)), p)
+}
+
+// WriteLevel writes p with the level, it is for the leveled writers of other loggers, e.g.
+// for the zerolog.LevelWriter of github.com/rs/zerolog
+//
+//	type zerologWriter struct{ *log.BridgeWriter }
+//
+//	func (w zerologWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+//		return w.BridgeWriter.WriteLevel(log.ZerologLevel(int8(level)), p)
+//	}
+//
+//	logger := zerolog.New(zerologWriter{&log.BridgeWriter{Writer: &log.FileWriter{Filename: "main.log"}}})
+func (w *BridgeWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	e := epool.Get().(*Entry)
+	e.buf = append(e.buf[:0], p...)
+	e.Level = level
+	e.name = ""
+	e.w = nil
+	e.durfmt = ""
+	e.hooks = nil
+
+	if w.Writer != nil {
+		_, err = w.Writer.WriteEntry(e)
+	} else {
+		_, err = IOWriter{os.Stderr}.WriteEntry(e)
+	}
+
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+
+	if err == nil {
+		n = len(p)
+	}
+	return
+}
+
+// ZerologLevel converts the level of github.com/rs/zerolog to Level.
+func ZerologLevel(level int8) Level {
+	switch level {
+	case -1:
+		return TraceLevel
+	case 0:
+		return DebugLevel
+	case 1:
+		return InfoLevel
+	case 2:
+		return WarnLevel
+	case 3:
+		return ErrorLevel
+	case 4:
+		return FatalLevel
+	case 5:
+		return PanicLevel
+	}
+	return noLevel
+}
+
+// LogrusLevel converts the level of github.com/sirupsen/logrus to Level.
+func LogrusLevel(level uint32) Level {
+	switch level {
+	case 0:
+		return PanicLevel
+	case 1:
+		return FatalLevel
+	case 2:
+		return ErrorLevel
+	case 3:
+		return WarnLevel
+	case 4:
+		return InfoLevel
+	case 5:
+		return DebugLevel
+	case 6:
+		return TraceLevel
+	}
+	return noLevel
+}
+
+// LogrusBridge logs the entries of github.com/sirupsen/logrus by Logger, the level of Logger
+// also applies to the entries. The logrus.Hook is not provided, because this module has no
+// dependencies and logrus.Hook refers to the types of logrus, it is a few lines by Log, e.g.
+//
+//	type logrusHook struct{ *log.LogrusBridge }
+//
+//	func (logrusHook) Levels() []logrus.Level { return logrus.AllLevels }
+//
+//	func (h logrusHook) Fire(e *logrus.Entry) error {
+//		return h.Log(uint32(e.Level), e.Message, e.Data)
+//	}
+//
+//	logrus.AddHook(logrusHook{&log.LogrusBridge{Logger: &log.DefaultLogger}})
+//	logrus.SetOutput(io.Discard)
+type LogrusBridge struct {
+	// Logger specifies the logger of entries, uses DefaultLogger if nil.
+	Logger *Logger
+}
+
+// Log logs the message and data of a logrus entry, the data keys are sorted. The fatal and
+// panic entries are logged at their levels but left to logrus to exit and panic.
+func (b *LogrusBridge) Log(level uint32, msg string, data map[string]interface{}) error {
+	logger := b.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+
+	e := logger.WithLevel(LogrusLevel(level))
+	if e == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err, ok := data[k].(error); ok {
+			e = e.AnErr(k, err)
+		} else {
+			e = e.Any(k, data[k])
+		}
+	}
+
+	// write the entry at its level without the exit or panic of Msg.
+	e.write(msg)
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+	return nil
+}
+
+// bridgeLevel converts the level value of other loggers to Level.
+func bridgeLevel(value []byte) (level Level) {
+	if len(value) < 2 || value[0] != '"' {
+		return noLevel
+	}
+	s := b2s(value[1 : len(value)-1])
+	switch s {
+	case "dpanic", "DPANIC": // zap
+		level = ErrorLevel
+	default:
+		level = ParseLevel(s)
+	}
+	return
+}
+
+var _ io.Writer = (*BridgeWriter)(nil)
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type levelRecorder []Level
+
+func (r *levelRecorder) WriteEntry(e *Entry) (int, error) {
+	*r = append(*r, e.Level)
+	return len(e.buf), nil
+}
+
+func TestBridgeWriterZap(t *testing.T) {
+	var levels levelRecorder
+	w := &BridgeWriter{Writer: &levels}
+
+	for _, level := range []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal", "unknown"} {
+		line := fmt.Sprintf(`{"level":"%s","ts":1699000000.123,"caller":"main.go:42","msg":"hello zap","n":42}`+"\n", level)
+		n, err := w.Write([]byte(line))
+		if err != nil || n != len(line) {
+			t.Errorf("bridge writer write error: n=%d err=%+v", n, err)
+		}
+	}
+
+	want := []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, ErrorLevel, PanicLevel, FatalLevel, noLevel}
+	if fmt.Sprint(levels) != fmt.Sprint(want) {
+		t.Errorf("bridge writer levels got %v want %v", levels, want)
+	}
+}
+
+func TestBridgeWriterLevelField(t *testing.T) {
+	var levels levelRecorder
+	w := &BridgeWriter{LevelField: "severity", Writer: &levels}
+
+	_, _ = w.Write([]byte(`{"severity":"warning","message":"hello"}` + "\n"))
+	_, _ = w.Write([]byte("a plain text line\n"))
+
+	if fmt.Sprint(levels) != fmt.Sprint([]Level{WarnLevel, noLevel}) {
+		t.Errorf("bridge writer levels mismatched: %v", levels)
+	}
+}
+
+func TestBridgeWriterZerolog(t *testing.T) {
+	var levels levelRecorder
+	w := &BridgeWriter{Writer: &levels}
+
+	for level := int8(-1); level <= 6; level++ {
+		_, _ = w.WriteLevel(ZerologLevel(level), []byte(`{"level":"x","message":"hello zerolog"}`+"\n"))
+	}
+
+	want := []Level{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, FatalLevel, PanicLevel, noLevel}
+	if fmt.Sprint(levels) != fmt.Sprint(want) {
+		t.Errorf("bridge writer zerolog levels got %v want %v", levels, want)
+	}
+}
+
+func TestLogrusBridge(t *testing.T) {
+	var b bytes.Buffer
+	bridge := &LogrusBridge{Logger: &Logger{Level: InfoLevel, Writer: IOWriter{&b}}}
+
+	_ = bridge.Log(5, "hello debug", nil)
+	if b.Len() != 0 {
+		t.Errorf("logrus bridge must follow the level of logger: %s", b.String())
+	}
+
+	_ = bridge.Log(3, "hello logrus", map[string]interface{}{"user": "alice", "n": 42, "error": errors.New("boom")})
+	s := b.String()
+	for _, v := range []string{`"level":"warn"`, `"error":"boom","n":42,"user":"alice"`, `"message":"hello logrus"`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("logrus bridge log must contain %s: %s", v, s)
+		}
+	}
+
+	b.Reset()
+	_ = bridge.Log(0, "hello panic", nil)
+	if s = b.String(); !strings.Contains(s, `"level":"panic"`) {
+		t.Errorf("logrus bridge must log panic entries: %s", s)
+	}
+}
+
+func TestLogrusBridgeLevel(t *testing.T) {
+	var levels levelRecorder
+	bridge := &LogrusBridge{Logger: &Logger{Level: TraceLevel, Writer: &levels}}
+
+	for level := uint32(0); level <= 6; level++ {
+		_ = bridge.Log(level, "hello logrus", nil)
+	}
+
+	want := []Level{PanicLevel, FatalLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel, TraceLevel}
+	if fmt.Sprint(levels) != fmt.Sprint(want) {
+		t.Errorf("logrus bridge levels got %v want %v", levels, want)
+	}
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"math"
+)
+
+// CBOREncoder is an Encoder that encodes entries to CBOR maps of RFC 8949, the entries
+// are concatenated without separators. The floats are encoded in the shortest form which
+// keeps the value.
+type CBOREncoder struct {
+	// Canonical specifies the deterministic encoding of RFC 8949, which sorts the keys of
+	// maps by length and then bytewise, e.g. for signed or audited logs.
+	Canonical bool
+}
+
+// SortedKeys reports whether the keys are sorted, see Encoder.
+func (enc CBOREncoder) SortedKeys() bool { return enc.Canonical }
+
+// BeginEntry implements Encoder.
+func (CBOREncoder) BeginEntry(dst []byte, n int) []byte { return cborAppendHead(dst, 5, uint64(n)) }
+
+// EndEntry implements Encoder.
+func (CBOREncoder) EndEntry(dst []byte) []byte { return dst }
+
+// AppendKey implements Encoder.
+func (CBOREncoder) AppendKey(dst []byte, key string) []byte { return cborAppendString(dst, key) }
+
+// AppendString implements Encoder.
+func (CBOREncoder) AppendString(dst []byte, s string) []byte { return cborAppendString(dst, s) }
+
+// AppendInt implements Encoder.
+func (CBOREncoder) AppendInt(dst []byte, n int64) []byte {
+	if n < 0 {
+		return cborAppendHead(dst, 1, uint64(-1-n))
+	}
+	return cborAppendHead(dst, 0, uint64(n))
+}
+
+// AppendUint implements Encoder.
+func (CBOREncoder) AppendUint(dst []byte, n uint64) []byte { return cborAppendHead(dst, 0, n) }
+
+// AppendFloat implements Encoder.
+func (CBOREncoder) AppendFloat(dst []byte, f float64) []byte { return cborAppendFloat(dst, f) }
+
+// AppendBool implements Encoder.
+func (CBOREncoder) AppendBool(dst []byte, b bool) []byte {
+	if b {
+		return append(dst, 0xf5)
+	}
+	return append(dst, 0xf4)
+}
+
+// AppendNull implements Encoder.
+func (CBOREncoder) AppendNull(dst []byte) []byte { return append(dst, 0xf6) }
+
+// BeginObject implements Encoder.
+func (CBOREncoder) BeginObject(dst []byte, n int) []byte { return cborAppendHead(dst, 5, uint64(n)) }
+
+// EndObject implements Encoder.
+func (CBOREncoder) EndObject(dst []byte) []byte { return dst }
+
+// BeginArray implements Encoder.
+func (CBOREncoder) BeginArray(dst []byte, n int) []byte { return cborAppendHead(dst, 4, uint64(n)) }
+
+// EndArray implements Encoder.
+func (CBOREncoder) EndArray(dst []byte) []byte { return dst }
+
+var _ Encoder = CBOREncoder{}
+
+// cborAppendHead appends the head of major type and argument n in the shortest form.
+func cborAppendHead(dst []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(dst, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(dst, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(dst, major|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func cborAppendString(dst []byte, s string) []byte {
+	dst = cborAppendHead(dst, 3, uint64(len(s)))
+	return append(dst, s...)
+}
+
+// cborAppendFloat appends f as half, single or double precision float, whichever is
+// the shortest without losing precision. The NaNs are appended as the canonical 0xf97e00.
+func cborAppendFloat(dst []byte, f float64) []byte {
+	if f != f {
+		return append(dst, 0xf9, 0x7e, 0x00)
+	}
+	f32 := float32(f)
+	if float64(f32) != f {
+		n := math.Float64bits(f)
+		return append(dst, 0xfb, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if h, ok := cborFloat16(f32); ok {
+		return append(dst, 0xf9, byte(h>>8), byte(h))
+	}
+	n := math.Float32bits(f32)
+	return append(dst, 0xfa, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// cborFloat16 converts f to the bits of half precision float if it is exact.
+func cborFloat16(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		// infinities, the nans are handled by cborAppendFloat
+		return sign | 0x7c00, mant == 0
+	case exp == 0 && mant == 0:
+		return sign, true
+	}
+
+	switch e := exp - 127 + 15; {
+	case e >= 31:
+		return 0, false
+	case e >= 1:
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(e)<<10 | uint16(mant>>13), true
+	default:
+		// subnormal half precision, the value is m * 2^-24
+		shift := uint(126 - exp)
+		full := mant | 0x800000
+		if exp == 0 || shift > 24 || full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+func TestCBOREncoder(t *testing.T) {
+	// the examples of RFC 8949 appendix A
+	cases := []struct {
+		JSON string
+		CBOR string
+	}{
+		{`0`, "00"},
+		{`23`, "17"},
+		{`24`, "1818"},
+		{`1000`, "1903e8"},
+		{`1000000`, "1a000f4240"},
+		{`18446744073709551615`, "1bffffffffffffffff"},
+		{`-1`, "20"},
+		{`-1000`, "3903e7"},
+		{`0.0`, "f90000"},
+		{`-0.0`, "f98000"},
+		{`1.0`, "f93c00"},
+		{`1.1`, "fb3ff199999999999a"},
+		{`1.5`, "f93e00"},
+		{`65504.0`, "f97bff"},
+		{`100000.0`, "fa47c35000"},
+		{`3.4028234663852886e+38`, "fa7f7fffff"},
+		{`1.0e+300`, "fb7e37e43c8800759c"},
+		{`5.960464477539063e-8`, "f90001"},
+		{`0.00006103515625`, "f90400"},
+		{`-4.0`, "f9c400"},
+		{`-4.1`, "fbc010666666666666"},
+		{`false`, "f4"},
+		{`true`, "f5"},
+		{`null`, "f6"},
+		{`""`, "60"},
+		{`"a"`, "6161"},
+		{`"IETF"`, "6449455446"},
+		{`"\"\\"`, "62225c"},
+		{`"ü"`, "62c3bc"},
+		{`[]`, "80"},
+		{`[1,2,3]`, "83010203"},
+		{`[1,[2,3],[4,5]]`, "8301820203820405"},
+		{`{}`, "a0"},
+		{`{"a":1,"b":[2,3]}`, "a26161016162820203"},
+	}
+
+	for _, c := range cases {
+		got := EncodeJSON(CBOREncoder{}, nil, []byte(`{"v":`+c.JSON+`}`))
+		// skips the head of map and key
+		if got := hexEncode(got[3:]); got != c.CBOR {
+			t.Errorf("cbor encoder of %s: got %s, want %s", c.JSON, got, c.CBOR)
+		}
+	}
+}
+
+func TestCBOREncoderCanonical(t *testing.T) {
+	json := []byte(`{"bb":1,"a":{"z":1,"y":2},"b":2,"aa":[{"d":1,"c":2}]}`)
+
+	got := hexEncode(EncodeJSON(CBOREncoder{Canonical: true}, nil, json))
+	want := "a4" + "6161" + "a2" + "6179" + "02" + "617a" + "01" + "6162" + "02" +
+		"626161" + "81" + "a2" + "6163" + "02" + "6164" + "01" + "626262" + "01"
+	if got != want {
+		t.Errorf("canonical cbor encoder: got %s, want %s", got, want)
+	}
+
+	got = hexEncode(EncodeJSON(CBOREncoder{}, nil, []byte(`{"b":1,"a":2}`)))
+	if want = "a2616201616102"; got != want {
+		t.Errorf("cbor encoder should keep the order of keys: got %s, want %s", got, want)
+	}
+
+	got = hexEncode(EncodeJSON(CBOREncoder{Canonical: true}, nil, []byte(`{"b\u0061":1,"a":2,"c":3}`)))
+	if want = "a361610261630362626101"; got != want {
+		t.Errorf("canonical cbor encoder should sort the unescaped keys: got %s, want %s", got, want)
+	}
+
+	if raceEnabled {
+		return
+	}
+	dst := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = EncodeJSON(CBOREncoder{Canonical: true}, dst[:0], json)
+	})
+	if allocs != 0 {
+		t.Errorf("canonical cbor encoder should not allocate, got %v allocs", allocs)
+	}
+}
+
+func TestCBORFloat16(t *testing.T) {
+	cases := []struct {
+		F     float32
+		Half  uint16
+		Exact bool
+	}{
+		{1, 0x3c00, true},
+		{-2, 0xc000, true},
+		{65504, 0x7bff, true},
+		{65536, 0, false},
+		{1.0 / 3, 0, false},
+		{float32(math.Inf(-1)), 0xfc00, true},
+		{5.960464477539063e-8, 0x0001, true},
+		{2.98e-8, 0, false},
+		{3 * 5.960464477539063e-8, 0x0003, true},
+	}
+
+	for _, c := range cases {
+		h, ok := cborFloat16(c.F)
+		if ok != c.Exact || (ok && h != c.Half) {
+			t.Errorf("cbor float16 of %v: got %#04x %v, want %#04x %v", c.F, h, ok, c.Half, c.Exact)
+		}
+	}
+}
+
+func BenchmarkCBOREncoder(b *testing.B) {
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     &EncodeWriter{Encoder: CBOREncoder{}, Writer: io.Discard},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("foo", "bar").Int("n", 42).Msg("hello cbor")
+	}
+}
@@ -0,0 +1,321 @@
+package log
+
+import (
+	"strconv"
+)
+
+// CEFEncoder is an Encoder that encodes entries to ArcSight Common Event Format, the
+// message is the Name of header, the level is the Severity, and the other fields are
+// the extensions, e.g.
+//
+//	CEF:0|Vendor|Product|1.0|login|user login|3|rt=1562736954277 suser=alice
+type CEFEncoder struct {
+	entryEncoder
+
+	// Vendor specifies the Device Vendor of header.
+	Vendor string
+
+	// Product specifies the Device Product of header.
+	Product string
+
+	// Version specifies the Device Version of header.
+	Version string
+
+	// EventClassField specifies the field of Device Event Class ID, uses "event" if empty.
+	// The level is used if the field is absent.
+	EventClassField string
+
+	// Extensions specifies the extension keys of fields, it takes precedence over the
+	// defaults, e.g. "time" to "rt" and "user" to "suser". An empty key drops the field.
+	Extensions map[string]string
+}
+
+var cefExtensions = map[string]string{
+	"time":        "rt",
+	"host":        "dvchost",
+	"hostname":    "dvchost",
+	"remote_addr": "src",
+	"remote_ip":   "src",
+	"user":        "suser",
+	"method":      "requestMethod",
+	"url":         "request",
+	"user_agent":  "requestClientApplication",
+	"error":       "reason",
+}
+
+// EncodeEntry encodes the whole json entry, see Encoder.
+func (enc CEFEncoder) EncodeEntry(dst, json []byte) []byte {
+	if len(json) == 0 || json[0] != '{' {
+		return append(dst, json...)
+	}
+
+	b := bbpool.Get().(*bb)
+	defer func() {
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+	}()
+
+	h := siemHeader(json, enc.EventClassField, b)
+
+	dst = append(dst, "CEF:0|"...)
+	for _, s := range []string{enc.Vendor, enc.Product, enc.Version, h.class, h.message} {
+		dst = siemAppendHeader(dst, s)
+		dst = append(dst, '|')
+	}
+	dst = strconv.AppendInt(dst, int64(siemSeverity(h.level)), 10)
+	dst = append(dst, '|')
+
+	first := true
+	siemRange(json, h, enc.Extensions, cefExtensions, "rt", b, func(key, value string) {
+		if !first {
+			dst = append(dst, ' ')
+		}
+		first = false
+		dst = append(dst, key...)
+		dst = append(dst, '=')
+		for i := 0; i < len(value); i++ {
+			switch c := value[i]; c {
+			case '\\', '=':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			default:
+				dst = append(dst, c)
+			}
+		}
+	})
+
+	return append(dst, '\n')
+}
+
+// LEEFEncoder is an Encoder that encodes entries to IBM QRadar Log Event Extended Format,
+// the level is the `sev` attribute, and the other fields are the attributes, e.g.
+//
+//	LEEF:1.0|Vendor|Product|1.0|login|sev=3	devTime=1562736954277	usrName=alice	msg=user login
+type LEEFEncoder struct {
+	entryEncoder
+
+	// Vendor specifies the Vendor of header.
+	Vendor string
+
+	// Product specifies the Product of header.
+	Product string
+
+	// Version specifies the Version of header.
+	Version string
+
+	// EventIDField specifies the field of EventID, uses "event" if empty.
+	// The level is used if the field is absent.
+	EventIDField string
+
+	// Attributes specifies the attribute keys of fields, it takes precedence over the
+	// defaults, e.g. "time" to "devTime" and "user" to "usrName". An empty key drops the field.
+	Attributes map[string]string
+
+	// Delimiter specifies the delimiter of attributes, uses tab of LEEF 1.0 if zero,
+	// otherwise writes LEEF 2.0 with the delimiter.
+	Delimiter byte
+}
+
+var leefAttributes = map[string]string{
+	"time":        "devTime",
+	"host":        "identHostName",
+	"hostname":    "identHostName",
+	"remote_addr": "src",
+	"remote_ip":   "src",
+	"user":        "usrName",
+	"category":    "cat",
+}
+
+// EncodeEntry encodes the whole json entry, see Encoder.
+func (enc LEEFEncoder) EncodeEntry(dst, json []byte) []byte {
+	if len(json) == 0 || json[0] != '{' {
+		return append(dst, json...)
+	}
+
+	b := bbpool.Get().(*bb)
+	defer func() {
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+	}()
+
+	h := siemHeader(json, enc.EventIDField, b)
+
+	delimiter := enc.Delimiter
+	if delimiter == 0 || delimiter == '\t' {
+		delimiter = '\t'
+		dst = append(dst, "LEEF:1.0|"...)
+	} else {
+		dst = append(dst, "LEEF:2.0|"...)
+	}
+	for _, s := range []string{enc.Vendor, enc.Product, enc.Version, h.class} {
+		dst = siemAppendHeader(dst, s)
+		dst = append(dst, '|')
+	}
+	if delimiter != '\t' {
+		dst = append(dst, delimiter, '|')
+	}
+
+	first := true
+	attr := func(key, value string) {
+		if !first {
+			dst = append(dst, delimiter)
+		}
+		first = false
+		dst = append(dst, key...)
+		dst = append(dst, '=')
+		for i := 0; i < len(value); i++ {
+			c := value[i]
+			if c == delimiter || c == '\n' || c == '\r' {
+				c = ' '
+			}
+			dst = append(dst, c)
+		}
+	}
+
+	attr("sev", strconv.Itoa(siemSeverity(h.level)))
+	siemRange(json, h, enc.Attributes, leefAttributes, "devTime", b, attr)
+	if h.message != "" {
+		attr("msg", h.message)
+	}
+
+	return append(dst, '\n')
+}
+
+type siemFields struct {
+	level      Level
+	message    string
+	class      string
+	classField string
+}
+
+// siemHeader returns the level, message and event class of json entry, the strings
+// are unescaped to b if needed.
+func siemHeader(json []byte, classField string, b *bb) (h siemFields) {
+	if classField == "" {
+		classField = "event"
+	}
+	h.classField = classField
+	h.level = parseJSONLevel(json, "level")
+
+	b.B = b.B[:0]
+	str := func(value []byte) string {
+		if len(value) < 2 || value[0] != '"' {
+			return string(value)
+		}
+		if len(value) == 2 {
+			return ""
+		}
+		start := len(b.B)
+		b.B = jsonUnescape(value[1:len(value)-1], b.B)
+		return string(b.B[start:])
+	}
+
+	jsonRange(json, func(key, value []byte) bool {
+		switch k := b2s(key); {
+		case (k == "message" || k == "msg") && h.message == "":
+			h.message = str(value)
+		case k == classField:
+			h.class = str(value)
+		}
+		return true
+	})
+	if h.class == "" && h.level != noLevel {
+		h.class = h.level.String()
+	}
+	return
+}
+
+// siemRange calls fn on the renamed key and unescaped value of the fields of json entry
+// except the header fields, the non-string values are passed as raw json, and the time
+// field is converted to unix milliseconds.
+func siemRange(json []byte, h siemFields, names, defaults map[string]string, timeName string, b *bb, fn func(key, value string)) {
+	jsonRange(json, func(key, value []byte) bool {
+		k := b2s(key)
+		switch k {
+		case "level", "message", "msg", h.classField:
+			return true
+		}
+		name, ok := names[k]
+		if !ok {
+			if name, ok = defaults[k]; !ok {
+				name = siemKey(k)
+			}
+		}
+		if name == "" {
+			return true
+		}
+		var v string
+		if len(value) >= 2 && value[0] == '"' {
+			v = encodeUnescape(value[1:len(value)-1], b)
+		} else {
+			v = b2s(value)
+		}
+		if name == timeName {
+			if ns, ok := parseEntryTime(v); ok {
+				v = strconv.FormatInt(ns/1e6, 10)
+			}
+		}
+		fn(name, v)
+		return true
+	})
+}
+
+// siemKey replaces the non-alphanumeric characters of key with underscores.
+func siemKey(key string) string {
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			b := []byte(key)
+			for j := i; j < len(b); j++ {
+				if c := b[j]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+					b[j] = '_'
+				}
+			}
+			return string(b)
+		}
+	}
+	return key
+}
+
+// siemAppendHeader appends the header field s with the pipes and backslashes escaped.
+func siemAppendHeader(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '|', '\\':
+			dst = append(dst, '\\', c)
+		case '\n', '\r':
+			dst = append(dst, ' ')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// siemSeverity returns the severity in 0-10 of level.
+func siemSeverity(level Level) int {
+	switch level {
+	case TraceLevel:
+		return 1
+	case DebugLevel:
+		return 2
+	case InfoLevel:
+		return 3
+	case WarnLevel:
+		return 6
+	case ErrorLevel:
+		return 8
+	case FatalLevel:
+		return 9
+	case PanicLevel:
+		return 10
+	}
+	return 5
+}
+
+var _ Encoder = CEFEncoder{}
+var _ Encoder = LEEFEncoder{}
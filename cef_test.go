@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCEFEncoder(t *testing.T) {
+	enc := CEFEncoder{
+		Vendor:     "Acme|Corp",
+		Product:    "web",
+		Version:    "1.0",
+		Extensions: map[string]string{"session": "cs1", "secret": ""},
+	}
+
+	cases := []struct {
+		JSON string
+		CEF  string
+	}{
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"warn","event":"login","user":"alice","session":"a=b\\c","secret":"x","req.id":1,"message":"user\nlogin"}`,
+			`CEF:0|Acme\|Corp|web|1.0|login|user login|6|rt=1562736954277 suser=alice cs1=a\=b\\c req_id=1` + "\n",
+		},
+		{
+			`{"time":1562736954,"level":"error","error":"a|b","obj":{"a":1},"message":""}`,
+			`CEF:0|Acme\|Corp|web|1.0|error||8|rt=1562736954000 reason=a|b obj={"a":1}` + "\n",
+		},
+		{
+			`{"foo":"bar"}`,
+			`CEF:0|Acme\|Corp|web|1.0|||5|foo=bar` + "\n",
+		},
+	}
+
+	for _, c := range cases {
+		if got := string(EncodeJSON(enc, nil, []byte(c.JSON))); got != c.CEF {
+			t.Errorf("cef encoder of %s:\ngot  %q\nwant %q", c.JSON, got, c.CEF)
+		}
+	}
+}
+
+func TestLEEFEncoder(t *testing.T) {
+	enc := LEEFEncoder{
+		Vendor:  "Acme",
+		Product: "web",
+		Version: "1.0",
+	}
+
+	json := `{"time":"2019-07-10T05:35:54.277Z","level":"info","event":"login","user":"alice","note":"a\tb","message":"user login"}`
+	got := string(EncodeJSON(enc, nil, []byte(json)))
+	want := "LEEF:1.0|Acme|web|1.0|login|sev=3\tdevTime=1562736954277\tusrName=alice\tnote=a b\tmsg=user login\n"
+	if got != want {
+		t.Errorf("leef encoder:\ngot  %q\nwant %q", got, want)
+	}
+
+	enc.Delimiter = '^'
+	got = string(EncodeJSON(enc, nil, []byte(json)))
+	want = "LEEF:2.0|Acme|web|1.0|login|^|sev=3^devTime=1562736954277^usrName=alice^note=a\tb^msg=user login\n"
+	if got != want {
+		t.Errorf("leef 2.0 encoder:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestCEFEncoderLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnixMs,
+		Writer:     &EncodeWriter{Encoder: CEFEncoder{Vendor: "Acme", Product: "web", Version: "1.0"}, Writer: &buf},
+	}
+	logger.Info().Str("user", "bob").Msg("hello")
+
+	if got, want := buf.String(), "CEF:0|Acme|web|1.0|info|hello|3|rt="; !strings.HasPrefix(got, want) || !strings.HasSuffix(got, " suser=bob\n") {
+		t.Errorf("cef encoder logger: got %q, want prefix %q", got, want)
+	}
+}
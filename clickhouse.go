@@ -0,0 +1,237 @@
+package log
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ClickHouseWriter is a Writer that inserts entries in large batches to a table of ClickHouse
+// by the http interface in JSONEachRow format, e.g.
+//
+//	CREATE TABLE logs (
+//		time DateTime64(9),
+//		level Enum8('' = 0, 'trace' = 1, 'debug' = 2, 'info' = 3, 'warn' = 4, 'error' = 5, 'fatal' = 6, 'panic' = 7),
+//		message String,
+//		fields Map(String, String)
+//	) ENGINE = MergeTree ORDER BY time;
+//
+// The fields of entry except time, level and message are stored in the fields column, the
+// non-string values are stored as raw json.
+type ClickHouseWriter struct {
+	// URL specifies the url of http interface, uses "http://localhost:8123" if empty.
+	URL string
+
+	// Database specifies the database of table, uses the default database of user if empty.
+	Database string
+
+	// Table specifies the table name, uses "logs" if empty.
+	Table string
+
+	// Username and Password specifies the credential of user.
+	Username string
+	Password string
+
+	// TimeColumn specifies the column of entry time, uses "time" if empty.
+	TimeColumn string
+
+	// LevelColumn specifies the column of entry level, uses "level" if empty.
+	LevelColumn string
+
+	// MessageColumn specifies the column of entry message, uses "message" if empty.
+	MessageColumn string
+
+	// FieldsColumn specifies the column of other entry fields, uses "fields" if empty.
+	FieldsColumn string
+
+	// AsyncInsert determines if inserts the batches by the asynchronous inserts of server.
+	AsyncInsert bool
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 10000 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Fallback specifies the fallback writer of the entries failed to insert.
+	Fallback Writer
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once    sync.Once
+	batcher batcher
+	url     string
+	header  http.Header
+	columns [4][]byte
+}
+
+func (w *ClickHouseWriter) init() {
+	w.once.Do(func() {
+		column := func(name, value string) string {
+			if name == "" {
+				return value
+			}
+			return name
+		}
+		names := [4]string{
+			column(w.TimeColumn, "time"),
+			column(w.LevelColumn, "level"),
+			column(w.MessageColumn, "message"),
+			column(w.FieldsColumn, "fields"),
+		}
+		for i, name := range names {
+			w.columns[i] = append(jsonAppendString([]byte{','}, name), ':')
+		}
+		// the fields column is the first one, as the order of columns is insignificant.
+		w.columns[3] = w.columns[3][1:]
+
+		table := w.Table
+		if table == "" {
+			table = "logs"
+		}
+		query := url.Values{
+			"query":                  {"INSERT INTO " + table + " FORMAT JSONEachRow"},
+			"date_time_input_format": {"best_effort"},
+		}
+		if w.Database != "" {
+			query.Set("database", w.Database)
+		}
+		if w.AsyncInsert {
+			query.Set("async_insert", "1")
+			query.Set("wait_for_async_insert", "1")
+		}
+		w.url = w.URL
+		if w.url == "" {
+			w.url = "http://localhost:8123"
+		}
+		w.url += "/?" + query.Encode()
+
+		w.header = http.Header{"Content-Type": []string{"application/x-ndjson"}}
+		if w.Username != "" {
+			w.header.Set("X-ClickHouse-User", w.Username)
+			w.header.Set("X-ClickHouse-Key", w.Password)
+		}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 10000
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.batcher.fail = func(b *batch, err error) {
+			if w.Fallback == nil {
+				return
+			}
+			for i := 0; i < b.Len(); i++ {
+				_, _ = w.Fallback.WriteEntry(&Entry{buf: b.Entry(i), Level: b.levels[i]})
+			}
+		}
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *ClickHouseWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *ClickHouseWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *ClickHouseWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	dst := buf.B[:0]
+	for i := 0; i < b.Len(); i++ {
+		entry := b.Entry(i)
+		ts := b.times[i]
+		var message []byte
+		var fields int
+		dst = append(dst, '{')
+		dst = append(dst, w.columns[3]...)
+		dst = append(dst, '{')
+		jsonRange(entry, func(key, value []byte) bool {
+			switch b2s(key) {
+			case "time":
+				if len(value) >= 2 && value[0] == '"' {
+					if n, ok := parseEntryTime(b2s(value[1 : len(value)-1])); ok {
+						ts = n
+					}
+				} else if n, ok := parseEntryTime(b2s(value)); ok {
+					ts = n
+				}
+				return true
+			case "level":
+				return true
+			case "message":
+				message = value
+				return true
+			}
+			if fields > 0 {
+				dst = append(dst, ',')
+			}
+			fields++
+			dst = append(dst, '"')
+			dst = append(dst, key...)
+			dst = append(dst, '"', ':')
+			if len(value) > 0 && value[0] == '"' {
+				dst = append(dst, value...)
+			} else {
+				dst = jsonAppendBytes(dst, value)
+			}
+			return true
+		})
+		dst = append(dst, '}')
+		dst = append(dst, w.columns[0]...)
+		dst = append(dst, '"')
+		dst = time.Unix(0, ts).UTC().AppendFormat(dst, time.RFC3339Nano)
+		dst = append(dst, '"')
+		dst = append(dst, w.columns[1]...)
+		dst = append(dst, '"')
+		if b.levels[i] != noLevel {
+			dst = append(dst, b.levels[i].String()...)
+		}
+		dst = append(dst, '"')
+		dst = append(dst, w.columns[2]...)
+		if len(message) == 0 {
+			message = []byte(`""`)
+		} else if message[0] != '"' {
+			message = jsonAppendBytes(nil, message)
+		}
+		dst = append(dst, message...)
+		dst = append(dst, '}', '\n')
+	}
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	return httpSend(w.Client, http.MethodPost, w.url, w.header, body)
+}
+
+var _ Writer = (*ClickHouseWriter)(nil)
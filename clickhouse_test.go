@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClickHouseWriter(t *testing.T) {
+	var mu sync.Mutex
+	var rows []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("query") != "INSERT INTO app_logs FORMAT JSONEachRow" || q.Get("database") != "mydb" || q.Get("async_insert") != "1" {
+			t.Errorf("clickhouse writer query mismatch: %s", r.URL)
+		}
+		if r.Header.Get("X-ClickHouse-User") != "default" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			t.Errorf("clickhouse writer headers mismatch: %v", r.Header)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Errorf("clickhouse writer json error: %+v: %s", err, scanner.Bytes())
+			}
+			rows = append(rows, row)
+		}
+	}))
+	defer server.Close()
+
+	w := &ClickHouseWriter{
+		URL:         server.URL,
+		Database:    "mydb",
+		Table:       "app_logs",
+		Username:    "default",
+		Password:    "secret",
+		AsyncInsert: true,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "b\"ar").Int("n", 42).Bool("ok", true).Msg("hello clickhouse")
+	_, _ = w.WriteEntry(&Entry{buf: []byte(`{"time":1562736954,"message":"no level"}` + "\n"), Level: noLevel})
+	if err := w.Close(); err != nil {
+		t.Fatalf("clickhouse writer close error: %+v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("clickhouse writer want 2 rows got %d", len(rows))
+	}
+	r := rows[0]
+	if r["level"] != "info" || r["message"] != "hello clickhouse" {
+		t.Errorf("clickhouse writer row mismatch: %v", r)
+	}
+	fields, _ := r["fields"].(map[string]interface{})
+	if fields["foo"] != "b\"ar" || fields["n"] != "42" || fields["ok"] != "true" || len(fields) != 3 {
+		t.Errorf("clickhouse writer fields mismatch: %v", fields)
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, r["time"].(string)); err != nil || time.Since(ts) > time.Minute {
+		t.Errorf("clickhouse writer time mismatch: %v", r["time"])
+	}
+	if r := rows[1]; r["level"] != "" || r["time"] != "2019-07-10T05:35:54Z" {
+		t.Errorf("clickhouse writer row mismatch: %v", r)
+	}
+}
+
+func BenchmarkClickHouseWriterSend(b *testing.B) {
+	w := &ClickHouseWriter{Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}}
+	w.init()
+
+	bt := new(batch)
+	logger := Logger{Writer: batchTestWriter{bt}}
+	for i := 0; i < 1000; i++ {
+		logger.Info().Str("foo", "bar").Int("n", i).Msg("hello clickhouse")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = w.send(bt)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// batchTestWriter appends the entries to batch.
+type batchTestWriter struct {
+	b *batch
+}
+
+func (w batchTestWriter) WriteEntry(e *Entry) (int, error) {
+	w.b.append(e, 0)
+	return len(e.buf), nil
+}
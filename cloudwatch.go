@@ -0,0 +1,282 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudWatchWriter is a Writer that puts entries in batches to AWS CloudWatch Logs,
+// the log group and stream are created if not exist. The batches are split by the
+// limits of PutLogEvents, and retried with exponential backoff on throttling.
+type CloudWatchWriter struct {
+	// Region specifies the aws region, uses AWS_REGION environment variable if empty.
+	Region string
+
+	// LogGroupName specifies the log group name.
+	LogGroupName string
+
+	// LogStreamName specifies the log stream name, uses hostname if empty.
+	LogStreamName string
+
+	// Endpoint specifies the endpoint url, uses "https://logs.{region}.amazonaws.com/" if empty.
+	Endpoint string
+
+	// Credentials specifies the credentials provider, uses AWSEnvCredentials if nil.
+	Credentials func() (AWSCredentials, error)
+
+	// BatchSize specifies the maximum entries of a batch, uses 10000 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once     sync.Once
+	batcher  batcher
+	signer   awsSigner
+	endpoint string
+	stream   string
+	token    string
+}
+
+const (
+	// cloudwatchMaxBytes is the maximum bytes of PutLogEvents, each event takes extra 26 bytes.
+	cloudwatchMaxBytes = 1048576
+	// cloudwatchMaxEvents is the maximum events of PutLogEvents.
+	cloudwatchMaxEvents = 10000
+	// cloudwatchMaxEventSize is the maximum bytes of an event.
+	cloudwatchMaxEventSize = 256*1024 - 26
+)
+
+func (w *CloudWatchWriter) init() {
+	w.once.Do(func() {
+		region := w.Region
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		w.endpoint = w.Endpoint
+		if w.endpoint == "" {
+			w.endpoint = "https://logs." + region + ".amazonaws.com/"
+		}
+		w.stream = w.LogStreamName
+		if w.stream == "" {
+			w.stream = hostname
+		}
+
+		w.signer.region = region
+		w.signer.service = "logs"
+		w.signer.credentials = w.Credentials
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 || w.batcher.size > cloudwatchMaxEvents {
+			w.batcher.size = cloudwatchMaxEvents
+		}
+		w.batcher.bytes = cloudwatchMaxBytes
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *CloudWatchWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *CloudWatchWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *CloudWatchWriter) send(b *batch) error {
+	// the events must be in chronological order.
+	timestamps := make([]int64, b.Len())
+	indices := make([]int, b.Len())
+	for i := range indices {
+		ts, ok := parseEntryTime(b2s(jsonFieldString(b.Entry(i), "time")))
+		if !ok {
+			ts = b.times[i]
+		}
+		timestamps[i], indices[i] = ts/int64(time.Millisecond), i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return timestamps[indices[i]] < timestamps[indices[j]]
+	})
+
+	sent := make([]bool, b.Len())
+	var err error
+	for start := 0; start < len(indices) && err == nil; {
+		// split the batch by the limits of PutLogEvents.
+		end, size := start, 0
+		for ; end < len(indices); end++ {
+			i := indices[end]
+			n := len(bytes.TrimRight(b.Entry(i), "\n"))
+			if n > cloudwatchMaxEventSize {
+				n = cloudwatchMaxEventSize
+			}
+			n += 26
+			if end > start && (size+n > cloudwatchMaxBytes || end-start >= cloudwatchMaxEvents ||
+				timestamps[i]-timestamps[indices[start]] >= int64(24*time.Hour/time.Millisecond)) {
+				break
+			}
+			size += n
+		}
+		err = w.put(b, indices[start:end], timestamps)
+		if err == nil {
+			for _, i := range indices[start:end] {
+				sent[i] = true
+			}
+		}
+		start = end
+	}
+
+	if err != nil {
+		b.filter(func(i int, _ []byte) bool {
+			return !sent[i]
+		})
+	}
+	return err
+}
+
+func (w *CloudWatchWriter) put(b *batch, indices []int, timestamps []int64) error {
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	for retry := 0; ; retry++ {
+		dst := append(buf.B[:0], "{\"logGroupName\":"...)
+		dst = jsonAppendString(dst, w.LogGroupName)
+		dst = append(dst, ",\"logStreamName\":"...)
+		dst = jsonAppendString(dst, w.stream)
+		if w.token != "" {
+			dst = append(dst, ",\"sequenceToken\":"...)
+			dst = jsonAppendString(dst, w.token)
+		}
+		dst = append(dst, ",\"logEvents\":["...)
+		for k, i := range indices {
+			if k > 0 {
+				dst = append(dst, ',')
+			}
+			message := bytes.TrimRight(b.Entry(i), "\n")
+			if len(message) > cloudwatchMaxEventSize {
+				message = message[:cloudwatchMaxEventSize]
+			}
+			dst = append(dst, "{\"timestamp\":"...)
+			dst = strconv.AppendInt(dst, timestamps[i], 10)
+			dst = append(dst, ",\"message\":"...)
+			dst = jsonAppendBytes(dst, message)
+			dst = append(dst, '}')
+		}
+		dst = append(dst, "]}"...)
+		buf.B = dst
+
+		data, err := w.call("PutLogEvents", buf.B)
+		if err == nil {
+			var resp struct {
+				NextSequenceToken string `json:"nextSequenceToken"`
+			}
+			if json.Unmarshal(data, &resp) == nil {
+				w.token = resp.NextSequenceToken
+			}
+			return nil
+		}
+
+		typ, expected := cloudwatchError(err)
+		switch {
+		case retry > 0:
+			return err
+		case typ == "ResourceNotFoundException":
+			if err = w.create(); err != nil {
+				return err
+			}
+		case typ == "InvalidSequenceTokenException":
+			w.token = expected
+		case typ == "DataAlreadyAcceptedException":
+			w.token = expected
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// create creates the log group and stream, the existing ones are ignored.
+func (w *CloudWatchWriter) create() error {
+	group := jsonAppendString([]byte("{\"logGroupName\":"), w.LogGroupName)
+	if _, err := w.call("CreateLogGroup", append(group, '}')); err != nil {
+		if typ, _ := cloudwatchError(err); typ != "ResourceAlreadyExistsException" {
+			return err
+		}
+	}
+	stream := append(group, ",\"logStreamName\":"...)
+	stream = jsonAppendString(stream, w.stream)
+	if _, err := w.call("CreateLogStream", append(stream, '}')); err != nil {
+		if typ, _ := cloudwatchError(err); typ != "ResourceAlreadyExistsException" {
+			return err
+		}
+	}
+	w.token = ""
+	return nil
+}
+
+func (w *CloudWatchWriter) call(action string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	if err = w.signer.sign(req, body); err != nil {
+		return nil, err
+	}
+
+	data, err := httpDo(w.Client, req, 64*1024)
+	if typ, _ := cloudwatchError(err); typ == "ThrottlingException" || typ == "ServiceUnavailableException" {
+		// unwraps permanentError to retry
+		var perm permanentError
+		if errors.As(err, &perm) {
+			err = perm.error
+		}
+	}
+	return data, err
+}
+
+// cloudwatchError returns the error type and expected sequence token of the cloudwatch error response.
+func cloudwatchError(err error) (typ, expected string) {
+	var e *httpStatusError
+	if !errors.As(err, &e) {
+		return
+	}
+	var resp struct {
+		Type     string `json:"__type"`
+		Expected string `json:"expectedSequenceToken"`
+	}
+	if json.Unmarshal([]byte(e.Body), &resp) != nil {
+		return
+	}
+	typ = resp.Type
+	if i := strings.LastIndexByte(typ, '#'); i >= 0 {
+		typ = typ[i+1:]
+	}
+	return typ, resp.Expected
+}
+
+var _ Writer = (*CloudWatchWriter)(nil)
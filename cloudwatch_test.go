@@ -0,0 +1,104 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloudWatchWriter(t *testing.T) {
+	type request struct {
+		Group     string `json:"logGroupName"`
+		Stream    string `json:"logStreamName"`
+		Token     string `json:"sequenceToken"`
+		LogEvents []struct {
+			Timestamp int64  `json:"timestamp"`
+			Message   string `json:"message"`
+		} `json:"logEvents"`
+	}
+
+	var mu sync.Mutex
+	var actions []string
+	var puts []request
+	var throttled bool
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Security-Token") != "TOKEN" {
+			t.Errorf("cloudwatch writer should sign requests: %v", r.Header)
+		}
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cloudwatch writer json error: %+v", err)
+		}
+		if req.Group != "mygroup" {
+			t.Errorf("cloudwatch writer group mismatch: %+v", req)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		actions = append(actions, action)
+		switch action {
+		case "CreateLogGroup":
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"__type":"ResourceAlreadyExistsException","message":"exists"}`))
+		case "CreateLogStream":
+			_, _ = rw.Write([]byte(`{}`))
+		case "PutLogEvents":
+			if len(actions) == 1 {
+				rw.WriteHeader(http.StatusBadRequest)
+				_, _ = rw.Write([]byte(`{"__type":"com.amazonaws.logs#ResourceNotFoundException","message":"not found"}`))
+				return
+			}
+			if len(puts) == 1 && !throttled {
+				throttled = true
+				rw.WriteHeader(http.StatusBadRequest)
+				_, _ = rw.Write([]byte(`{"__type":"ThrottlingException","message":"rate exceeded"}`))
+				return
+			}
+			puts = append(puts, req)
+			_, _ = rw.Write([]byte(`{"nextSequenceToken":"token` + string(rune('0'+len(puts))) + `"}`))
+		}
+	}))
+	defer server.Close()
+
+	w := &CloudWatchWriter{
+		Region:        "us-east-1",
+		LogGroupName:  "mygroup",
+		LogStreamName: "mystream",
+		Endpoint:      server.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}, nil
+		},
+		BatchSize: 2,
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+
+	logger.Info().Msg("first")
+	logger.Info().Msg("second")
+	logger.Info().Msg("third")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("cloudwatch writer close error: %+v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(actions, ",") != "PutLogEvents,CreateLogGroup,CreateLogStream,PutLogEvents,PutLogEvents,PutLogEvents" {
+		t.Errorf("cloudwatch writer actions mismatch: %v", actions)
+	}
+	if len(puts) != 2 || len(puts[0].LogEvents) != 2 || len(puts[1].LogEvents) != 1 {
+		t.Fatalf("cloudwatch writer puts mismatch: %+v", puts)
+	}
+	if puts[0].Stream != "mystream" || puts[0].Token != "" || puts[1].Token != "token1" {
+		t.Errorf("cloudwatch writer sequence token mismatch: %+v", puts)
+	}
+	event := puts[0].LogEvents[0]
+	if !strings.HasSuffix(event.Message, `"message":"first"}`) || time.Since(time.Unix(0, event.Timestamp*int64(time.Millisecond))) > time.Minute {
+		t.Errorf("cloudwatch writer event mismatch: %+v", event)
+	}
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// CoalesceWriter is an Writer that coalesces the pending entries into a single write on
+// the underlying writer, it reduces the syscalls of the stream sinks under load, e.g. the
+// files, the tcp connections of syslog or fluentd. The entries are copied into a buffer
+// which is written at once, rather than by writev.
+//
+// It is not for the datagram sinks which expect a write per entry, e.g. udp syslog.
+type CoalesceWriter struct {
+	// MaxBatchBytes specifies the max bytes of a write, uses 64KB if zero.
+	MaxBatchBytes int
+
+	// MaxLatency specifies the max delay of a pending entry, uses 10 milliseconds if zero.
+	MaxLatency time.Duration
+
+	// Writer specifies the writer of coalesced output.
+	Writer io.Writer
+
+	mu    sync.Mutex
+	buf   []byte
+	timer *time.Timer
+	err   error
+}
+
+// WriteEntry implements Writer.
+func (w *CoalesceWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer, it buffers p and returns the error of a previous background
+// write once if any.
+func (w *CoalesceWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if n, err = w.write(p); err == nil && w.err != nil {
+		err, w.err = w.err, nil
+	}
+	return
+}
+
+func (w *CoalesceWriter) write(p []byte) (n int, err error) {
+	max := w.MaxBatchBytes
+	if max <= 0 {
+		max = 64 * 1024
+	}
+
+	if len(w.buf) > 0 && len(w.buf)+len(p) > max {
+		if err = w.flush(); err != nil {
+			return
+		}
+	}
+	if len(p) >= max {
+		return w.Writer.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	if w.timer == nil {
+		latency := w.MaxLatency
+		if latency <= 0 {
+			latency = 10 * time.Millisecond
+		}
+		w.timer = time.AfterFunc(latency, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.timer = nil
+			if err := w.flush(); err != nil {
+				w.err = err
+			}
+		})
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the pending entries to the underlying writer.
+func (w *CoalesceWriter) Flush() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		err, w.err = w.err, nil
+		return
+	}
+	return w.flush()
+}
+
+func (w *CoalesceWriter) flush() (err error) {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err = w.Writer.Write(w.buf)
+	w.buf = w.buf[:0]
+	return
+}
+
+// Close implements io.Closer, it writes the pending entries, and closes the underlying
+// Writer if it is an io.Closer.
+func (w *CoalesceWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	err = w.flush()
+	if closer, ok := w.Writer.(io.Closer); ok {
+		if err1 := closer.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return
+}
+
+var _ Writer = (*CoalesceWriter)(nil)
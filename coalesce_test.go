@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type coalesceBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	err    error
+}
+
+func (b *coalesceBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes++
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.buf.Write(p)
+}
+
+func (b *coalesceBuffer) Writes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.writes
+}
+
+func TestCoalesceWriter(t *testing.T) {
+	var buf coalesceBuffer
+	w := &CoalesceWriter{MaxBatchBytes: 100, MaxLatency: time.Hour, Writer: &buf}
+
+	for i := 0; i < 20; i++ {
+		_, _ = wlprintf(w, InfoLevel, "0123456789")
+	}
+	if n := buf.Writes(); n != 1 {
+		t.Errorf("coalesce writer must write 1 full batch, not %d", n)
+	}
+
+	_, _ = w.Write(make([]byte, 200))
+	if n := buf.Writes(); n != 3 {
+		t.Errorf("coalesce writer must write the pending and the large entry, not %d", n)
+	}
+
+	_, _ = wlprintf(w, InfoLevel, "tail")
+	if err := w.Close(); err != nil {
+		t.Fatalf("coalesce writer close error: %+v", err)
+	}
+	if s := buf.buf.String(); strings.Count(s, "0123456789") != 20 || !strings.HasSuffix(s, "tail") {
+		t.Errorf("coalesce writer output mismatch: %q", s)
+	}
+}
+
+func TestCoalesceWriterLatency(t *testing.T) {
+	buf := &coalesceBuffer{}
+	w := &CoalesceWriter{MaxLatency: time.Millisecond, Writer: buf}
+
+	_, _ = wlprintf(w, InfoLevel, "hello coalesce writer")
+	for i := 0; i < 100 && buf.Writes() == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if buf.Writes() != 1 {
+		t.Fatalf("coalesce writer must write after the max latency")
+	}
+
+	buf.mu.Lock()
+	buf.err = errors.New("test error")
+	buf.mu.Unlock()
+	_, _ = wlprintf(w, InfoLevel, "hello coalesce writer")
+	for i := 0; i < 100 && buf.Writes() == 1; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := wlprintf(w, InfoLevel, "hello"); err == nil {
+		t.Errorf("coalesce writer must return the background write error")
+	}
+
+	buf.mu.Lock()
+	buf.err = nil
+	buf.mu.Unlock()
+	if _, err := wlprintf(w, InfoLevel, "world"); err != nil {
+		t.Errorf("coalesce writer must return the background write error once: %+v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("coalesce writer flush error: %+v", err)
+	}
+	if s := buf.buf.String(); !strings.HasSuffix(s, "helloworld") {
+		t.Errorf("coalesce writer must buffer the entries with the background write error: %q", s)
+	}
+}
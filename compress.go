@@ -0,0 +1,116 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+)
+
+// CompressWriter is an Writer that compresses the output stream by gzip or a pluggable
+// compressor before writing to the underlying writer, and flushes the compressed stream
+// periodically so that the tail-readers see the recent entries.
+type CompressWriter struct {
+	// Level specifies the level of gzip, uses gzip.DefaultCompression if zero.
+	Level int
+
+	// Compressor specifies an optional compressor instead of gzip, e.g. zstd of klauspost,
+	//
+	//	func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+	//
+	// It should implement Flush() error for the periodic flushing.
+	Compressor func(w io.Writer) (io.WriteCloser, error)
+
+	// FlushInterval specifies the interval of flushing, uses 1 second if zero, and
+	// flushes only on Close if negative.
+	FlushInterval time.Duration
+
+	// Writer specifies the writer of compressed output.
+	Writer io.Writer
+
+	mu    sync.Mutex
+	cw    io.WriteCloser
+	timer *time.Timer
+}
+
+// WriteEntry implements Writer.
+func (w *CompressWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer.
+func (w *CompressWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cw == nil {
+		if w.Compressor != nil {
+			w.cw, err = w.Compressor(w.Writer)
+		} else {
+			level := w.Level
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			w.cw, err = gzip.NewWriterLevel(w.Writer, level)
+		}
+		if err != nil {
+			w.cw = nil
+			return
+		}
+	}
+
+	n, err = w.cw.Write(p)
+
+	if w.timer == nil && w.FlushInterval >= 0 {
+		interval := w.FlushInterval
+		if interval == 0 {
+			interval = time.Second
+		}
+		w.timer = time.AfterFunc(interval, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.timer = nil
+			_ = w.flush()
+		})
+	}
+
+	return
+}
+
+// Flush flushes the compressed stream to the underlying writer.
+func (w *CompressWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *CompressWriter) flush() error {
+	if flusher, ok := w.cw.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close implements io.Closer, it finishes the compressed stream, and closes the underlying
+// Writer if it is an io.Closer.
+func (w *CompressWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.cw != nil {
+		err = w.cw.Close()
+		w.cw = nil
+	}
+	if closer, ok := w.Writer.(io.Closer); ok {
+		if err1 := closer.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return
+}
+
+var _ Writer = (*CompressWriter)(nil)
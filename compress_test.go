@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type compressBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *compressBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *compressBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func TestCompressWriter(t *testing.T) {
+	var buf compressBuffer
+	w := &CompressWriter{Level: gzip.BestSpeed, FlushInterval: 10 * time.Millisecond, Writer: &buf}
+	logger := Logger{Writer: w}
+
+	for i := 0; i < 100; i++ {
+		logger.Info().Int("i", i).Msg("hello world")
+	}
+
+	for i := 0; i < 100 && buf.Len() == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if buf.Len() == 0 {
+		t.Fatalf("compress writer must flush periodically")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("compress writer close error: %+v", err)
+	}
+
+	r, err := gzip.NewReader(&buf.buf)
+	if err != nil {
+		t.Fatalf("gzip reader error: %+v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("gzip read error: %+v", err)
+	}
+	if n := strings.Count(string(data), "hello world"); n != 100 {
+		t.Errorf("decompressed output must have 100 entries, not %d", n)
+	}
+}
+
+func TestCompressWriterCompressor(t *testing.T) {
+	var buf bytes.Buffer
+	w := &CompressWriter{
+		Compressor: func(w io.Writer) (io.WriteCloser, error) {
+			return zlib.NewWriter(w), nil
+		},
+		FlushInterval: -1,
+		Writer:        &buf,
+	}
+	_, _ = w.Write([]byte("hello\n"))
+	if err := w.Flush(); err != nil || buf.Len() == 0 {
+		t.Fatalf("compress writer flush must write output, error: %+v", err)
+	}
+	_ = w.Close()
+
+	r, err := zlib.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("zlib reader error: %+v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "hello\n" {
+		t.Errorf("decompressed output got %q", data)
+	}
+}
@@ -1,10 +1,17 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // IsTerminal returns whether the given file descriptor is a terminal.
@@ -26,6 +33,43 @@ type ConsoleWriter struct {
 	// ColorOutput determines if used colorized output.
 	ColorOutput bool
 
+	// AutoColor determines if used colorized output by the terminal detection of Writer and
+	// the NO_COLOR environment variable, it overrides ColorOutput.
+	AutoColor bool
+
+	// Theme specifies the colors of colorized output, uses DefaultConsoleTheme if nil.
+	Theme *ConsoleTheme
+
+	// PartsOrder specifies the order of header parts, uses ["time", "level", "caller", "message"]
+	// if nil. The "caller" part contains goid, the "message" part starts with ">", and the other
+	// parts are the values of fields which are not rendered again.
+	PartsOrder []string
+
+	// FieldsExclude specifies the keys of fields which are not rendered.
+	FieldsExclude []string
+
+	// FieldsJSON determines if rendering the fields as a JSON object instead of key=value.
+	FieldsJSON bool
+
+	// LevelWidth specifies the width of level badges, the levels are padded or truncated.
+	LevelWidth int
+
+	// CallerWidth specifies the width of caller column, the callers are padded or truncated
+	// from the left.
+	CallerWidth int
+
+	// MessageWidth specifies the max width of messages, the longer messages are elided, and
+	// the shorter messages are padded if followed by fields.
+	MessageWidth int
+
+	// DurationWidth specifies the width of duration values, e.g. "1.5ms", which are right-aligned.
+	DurationWidth int
+
+	// Multiline determines if rendering the objects, the arrays and the multi-line strings
+	// of fields as indented blocks under the line, e.g. the nested objects and the errors
+	// with stack traces.
+	Multiline bool
+
 	// QuoteString determines if quoting string values.
 	QuoteString bool
 
@@ -40,6 +84,56 @@ type ConsoleWriter struct {
 	Writer io.Writer
 }
 
+// ConsoleTheme specifies the colors of ConsoleWriter. The colors are ANSI escape sequences,
+// e.g. "\x1b[32m", "\x1b[2m" for dim or "\x1b[38;2;255;135;0m" for true-color, and
+// the empty colors are not colorized.
+type ConsoleTheme struct {
+	// Time specifies the color of time.
+	Time string
+
+	// Levels specifies the colors of levels, the absent levels use the color of custom
+	// level or gray.
+	Levels map[Level]string
+
+	// Caller specifies the color of goid and caller.
+	Caller string
+
+	// Arrow specifies the color of the ">" before message.
+	Arrow string
+
+	// Message specifies the color of message.
+	Message string
+
+	// Key specifies the color of keys.
+	Key string
+
+	// Value specifies the color of values.
+	Value string
+
+	// Fields specifies the colors of the whole key and value of specific keys.
+	Fields map[string]string
+}
+
+// DefaultConsoleTheme is the default colors of ConsoleWriter.
+var DefaultConsoleTheme = ConsoleTheme{
+	Time: "\x1b[90m",
+	Levels: map[Level]string{
+		TraceLevel: "\x1b[35m",
+		DebugLevel: "\x1b[33m",
+		InfoLevel:  "\x1b[32m",
+		WarnLevel:  "\x1b[31m",
+		ErrorLevel: "\x1b[31m",
+		FatalLevel: "\x1b[31m",
+		PanicLevel: "\x1b[31m",
+	},
+	Arrow: "\x1b[36m",
+	Key:   "\x1b[36m",
+	Value: "\x1b[90m",
+	Fields: map[string]string{
+		"error": "\x1b[31m",
+	},
+}
+
 // Close implements io.Closer, will closes the underlying Writer if not empty.
 func (w *ConsoleWriter) Close() (err error) {
 	if w.Writer != nil {
@@ -76,89 +170,152 @@ func (w *ConsoleWriter) format(out io.Writer, args *FormatterArgs) (n int, err e
 	b.B = b.B[:0]
 	defer bbpool.Put(b)
 
-	const (
-		Reset   = "\x1b[0m"
-		Black   = "\x1b[30m"
-		Red     = "\x1b[31m"
-		Green   = "\x1b[32m"
-		Yellow  = "\x1b[33m"
-		Blue    = "\x1b[34m"
-		Magenta = "\x1b[35m"
-		Cyan    = "\x1b[36m"
-		White   = "\x1b[37m"
-		Gray    = "\x1b[90m"
-	)
+	const Reset = "\x1b[0m"
+
+	theme := w.Theme
+	if theme == nil {
+		theme = &DefaultConsoleTheme
+	}
 
 	// colorful level string
 	var color, three string
 	switch args.Level {
 	case "trace":
-		color, three = Magenta, "TRC"
+		three = "TRC"
 	case "debug":
-		color, three = Yellow, "DBG"
+		three = "DBG"
 	case "info":
-		color, three = Green, "INF"
+		three = "INF"
 	case "warn":
-		color, three = Red, "WRN"
+		three = "WRN"
 	case "error":
-		color, three = Red, "ERR"
+		three = "ERR"
 	case "fatal":
-		color, three = Red, "FTL"
+		three = "FTL"
 	case "panic":
-		color, three = Red, "PNC"
+		three = "PNC"
 	default:
-		color, three = Gray, "???"
+		color, three = "\x1b[90m", "???"
+		if c := customLevelByName(args.Level); c != nil {
+			three = c.Short
+			if c.Color != "" {
+				color = c.Color
+			}
+		}
+	}
+	if c, ok := theme.Levels[ParseLevel(args.Level)]; ok {
+		color = c
 	}
 
 	// pretty console writer
-	if w.ColorOutput {
-		// header
-		fmt.Fprintf(b, "%s%s%s %s%s%s ", Gray, args.Time, Reset, color, three, Reset)
-		if args.Caller != "" {
-			fmt.Fprintf(b, "%s %s %s>%s", args.Goid, args.Caller, Cyan, Reset)
-		} else {
-			fmt.Fprintf(b, "%s>%s", Cyan, Reset)
-		}
-		if !w.EndWithMessage {
-			fmt.Fprintf(b, " %s", args.Message)
+	colored := w.colorOutput()
+	if !colored {
+		theme, color = &ConsoleTheme{}, ""
+	}
+
+	// header
+	parts := w.PartsOrder
+	if parts == nil {
+		parts = defaultConsoleParts
+	}
+	for _, part := range parts {
+		start := len(b.B)
+		if start != 0 {
+			b.B = append(b.B, ' ')
 		}
-		// key and values
-		for _, kv := range args.KeyValues {
-			if w.QuoteString && kv.ValueType == 's' {
-				kv.Value = strconv.Quote(kv.Value)
+		switch part {
+		case "time":
+			b.B = appendColor(b.B, theme.Time, args.Time)
+		case "level":
+			b.B = appendColor(b.B, color, consoleFit(three, w.LevelWidth, false))
+		case "caller":
+			if args.Caller == "" {
+				if w.CallerWidth > 0 {
+					b.B = append(b.B, strings.Repeat(" ", w.CallerWidth)...)
+				} else {
+					b.B = b.B[:start]
+				}
+				continue
+			}
+			b.B = appendColor(b.B, theme.Caller, consoleFit(args.Goid+" "+args.Caller, w.CallerWidth, true))
+		case "message":
+			b.B = appendColor(b.B, theme.Arrow, ">")
+			if !w.EndWithMessage {
+				b.B = append(b.B, ' ')
+				message := args.Message
+				if w.MessageWidth > 0 {
+					if utf8.RuneCountInString(message) > w.MessageWidth || len(args.KeyValues) != 0 {
+						message = consoleFit(message, w.MessageWidth, false)
+					}
+				}
+				b.B = appendColor(b.B, theme.Message, message)
 			}
-			if kv.Key == "error" {
-				fmt.Fprintf(b, " %s%s=%s%s", Red, kv.Key, kv.Value, Reset)
+		default:
+			if value := args.Get(part); value != "" {
+				b.B = appendColor(b.B, theme.Value, value)
 			} else {
-				fmt.Fprintf(b, " %s%s=%s%s%s", Cyan, kv.Key, Gray, kv.Value, Reset)
+				b.B = b.B[:start]
 			}
 		}
-		// message
-		if w.EndWithMessage {
-			fmt.Fprintf(b, "%s %s", Reset, args.Message)
-		}
+	}
+
+	// key and values
+	if w.FieldsJSON {
+		w.appendFieldsJSON(b, args, theme)
 	} else {
-		// header
-		fmt.Fprintf(b, "%s %s ", args.Time, three)
-		if args.Caller != "" {
-			fmt.Fprintf(b, "%s %s >", args.Goid, args.Caller)
-		} else {
-			fmt.Fprint(b, ">")
-		}
-		if !w.EndWithMessage {
-			fmt.Fprintf(b, " %s", args.Message)
-		}
-		// key and values
 		for _, kv := range args.KeyValues {
+			if w.excluded(kv.Key) || w.block(kv.Value, kv.ValueType) {
+				continue
+			}
 			if w.QuoteString && kv.ValueType == 's' {
-				fmt.Fprintf(b, " %s=%s", kv.Key, strconv.Quote(kv.Value))
+				kv.Value = strconv.Quote(kv.Value)
+			}
+			if w.DurationWidth > 0 && consoleDuration(kv.Value) {
+				if n := w.DurationWidth - len(kv.Value); n > 0 {
+					kv.Value = strings.Repeat(" ", n) + kv.Value
+				}
+			}
+			if c, ok := theme.Fields[kv.Key]; ok {
+				fmt.Fprintf(b, " %s%s=%s%s", c, kv.Key, kv.Value, Reset)
+			} else if theme.Key != "" || theme.Value != "" {
+				fmt.Fprintf(b, " %s%s=%s%s%s", theme.Key, kv.Key, theme.Value, kv.Value, Reset)
 			} else {
 				fmt.Fprintf(b, " %s=%s", kv.Key, kv.Value)
 			}
 		}
-		// message
-		if w.EndWithMessage {
-			fmt.Fprintf(b, " %s", args.Message)
+	}
+
+	// message
+	if w.EndWithMessage {
+		if colored {
+			b.B = append(b.B, Reset...)
+		}
+		b.B = append(b.B, ' ')
+		b.B = appendColor(b.B, theme.Message, args.Message)
+	}
+
+	// blocks
+	if w.Multiline {
+		for _, kv := range args.KeyValues {
+			if w.excluded(kv.Key) || !w.block(kv.Value, kv.ValueType) {
+				continue
+			}
+			b.B = append(b.B, "\n  "...)
+			b.B = appendColor(b.B, theme.Key, kv.Key+":")
+			if kv.ValueType == 'o' {
+				b.B = append(b.B, ' ')
+				var ib bytes.Buffer
+				if json.Indent(&ib, []byte(kv.Value), "  ", "  ") == nil {
+					b.B = append(b.B, ib.Bytes()...)
+				} else {
+					b.B = append(b.B, kv.Value...)
+				}
+				continue
+			}
+			for _, line := range strings.Split(strings.TrimRight(kv.Value, "\n"), "\n") {
+				b.B = append(b.B, "\n    "...)
+				b.B = append(b.B, line...)
+			}
 		}
 	}
 
@@ -175,6 +332,121 @@ func (w *ConsoleWriter) format(out io.Writer, args *FormatterArgs) (n int, err e
 	return out.Write(b.B)
 }
 
+// defaultConsoleParts is the default order of header parts.
+var defaultConsoleParts = []string{"time", "level", "caller", "message"}
+
+// consoleFit pads s to width, or truncates s with an ellipsis, it keeps the tail of s
+// if left is true. It returns s if width is not positive.
+func consoleFit(s string, width int, left bool) string {
+	n := utf8.RuneCountInString(s)
+	switch {
+	case width <= 0 || n == width:
+		return s
+	case n < width:
+		return s + strings.Repeat(" ", width-n)
+	case width == 1:
+		return "…"
+	}
+	r := []rune(s)
+	if left {
+		return "…" + string(r[n-width+1:])
+	}
+	return string(r[:width-1]) + "…"
+}
+
+// consoleDuration reports whether s is a duration, e.g. "1.5ms".
+func consoleDuration(s string) bool {
+	if s == "" || s[len(s)-1] != 's' || (s[0] < '0' || s[0] > '9') && s[0] != '-' {
+		return false
+	}
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+// block reports whether the value is rendered as a block under the line.
+func (w *ConsoleWriter) block(value string, typ byte) bool {
+	return w.Multiline && (typ == 'o' || typ == 's' && strings.IndexByte(value, '\n') >= 0)
+}
+
+// excluded reports whether the key is excluded from the fields.
+func (w *ConsoleWriter) excluded(key string) bool {
+	for _, k := range w.FieldsExclude {
+		if k == key {
+			return true
+		}
+	}
+	for _, k := range w.PartsOrder {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFieldsJSON appends the fields as a JSON object.
+func (w *ConsoleWriter) appendFieldsJSON(b *bb, args *FormatterArgs, theme *ConsoleTheme) {
+	e := Entry{buf: b.B}
+	n := 0
+	for _, kv := range args.KeyValues {
+		if w.excluded(kv.Key) || w.block(kv.Value, kv.ValueType) {
+			continue
+		}
+		if n == 0 {
+			e.buf = append(e.buf, " {"...)
+		} else {
+			e.buf = append(e.buf, ',')
+		}
+		n++
+		e.buf = append(e.buf, theme.Key...)
+		e.buf = append(e.buf, '"')
+		e.escapes(kv.Key)
+		e.buf = append(e.buf, '"', ':')
+		e.buf = append(e.buf, theme.Value...)
+		if kv.ValueType == 's' {
+			e.buf = append(e.buf, '"')
+			e.escapes(kv.Value)
+			e.buf = append(e.buf, '"')
+		} else {
+			e.buf = append(e.buf, kv.Value...)
+		}
+		if theme.Key != "" || theme.Value != "" {
+			e.buf = append(e.buf, "\x1b[0m"...)
+		}
+	}
+	if n != 0 {
+		e.buf = append(e.buf, '}')
+	}
+	b.B = e.buf
+}
+
+// colorOutput reports whether the output is colorized, see https://no-color.org
+func (w *ConsoleWriter) colorOutput() bool {
+	if !w.AutoColor {
+		return w.ColorOutput
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	out := w.Writer
+	if out == nil {
+		out = os.Stderr
+	}
+	if f, ok := out.(*os.File); ok {
+		return IsTerminal(f.Fd())
+	}
+	return false
+}
+
+// appendColor appends the colorized s to dst, or s if color is empty.
+func appendColor(dst []byte, color, s string) []byte {
+	if color == "" {
+		return append(dst, s...)
+	}
+	dst = append(dst, color...)
+	dst = append(dst, s...)
+	return append(dst, "\x1b[0m"...)
+}
+
 type LogfmtFormatter struct {
 	TimeField string
 }
@@ -217,4 +489,89 @@ func (f LogfmtFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, e
 	return out.Write(b.B)
 }
 
+// GlogFormatter is a formatter of ConsoleWriter which produces the glog/klog compatible lines,
+//
+//	I0102 15:04:05.000000   12345 file.go:42] msg key="value"
+//
+// so that the tools parsing klog output can consume them.
+type GlogFormatter struct {
+	// TimeFormat specifies the time format of logger, uses time.RFC3339 if empty, the unix
+	// timestamps are detected as well. It uses the current time if parsing failed.
+	TimeFormat string
+}
+
+// Formatter implements ConsoleWriter.Formatter.
+func (f GlogFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, err error) {
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	defer bbpool.Put(b)
+
+	var c byte
+	switch ParseLevel(args.Level) {
+	case WarnLevel:
+		c = 'W'
+	case ErrorLevel:
+		c = 'E'
+	case FatalLevel, PanicLevel:
+		c = 'F'
+	default:
+		c = 'I'
+	}
+
+	t := glogTime(args.Time, f.TimeFormat)
+	_, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	fmt.Fprintf(b, "%c%02d%02d %02d:%02d:%02d.%06d %7d ", c, month, day, hour, min, sec, t.Nanosecond()/1000, pid)
+	if args.Caller != "" {
+		b.B = append(b.B, filepath.Base(args.Caller)...)
+	} else {
+		b.B = append(b.B, "???:1"...)
+	}
+	b.B = append(b.B, "] "...)
+	b.B = append(b.B, args.Message...)
+	// key and values
+	for _, kv := range args.KeyValues {
+		if kv.ValueType == 's' {
+			fmt.Fprintf(b, " %s=%s", kv.Key, strconv.Quote(kv.Value))
+		} else {
+			fmt.Fprintf(b, " %s=%s", kv.Key, kv.Value)
+		}
+	}
+	b.B = append(b.B, '\n')
+	// stack
+	if args.Stack != "" {
+		b.B = append(b.B, args.Stack...)
+		if args.Stack[len(args.Stack)-1] != '\n' {
+			b.B = append(b.B, '\n')
+		}
+	}
+
+	return out.Write(b.B)
+}
+
+// glogTime parses the time field of logger.
+func glogTime(s, layout string) time.Time {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && strings.IndexAny(s, "-:T ") < 0 {
+			switch {
+			case f < 1e11:
+				return time.Unix(0, int64(f*1e9))
+			case f < 1e14:
+				return time.Unix(0, int64(f*1e6))
+			case f < 1e17:
+				return time.Unix(0, int64(f*1e3))
+			default:
+				return time.Unix(0, int64(f))
+			}
+		}
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if t, err := time.Parse(layout, s); err == nil {
+		return t
+	}
+	return timeNow()
+}
+
 var _ Writer = (*ConsoleWriter)(nil)
@@ -1,11 +1,13 @@
 package log
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -321,3 +323,142 @@ func TestConsoleWriterLogfmt(t *testing.T) {
 		KeysAndValues("foo", "bar", "number", 42).
 		Msg("aaaa 'b' cccc")
 }
+
+func TestConsoleWriterTheme(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{
+		ColorOutput: true,
+		Theme: &ConsoleTheme{
+			Levels: map[Level]string{InfoLevel: "\x1b[38;2;0;255;0m"},
+			Key:    "\x1b[2m",
+			Value:  "\x1b[97m",
+		},
+		Writer: &b,
+	}
+
+	_, err := wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","error":"i am test error","foo":"bar","message":"hello theme"}`+"\n")
+	if err != nil {
+		t.Errorf("test theme console writer error: %+v", err)
+	}
+	if got, want := b.String(), "2019-07-10T05:35:54.277Z \x1b[38;2;0;255;0mINF\x1b[0m > hello theme \x1b[2merror=\x1b[97mi am test error\x1b[0m \x1b[2mfoo=\x1b[97mbar\x1b[0m\n"; got != want {
+		t.Errorf("test theme console writer got %q, want %q", got, want)
+	}
+}
+
+func TestConsoleWriterAutoColor(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{ColorOutput: true, AutoColor: true, Writer: &b}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello auto color"}`+"\n")
+	if strings.Contains(b.String(), "\x1b[") {
+		t.Errorf("test auto color console writer must not colorize a non-terminal: %q", b.String())
+	}
+
+	w.Writer = os.Stdout
+	if IsTerminal(os.Stdout.Fd()) {
+		os.Setenv("NO_COLOR", "1")
+		defer os.Unsetenv("NO_COLOR")
+		if w.colorOutput() {
+			t.Errorf("test auto color console writer must respect NO_COLOR")
+		}
+	}
+}
+
+func TestConsoleWriterPartsOrder(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{
+		PartsOrder:    []string{"level", "request_id", "time", "message"},
+		FieldsExclude: []string{"noisy"},
+		Writer:        &b,
+	}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","caller":"test.go:42","request_id":"abc","noisy":1,"foo":"bar","message":"hello parts"}`+"\n")
+	if got, want := b.String(), "INF abc 2019-07-10T05:35:54.277Z > hello parts foo=bar\n"; got != want {
+		t.Errorf("test parts order console writer got %q, want %q", got, want)
+	}
+}
+
+func TestConsoleWriterFieldsJSON(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{FieldsJSON: true, EndWithMessage: true, Writer: &b}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","foo":"b\"ar","n":42,"obj":{"a":[1,2]},"message":"hello json fields"}`+"\n")
+	if got, want := b.String(), `2019-07-10T05:35:54.277Z INF > {"foo":"b\"ar","n":42,"obj":{"a":[1,2]}} hello json fields`+"\n"; got != want {
+		t.Errorf("test json fields console writer got %q, want %q", got, want)
+	}
+}
+
+func TestConsoleWriterMultiline(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{Multiline: true, Writer: &b}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"error","foo":"bar","obj":{"a":[1,2]},"error":"open failed\nmain.main()\n\tmain.go:42","message":"hello multiline"}`+"\n")
+	want := "2019-07-10T05:35:54.277Z ERR > hello multiline foo=bar\n" +
+		"  obj: {\n" +
+		"    \"a\": [\n" +
+		"      1,\n" +
+		"      2\n" +
+		"    ]\n" +
+		"  }\n" +
+		"  error:\n" +
+		"    open failed\n" +
+		"    main.main()\n" +
+		"    \tmain.go:42\n"
+	if got := b.String(); got != want {
+		t.Errorf("test multiline console writer got %q, want %q", got, want)
+	}
+}
+
+func TestConsoleWriterWidth(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{
+		LevelWidth:    4,
+		CallerWidth:   12,
+		MessageWidth:  8,
+		DurationWidth: 8,
+		Writer:        &b,
+	}
+
+	for _, s := range []string{
+		`{"time":"2019-07-10T05:35:54.277Z","level":"info","goid":"1","caller":"handler/server.go:42","elapsed":"1.5ms","message":"hello width"}`,
+		`{"time":"2019-07-10T05:35:54.277Z","level":"warn","elapsed":"120ms","message":"hi"}`,
+		`{"time":"2019-07-10T05:35:54.277Z","level":"warn","message":"hi"}`,
+	} {
+		_, _ = wlprintf(w, InfoLevel, "%s\n", s)
+	}
+	want := "2019-07-10T05:35:54.277Z INF  …erver.go:42 > hello w… elapsed=   1.5ms\n" +
+		"2019-07-10T05:35:54.277Z WRN               > hi       elapsed=   120ms\n" +
+		"2019-07-10T05:35:54.277Z WRN               > hi\n"
+	if got := b.String(); got != want {
+		t.Errorf("test width console writer got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGlogFormatter(t *testing.T) {
+	var b bytes.Buffer
+	w := &ConsoleWriter{Formatter: GlogFormatter{}.Formatter, Writer: &b}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277123+08:00","level":"warn","goid":1,"caller":"/src/handler/server.go:42","foo":"bar","n":42,"message":"hello glog"}`+"\n")
+	_, _ = wlprintf(w, InfoLevel, `{"time":"1562736954","level":"debug","message":"hello unix"}`+"\n")
+
+	unix := time.Unix(1562736954, 0)
+	want := fmt.Sprintf("W0710 05:35:54.277123 %7d server.go:42] hello glog foo=\"bar\" n=42\n", pid) +
+		fmt.Sprintf("I%02d%02d %02d:%02d:%02d.000000 %7d ???:1] hello unix\n", unix.Month(), unix.Day(), unix.Hour(), unix.Minute(), unix.Second(), pid)
+	if got := b.String(); got != want {
+		t.Errorf("test glog formatter got %q, want %q", got, want)
+	}
+
+	glog := Logger{
+		Caller:     1,
+		TimeFormat: "0102 15:04:05.999999",
+		Writer: &ConsoleWriter{
+			Formatter: GlogFormatter{TimeFormat: "0102 15:04:05.999999"}.Formatter,
+			Writer:    &b,
+		},
+	}
+	b.Reset()
+	glog.Error().Msg("hello glog error")
+	if got := b.String(); !strings.HasPrefix(got, "E") || !strings.Contains(got, " console_test.go:") || !strings.HasSuffix(got, "] hello glog error\n") {
+		t.Errorf("test glog formatter got %q", got)
+	}
+}
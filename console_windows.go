@@ -6,6 +6,8 @@ package log
 import (
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"unsafe"
@@ -22,32 +24,44 @@ func isTerminal(fd uintptr, _, _ string) bool {
 }
 
 var (
-	kernel32                = syscall.NewLazyDLL("kernel32.dll")
-	setConsoleMode          = kernel32.NewProc("SetConsoleMode").Call
-	setConsoleTextAttribute = kernel32.NewProc("SetConsoleTextAttribute").Call
+	kernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	setConsoleMode             = kernel32.NewProc("SetConsoleMode").Call
+	setConsoleTextAttribute    = kernel32.NewProc("SetConsoleTextAttribute").Call
+	getConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo").Call
 
 	muConsole   sync.Mutex
 	onceConsole sync.Once
-	isvt        bool
+	vtBuild     bool
+	vtConsoles  sync.Map // map[syscall.Handle]bool
 )
 
-// WriteEntry implements Writer
+// WriteEntry implements Writer, it enables the virtual terminal processing of the console
+// on windows 10 and later, or colorizes the legacy console by SetConsoleTextAttribute.
 func (w *ConsoleWriter) WriteEntry(e *Entry) (n int, err error) {
-	onceConsole.Do(func() { isvt = isVirtualTerminal() })
-
 	out := w.Writer
 	if out == nil {
 		out = os.Stderr
 	}
-	if isvt {
-		n, err = w.write(out, e.buf)
+	if h, ok := consoleHandle(out); ok && !isVirtualTerminal(h) {
+		n, err = w.writew(out, h, e.buf)
 	} else {
-		n, err = w.writew(out, e.buf)
+		n, err = w.write(out, e.buf)
 	}
 	return
 }
 
-func (w *ConsoleWriter) writew(out io.Writer, p []byte) (n int, err error) {
+// consoleHandle returns the console handle of out, it returns false if out is not a console.
+func consoleHandle(out io.Writer) (syscall.Handle, bool) {
+	f, ok := out.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	var mode uint32
+	h := syscall.Handle(f.Fd())
+	return h, syscall.GetConsoleMode(h, &mode) == nil
+}
+
+func (w *ConsoleWriter) writew(out io.Writer, h syscall.Handle, p []byte) (n int, err error) {
 	muConsole.Lock()
 	defer muConsole.Unlock()
 
@@ -60,23 +74,24 @@ func (w *ConsoleWriter) writew(out io.Writer, p []byte) (n int, err error) {
 		return
 	}
 	n = 0
-	// uintptr color
-	const (
-		Black  = 0
-		Blue   = 1
-		Green  = 2
-		Aqua   = 3
-		Red    = 4
-		Purple = 5
-		Yellow = 6
-		White  = 7
-		Gray   = 8
-	)
+
+	// the original attributes of console
+	var info struct {
+		size, cursor  [2]int16
+		attributes    uint16
+		window        [4]int16
+		maxWindowSize [2]int16
+	}
+	var reset uint16 = 7 // white
+	if ret, _, _ := getConsoleScreenBufferInfo(uintptr(h), uintptr(unsafe.Pointer(&info))); ret != 0 {
+		reset = info.attributes
+	}
+
 	// color print
-	var cprint = func(color uintptr, b []byte) {
-		if color != White {
-			setConsoleTextAttribute(uintptr(syscall.Stderr), color)
-			defer setConsoleTextAttribute(uintptr(syscall.Stderr), White)
+	var cprint = func(attr uint16, b []byte) {
+		if attr != reset {
+			setConsoleTextAttribute(uintptr(h), uintptr(attr))
+			defer setConsoleTextAttribute(uintptr(h), uintptr(reset))
 		}
 		var i int
 		i, err = out.Write(b)
@@ -87,83 +102,149 @@ func (w *ConsoleWriter) writew(out io.Writer, p []byte) (n int, err error) {
 	b2.B = b2.B[:0]
 	defer bbpool.Put(b2)
 
-	var color uintptr = White
-	var length = len(b.B)
-	var c uint32
+	attr := reset
+	length := len(b.B)
 	for i := 0; i < length; i++ {
-		if b.B[i] == '\x1b' {
-			switch {
-			case length-i > 3 &&
-				b.B[i+1] == '[' &&
-				'0' <= b.B[i+2] && b.B[i+2] <= '9' &&
-				b.B[i+3] == 'm':
-				c = uint32(b.B[i+2] - '0')
-				i += 3
-			case length-i > 4 &&
-				b.B[i+1] == '[' &&
-				'0' <= b.B[i+2] && b.B[i+2] <= '9' &&
-				'0' <= b.B[i+3] && b.B[i+3] <= '9' &&
-				b.B[i+4] == 'm':
-				c = uint32(b.B[i+2]-'0')*10 + uint32(b.B[i+3]-'0')
-				i += 4
-			}
-			if len(b2.B) > 0 {
-				cprint(color, b2.B)
-			}
-			b2.B = b2.B[:0]
-			switch c {
-			case 0: // Reset
-				color = White
-			case 30: // Black
-				color = Black
-			case 90: // Gray
-				color = Gray
-			case 31, 91: // Red, BrightRed
-				color = Red
-			case 32, 92: // Green, BrightGreen
-				color = Green
-			case 33, 93: // Yellow, BrightYellow
-				color = Yellow
-			case 34, 94: // Blue, BrightBlue
-				color = Blue
-			case 35, 95: // Magenta, BrightMagenta
-				color = Purple
-			case 36, 96: // Cyan, BrightCyan
-				color = Aqua
-			case 37, 97: // White, BrightWhite
-				color = White
-			default:
-				color = White
-			}
-		} else {
+		if b.B[i] != '\x1b' || i+1 >= length || b.B[i+1] != '[' {
 			b2.B = append(b2.B, b.B[i])
+			continue
+		}
+		// control sequence introducer, e.g. "\x1b[1;31m" and "\x1b[38;2;255;0;0m"
+		j := i + 2
+		for j < length && (b.B[j] >= '0' && b.B[j] <= '9' || b.B[j] == ';') {
+			j++
+		}
+		if j == length {
+			break
 		}
+		if len(b2.B) > 0 {
+			cprint(attr, b2.B)
+		}
+		b2.B = b2.B[:0]
+		if b.B[j] == 'm' {
+			attr = sgrAttributes(attr, reset, b.B[i+2:j])
+		}
+		i = j
 	}
 
 	if len(b2.B) != 0 {
-		cprint(White, b2.B)
+		cprint(attr, b2.B)
+	}
+
+	return
+}
+
+// sgrAttributes applies the parameters of ANSI select graphic rendition to the console
+// attributes, the 256 and true colors are approximated to the 16 colors.
+func sgrAttributes(attr, reset uint16, params []byte) uint16 {
+	const intensity = 8
+	// ansi colors are red=1, green=2 and blue=4, and console colors are blue=1, green=2 and red=4.
+	color := func(c int) uint16 {
+		return uint16(c&1<<2 | c&2 | c&4>>2)
+	}
+
+	var ps []int
+	for _, s := range strings.Split(string(params), ";") {
+		p, _ := strconv.Atoi(s)
+		ps = append(ps, p)
+	}
+	for k := 0; k < len(ps); k++ {
+		switch p := ps[k]; {
+		case p == 0:
+			attr = reset
+		case p == 1:
+			attr |= intensity
+		case p == 22:
+			attr &^= intensity
+		case p >= 30 && p <= 37:
+			attr = attr&^7 | color(p-30)
+		case p >= 90 && p <= 97:
+			attr = attr&^15 | color(p-90) | intensity
+		case p == 39:
+			attr = attr&^15 | reset&15
+		case p == 38 && k+2 < len(ps) && ps[k+1] == 5:
+			// 256 colors
+			c := ps[k+2]
+			switch {
+			case c < 8:
+				attr = attr&^15 | color(c)
+			case c < 16:
+				attr = attr&^15 | color(c-8) | intensity
+			case c < 232:
+				c -= 16
+				attr = attr&^15 | rgbAttributes(c/36*51, c/6%6*51, c%6*51)
+			default:
+				attr = attr&^15 | rgbAttributes((c-232)*10+8, (c-232)*10+8, (c-232)*10+8)
+			}
+			k += 2
+		case p == 38 && k+4 < len(ps) && ps[k+1] == 2:
+			// true colors
+			attr = attr&^15 | rgbAttributes(ps[k+2], ps[k+3], ps[k+4])
+			k += 4
+		}
 	}
+	return attr
+}
 
+// rgbAttributes approximates the rgb color to the console attributes.
+func rgbAttributes(r, g, b int) (attr uint16) {
+	if r > 127 {
+		attr |= 4
+	}
+	if g > 127 {
+		attr |= 2
+	}
+	if b > 127 {
+		attr |= 1
+	}
+	if r > 191 || g > 191 || b > 191 {
+		attr |= 8
+	} else if attr == 0 && (r > 63 || g > 63 || b > 63) {
+		attr = 8 // gray
+	}
 	return
 }
 
-func isVirtualTerminal() bool {
+// isVirtualTerminal enables the virtual terminal processing of console handle h, it returns
+// false on the windows earlier than 10 build 16257.
+func isVirtualTerminal(h syscall.Handle) bool {
+	onceConsole.Do(func() { vtBuild = windowsBuild() >= 16257 })
+	if !vtBuild {
+		return false
+	}
+
+	if v, ok := vtConsoles.Load(h); ok {
+		return v.(bool)
+	}
+
+	var vt bool
+	var mode uint32
+	if syscall.GetConsoleMode(h, &mode) == nil {
+		// enable ENABLE_VIRTUAL_TERMINAL_PROCESSING
+		ret, _, _ := setConsoleMode(uintptr(h), uintptr(mode|0x4))
+		vt = ret != 0
+	}
+	vtConsoles.Store(h, vt)
+	return vt
+}
+
+// windowsBuild returns the build number of windows.
+func windowsBuild() (n uint32) {
 	var h syscall.Handle
 	var b [64]uint16
-	var n uint32
 
 	// open registry
 	err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, syscall.StringToUTF16Ptr(`SOFTWARE\Microsoft\Windows NT\CurrentVersion`), 0, syscall.KEY_READ, &h)
 	if err != nil {
-		return false
+		return
 	}
 	defer syscall.RegCloseKey(h)
 
 	// read windows build number
-	n = uint32(len(b))
-	err = syscall.RegQueryValueEx(h, syscall.StringToUTF16Ptr(`CurrentBuild`), nil, nil, (*byte)(unsafe.Pointer(&b[0])), &n)
+	size := uint32(len(b))
+	err = syscall.RegQueryValueEx(h, syscall.StringToUTF16Ptr(`CurrentBuild`), nil, nil, (*byte)(unsafe.Pointer(&b[0])), &size)
 	if err != nil {
-		return false
+		return
 	}
 	for i := 0; i < len(b); i++ {
 		if b[i] == 0 {
@@ -171,19 +252,5 @@ func isVirtualTerminal() bool {
 		}
 		n = n*10 + uint32(b[i]-'0')
 	}
-
-	// return if lower than windows 10 16257
-	if n < 16257 {
-		return false
-	}
-
-	// get console mode
-	err = syscall.GetConsoleMode(syscall.Stderr, &n)
-	if err != nil {
-		return false
-	}
-
-	// enable ENABLE_VIRTUAL_TERMINAL_PROCESSING
-	ret, _, _ := setConsoleMode(uintptr(syscall.Stderr), uintptr(n|0x4))
-	return ret != 0
+	return
 }
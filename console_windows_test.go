@@ -0,0 +1,40 @@
+//go:build windows
+// +build windows
+
+package log
+
+import (
+	"testing"
+)
+
+func TestSgrAttributes(t *testing.T) {
+	const reset = 7
+	cases := []struct {
+		Params string
+		Attr   uint16
+	}{
+		{"0", reset},
+		{"", reset},
+		{"31", 4},
+		{"32", 2},
+		{"36", 3},
+		{"90", 8},
+		{"1;31", 12},
+		{"91", 12},
+		{"2", reset},
+		{"38;5;9", 12},
+		{"38;5;46", 10},
+		{"38;2;255;135;0", 14},
+		{"38;2;0;0;128", 1},
+	}
+
+	for _, c := range cases {
+		if attr := sgrAttributes(reset, reset, []byte(c.Params)); attr != c.Attr {
+			t.Errorf("sgrAttributes(%q) got %d, want %d", c.Params, attr, c.Attr)
+		}
+	}
+
+	if attr := sgrAttributes(4, reset, []byte("0")); attr != reset {
+		t.Errorf("sgrAttributes must reset the attributes, got %d", attr)
+	}
+}
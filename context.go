@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+)
+
+type loggerContextKey struct{}
+
+// WithContext returns a copy of ctx with the logger associated, which can be
+// retrieved later by Ctx.
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	if lp, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && lp == l {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// Ctx returns the logger associated with ctx, or &DefaultLogger if no logger is associated.
+func Ctx(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return &DefaultLogger
+}
+
+// WithFields returns a copy of ctx with a derived logger of Ctx(ctx), the fields are appended
+// to the Context of derived logger. It allows the request handlers attach the request-scoped fields,
+// e.g. request_id, without threading a logger through every function.
+//
+//	ctx = log.WithFields(r.Context(), log.NewContext(nil).Str("request_id", id).Value())
+//	log.Ctx(ctx).Info().Msg("hello world")
+func WithFields(ctx context.Context, fields Context) context.Context {
+	l := *Ctx(ctx)
+	l.Context = append(l.Context[:len(l.Context):len(l.Context)], fields...)
+	return context.WithValue(ctx, loggerContextKey{}, &l)
+}
+
+// TraceContextFunc specifies the extractor of the active span from ctx for Entry.TraceContext,
+// it returns zero traceID if no valid span is active. e.g. for OpenTelemetry
+//
+//	log.TraceContextFunc = func(ctx context.Context) ([16]byte, [8]byte, byte) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return sc.TraceID(), sc.SpanID(), byte(sc.TraceFlags())
+//	}
+var TraceContextFunc func(ctx context.Context) (traceID [16]byte, spanID [8]byte, traceFlags byte)
+
+// TraceContext adds the "trace_id", "span_id" and "trace_flags" fields of the active
+// span of ctx to the entry, by using TraceContextFunc.
+func (e *Entry) TraceContext(ctx context.Context) *Entry {
+	if e == nil || ctx == nil || TraceContextFunc == nil {
+		return e
+	}
+	traceID, spanID, flags := TraceContextFunc(ctx)
+	if traceID == ([16]byte{}) {
+		return e
+	}
+	return e.Hex("trace_id", traceID[:]).Hex("span_id", spanID[:]).Hex("trace_flags", []byte{flags})
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLoggerWithContext(t *testing.T) {
+	var b bytes.Buffer
+	logger := Logger{
+		Context: NewContext(nil).Str("service", "api").Value(),
+		Writer:  IOWriter{&b},
+	}
+
+	ctx := logger.WithContext(context.Background())
+	if l := Ctx(ctx); l != &logger {
+		t.Errorf("Ctx should return the associated logger: %p != %p", l, &logger)
+	}
+	if ctx2 := logger.WithContext(ctx); ctx2 != ctx {
+		t.Errorf("WithContext should return ctx if logger is associated already")
+	}
+
+	ctx = WithFields(ctx, NewContext(nil).Str("request_id", "123").Value())
+	ctx2 := WithFields(ctx, NewContext(nil).Int("user_id", 42).Value())
+	ctx3 := WithFields(ctx, NewContext(nil).Int("user_id", 24).Value())
+
+	Ctx(ctx2).Info().Msg("hello ctx2")
+	Ctx(ctx3).Info().Msg("hello ctx3")
+	logger.Info().Msg("hello logger")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), b.String())
+	}
+	if !strings.HasSuffix(lines[0], `"service":"api","request_id":"123","user_id":42,"message":"hello ctx2"}`) {
+		t.Errorf("unexpected ctx2 output: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"service":"api","request_id":"123","user_id":24,"message":"hello ctx3"}`) {
+		t.Errorf("unexpected ctx3 output: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], `"service":"api","message":"hello logger"}`) {
+		t.Errorf("unexpected logger output: %s", lines[2])
+	}
+}
+
+func TestCtxDefaultLogger(t *testing.T) {
+	if l := Ctx(context.Background()); l != &DefaultLogger {
+		t.Errorf("Ctx should return &DefaultLogger if no logger is associated")
+	}
+	if l := Ctx(nil); l != &DefaultLogger {
+		t.Errorf("Ctx should return &DefaultLogger for nil context")
+	}
+}
+
+type traceContextKey struct{}
+
+func TestEntryTraceContext(t *testing.T) {
+	var b bytes.Buffer
+	logger := Logger{Writer: IOWriter{&b}}
+
+	logger.Info().TraceContext(context.Background()).Msg("no extractor")
+	if strings.Contains(b.String(), "trace_id") {
+		t.Errorf("TraceContext should be no-op without TraceContextFunc: %s", b.String())
+	}
+
+	TraceContextFunc = func(ctx context.Context) (traceID [16]byte, spanID [8]byte, flags byte) {
+		if v, ok := ctx.Value(traceContextKey{}).(string); ok && v != "" {
+			copy(traceID[:], "0123456789abcdef")
+			copy(spanID[:], v)
+			flags = 1
+		}
+		return
+	}
+	defer func() { TraceContextFunc = nil }()
+
+	b.Reset()
+	logger.Info().TraceContext(context.Background()).Msg("no span")
+	if strings.Contains(b.String(), "trace_id") {
+		t.Errorf("TraceContext should be no-op without active span: %s", b.String())
+	}
+
+	b.Reset()
+	ctx := context.WithValue(context.Background(), traceContextKey{}, "span0001")
+	logger.Info().TraceContext(ctx).Msg("with span")
+	if !strings.Contains(b.String(), `"trace_id":"30313233343536373839616263646566","span_id":"7370616e30303031","trace_flags":"01"`) {
+		t.Errorf("TraceContext should add trace fields: %s", b.String())
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// CSVWriter is an Writer that projects the fields of entries into the columns of CSV,
+// the absent fields are empty, and the non-string values are written as raw json.
+type CSVWriter struct {
+	// Columns specifies the fields of columns in order, must not be empty.
+	Columns []string
+
+	// Header determines if writes the columns as header row before the first entry.
+	Header bool
+
+	// Comma specifies the delimiter of columns, uses ',' if zero, e.g. '\t' or ';'.
+	Comma byte
+
+	// UseCRLF determines if ends the rows with \r\n.
+	UseCRLF bool
+
+	// Writer is the output destination. using os.Stdout if empty.
+	Writer io.Writer
+
+	mu     sync.Mutex
+	header bool
+}
+
+// Close implements io.Closer, will closes the underlying Writer if not empty.
+func (w *CSVWriter) Close() (err error) {
+	if w.Writer != nil {
+		if closer, ok := w.Writer.(io.Closer); ok {
+			err = closer.Close()
+		}
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *CSVWriter) WriteEntry(e *Entry) (n int, err error) {
+	out := w.Writer
+	if out == nil {
+		out = os.Stdout
+	}
+	comma := w.Comma
+	if comma == 0 {
+		comma = ','
+	}
+
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	t := bbpool.Get().(*bb)
+	defer func() {
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+		if cap(t.B) <= bbcap {
+			bbpool.Put(t)
+		}
+	}()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.Header && !w.header {
+		w.header = true
+		for i, column := range w.Columns {
+			if i > 0 {
+				b.B = append(b.B, comma)
+			}
+			b.B = csvAppendField(b.B, column, comma)
+		}
+		b.B = w.appendEOL(b.B)
+	}
+
+	for i, column := range w.Columns {
+		if i > 0 {
+			b.B = append(b.B, comma)
+		}
+		value := jsonGetField(e.buf, column)
+		switch {
+		case len(value) == 0, b2s(value) == "null":
+		case value[0] == '"' && len(value) >= 2:
+			b.B = csvAppendField(b.B, encodeUnescape(value[1:len(value)-1], t), comma)
+		default:
+			b.B = csvAppendField(b.B, b2s(value), comma)
+		}
+	}
+	b.B = w.appendEOL(b.B)
+
+	return out.Write(b.B)
+}
+
+func (w *CSVWriter) appendEOL(dst []byte) []byte {
+	if w.UseCRLF {
+		return append(dst, '\r', '\n')
+	}
+	return append(dst, '\n')
+}
+
+// csvAppendField appends the field of csv, it is quoted if it contains the comma,
+// quotes, newlines or leading space, and the quotes are doubled.
+func csvAppendField(dst []byte, field string, comma byte) []byte {
+	quote := len(field) > 0 && (field[0] == ' ' || field[0] == '\t')
+	for i := 0; i < len(field) && !quote; i++ {
+		switch field[i] {
+		case comma, '"', '\r', '\n':
+			quote = true
+		}
+	}
+	if !quote {
+		return append(dst, field...)
+	}
+	dst = append(dst, '"')
+	for i := 0; i < len(field); i++ {
+		if field[i] == '"' {
+			dst = append(dst, '"')
+		}
+		dst = append(dst, field[i])
+	}
+	return append(dst, '"')
+}
+
+var _ Writer = (*CSVWriter)(nil)
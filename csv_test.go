@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &CSVWriter{
+		Columns: []string{"time", "level", "user", "n", "obj", "message"},
+		Header:  true,
+		Writer:  &buf,
+	}
+
+	_, _ = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","user":"a,b","n":42,"obj":{"a":"b"},"message":"say \"hi\"\nbye"}`+"\n")
+	_, _ = wlprintf(w, WarnLevel, `{"time":"2019-07-10T05:35:55.277Z","level":"warn","user":null,"message":" lead"}`+"\n")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("csv read error: %+v", err)
+	}
+	want := [][]string{
+		{"time", "level", "user", "n", "obj", "message"},
+		{"2019-07-10T05:35:54.277Z", "info", "a,b", "42", `{"a":"b"}`, "say \"hi\"\nbye"},
+		{"2019-07-10T05:35:55.277Z", "warn", "", "", "", " lead"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("csv writer: got %q, want %q", records, want)
+	}
+}
+
+func TestCSVWriterComma(t *testing.T) {
+	var buf bytes.Buffer
+	w := &CSVWriter{
+		Columns: []string{"level", "message"},
+		Comma:   ';',
+		UseCRLF: true,
+		Writer:  &buf,
+	}
+
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","message":"a;b,c"}`+"\n")
+
+	if got, want := buf.String(), "info;\"a;b,c\"\r\n"; got != want {
+		t.Errorf("csv writer: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DatadogWriter is a Writer that sends entries in batches to Datadog logs intake api,
+// the level is mapped to the "status" attribute of logs.
+type DatadogWriter struct {
+	// APIKey specifies the api key of Datadog.
+	APIKey string
+
+	// Site specifies the site of Datadog, uses "datadoghq.com" if empty.
+	Site string
+
+	// URL specifies the intake url, uses "https://http-intake.logs.{Site}/api/v2/logs" if empty.
+	URL string
+
+	// Source specifies the "ddsource" attribute of logs, uses "go" if empty.
+	Source string
+
+	// Service specifies the "service" attribute of logs.
+	Service string
+
+	// Tags specifies the "ddtags" attribute of logs, e.g. "env:prod,version:1.0".
+	Tags string
+
+	// Host specifies the "hostname" attribute of logs, uses hostname if empty.
+	Host string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 1000 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once     sync.Once
+	batcher  batcher
+	url      string
+	header   http.Header
+	metadata []byte
+}
+
+func (w *DatadogWriter) init() {
+	w.once.Do(func() {
+		w.url = w.URL
+		if w.url == "" {
+			site := w.Site
+			if site == "" {
+				site = "datadoghq.com"
+			}
+			w.url = "https://http-intake.logs." + site + "/api/v2/logs"
+		}
+
+		w.header = http.Header{
+			"Content-Type": []string{"application/json"},
+			"Dd-Api-Key":   []string{w.APIKey},
+		}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+
+		source := w.Source
+		if source == "" {
+			source = "go"
+		}
+		host := w.Host
+		if host == "" {
+			host = hostname
+		}
+		w.metadata = append(w.metadata, "\"ddsource\":"...)
+		w.metadata = jsonAppendString(w.metadata, source)
+		if w.Service != "" {
+			w.metadata = append(w.metadata, ",\"service\":"...)
+			w.metadata = jsonAppendString(w.metadata, w.Service)
+		}
+		if w.Tags != "" {
+			w.metadata = append(w.metadata, ",\"ddtags\":"...)
+			w.metadata = jsonAppendString(w.metadata, w.Tags)
+		}
+		w.metadata = append(w.metadata, ",\"hostname\":"...)
+		w.metadata = jsonAppendString(w.metadata, host)
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 || w.batcher.size > 1000 {
+			w.batcher.size = 1000
+		}
+		w.batcher.bytes = 4 * 1024 * 1024
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *DatadogWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *DatadogWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *DatadogWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	buf.B = buf.B[:0]
+	defer bbpool.Put(buf)
+
+	dst := append(buf.B, '[')
+	for i := 0; i < b.Len(); i++ {
+		entry := bytes.TrimRight(b.Entry(i), "\n")
+		if len(entry) < 2 || entry[0] != '{' {
+			continue
+		}
+		if len(dst) > 1 {
+			dst = append(dst, ',')
+		}
+		level := b.levels[i]
+		if level == noLevel {
+			level = parseJSONLevel(entry, "level")
+		}
+		dst = append(dst, '{')
+		dst = append(dst, w.metadata...)
+		dst = append(dst, ",\"status\":\""...)
+		dst = append(dst, datadogStatus(level)...)
+		dst = append(dst, '"')
+		if len(entry) > 2 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, entry[1:]...)
+	}
+	dst = append(dst, ']')
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	return httpSend(w.Client, http.MethodPost, w.url, w.header, body)
+}
+
+// datadogStatus converts Level to the status of Datadog logs.
+func datadogStatus(level Level) string {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "critical"
+	case PanicLevel:
+		return "emergency"
+	}
+	return "info"
+}
+
+var _ Writer = (*DatadogWriter)(nil)
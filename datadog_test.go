@@ -0,0 +1,73 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDatadogWriter(t *testing.T) {
+	logs := make(chan []map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "key1" || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("datadog writer unexpected request: %v", r.Header)
+		}
+		zr, _ := gzip.NewReader(r.Body)
+		var v []map[string]interface{}
+		if err := json.NewDecoder(zr).Decode(&v); err != nil {
+			t.Errorf("datadog writer json error: %+v", err)
+		}
+		logs <- v
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	w := &DatadogWriter{
+		APIKey:  "key1",
+		URL:     server.URL + "/api/v2/logs",
+		Service: "myapp",
+		Tags:    "env:test",
+		Gzip:    true,
+	}
+	logger := Logger{Writer: w}
+
+	logger.Warn().Str("foo", "bar").Msg("hello datadog")
+	logger.Error().Msg("error")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("datadog writer close error: %+v", err)
+	}
+
+	v := <-logs
+	if len(v) != 2 {
+		t.Fatalf("datadog writer should send 2 logs: %+v", v)
+	}
+	if v[0]["ddsource"] != "go" || v[0]["service"] != "myapp" || v[0]["ddtags"] != "env:test" || v[0]["hostname"] != hostname {
+		t.Errorf("datadog writer metadata mismatch: %+v", v[0])
+	}
+	if v[0]["status"] != "warn" || v[0]["message"] != "hello datadog" || v[0]["foo"] != "bar" {
+		t.Errorf("datadog writer log mismatch: %+v", v[0])
+	}
+	if v[1]["status"] != "error" {
+		t.Errorf("datadog writer status mismatch: %+v", v[1])
+	}
+}
+
+func TestDatadogStatus(t *testing.T) {
+	cases := map[Level]string{
+		TraceLevel: "debug",
+		DebugLevel: "debug",
+		InfoLevel:  "info",
+		WarnLevel:  "warn",
+		ErrorLevel: "error",
+		FatalLevel: "critical",
+		PanicLevel: "emergency",
+	}
+	for level, status := range cases {
+		if s := datadogStatus(level); s != status {
+			t.Errorf("datadogStatus(%v) = %s, want %s", level, s, status)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupWriter is a Writer that collapses consecutive duplicate entries within Window
+// into the first entry with a "count" field and a "last_time" field of the last duplicate,
+// the "last_time" field is named as "last_" + TimeField.
+// Two entries are duplicate if they are same except the TimeField.
+//
+// Note that the entry is held until a different entry arrives or the window closes.
+type DedupWriter struct {
+	// Window specifies the time window of deduplication, uses 1 second if zero.
+	Window time.Duration
+
+	// TimeField specifies the time field name of entries, uses "time" if empty.
+	TimeField string
+
+	// Writer specifies the writer of output.
+	Writer Writer
+
+	mu    sync.Mutex
+	timer *time.Timer
+	hash  uint64
+	start int64
+	count int
+	level Level
+	first []byte
+	last  []byte
+}
+
+// Close writes the pending entry and closes the underlying writer.
+func (w *DedupWriter) Close() (err error) {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	err = w.flush()
+	w.mu.Unlock()
+
+	if closer, ok := w.Writer.(io.Closer); ok {
+		if err1 := closer.Close(); err1 != nil {
+			err = err1
+		}
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *DedupWriter) WriteEntry(e *Entry) (n int, err error) {
+	field := w.TimeField
+	if field == "" {
+		field = "time"
+	}
+	window := w.Window
+	if window <= 0 {
+		window = time.Second
+	}
+
+	value := jsonGetField(e.buf, field)
+	hash := dedupHash(e.buf, value)
+	now := timeNow().UnixNano()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n = len(e.buf)
+
+	if w.count > 0 && w.hash == hash && now < w.start+int64(window) {
+		w.count++
+		w.last = append(w.last[:0], value...)
+		return
+	}
+
+	err = w.flush()
+
+	w.hash, w.start, w.count, w.level = hash, now, 1, e.Level
+	w.first = append(w.first[:0], e.buf...)
+	w.last = w.last[:0]
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(window, w.expire)
+	} else {
+		w.timer.Reset(window)
+	}
+
+	return
+}
+
+func (w *DedupWriter) expire() {
+	window := w.Window
+	if window <= 0 {
+		window = time.Second
+	}
+
+	w.mu.Lock()
+	if w.count > 0 && timeNow().UnixNano() >= w.start+int64(window) {
+		_ = w.flush()
+	}
+	w.mu.Unlock()
+}
+
+// flush writes the pending entry, with the count of duplicates if any.
+func (w *DedupWriter) flush() (err error) {
+	if w.count == 0 {
+		return
+	}
+
+	e := epool.Get().(*Entry)
+	e.Level = w.level
+	e.name = ""
+	if i := bytes.LastIndexByte(w.first, '}'); w.count > 1 && i >= 0 {
+		e.buf = append(e.buf[:0], w.first[:i]...)
+		e.buf = append(e.buf, ",\"count\":"...)
+		e.buf = strconv.AppendInt(e.buf, int64(w.count), 10)
+		if len(w.last) > 0 {
+			field := w.TimeField
+			if field == "" {
+				field = "time"
+			}
+			e.buf = append(e.buf, ",\"last_"...)
+			e.buf = append(e.buf, field...)
+			e.buf = append(e.buf, '"', ':')
+			e.buf = append(e.buf, w.last...)
+		}
+		e.buf = append(e.buf, w.first[i:]...)
+	} else {
+		e.buf = append(e.buf[:0], w.first...)
+	}
+
+	_, err = w.Writer.WriteEntry(e)
+
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+
+	w.count = 0
+	return
+}
+
+// dedupHash returns the FNV-1a hash of buf excluding value, which is a sub slice of buf.
+func dedupHash(buf, value []byte) uint64 {
+	i := len(buf)
+	if len(value) > 0 {
+		i = cap(buf) - cap(value)
+	}
+	h := uint64(14695981039346656037)
+	for _, c := range buf[:i] {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	for _, c := range buf[i+len(value):] {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+var _ Writer = (*DedupWriter)(nil)
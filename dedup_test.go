@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestDedupWriter(t *testing.T) {
+	var b syncBuffer
+	w := &DedupWriter{
+		Window: time.Minute,
+		Writer: IOWriter{&b},
+	}
+	logger := Logger{
+		TimeFormat: TimeFormatUnixMs,
+		Writer:     w,
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Info().Str("foo", "bar").Msg("hello dedup")
+		time.Sleep(2 * time.Millisecond)
+	}
+	logger.Info().Str("foo", "baz").Msg("hello dedup")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("dedup writer should write 1 entry, got %d: %s", len(lines), b.String())
+	}
+	if !strings.Contains(lines[0], `"foo":"bar","message":"hello dedup","count":5,"last_time":`) {
+		t.Errorf("dedup writer should collapse duplicates: %s", lines[0])
+	}
+	if first, last := jsonGetField([]byte(lines[0]), "time"), jsonGetField([]byte(lines[0]), "last_time"); string(first) >= string(last) {
+		t.Errorf("dedup writer should preserve first and last timestamps: %s", lines[0])
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("dedup writer close error: %+v", err)
+	}
+	if !strings.HasSuffix(b.String(), `"foo":"baz","message":"hello dedup"}`+"\n") {
+		t.Errorf("dedup writer should write pending entry on close: %s", b.String())
+	}
+}
+
+func TestDedupWriterWindow(t *testing.T) {
+	var b syncBuffer
+	w := &DedupWriter{
+		Window: 20 * time.Millisecond,
+		Writer: IOWriter{&b},
+	}
+	logger := Logger{Writer: w}
+
+	logger.Warn().Msg("hello window")
+	logger.Warn().Msg("hello window")
+	time.Sleep(50 * time.Millisecond)
+	if !strings.Contains(b.String(), `"message":"hello window","count":2,"last_time":`) {
+		t.Errorf("dedup writer should write pending entry when window closes: %s", b.String())
+	}
+
+	logger.Warn().Msg("hello window")
+	time.Sleep(50 * time.Millisecond)
+	if n := strings.Count(b.String(), "\n"); n != 2 {
+		t.Errorf("dedup writer should write 2 entries, got %d: %s", n, b.String())
+	}
+}
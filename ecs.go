@@ -0,0 +1,128 @@
+package log
+
+// ECSVersion is the version of Elastic Common Schema written by ECSEncoder.
+const ECSVersion = "8.11.0"
+
+// ECSEncoder is an Encoder that renames the top-level fields of entries to Elastic Common
+// Schema, e.g. `@timestamp`, `log.level`, `error.message` and `trace.id`, and adds the
+// `ecs.version` field, so the entries can be indexed by Elasticsearch without ingest pipelines.
+type ECSEncoder struct {
+	// Encoder specifies the underlying encoder, uses JSONEncoder if nil.
+	Encoder Encoder
+
+	// TimeField specifies the time field of logger, uses "time" if empty.
+	TimeField string
+
+	// Fields specifies the additional renaming of fields, it takes precedence over the defaults.
+	Fields map[string]string
+}
+
+// ecsFields is the default renaming of ECSEncoder.
+var ecsFields = map[string]string{
+	"level":    "log.level",
+	"msg":      "message",
+	"error":    "error.message",
+	"stack":    "error.stack_trace",
+	"goid":     "process.thread.id",
+	"logger":   "log.logger",
+	"trace_id": "trace.id",
+	"traceid":  "trace.id",
+	"span_id":  "span.id",
+	"spanid":   "span.id",
+	"host":     "host.hostname",
+	"hostname": "host.hostname",
+	"service":  "service.name",
+}
+
+func (enc ECSEncoder) encoder() Encoder {
+	if enc.Encoder == nil {
+		return JSONEncoder{}
+	}
+	return enc.Encoder
+}
+
+// rename returns the ECS name of key.
+func (enc ECSEncoder) rename(key string) string {
+	if name, ok := enc.Fields[key]; ok {
+		return name
+	}
+	if key == enc.TimeField || (enc.TimeField == "" && key == "time") {
+		return "@timestamp"
+	}
+	if name, ok := ecsFields[key]; ok {
+		return name
+	}
+	return key
+}
+
+// BeginEntry implements Encoder.
+func (enc ECSEncoder) BeginEntry(dst []byte, n int) []byte {
+	e := enc.encoder()
+	dst = e.BeginEntry(dst, n+1)
+	dst = e.AppendKey(dst, "ecs.version")
+	return e.AppendString(dst, ECSVersion)
+}
+
+// EndEntry implements Encoder.
+func (enc ECSEncoder) EndEntry(dst []byte) []byte { return enc.encoder().EndEntry(dst) }
+
+// AppendKey implements Encoder.
+func (enc ECSEncoder) AppendKey(dst []byte, key string) []byte {
+	return enc.encoder().AppendKey(dst, enc.rename(key))
+}
+
+// AppendString implements Encoder.
+func (enc ECSEncoder) AppendString(dst []byte, s string) []byte {
+	return enc.encoder().AppendString(dst, s)
+}
+
+// AppendInt implements Encoder.
+func (enc ECSEncoder) AppendInt(dst []byte, n int64) []byte { return enc.encoder().AppendInt(dst, n) }
+
+// AppendUint implements Encoder.
+func (enc ECSEncoder) AppendUint(dst []byte, n uint64) []byte {
+	return enc.encoder().AppendUint(dst, n)
+}
+
+// AppendFloat implements Encoder.
+func (enc ECSEncoder) AppendFloat(dst []byte, f float64) []byte {
+	return enc.encoder().AppendFloat(dst, f)
+}
+
+// AppendBool implements Encoder.
+func (enc ECSEncoder) AppendBool(dst []byte, b bool) []byte { return enc.encoder().AppendBool(dst, b) }
+
+// AppendNull implements Encoder.
+func (enc ECSEncoder) AppendNull(dst []byte) []byte { return enc.encoder().AppendNull(dst) }
+
+// AppendJSON appends the nested objects and arrays by the underlying encoder, so that
+// only the top-level fields are renamed.
+func (enc ECSEncoder) AppendJSON(dst, json []byte) []byte {
+	x := newEncoder(enc.encoder())
+	dst = x.value(dst, json)
+	x.free()
+	return dst
+}
+
+// SortedKeys reports whether the underlying encoder sorts the keys, see Encoder.
+// The top-level fields are sorted by the names before renaming.
+func (enc ECSEncoder) SortedKeys() bool {
+	sorter, ok := enc.encoder().(interface{ SortedKeys() bool })
+	return ok && sorter.SortedKeys()
+}
+
+// BeginObject implements Encoder, it is unused as the nested objects are appended by AppendJSON.
+func (enc ECSEncoder) BeginObject(dst []byte, n int) []byte {
+	return enc.encoder().BeginObject(dst, n)
+}
+
+// EndObject implements Encoder.
+func (enc ECSEncoder) EndObject(dst []byte) []byte { return enc.encoder().EndObject(dst) }
+
+// BeginArray implements Encoder, it is unused as the nested arrays are appended by AppendJSON.
+func (enc ECSEncoder) BeginArray(dst []byte, n int) []byte { return enc.encoder().BeginArray(dst, n) }
+
+// EndArray implements Encoder.
+func (enc ECSEncoder) EndArray(dst []byte) []byte { return enc.encoder().EndArray(dst) }
+
+var _ Encoder = ECSEncoder{}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestECSEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Writer: &EncodeWriter{Encoder: ECSEncoder{Fields: map[string]string{"user": "user.name"}}, Writer: &buf},
+	}
+
+	logger.Error().Err(errors.New("an error")).Str("trace_id", "abc").Str("user", "alice").
+		RawJSON("obj", []byte(`{"error":1,"level":2}`)).Msg("hello ecs")
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("json unmarshal %s error: %+v", buf.Bytes(), err)
+	}
+	if _, ok := m["@timestamp"]; !ok {
+		t.Errorf("ecs encoder should rename time to @timestamp: %s", buf.Bytes())
+	}
+	delete(m, "@timestamp")
+
+	want := map[string]interface{}{
+		"ecs.version":   ECSVersion,
+		"log.level":     "error",
+		"error.message": "an error",
+		"trace.id":      "abc",
+		"user.name":     "alice",
+		"obj":           map[string]interface{}{"error": float64(1), "level": float64(2)},
+		"message":       "hello ecs",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("ecs encoder: got %+v, want %+v", m, want)
+	}
+}
+
+func TestECSEncoderUnderlying(t *testing.T) {
+	got := string(EncodeJSON(ECSEncoder{Encoder: LogfmtEncoder{}, TimeField: "ts"}, nil, []byte(`{"ts":1,"level":"info","stack":"a b","obj":{"msg":1}}`)))
+	want := `ecs.version=8.11.0 @timestamp=1 log.level=info error.stack_trace="a b" obj="{\"msg\":1}"` + "\n"
+	if got != want {
+		t.Errorf("ecs logfmt encoder: got %q, want %q", got, want)
+	}
+
+	got = hexEncode(EncodeJSON(ECSEncoder{Encoder: CBOREncoder{Canonical: true}}, nil, []byte(`{"level":"info","a":{"z":1,"y":2}}`)))
+	want = "a3" + "6b6563732e76657273696f6e" + "66382e31312e30" + "6161" + "a2" + "6179" + "02" + "617a" + "01" + "696c6f672e6c6576656c" + "64696e666f"
+	if got != want {
+		t.Errorf("ecs cbor encoder: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ElasticWriter is a Writer that indexes entries in batches to Elasticsearch/OpenSearch
+// via the bulk api. The batches are flushed by a background goroutine, and the WriteEntry
+// blocks if the pending batch is full and the previous one is still being indexed.
+type ElasticWriter struct {
+	// URL specifies the base url of cluster, uses "http://localhost:9200" if empty.
+	URL string
+
+	// Index specifies the index name or data stream, uses "logs" if empty.
+	Index string
+
+	// IndexDateFormat specifies the date layout of rolling index names, e.g. "2006.01.02"
+	// makes the daily indices like "logs-2025.01.02" by the entry time in UTC.
+	IndexDateFormat string
+
+	// Username and Password specifies the basic authentication.
+	Username string
+	Password string
+
+	// APIKey specifies the base64 encoded api key authentication.
+	APIKey string
+
+	// Headers specifies the extra http headers of requests.
+	Headers map[string]string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 1000 if zero.
+	BatchSize int
+
+	// BatchBytes specifies the maximum bytes of a request, uses 5MB if zero.
+	BatchBytes int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// DeadLetter specifies the fallback writer of rejected documents and failed requests.
+	DeadLetter Writer
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once    sync.Once
+	batcher batcher
+	url     string
+	header  http.Header
+}
+
+func (w *ElasticWriter) init() {
+	w.once.Do(func() {
+		w.url = w.URL
+		if w.url == "" {
+			w.url = "http://localhost:9200"
+		}
+		w.url = strings.TrimRight(w.url, "/") + "/_bulk"
+
+		w.header = http.Header{"Content-Type": []string{"application/x-ndjson"}}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+		switch {
+		case w.APIKey != "":
+			w.header.Set("Authorization", "ApiKey "+w.APIKey)
+		case w.Username != "":
+			w.header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(w.Username+":"+w.Password)))
+		}
+		for k, v := range w.Headers {
+			w.header.Set(k, v)
+		}
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 1000
+		}
+		w.batcher.bytes = w.BatchBytes
+		if w.batcher.bytes <= 0 {
+			w.batcher.bytes = 5 * 1024 * 1024
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.batcher.fail = func(b *batch, err error) {
+			for i := 0; i < b.Len(); i++ {
+				w.deadLetter(b, i, b.Entry(i))
+			}
+		}
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *ElasticWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *ElasticWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *ElasticWriter) deadLetter(b *batch, i int, entry []byte) {
+	if w.DeadLetter != nil {
+		_, _ = w.DeadLetter.WriteEntry(&Entry{buf: entry, Level: b.levels[i]})
+	}
+}
+
+func (w *ElasticWriter) send(b *batch) error {
+	index := w.Index
+	if index == "" {
+		index = "logs"
+	}
+
+	buf := bbpool.Get().(*bb)
+	buf.B = buf.B[:0]
+	defer bbpool.Put(buf)
+
+	dst := buf.B
+	for i := 0; i < b.Len(); i++ {
+		entry := b.Entry(i)
+		dst = append(dst, "{\"create\":{\"_index\":\""...)
+		dst = append(dst, index...)
+		if w.IndexDateFormat != "" {
+			ts, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+			if !ok {
+				ts = b.times[i]
+			}
+			dst = append(dst, '-')
+			dst = time.Unix(0, ts).UTC().AppendFormat(dst, w.IndexDateFormat)
+		}
+		dst = append(dst, "\"}}\n"...)
+		dst = append(dst, entry...)
+		if len(entry) == 0 || entry[len(entry)-1] != '\n' {
+			dst = append(dst, '\n')
+		}
+	}
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	data, err := httpRequest(w.Client, http.MethodPost, w.url, w.header, body, 64*1024*1024)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err = json.Unmarshal(data, &result); err != nil || !result.Errors {
+		return nil
+	}
+
+	// keeps the throttled documents for retrying, and sends the rejected to dead letter.
+	b.filter(func(i int, entry []byte) bool {
+		if i >= len(result.Items) {
+			return false
+		}
+		for _, item := range result.Items[i] {
+			switch {
+			case item.Status == http.StatusTooManyRequests:
+				return true
+			case item.Status >= 300:
+				w.deadLetter(b, i, entry)
+			}
+		}
+		return false
+	})
+	if b.Len() > 0 {
+		return errors.New("log: elastic bulk throttled " + strconv.Itoa(b.Len()) + " documents")
+	}
+	return nil
+}
+
+var _ Writer = (*ElasticWriter)(nil)
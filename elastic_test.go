@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticWriter(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("elastic writer unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "elastic" || pass != "secret" {
+			t.Errorf("elastic writer should send basic auth: %v", r.Header)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		var n int
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+			n++
+		}
+		switch calls {
+		case 1:
+			// accepted, rejected, throttled
+			_, _ = rw.Write([]byte(`{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":400,"error":{"type":"mapper_parsing_exception"}}},{"create":{"status":429}}]}`))
+		default:
+			_, _ = rw.Write([]byte(`{"errors":false,"items":[{"create":{"status":201}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	var dead bytes.Buffer
+	w := &ElasticWriter{
+		URL:             server.URL,
+		Index:           "logs",
+		IndexDateFormat: "2006.01.02",
+		Username:        "elastic",
+		Password:        "secret",
+		BatchSize:       3,
+		DeadLetter:      IOWriter{&dead},
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+
+	logger.Info().Msg("accepted")
+	logger.Info().Msg("rejected")
+	logger.Info().Msg("throttled")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("elastic writer close error: %+v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(lines) != 8 {
+		t.Fatalf("elastic writer should retry throttled documents, calls=%d lines=%q", calls, lines)
+	}
+	index := `{"create":{"_index":"logs-` + timeNow().UTC().Format("2006.01.02") + `"}}`
+	if lines[0] != index || lines[6] != index {
+		t.Errorf("elastic writer index mismatch: %s", lines[0])
+	}
+	if !strings.Contains(lines[7], `"message":"throttled"`) {
+		t.Errorf("elastic writer should retry throttled document: %s", lines[7])
+	}
+	if !strings.Contains(dead.String(), `"message":"rejected"`) || strings.Count(dead.String(), "\n") != 1 {
+		t.Errorf("elastic writer should send rejected document to dead letter: %s", dead.String())
+	}
+}
@@ -0,0 +1,440 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Encoder encodes the json entries to a custom wire format, e.g. LogfmtEncoder. It is
+// not a part of Logger, the entries are always built as json by the Entry API, and then
+// transcoded by EncodeWriter or EncodeJSON as a post-processing step.
+//
+// EncodeJSON parses the json entry again, and calls BeginEntry, the key and value methods
+// of each field in order, and EndEntry. So the encoding costs more than writing json,
+// which is the fastest format of logger. The n of BeginEntry, BeginObject and BeginArray
+// is the count of fields or elements. The strings passed to the encoder are only valid
+// during the call.
+//
+// If the encoder has an `AppendJSON(dst, json []byte) []byte` method, the nested objects
+// and arrays are passed to it as raw json instead of BeginObject and BeginArray. If the
+// encoder has a `SortedKeys() bool` method which returns true, the fields of objects are
+// passed in the deterministic order of RFC 8949. If the encoder has an `EncodeEntry(dst,
+// json []byte) []byte` method, the whole json entry is passed to it instead, e.g. for the
+// formats which need the fields out of order.
+type Encoder interface {
+	BeginEntry(dst []byte, n int) []byte
+	EndEntry(dst []byte) []byte
+	AppendKey(dst []byte, key string) []byte
+	AppendString(dst []byte, s string) []byte
+	AppendInt(dst []byte, n int64) []byte
+	AppendUint(dst []byte, n uint64) []byte
+	AppendFloat(dst []byte, f float64) []byte
+	AppendBool(dst []byte, b bool) []byte
+	AppendNull(dst []byte) []byte
+	BeginObject(dst []byte, n int) []byte
+	EndObject(dst []byte) []byte
+	BeginArray(dst []byte, n int) []byte
+	EndArray(dst []byte) []byte
+}
+
+// EncodeWriter is a Writer that transcodes the json entries by Encoder before writing
+// them to Writer, it should be the last one of a writer chain, because the writers
+// parsing json entries, e.g. ConsoleWriter and the batched writers, expect json input.
+type EncodeWriter struct {
+	// Encoder specifies the encoder of entries, e.g. LogfmtEncoder.
+	Encoder Encoder
+
+	// Writer specifies the writer of encoded output.
+	Writer io.Writer
+}
+
+// WriteEntry implements Writer.
+func (w *EncodeWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer, it encodes p as a json entry.
+func (w *EncodeWriter) Write(p []byte) (n int, err error) {
+	b := bbpool.Get().(*bb)
+	b.B = EncodeJSON(w.Encoder, b.B[:0], p)
+	_, err = w.Writer.Write(b.B)
+	if cap(b.B) <= bbcap {
+		bbpool.Put(b)
+	}
+	if err == nil {
+		n = len(p)
+	}
+	return
+}
+
+// Close implements io.Closer, and closes the underlying Writer.
+func (w *EncodeWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+var _ Writer = (*EncodeWriter)(nil)
+
+// EncodeJSON appends the json entry encoded by enc to dst, the input which is not
+// a json object is appended as is.
+func EncodeJSON(enc Encoder, dst, json []byte) []byte {
+	if len(json) == 0 || json[0] != '{' {
+		return append(dst, json...)
+	}
+
+	if e, ok := enc.(interface {
+		EncodeEntry(dst, json []byte) []byte
+	}); ok {
+		return e.EncodeEntry(dst, json)
+	}
+
+	x := newEncoder(enc)
+	dst = enc.BeginEntry(dst, jsonCount(json))
+	dst = x.fields(dst, json)
+	dst = enc.EndEntry(dst)
+	x.free()
+
+	return dst
+}
+
+// encoder streams the json entry to Encoder.
+type encoder struct {
+	enc    Encoder
+	raw    interface{ AppendJSON(dst, json []byte) []byte }
+	sorted bool
+	b      *bb
+}
+
+func newEncoder(enc Encoder) (x encoder) {
+	x.enc, x.b = enc, bbpool.Get().(*bb)
+	x.raw, _ = enc.(interface {
+		AppendJSON(dst, json []byte) []byte
+	})
+	if sorter, ok := enc.(interface{ SortedKeys() bool }); ok {
+		x.sorted = sorter.SortedKeys()
+	}
+	return
+}
+
+func (x *encoder) free() {
+	if cap(x.b.B) <= bbcap {
+		bbpool.Put(x.b)
+	}
+}
+
+func (x *encoder) fields(dst, json []byte) []byte {
+	if x.sorted {
+		return x.sortedFields(dst, json)
+	}
+	jsonRange(json, func(key, value []byte) bool {
+		dst = x.enc.AppendKey(dst, encodeUnescape(key, x.b))
+		dst = x.value(dst, value)
+		return true
+	})
+	return dst
+}
+
+// encodeFields is the fields of an object sorted by sortedFields.
+type encodeFields struct {
+	fields []encodeField
+	keys   []byte
+}
+
+type encodeField struct {
+	start, end int
+	key        string
+	value      []byte
+}
+
+func (fs *encodeFields) Len() int { return len(fs.fields) }
+
+func (fs *encodeFields) Swap(i, j int) { fs.fields[i], fs.fields[j] = fs.fields[j], fs.fields[i] }
+
+func (fs *encodeFields) Less(i, j int) bool {
+	a, b := fs.fields[i].key, fs.fields[j].key
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+var efpool = sync.Pool{
+	New: func() interface{} {
+		return new(encodeFields)
+	},
+}
+
+// sortedFields is like fields in the deterministic order of RFC 8949, which sorts
+// the keys by length and then bytewise.
+func (x *encoder) sortedFields(dst, json []byte) []byte {
+	fs := efpool.Get().(*encodeFields)
+	fs.fields, fs.keys = fs.fields[:0], fs.keys[:0]
+	escaped := false
+	jsonRange(json, func(key, value []byte) bool {
+		f := encodeField{key: b2s(key), value: value}
+		if bytes.IndexByte(key, '\\') >= 0 {
+			f.start = len(fs.keys)
+			fs.keys = jsonUnescape(key, fs.keys)
+			f.end = len(fs.keys)
+			escaped = true
+		}
+		fs.fields = append(fs.fields, f)
+		return true
+	})
+	if escaped {
+		// references the unescaped keys after fs.keys stops growing
+		for i, f := range fs.fields {
+			if f.end != 0 {
+				fs.fields[i].key = b2s(fs.keys[f.start:f.end])
+			}
+		}
+	}
+	sort.Stable(fs)
+	for _, f := range fs.fields {
+		dst = x.enc.AppendKey(dst, f.key)
+		dst = x.value(dst, f.value)
+	}
+	if cap(fs.fields) <= 256 && cap(fs.keys) <= bbcap {
+		efpool.Put(fs)
+	}
+	return dst
+}
+
+func (x *encoder) value(dst, value []byte) []byte {
+	enc := x.enc
+	if len(value) == 0 {
+		return enc.AppendNull(dst)
+	}
+	switch value[0] {
+	case '"':
+		if len(value) < 2 {
+			return enc.AppendString(dst, "")
+		}
+		return enc.AppendString(dst, encodeUnescape(value[1:len(value)-1], x.b))
+	case 't':
+		return enc.AppendBool(dst, true)
+	case 'f':
+		return enc.AppendBool(dst, false)
+	case 'n':
+		return enc.AppendNull(dst)
+	case '{':
+		if x.raw != nil {
+			return x.raw.AppendJSON(dst, value)
+		}
+		dst = enc.BeginObject(dst, jsonCount(value))
+		dst = x.fields(dst, value)
+		return enc.EndObject(dst)
+	case '[':
+		if x.raw != nil {
+			return x.raw.AppendJSON(dst, value)
+		}
+		n := 0
+		jsonRangeArray(value, func([]byte) bool {
+			n++
+			return true
+		})
+		dst = enc.BeginArray(dst, n)
+		jsonRangeArray(value, func(value []byte) bool {
+			dst = x.value(dst, value)
+			return true
+		})
+		return enc.EndArray(dst)
+	}
+	if n, err := strconv.ParseInt(b2s(value), 10, 64); err == nil {
+		return enc.AppendInt(dst, n)
+	}
+	if n, err := strconv.ParseUint(b2s(value), 10, 64); err == nil {
+		return enc.AppendUint(dst, n)
+	}
+	if f, err := strconv.ParseFloat(b2s(value), 64); err == nil {
+		return enc.AppendFloat(dst, f)
+	}
+	return enc.AppendString(dst, b2s(value))
+}
+
+// encodeUnescape returns the unescaped json string s, which is unescaped to b if needed.
+func encodeUnescape(s []byte, b *bb) string {
+	for _, c := range s {
+		if c == '\\' {
+			b.B = jsonUnescape(s, b.B[:0])
+			return b2s(b.B)
+		}
+	}
+	return b2s(s)
+}
+
+// jsonCount returns the count of top-level fields of json object.
+func jsonCount(json []byte) (n int) {
+	jsonRange(json, func(key, value []byte) bool {
+		n++
+		return true
+	})
+	return
+}
+
+// JSONEncoder is an Encoder that encodes entries to json, it is the reference of custom
+// encoders, and the logger writes json natively if no encoder is specified.
+type JSONEncoder struct{}
+
+// jsonAppendSep appends the comma before the key or value unless it is the first of object or array.
+func jsonAppendSep(dst []byte) []byte {
+	if n := len(dst); n > 0 && dst[n-1] != ':' && dst[n-1] != '{' && dst[n-1] != '[' {
+		dst = append(dst, ',')
+	}
+	return dst
+}
+
+// BeginEntry implements Encoder.
+func (JSONEncoder) BeginEntry(dst []byte, n int) []byte { return append(dst, '{') }
+
+// EndEntry implements Encoder.
+func (JSONEncoder) EndEntry(dst []byte) []byte { return append(dst, '}', '\n') }
+
+// AppendKey implements Encoder.
+func (JSONEncoder) AppendKey(dst []byte, key string) []byte {
+	return append(jsonAppendString(jsonAppendSep(dst), key), ':')
+}
+
+// AppendString implements Encoder.
+func (JSONEncoder) AppendString(dst []byte, s string) []byte {
+	return jsonAppendString(jsonAppendSep(dst), s)
+}
+
+// AppendInt implements Encoder.
+func (JSONEncoder) AppendInt(dst []byte, n int64) []byte {
+	return strconv.AppendInt(jsonAppendSep(dst), n, 10)
+}
+
+// AppendUint implements Encoder.
+func (JSONEncoder) AppendUint(dst []byte, n uint64) []byte {
+	return strconv.AppendUint(jsonAppendSep(dst), n, 10)
+}
+
+// AppendFloat implements Encoder.
+func (JSONEncoder) AppendFloat(dst []byte, f float64) []byte {
+	return strconv.AppendFloat(jsonAppendSep(dst), f, 'f', -1, 64)
+}
+
+// AppendBool implements Encoder.
+func (JSONEncoder) AppendBool(dst []byte, b bool) []byte {
+	return strconv.AppendBool(jsonAppendSep(dst), b)
+}
+
+// AppendNull implements Encoder.
+func (JSONEncoder) AppendNull(dst []byte) []byte { return append(jsonAppendSep(dst), "null"...) }
+
+// BeginObject implements Encoder.
+func (JSONEncoder) BeginObject(dst []byte, n int) []byte { return append(jsonAppendSep(dst), '{') }
+
+// EndObject implements Encoder.
+func (JSONEncoder) EndObject(dst []byte) []byte { return append(dst, '}') }
+
+// BeginArray implements Encoder.
+func (JSONEncoder) BeginArray(dst []byte, n int) []byte { return append(jsonAppendSep(dst), '[') }
+
+// EndArray implements Encoder.
+func (JSONEncoder) EndArray(dst []byte) []byte { return append(dst, ']') }
+
+// LogfmtEncoder is an Encoder that encodes entries to logfmt, the key=value pairs are
+// separated by spaces in the order of fields, the values are quoted if needed, and the
+// nested objects and arrays are quoted as json.
+type LogfmtEncoder struct{}
+
+// BeginEntry implements Encoder.
+func (LogfmtEncoder) BeginEntry(dst []byte, n int) []byte { return dst }
+
+// EndEntry implements Encoder.
+func (LogfmtEncoder) EndEntry(dst []byte) []byte { return append(dst, '\n') }
+
+// AppendKey implements Encoder, the spaces, equal signs, quotes and backslashes of key
+// are replaced with underscores.
+func (LogfmtEncoder) AppendKey(dst []byte, key string) []byte {
+	if n := len(dst); n > 0 && dst[n-1] != '\n' {
+		dst = append(dst, ' ')
+	}
+	if key == "" {
+		return append(dst, '_', '=')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' {
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return append(dst, '=')
+}
+
+// AppendString implements Encoder, the s is quoted if it is empty or contains spaces,
+// equal signs, quotes, backslashes or control characters.
+func (LogfmtEncoder) AppendString(dst []byte, s string) []byte {
+	quote := s == ""
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			quote = true
+			break
+		}
+	}
+	if quote {
+		return strconv.AppendQuote(dst, s)
+	}
+	return append(dst, s...)
+}
+
+// AppendInt implements Encoder.
+func (LogfmtEncoder) AppendInt(dst []byte, n int64) []byte { return strconv.AppendInt(dst, n, 10) }
+
+// AppendUint implements Encoder.
+func (LogfmtEncoder) AppendUint(dst []byte, n uint64) []byte { return strconv.AppendUint(dst, n, 10) }
+
+// AppendFloat implements Encoder.
+func (LogfmtEncoder) AppendFloat(dst []byte, f float64) []byte {
+	return strconv.AppendFloat(dst, f, 'f', -1, 64)
+}
+
+// AppendBool implements Encoder.
+func (LogfmtEncoder) AppendBool(dst []byte, b bool) []byte { return strconv.AppendBool(dst, b) }
+
+// AppendNull implements Encoder.
+func (LogfmtEncoder) AppendNull(dst []byte) []byte { return append(dst, "null"...) }
+
+// AppendJSON appends the nested objects and arrays as quoted json.
+func (LogfmtEncoder) AppendJSON(dst, json []byte) []byte { return jsonAppendBytes(dst, json) }
+
+// BeginObject implements Encoder, it is unused as the nested objects are appended by AppendJSON.
+func (LogfmtEncoder) BeginObject(dst []byte, n int) []byte { return dst }
+
+// EndObject implements Encoder.
+func (LogfmtEncoder) EndObject(dst []byte) []byte { return dst }
+
+// BeginArray implements Encoder, it is unused as the nested arrays are appended by AppendJSON.
+func (LogfmtEncoder) BeginArray(dst []byte, n int) []byte { return dst }
+
+// EndArray implements Encoder.
+func (LogfmtEncoder) EndArray(dst []byte) []byte { return dst }
+
+var _ Encoder = JSONEncoder{}
+var _ Encoder = LogfmtEncoder{}
+
+// entryEncoder implements the streaming methods of Encoder as no-op, it is embedded by
+// the encoders which encode the whole entry by EncodeEntry.
+type entryEncoder struct{}
+
+func (entryEncoder) BeginEntry(dst []byte, n int) []byte      { return dst }
+func (entryEncoder) EndEntry(dst []byte) []byte               { return dst }
+func (entryEncoder) AppendKey(dst []byte, key string) []byte  { return dst }
+func (entryEncoder) AppendString(dst []byte, s string) []byte { return dst }
+func (entryEncoder) AppendInt(dst []byte, n int64) []byte     { return dst }
+func (entryEncoder) AppendUint(dst []byte, n uint64) []byte   { return dst }
+func (entryEncoder) AppendFloat(dst []byte, f float64) []byte { return dst }
+func (entryEncoder) AppendBool(dst []byte, b bool) []byte     { return dst }
+func (entryEncoder) AppendNull(dst []byte) []byte             { return dst }
+func (entryEncoder) BeginObject(dst []byte, n int) []byte     { return dst }
+func (entryEncoder) EndObject(dst []byte) []byte              { return dst }
+func (entryEncoder) BeginArray(dst []byte, n int) []byte      { return dst }
+func (entryEncoder) EndArray(dst []byte) []byte               { return dst }
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	cases := []struct {
+		JSON   string
+		Logfmt string
+	}{
+		{`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello world"}` + "\n", `time=2019-07-10T05:35:54.277Z level=info message="hello world"` + "\n"},
+		{`{"n":42,"f":1.5,"ok":true,"no":false,"nil":null}`, `n=42 f=1.5 ok=true no=false nil=null` + "\n"},
+		{`{"empty":"","eq":"a=b","quote":"a\"b","newline":"a\nb"}`, `empty="" eq="a=b" quote="a\"b" newline="a\nb"` + "\n"},
+		{`{"obj":{"a":1,"b":"c"},"arr":[1,2]}`, `obj="{\"a\":1,\"b\":\"c\"}" arr="[1,2]"` + "\n"},
+		{`{"a b":1,"a=b":2,"":3}`, `a_b=1 a_b=2 _=3` + "\n"},
+		{"a plain message\n", "a plain message\n"},
+	}
+
+	for _, c := range cases {
+		if got := string(EncodeJSON(LogfmtEncoder{}, nil, []byte(c.JSON))); got != c.Logfmt {
+			t.Errorf("logfmt encoder of %s: got %q, want %q", c.JSON, got, c.Logfmt)
+		}
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	cases := []string{
+		`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello world"}`,
+		`{"n":42,"u":18446744073709551615,"f":1.5,"ok":true,"no":false,"nil":null}`,
+		`{"quote":"a\"b","newline":"a\nb","unicode":"\u00e9\u4e16"}`,
+		`{"obj":{"a":1,"b":{"c":[1,"2",{"d":null}]}},"arr":[[],{}],"empty":""}`,
+		`{"a\u0020b":1}`,
+	}
+
+	for _, c := range cases {
+		got := EncodeJSON(JSONEncoder{}, nil, []byte(c))
+		var want, value interface{}
+		if err := json.Unmarshal([]byte(c), &want); err != nil {
+			t.Fatalf("json unmarshal %s error: %+v", c, err)
+		}
+		if err := json.Unmarshal(got, &value); err != nil {
+			t.Fatalf("json unmarshal %s error: %+v", got, err)
+		}
+		if !reflect.DeepEqual(value, want) {
+			t.Errorf("json encoder of %s: got %s", c, got)
+		}
+	}
+}
+
+func TestLoggerEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:      InfoLevel,
+		TimeFormat: TimeFormatUnix,
+		Writer:     &EncodeWriter{Encoder: LogfmtEncoder{}, Writer: &buf},
+	}
+
+	logger.Info().Str("foo", "bar baz").Int("n", 42).Msg("hello logfmt")
+	logger.Debug().Msg("filtered")
+	logger.Warn().Bool("ok", true).Msgf("a %s", "warning")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("logger encoder lines: got %d, want 2: %s", len(lines), buf.Bytes())
+	}
+	if got, want := string(lines[0][bytes.IndexByte(lines[0], ' '):]), ` level=info foo="bar baz" n=42 message="hello logfmt"`; got != want {
+		t.Errorf("logger encoder: got %q, want %q", got, want)
+	}
+	if got, want := string(lines[1][bytes.IndexByte(lines[1], ' '):]), ` level=warn ok=true message="a warning"`; got != want {
+		t.Errorf("logger encoder: got %q, want %q", got, want)
+	}
+}
+
+func BenchmarkLogfmtEncoder(b *testing.B) {
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     &EncodeWriter{Encoder: LogfmtEncoder{}, Writer: io.Discard},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("foo", "bar").Int("n", 42).Msg("hello logfmt")
+	}
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// EncryptWriter is an Writer that encrypts each entry to a record of AES-GCM, so that the
+// logs at rest are unreadable without the keys. A record is
+//
+//	key id (4 bytes) | length of sealed (4 bytes) | nonce (12 bytes) | sealed entry
+//
+// in big endian, and the records are decrypted by DecryptReader. The keys can be rotated by
+// SetKey while logging, e.g. per file.
+type EncryptWriter struct {
+	// Key specifies the AES key of 16, 24 or 32 bytes, use SetKey to change it while logging.
+	Key []byte
+
+	// KeyID specifies the id of Key in records, for DecryptReader to look up the key.
+	KeyID uint32
+
+	// Writer specifies the writer of output, e.g. FileWriter.
+	Writer io.Writer
+
+	mu   sync.Mutex
+	aead cipher.AEAD
+	key  []byte
+	buf  []byte
+}
+
+// ErrDecrypt is returned by DecryptReader if a record is corrupted or the key is absent.
+var ErrDecrypt = errors.New("log: failed to decrypt record")
+
+const encryptHeaderSize = 4 + 4 + 12
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Close implements io.Closer, will closes the underlying Writer if not empty.
+func (w *EncryptWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+// SetKey rotates the key and its id of the later records, it is safe to call while logging.
+func (w *EncryptWriter) SetKey(id uint32, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.Key, w.KeyID = key, id
+	w.aead, w.key = aead, append(w.key[:0], key...)
+	w.mu.Unlock()
+	return nil
+}
+
+// WriteEntry implements Writer.
+func (w *EncryptWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer, it writes p as an encrypted record.
+func (w *EncryptWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.aead == nil || string(w.key) != string(w.Key) {
+		if w.aead, err = newAEAD(w.Key); err != nil {
+			return
+		}
+		w.key = append(w.key[:0], w.Key...)
+	}
+
+	size := len(p) + w.aead.Overhead()
+	var header [encryptHeaderSize]byte
+	w.buf = append(w.buf[:0], header[:]...)
+	binary.BigEndian.PutUint32(w.buf[0:], w.KeyID)
+	binary.BigEndian.PutUint32(w.buf[4:], uint32(size))
+	nonce := w.buf[8:encryptHeaderSize]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+	w.buf = w.aead.Seal(w.buf, nonce, p, w.buf[:4])
+
+	if _, err = w.Writer.Write(w.buf); err != nil {
+		return
+	}
+	if cap(w.buf) > bbcap {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// DecryptReader is an io.Reader that decrypts the records written by EncryptWriter.
+type DecryptReader struct {
+	// Keys specifies the AES keys by the key ids.
+	Keys map[uint32][]byte
+
+	// Reader specifies the reader of records.
+	Reader io.Reader
+
+	aeads map[uint32]cipher.AEAD
+	buf   []byte
+	plain []byte
+}
+
+// Read implements io.Reader.
+func (r *DecryptReader) Read(p []byte) (n int, err error) {
+	for len(r.plain) == 0 {
+		if err = r.next(); err != nil {
+			return
+		}
+	}
+	n = copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return
+}
+
+// next decrypts the next record.
+func (r *DecryptReader) next() error {
+	var header [encryptHeaderSize]byte
+	if _, err := io.ReadFull(r.Reader, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return ErrDecrypt
+		}
+		return err
+	}
+
+	id := binary.BigEndian.Uint32(header[0:])
+	aead, ok := r.aeads[id]
+	if !ok {
+		key, ok := r.Keys[id]
+		if !ok {
+			return ErrDecrypt
+		}
+		var err error
+		if aead, err = newAEAD(key); err != nil {
+			return err
+		}
+		if r.aeads == nil {
+			r.aeads = make(map[uint32]cipher.AEAD)
+		}
+		r.aeads[id] = aead
+	}
+
+	size := int(binary.BigEndian.Uint32(header[4:]))
+	if size < aead.Overhead() || size > 1<<30 {
+		return ErrDecrypt
+	}
+	if cap(r.buf) < size {
+		r.buf = make([]byte, size)
+	}
+	r.buf = r.buf[:size]
+	if _, err := io.ReadFull(r.Reader, r.buf); err != nil {
+		return ErrDecrypt
+	}
+
+	plain, err := aead.Open(r.buf[:0], header[8:], r.buf, header[:4])
+	if err != nil {
+		return ErrDecrypt
+	}
+	r.plain = plain
+	return nil
+}
+
+var _ Writer = (*EncryptWriter)(nil)
+var _ io.Reader = (*DecryptReader)(nil)
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestEncryptWriter(t *testing.T) {
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 16)
+
+	var buf bytes.Buffer
+	w := &EncryptWriter{Key: key1, KeyID: 1, Writer: &buf}
+	logger := Logger{Writer: w}
+	logger.Info().Str("secret", "foo").Msg("hello")
+
+	if err := w.SetKey(3, []byte("short")); err == nil {
+		t.Fatalf("set invalid key must return error")
+	}
+	if err := w.SetKey(2, key2); err != nil {
+		t.Fatalf("set key error: %+v", err)
+	}
+	logger.Info().Str("secret", "bar").Msg("world")
+
+	if bytes.Contains(buf.Bytes(), []byte("secret")) {
+		t.Fatalf("encrypted output must not contain the plain text")
+	}
+
+	data, err := io.ReadAll(&DecryptReader{
+		Keys:   map[uint32][]byte{1: key1, 2: key2},
+		Reader: bytes.NewReader(buf.Bytes()),
+	})
+	if err != nil {
+		t.Fatalf("decrypt error: %+v", err)
+	}
+	if lines := bytes.Split(bytes.TrimSpace(data), []byte("\n")); len(lines) != 2 ||
+		!bytes.HasSuffix(lines[0], []byte(`"secret":"foo","message":"hello"}`)) ||
+		!bytes.HasSuffix(lines[1], []byte(`"secret":"bar","message":"world"}`)) {
+		t.Errorf("decrypted output got %s", data)
+	}
+
+	_, err = io.ReadAll(&DecryptReader{
+		Keys:   map[uint32][]byte{1: key1},
+		Reader: bytes.NewReader(buf.Bytes()),
+	})
+	if !errors.Is(err, ErrDecrypt) {
+		t.Errorf("decrypt without key must return ErrDecrypt, not %+v", err)
+	}
+
+	tampered := append([]byte(nil), buf.Bytes()...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = io.ReadAll(&DecryptReader{
+		Keys:   map[uint32][]byte{1: key1, 2: key2},
+		Reader: bytes.NewReader(tampered),
+	})
+	if !errors.Is(err, ErrDecrypt) {
+		t.Errorf("decrypt tampered must return ErrDecrypt, not %+v", err)
+	}
+
+	_, err = io.ReadAll(&DecryptReader{
+		Keys:   map[uint32][]byte{1: key1, 2: key2},
+		Reader: bytes.NewReader(buf.Bytes()[:buf.Len()-1]),
+	})
+	if !errors.Is(err, ErrDecrypt) {
+		t.Errorf("decrypt truncated must return ErrDecrypt, not %+v", err)
+	}
+
+	w.Key = []byte("short")
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Errorf("encrypt with invalid key must return error")
+	}
+}
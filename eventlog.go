@@ -5,6 +5,7 @@ package log
 
 import (
 	"errors"
+	"strconv"
 	"sync"
 	"syscall"
 	"unsafe"
@@ -21,6 +22,28 @@ type EventlogWriter struct {
 	// Event Host, optional
 	Host string
 
+	// Event Log, the Source is installed to this log by InstallEventlogSource if not empty,
+	// it allows writing to custom logs besides Application.
+	Log string
+
+	// MessageFile specifies the message file of installed Source, uses EventCreate.exe if empty.
+	MessageFile string
+
+	// IDField specifies the field of event id in log event, uses `eid` if empty.
+	IDField string
+
+	// LevelIDs specifies the event id of levels, it takes precedence over ID.
+	LevelIDs map[Level]uintptr
+
+	// Category specifies the default event category, optional.
+	Category uint16
+
+	// CategoryField specifies the field of event category in log event, optional.
+	CategoryField string
+
+	// LevelCategories specifies the event category of levels, it takes precedence over Category.
+	LevelCategories map[Level]uint16
+
 	once       sync.Once
 	register   *syscall.LazyProc
 	deregister *syscall.LazyProc
@@ -41,7 +64,7 @@ func (w *EventlogWriter) Close() (err error) {
 // WriteEntry implements Writer.
 func (w *EventlogWriter) WriteEntry(e *Entry) (n int, err error) {
 	w.once.Do(func() {
-		if w.ID == 0 {
+		if w.ID == 0 && len(w.LevelIDs) == 0 {
 			err = errors.New("Specify eventlog default id")
 			return
 		}
@@ -51,6 +74,12 @@ func (w *EventlogWriter) WriteEntry(e *Entry) (n int, err error) {
 			return
 		}
 
+		if w.Log != "" && w.Host == "" {
+			if err = InstallEventlogSource(w.Log, w.Source, w.MessageFile, 0); err != nil {
+				return
+			}
+		}
+
 		var s *uint16
 		if w.Host != "" {
 			s = syscall.StringToUTF16Ptr(w.Host)
@@ -100,8 +129,8 @@ func (w *EventlogWriter) WriteEntry(e *Entry) (n int, err error) {
 		etype = EVENTLOG_INFORMATION_TYPE
 	}
 
-	var ecat uintptr = 0
-	var eid = w.ID
+	var ecat = uintptr(w.category(e))
+	var eid = w.id(e)
 	var ss = []*uint16{syscall.StringToUTF16Ptr(b2s(e.buf))}
 
 	var ret uintptr
@@ -116,4 +145,120 @@ func (w *EventlogWriter) WriteEntry(e *Entry) (n int, err error) {
 	return
 }
 
+// id returns the event id of entry, the field of log event takes precedence over levels.
+func (w *EventlogWriter) id(e *Entry) uintptr {
+	field := w.IDField
+	if field == "" {
+		field = "eid"
+	}
+	if value := jsonFieldString(e.buf, field); len(value) != 0 {
+		if n, err := strconv.ParseUint(b2s(value), 10, 32); err == nil {
+			return uintptr(n)
+		}
+	}
+	if id, ok := w.LevelIDs[e.Level]; ok {
+		return id
+	}
+	return w.ID
+}
+
+// category returns the event category of entry, the field of log event takes precedence over levels.
+func (w *EventlogWriter) category(e *Entry) uint16 {
+	if w.CategoryField != "" {
+		if value := jsonFieldString(e.buf, w.CategoryField); len(value) != 0 {
+			if n, err := strconv.ParseUint(b2s(value), 10, 16); err == nil {
+				return uint16(n)
+			}
+		}
+	}
+	if category, ok := w.LevelCategories[e.Level]; ok {
+		return category
+	}
+	return w.Category
+}
+
+// InstallEventlogSource registers the source to the event log under
+// HKLM\SYSTEM\CurrentControlSet\Services\EventLog, it does nothing if the source is registered.
+// The msgfile specifies the message file of event ids, uses EventCreate.exe if empty, which
+// formats the ids in range 1-1000 with the message as is. The categories specifies the count
+// of categories in msgfile, zero means no categories. It requires the administrator privilege.
+func InstallEventlogSource(log, source, msgfile string, categories uint32) error {
+	if log == "" || source == "" {
+		return errors.New("Specify eventlog log and source")
+	}
+	if msgfile == "" {
+		msgfile = `%SystemRoot%\System32\EventCreate.exe`
+	}
+
+	path := `SYSTEM\CurrentControlSet\Services\EventLog\` + log + `\` + source
+
+	var key syscall.Handle
+	if syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, syscall.StringToUTF16Ptr(path), 0, syscall.KEY_READ, &key) == nil {
+		syscall.RegCloseKey(key)
+		return nil
+	}
+
+	advapi32 := syscall.NewLazyDLL("advapi32.dll")
+	create := advapi32.NewProc("RegCreateKeyExW")
+	set := advapi32.NewProc("RegSetValueExW")
+
+	var disposition uint32
+	ret, _, _ := syscall.Syscall9(create.Addr(), 9, uintptr(syscall.HKEY_LOCAL_MACHINE), uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))), 0, 0, 0, uintptr(syscall.KEY_WRITE), 0, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(&disposition)))
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+	defer syscall.RegCloseKey(key)
+
+	setString := func(name, value string) error {
+		data, _ := syscall.UTF16FromString(value)
+		ret, _, _ := syscall.Syscall6(set.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(name))), 0, syscall.REG_EXPAND_SZ, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)*2))
+		if ret != 0 {
+			return syscall.Errno(ret)
+		}
+		return nil
+	}
+	setDword := func(name string, value uint32) error {
+		ret, _, _ := syscall.Syscall6(set.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(name))), 0, syscall.REG_DWORD, uintptr(unsafe.Pointer(&value)), 4)
+		if ret != 0 {
+			return syscall.Errno(ret)
+		}
+		return nil
+	}
+
+	const (
+		EVENTLOG_ERROR_TYPE       = 0x0001
+		EVENTLOG_WARNING_TYPE     = 0x0002
+		EVENTLOG_INFORMATION_TYPE = 0x0004
+	)
+
+	if err := setString("EventMessageFile", msgfile); err != nil {
+		return err
+	}
+	if err := setDword("TypesSupported", EVENTLOG_ERROR_TYPE|EVENTLOG_WARNING_TYPE|EVENTLOG_INFORMATION_TYPE); err != nil {
+		return err
+	}
+	if categories > 0 {
+		if err := setString("CategoryMessageFile", msgfile); err != nil {
+			return err
+		}
+		if err := setDword("CategoryCount", categories); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveEventlogSource deletes the source registered by InstallEventlogSource.
+func RemoveEventlogSource(log, source string) error {
+	advapi32 := syscall.NewLazyDLL("advapi32.dll")
+	remove := advapi32.NewProc("RegDeleteKeyW")
+
+	path := `SYSTEM\CurrentControlSet\Services\EventLog\` + log + `\` + source
+	ret, _, _ := syscall.Syscall(remove.Addr(), 2, uintptr(syscall.HKEY_LOCAL_MACHINE), uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))), 0)
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+	return nil
+}
+
 var _ Writer = (*EventlogWriter)(nil)
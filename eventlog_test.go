@@ -20,3 +20,37 @@ func TestEventlogWriter(t *testing.T) {
 		}
 	}
 }
+
+func TestEventlogWriterIDCategory(t *testing.T) {
+	w := &EventlogWriter{
+		Source:          ".NET Runtime",
+		ID:              1000,
+		LevelIDs:        map[Level]uintptr{ErrorLevel: 1001},
+		Category:        1,
+		CategoryField:   "cat",
+		LevelCategories: map[Level]uint16{WarnLevel: 2},
+	}
+
+	cases := []struct {
+		Level    Level
+		JSON     string
+		ID       uintptr
+		Category uint16
+	}{
+		{InfoLevel, `{"level":"info","message":"a"}`, 1000, 1},
+		{ErrorLevel, `{"level":"error","message":"a"}`, 1001, 1},
+		{ErrorLevel, `{"level":"error","eid":42,"message":"a"}`, 42, 1},
+		{WarnLevel, `{"level":"warn","message":"a"}`, 1000, 2},
+		{WarnLevel, `{"level":"warn","cat":"3","message":"a"}`, 1000, 3},
+	}
+
+	for _, c := range cases {
+		e := &Entry{Level: c.Level, buf: []byte(c.JSON)}
+		if id := w.id(e); id != c.ID {
+			t.Errorf("eventlog id of %s: got %d, want %d", c.JSON, id, c.ID)
+		}
+		if category := w.category(e); category != c.Category {
+			t.Errorf("eventlog category of %s: got %d, want %d", c.JSON, category, c.Category)
+		}
+	}
+}
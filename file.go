@@ -1,6 +1,7 @@
 package log
 
 import (
+	"compress/gzip"
 	"crypto/md5"
 	"io"
 	"os"
@@ -42,10 +43,51 @@ type FileWriter struct {
 	// is to retain all old log files
 	MaxBackups int
 
+	// MaxTotalSize is the maximum total size in bytes of the log file and old log
+	// files, the oldest ones are deleted after rotation when exceeded.  The default
+	// is no limit, it is ignored if Cleaner is set.
+	MaxTotalSize int64
+
+	// MaxFileAge is the maximum duration of the log file since it was opened before
+	// it gets rotated.
+	MaxFileAge time.Duration
+
+	// RotateEvery specifies the interval of scheduled rotation, which is aligned to
+	// the wall clock, e.g. time.Hour rotates hourly and 24*time.Hour rotates daily.
+	// The log file gets rotated on whichever comes first of MaxSize, MaxFileAge and
+	// RotateEvery, even if nothing is written.
+	RotateEvery time.Duration
+
+	// RotateOffset specifies the offset of scheduled rotation, e.g. 5*time.Minute
+	// with RotateEvery of 24*time.Hour rotates daily at 00:05.
+	RotateOffset time.Duration
+
+	// SyncPolicy specifies the policy of fsync, uses SyncNever if zero. The log file is
+	// synced before rotation and close if not SyncNever.
+	SyncPolicy SyncPolicy
+
+	// SyncBytes specifies the bytes of SyncEveryBytes.
+	SyncBytes int64
+
+	// SyncInterval specifies the interval of SyncEveryInterval, uses 1 second if zero.
+	SyncInterval time.Duration
+
+	// Shared determines if multiple processes append to the same Filename instead of the
+	// timestamped files, each entry is written by a single O_APPEND write so the lines are
+	// not interleaved, and the rotation is coordinated by the lock of Filename+".lock"
+	// (flock on unix, LockFileEx on windows) so only one process renames the file to a
+	// backup, Rotate fails on the other platforms. The other processes reopen Filename
+	// within a second. Footer is ignored in this mode.
+	Shared bool
+
 	// make aligncheck happy
-	mu   sync.Mutex
-	size int64
-	file *os.File
+	mu       sync.Mutex
+	size     int64
+	file     *os.File
+	timer    *time.Timer
+	unsynced int64
+	synctime *time.Timer
+	checked  time.Time
 
 	// FileMode represents the file's mode and permission bits.  The default
 	// mode is 0644
@@ -59,6 +101,10 @@ type FileWriter struct {
 	// log files is the computer's local time.  The default is to use UTC time.
 	LocalTime bool
 
+	// Location specifies the time zone of timestamps in log files and the boundaries of
+	// RotateEvery regardless of the host time zone, it overrides LocalTime if not nil.
+	Location *time.Location
+
 	// HostName determines if the hostname used for formatting in log files.
 	HostName bool
 
@@ -68,14 +114,73 @@ type FileWriter struct {
 	// EnsureFolder ensures the file directory creation before writing.
 	EnsureFolder bool
 
+	// Template specifies an optional template of log file names instead of the layout of
+	// TimeFormat, HostName and ProcessID, e.g. `{name}.{hostname}.{pid}.{2006-01-02T15}.log`,
+	// which is placed in the directory of Filename. The placeholders are
+	//
+	//	{name}      the base of Filename without the extension
+	//	{hostname}  the hostname
+	//	{pid}       the process id
+	//	{key}       the value of key in Placeholders
+	//	{layout}    the time.Time format of layout, or TimeFormatUnix, TimeFormatUnixMs
+	//
+	// The old log files are matched by the template with all placeholders except {name} as
+	// wildcards, so the files of other hosts and pids are cleaned up as well. The template
+	// should contain a time layout, otherwise the rotations reopen the same file.
+	Template string
+
+	// Placeholders specifies the custom placeholders of Template.
+	Placeholders map[string]string
+
+	// ErrorHandler specifies an optional handler of errors, e.g. disk full, including the
+	// errors of rotation and compression in background. It must not write to the FileWriter.
+	ErrorHandler func(err error)
+
+	// Fallback specifies an optional writer of logs when the log file can't be written,
+	// e.g. os.Stderr.
+	Fallback io.Writer
+
 	// Header specifies an optional header function of log file after rotation,
 	Header func(fileinfo os.FileInfo) []byte
 
+	// Footer specifies an optional footer function of log file before rotation and close,
+	// e.g. a marker for downstream parsers to detect the end of file.
+	Footer func(fileinfo os.FileInfo) []byte
+
+	// Compress determines if the rotated log files are compressed by gzip in background,
+	// the compressed files have the suffix of CompressExt.
+	Compress bool
+
+	// Compressor specifies an optional compressor instead of gzip for Compress, e.g. zstd.
+	Compressor func(w io.Writer) (io.WriteCloser, error)
+
+	// CompressExt specifies the suffix of compressed log files, uses ".gz" if empty.
+	CompressExt string
+
+	// OnRotate specifies an optional callback of the old log file after rotation, it is
+	// called with the path of closed (and compressed) file in background before cleanup,
+	// e.g. uploading to an archive.
+	OnRotate func(oldPath string)
+
 	// Cleaner specifies an optional cleanup function of log backups after rotation,
 	// if not set, the default behavior is to delete more than MaxBackups log files.
 	Cleaner func(filename string, maxBackups int, matches []os.FileInfo)
 }
 
+// SyncPolicy specifies the policy of fsync of FileWriter.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves the flushing to the operating system.
+	SyncNever SyncPolicy = iota
+	// SyncEveryWrite syncs the log file after every write.
+	SyncEveryWrite
+	// SyncEveryBytes syncs the log file after every SyncBytes written.
+	SyncEveryBytes
+	// SyncEveryInterval syncs the log file in SyncInterval after a write.
+	SyncEveryInterval
+)
+
 // WriteEntry implements Writer.  If a write would cause the log file to be larger
 // than MaxSize, the file is closed, rotate to include a timestamp of the
 // current time, and update symlink with log name file to the new file.
@@ -105,32 +210,122 @@ func (w *FileWriter) write(p []byte) (n int, err error) {
 		if w.EnsureFolder {
 			err = os.MkdirAll(filepath.Dir(w.Filename), 0755)
 			if err != nil {
-				return
+				return w.fallback(p, 0, err)
 			}
 		}
 		err = w.create()
 		if err != nil {
-			return
+			return w.fallback(p, 0, err)
 		}
 	}
 
 	n, err = w.file.Write(p)
 	if err != nil {
-		return
+		return w.fallback(p, n, err)
 	}
 
 	w.size += int64(n)
+	w.unsynced += int64(n)
+	switch w.SyncPolicy {
+	case SyncEveryWrite:
+		err = w.sync()
+	case SyncEveryBytes:
+		if w.unsynced >= w.SyncBytes {
+			err = w.sync()
+		}
+	case SyncEveryInterval:
+		if w.synctime == nil {
+			interval := w.SyncInterval
+			if interval <= 0 {
+				interval = time.Second
+			}
+			w.synctime = time.AfterFunc(interval, func() {
+				w.mu.Lock()
+				w.synctime = nil
+				if w.file != nil {
+					if err := w.sync(); err != nil {
+						w.report(err)
+					}
+				}
+				w.mu.Unlock()
+			})
+		}
+	}
+	if err != nil {
+		w.report(err)
+		return
+	}
+
+	if w.Shared {
+		if now := timeNow(); now.Sub(w.checked) >= time.Second {
+			w.checked = now
+			if w.moved() {
+				err = w.rotate()
+				if err != nil {
+					w.report(err)
+				}
+				return
+			}
+		}
+	}
+
 	if w.MaxSize > 0 && w.size > w.MaxSize && w.Filename != "" {
 		err = w.rotate()
+		if err != nil {
+			w.report(err)
+		}
+	}
+
+	return
+}
+
+// fallback reports the error, and writes the unwritten p[n:] to Fallback if not nil.
+func (w *FileWriter) fallback(p []byte, n int, err error) (int, error) {
+	w.report(err)
+	if w.Fallback == nil {
+		return n, err
+	}
+	m, err := w.Fallback.Write(p[n:])
+	return n + m, err
+}
+
+func (w *FileWriter) report(err error) {
+	if w.ErrorHandler != nil {
+		w.ErrorHandler(err)
 	}
+}
 
+// Sync commits the current logfile to stable storage.
+func (w *FileWriter) Sync() (err error) {
+	w.mu.Lock()
+	if w.file != nil {
+		err = w.sync()
+	}
+	w.mu.Unlock()
 	return
 }
 
+func (w *FileWriter) sync() error {
+	w.unsynced = 0
+	return w.file.Sync()
+}
+
 // Close implements io.Closer, and closes the current logfile.
 func (w *FileWriter) Close() (err error) {
 	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.synctime != nil {
+		w.synctime.Stop()
+		w.synctime = nil
+	}
 	if w.file != nil {
+		w.footer()
+		if w.SyncPolicy != SyncNever {
+			_ = w.sync()
+		}
 		err = w.file.Close()
 		w.file = nil
 		w.size = 0
@@ -152,18 +347,40 @@ func (w *FileWriter) Rotate() (err error) {
 }
 
 func (w *FileWriter) rotate() (err error) {
+	now := timeNow()
+	var oldname string
+	if w.Shared {
+		if oldname, err = w.rename(now); err != nil {
+			return err
+		}
+	}
 	var file *os.File
-	file, err = os.OpenFile(w.fileargs(timeNow()))
+	file, err = os.OpenFile(w.openargs(now))
 	if err != nil {
 		return err
 	}
 	if w.file != nil {
+		if !w.Shared {
+			w.footer()
+			oldname = w.file.Name()
+		}
+		if w.SyncPolicy != SyncNever {
+			_ = w.sync()
+		}
 		w.file.Close()
 	}
 	w.file = file
 	w.size = 0
+	w.checked = now
+	w.schedule(now)
 
-	if w.Header != nil {
+	if w.Shared {
+		if st, err := file.Stat(); err == nil {
+			w.size = st.Size()
+		}
+	}
+
+	if w.Header != nil && w.size == 0 {
 		st, err := file.Stat()
 		if err != nil {
 			return err
@@ -177,10 +394,23 @@ func (w *FileWriter) rotate() (err error) {
 		}
 	}
 
-	go func(newname string) {
-		os.Remove(w.Filename)
-		if !w.ProcessID {
-			_ = os.Symlink(filepath.Base(newname), w.Filename)
+	go func(newname, oldname string) {
+		if w.Compress && oldname != "" {
+			if err := w.compress(oldname); err == nil {
+				oldname += w.compressExt()
+			} else {
+				w.report(err)
+			}
+		}
+		if w.OnRotate != nil && oldname != "" {
+			w.OnRotate(oldname)
+		}
+
+		if !w.Shared {
+			os.Remove(w.Filename)
+			if !w.ProcessID {
+				_ = os.Symlink(filepath.Base(newname), w.Filename)
+			}
 		}
 
 		uid, _ := strconv.Atoi(os.Getenv("SUDO_UID"))
@@ -202,14 +432,25 @@ func (w *FileWriter) rotate() (err error) {
 		}
 
 		base, ext := filepath.Base(w.Filename), filepath.Ext(w.Filename)
-		prefix, extgz := base[:len(base)-len(ext)]+".", ext+".gz"
+		prefix, extgz := base[:len(base)-len(ext)]+".", ext+w.compressExt()
 		exclude := prefix + "error" + ext
 
+		var pattern string
+		if w.Template != "" {
+			pattern = w.expand(nil)
+		}
+
 		matches := make([]os.FileInfo, 0)
 		for _, info := range infos {
 			name := info.Name()
-			if name != base && name != exclude &&
-				strings.HasPrefix(name, prefix) &&
+			if name == base || name == exclude {
+				continue
+			}
+			if pattern != "" {
+				if ok, _ := filepath.Match(pattern, strings.TrimSuffix(name, w.compressExt())); ok {
+					matches = append(matches, info)
+				}
+			} else if strings.HasPrefix(name, prefix) &&
 				(strings.HasSuffix(name, ext) || strings.HasSuffix(name, extgz)) {
 				matches = append(matches, info)
 			}
@@ -221,21 +462,103 @@ func (w *FileWriter) rotate() (err error) {
 		if w.Cleaner != nil {
 			w.Cleaner(w.Filename, w.MaxBackups, matches)
 		} else {
-			for i := 0; i < len(matches)-w.MaxBackups-1; i++ {
-				os.Remove(filepath.Join(dir, matches[i].Name()))
+			var total int64
+			for i := len(matches) - 1; i >= 0; i-- {
+				total += matches[i].Size()
+				if i < len(matches)-w.MaxBackups-1 ||
+					(w.MaxTotalSize > 0 && total > w.MaxTotalSize && i < len(matches)-1) {
+					os.Remove(filepath.Join(dir, matches[i].Name()))
+				}
 			}
 		}
-	}(w.file.Name())
+	}(w.file.Name(), oldname)
 
 	return
 }
 
+// footer writes the footer to the current log file.
+func (w *FileWriter) footer() {
+	if w.Footer == nil {
+		return
+	}
+	st, err := w.file.Stat()
+	if err != nil {
+		return
+	}
+	if b := w.Footer(st); b != nil {
+		n, _ := w.file.Write(b)
+		w.size += int64(n)
+	}
+}
+
+func (w *FileWriter) compressExt() string {
+	if w.CompressExt == "" {
+		return ".gz"
+	}
+	return w.CompressExt
+}
+
+// compress compresses the file to a temporary file, renames it to the compressed
+// name with the same modification time, and removes the file.
+func (w *FileWriter) compress(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	st, err := src.Stat()
+	if err != nil {
+		return
+	}
+
+	dstname := name + w.compressExt()
+	tmpname := dstname + ".tmp"
+	dst, err := os.OpenFile(tmpname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, st.Mode())
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmpname)
+		}
+	}()
+
+	var cw io.WriteCloser
+	if w.Compressor != nil {
+		cw, err = w.Compressor(dst)
+	} else {
+		cw = gzip.NewWriter(dst)
+	}
+	if err == nil {
+		if _, err = io.Copy(cw, src); err == nil {
+			err = cw.Close()
+		}
+	}
+	if err1 := dst.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	if err != nil {
+		return
+	}
+
+	_ = os.Chtimes(tmpname, st.ModTime(), st.ModTime())
+	if err = os.Rename(tmpname, dstname); err != nil {
+		return
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
 func (w *FileWriter) create() (err error) {
-	w.file, err = os.OpenFile(w.fileargs(timeNow()))
+	now := timeNow()
+	w.checked = now
+	w.file, err = os.OpenFile(w.openargs(now))
 	if err != nil {
 		return err
 	}
 	w.size = 0
+	w.schedule(now)
 	st, err := w.file.Stat()
 	if err == nil {
 		w.size = st.Size()
@@ -251,44 +574,182 @@ func (w *FileWriter) create() (err error) {
 		}
 	}
 
-	os.Remove(w.Filename)
-	if !w.ProcessID {
-		_ = os.Symlink(filepath.Base(w.file.Name()), w.Filename)
+	if !w.Shared {
+		os.Remove(w.Filename)
+		if !w.ProcessID {
+			_ = os.Symlink(filepath.Base(w.file.Name()), w.Filename)
+		}
+	}
+
+	return
+}
+
+// openargs returns the filename, flag, perm of the current log file.
+func (w *FileWriter) openargs(now time.Time) (filename string, flag int, perm os.FileMode) {
+	filename, flag, perm = w.fileargs(now)
+	if w.Shared {
+		filename = w.Filename
+	}
+	return
+}
+
+// rename renames the shared Filename to a backup under the advisory lock, it returns
+// an empty oldname if the file has been renamed by another process.
+func (w *FileWriter) rename(now time.Time) (oldname string, err error) {
+	unlock, err := lockFile(w.Filename + ".lock")
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	st, err := os.Stat(w.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if w.file != nil {
+		if fst, err := w.file.Stat(); err != nil || !os.SameFile(st, fst) {
+			return "", nil
+		}
 	}
 
+	oldname, _, _ = w.fileargs(now)
+	err = os.Rename(w.Filename, oldname)
+	if err != nil {
+		oldname = ""
+	}
 	return
 }
 
+// moved reports whether the shared Filename has been renamed by another process, and
+// updates the size of log file.
+func (w *FileWriter) moved() bool {
+	st, err := os.Stat(w.Filename)
+	if err != nil {
+		return true
+	}
+	fst, err := w.file.Stat()
+	if err != nil || !os.SameFile(st, fst) {
+		return true
+	}
+	w.size = fst.Size()
+	return false
+}
+
+// schedule arms the timer of rotation by MaxFileAge and RotateEvery.
+func (w *FileWriter) schedule(now time.Time) {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+
+	var deadline time.Time
+	if w.MaxFileAge > 0 {
+		deadline = now.Add(w.MaxFileAge)
+	}
+	if w.RotateEvery > 0 {
+		if next := w.nextRotate(now); deadline.IsZero() || next.Before(deadline) {
+			deadline = next
+		}
+	}
+	if deadline.IsZero() {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(deadline.Sub(now), func() {
+		w.mu.Lock()
+		if w.timer == timer && w.file != nil {
+			if err := w.rotate(); err != nil {
+				w.report(err)
+			}
+		}
+		w.mu.Unlock()
+	})
+	w.timer = timer
+}
+
+// nextRotate returns the next scheduled rotation time after now, the boundaries are
+// aligned to the wall clock of location, so that daily rotation happens at midnight
+// even across the daylight saving time changes.
+func (w *FileWriter) nextRotate(now time.Time) time.Time {
+	loc := w.location()
+	now = now.In(loc)
+
+	// wall clock elapsed since 1970-01-01 00:00 of location
+	year, month, day := now.Date()
+	days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400
+	wall := time.Duration(days)*24*time.Hour +
+		time.Duration(now.Hour())*time.Hour +
+		time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second +
+		time.Duration(now.Nanosecond())
+
+	for k := (wall-w.RotateOffset)/w.RotateEvery + 1; ; k++ {
+		next := k*w.RotateEvery + w.RotateOffset
+		// splits the wall clock to keep int in range of 32-bit platforms
+		rem := next % (24 * time.Hour)
+		t := time.Date(1970, 1, 1+int(next/(24*time.Hour)),
+			int(rem/time.Hour), int(rem%time.Hour/time.Minute), int(rem%time.Minute/time.Second), int(rem%time.Second), loc)
+		if t.After(now) {
+			return t
+		}
+	}
+}
+
+// location returns the location of timestamps and rotation boundaries.
+func (w *FileWriter) location() *time.Location {
+	switch {
+	case w.Location != nil:
+		return w.Location
+	case w.LocalTime:
+		return time.Local
+	}
+	return time.UTC
+}
+
 // fileargs returns a new filename, flag, perm based on the original name and the given time.
 func (w *FileWriter) fileargs(now time.Time) (filename string, flag int, perm os.FileMode) {
-	if !w.LocalTime {
-		now = now.UTC()
-	}
+	now = now.In(w.location())
 
 	// filename
-	ext := filepath.Ext(w.Filename)
-	prefix := w.Filename[0 : len(w.Filename)-len(ext)]
-	switch w.TimeFormat {
-	case "":
-		filename = prefix + now.Format(".2006-01-02T15-04-05")
-	case TimeFormatUnix:
-		filename = prefix + "." + strconv.FormatInt(now.Unix(), 10)
-	case TimeFormatUnixMs:
-		filename = prefix + "." + strconv.FormatInt(now.UnixNano()/1000000, 10)
-	default:
-		filename = prefix + "." + now.Format(w.TimeFormat)
-	}
-	if w.HostName {
-		if w.ProcessID {
-			filename += "." + hostname + "-" + strconv.Itoa(pid) + ext
-		} else {
-			filename += "." + hostname + ext
-		}
+	if w.Template != "" {
+		filename = filepath.Join(filepath.Dir(w.Filename), w.expand(func(layout string) string {
+			switch layout {
+			case TimeFormatUnix:
+				return strconv.FormatInt(now.Unix(), 10)
+			case TimeFormatUnixMs:
+				return strconv.FormatInt(now.UnixNano()/1000000, 10)
+			}
+			return now.Format(layout)
+		}))
 	} else {
-		if w.ProcessID {
-			filename += "." + strconv.Itoa(pid) + ext
+		ext := filepath.Ext(w.Filename)
+		prefix := w.Filename[0 : len(w.Filename)-len(ext)]
+		switch w.TimeFormat {
+		case "":
+			filename = prefix + now.Format(".2006-01-02T15-04-05")
+		case TimeFormatUnix:
+			filename = prefix + "." + strconv.FormatInt(now.Unix(), 10)
+		case TimeFormatUnixMs:
+			filename = prefix + "." + strconv.FormatInt(now.UnixNano()/1000000, 10)
+		default:
+			filename = prefix + "." + now.Format(w.TimeFormat)
+		}
+		if w.HostName {
+			if w.ProcessID {
+				filename += "." + hostname + "-" + strconv.Itoa(pid) + ext
+			} else {
+				filename += "." + hostname + ext
+			}
 		} else {
-			filename += ext
+			if w.ProcessID {
+				filename += "." + strconv.Itoa(pid) + ext
+			} else {
+				filename += ext
+			}
 		}
 	}
 
@@ -304,6 +765,44 @@ func (w *FileWriter) fileargs(now time.Time) (filename string, flag int, perm os
 	return
 }
 
+// expand expands the placeholders of Template, the time layouts are formatted by timefmt.
+// If timefmt is nil, it returns the wildcard pattern with all placeholders except {name}
+// replaced by "*".
+func (w *FileWriter) expand(timefmt func(layout string) string) string {
+	var b strings.Builder
+	s := w.Template
+	for {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			break
+		}
+		b.WriteString(s[:i])
+		key := s[i+1 : i+j]
+		switch value, ok := w.Placeholders[key]; {
+		case key == "name" && !ok:
+			base := filepath.Base(w.Filename)
+			b.WriteString(base[:len(base)-len(filepath.Ext(base))])
+		case timefmt == nil:
+			b.WriteByte('*')
+		case ok:
+			b.WriteString(value)
+		case key == "hostname":
+			b.WriteString(hostname)
+		case key == "pid":
+			b.WriteString(strconv.Itoa(pid))
+		default:
+			b.WriteString(timefmt(key))
+		}
+		s = s[i+j+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
 var hostname, machine = func() (string, [16]byte) {
 	// host
 	host, err := os.Hostname()
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package log
+
+import (
+	"errors"
+)
+
+// lockFile is not supported on the platforms without flock or LockFileEx.
+func lockFile(name string) (unlock func(), err error) {
+	return nil, errors.New("log: file lock is not supported")
+}
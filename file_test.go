@@ -1,11 +1,17 @@
 package log
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
 	"time"
+	_ "time/tzdata"
 )
 
 func TestFileWriter(t *testing.T) {
@@ -339,6 +345,31 @@ func TestFileWriterFileargs(t *testing.T) {
 			t.Fatalf("expected: %q, actual: %q", expected, name)
 		}
 	})
+	t.Run("template", func(t *testing.T) {
+		origHost := hostname
+		hostname = "shire"
+		defer func() { hostname = origHost }()
+		origPid := pid
+		pid = 198400
+		defer func() { pid = origPid }()
+
+		w := &FileWriter{
+			Filename:     "logs/file-output.log",
+			Template:     "{name}.{region}.{hostname}.{pid}.{2006-01-02T15}.log",
+			Placeholders: map[string]string{"region": "us-east-1"},
+		}
+		expected := filepath.Join("logs", "file-output.us-east-1.shire.198400.2020-08-12T16.log")
+		if name, _, _ := w.fileargs(d); name != expected {
+			t.Fatalf("expected: %q, actual: %q", expected, name)
+		}
+		pattern := w.expand(nil)
+		if pattern != "file-output.*.*.*.*.log" {
+			t.Fatalf("pattern expected: %q, actual: %q", "file-output.*.*.*.*.log", pattern)
+		}
+		if ok, _ := filepath.Match(pattern, "file-output.eu-west-1.bree.1.2020-08-11T10.log"); !ok {
+			t.Fatalf("pattern %q must match the files of other hosts", pattern)
+		}
+	})
 	t.Run("hostname or pid appears", func(t *testing.T) {
 		origHost := hostname
 		hostname = "shire"
@@ -367,3 +398,417 @@ func TestFileWriterFileargs(t *testing.T) {
 		}
 	})
 }
+
+func TestFileWriterNextRotate(t *testing.T) {
+	now := time.Date(2020, 8, 12, 16, 7, 0, 0, time.UTC)
+
+	cases := []struct {
+		every    time.Duration
+		offset   time.Duration
+		expected time.Time
+	}{
+		{time.Hour, 0, time.Date(2020, 8, 12, 17, 0, 0, 0, time.UTC)},
+		{time.Hour, 30 * time.Minute, time.Date(2020, 8, 12, 16, 30, 0, 0, time.UTC)},
+		{time.Hour, 5 * time.Minute, time.Date(2020, 8, 12, 17, 5, 0, 0, time.UTC)},
+		{24 * time.Hour, 0, time.Date(2020, 8, 13, 0, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, 5 * time.Minute, time.Date(2020, 8, 13, 0, 5, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		w := &FileWriter{RotateEvery: c.every, RotateOffset: c.offset}
+		if next := w.nextRotate(now); !next.Equal(c.expected) {
+			t.Errorf("every=%s offset=%s expected: %s, actual: %s", c.every, c.offset, c.expected, next)
+		}
+	}
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	w := &FileWriter{RotateEvery: 24 * time.Hour, Location: loc}
+	expected := time.Date(2020, 8, 14, 0, 0, 0, 0, loc)
+	if next := w.nextRotate(now); !next.Equal(expected) {
+		t.Errorf("location expected: %s, actual: %s", expected, next)
+	}
+}
+
+func TestFileWriterLocation(t *testing.T) {
+	// the time/tzdata is embedded, to keep the daylight saving time cases running on
+	// every platform, including the 32-bit ones.
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location error: %+v", err)
+	}
+
+	cases := []struct {
+		now      time.Time
+		every    time.Duration
+		expected time.Time
+	}{
+		// daylight saving time starts at 2020-03-08 02:00
+		{time.Date(2020, 3, 7, 12, 0, 0, 0, loc), 24 * time.Hour, time.Date(2020, 3, 8, 0, 0, 0, 0, loc)},
+		{time.Date(2020, 3, 8, 12, 0, 0, 0, loc), 24 * time.Hour, time.Date(2020, 3, 9, 0, 0, 0, 0, loc)},
+		{time.Date(2020, 3, 8, 1, 30, 0, 0, loc), time.Hour, time.Date(2020, 3, 8, 3, 0, 0, 0, loc)},
+		// daylight saving time ends at 2020-11-01 02:00
+		{time.Date(2020, 11, 1, 12, 0, 0, 0, loc), 24 * time.Hour, time.Date(2020, 11, 2, 0, 0, 0, 0, loc)},
+		{time.Date(2020, 11, 1, 0, 30, 0, 0, loc), time.Hour, time.Date(2020, 11, 1, 1, 0, 0, 0, loc)},
+		{time.Date(2020, 11, 1, 23, 30, 0, 0, loc), time.Hour, time.Date(2020, 11, 2, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		w := &FileWriter{RotateEvery: c.every, Location: loc}
+		if next := w.nextRotate(c.now); !next.Equal(c.expected) {
+			t.Errorf("now=%s every=%s expected: %s, actual: %s", c.now, c.every, c.expected, next)
+		}
+	}
+
+	w := &FileWriter{Filename: "file-output.log", Location: loc}
+	expected := "file-output.2020-08-12T12-07-00.log"
+	if name, _, _ := w.fileargs(time.Date(2020, 8, 12, 16, 7, 0, 0, time.UTC)); name != expected {
+		t.Errorf("expected: %q, actual: %q", expected, name)
+	}
+}
+
+func TestFileWriterMaxFileAge(t *testing.T) {
+	filename := "file-max-age.log"
+
+	w := &FileWriter{
+		Filename:   filename,
+		MaxBackups: 10,
+		MaxFileAge: 1100 * time.Millisecond,
+	}
+	_, err := wlprintf(w, InfoLevel, "hello file writer!\n")
+	if err != nil {
+		t.Fatalf("file writer error: %+v", err)
+	}
+
+	time.Sleep(2500 * time.Millisecond)
+	w.Close()
+
+	matches, _ := filepath.Glob("file-max-age.*.log")
+	if len(matches) < 2 {
+		t.Errorf("file writer must rotate by max file age, got %+v", matches)
+	}
+	for i := range matches {
+		os.Remove(matches[i])
+	}
+
+	os.Remove(filename)
+}
+
+func TestFileWriterMaxTotalSize(t *testing.T) {
+	filename := "file-total-size.log"
+	text := "hello file writer!\n"
+
+	w := &FileWriter{
+		Filename:     filename,
+		MaxBackups:   10,
+		MaxTotalSize: int64(len(text)) * 2,
+	}
+
+	for i := 0; i < 4; i++ {
+		_, err := wlprintf(w, InfoLevel, text)
+		if err != nil {
+			t.Fatalf("file writer error: %+v", err)
+		}
+		time.Sleep(1100 * time.Millisecond)
+		_ = w.Rotate()
+	}
+	time.Sleep(100 * time.Millisecond)
+	w.Close()
+
+	matches, _ := filepath.Glob("file-total-size.*.log")
+	var total int64
+	for i := range matches {
+		if st, err := os.Stat(matches[i]); err == nil {
+			total += st.Size()
+		}
+		os.Remove(matches[i])
+	}
+	if total > w.MaxTotalSize {
+		t.Errorf("file writer total size %d exceeds %d, files: %+v", total, w.MaxTotalSize, matches)
+	}
+	if len(matches) == 0 {
+		t.Errorf("file writer must keep the newest files")
+	}
+
+	os.Remove(filename)
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	filename := "file-compress.log"
+	text := "hello file writer!\n"
+
+	w := &FileWriter{
+		Filename:   filename,
+		MaxBackups: 10,
+		Compress:   true,
+	}
+	_, err := wlprintf(w, InfoLevel, text)
+	if err != nil {
+		t.Fatalf("file writer error: %+v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	_ = w.Rotate()
+	time.Sleep(200 * time.Millisecond)
+	w.Close()
+
+	matches, _ := filepath.Glob("file-compress.*.log.gz")
+	if len(matches) != 1 {
+		t.Fatalf("filepath glob return %+v number mismath", matches)
+	}
+	file, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatalf("open compressed file error: %+v", err)
+	}
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip reader error: %+v", err)
+	}
+	data, _ := io.ReadAll(r)
+	file.Close()
+	if string(data) != text {
+		t.Errorf("read compressed content mismath: data=[%s], text=[%s]", data, text)
+	}
+
+	logs, _ := filepath.Glob("file-compress.*.log")
+	if len(logs) != 1 {
+		t.Errorf("file writer must remove the compressed log file, got %+v", logs)
+	}
+
+	for _, name := range append(matches, logs...) {
+		os.Remove(name)
+	}
+	os.Remove(filename)
+}
+
+func TestFileWriterOnRotate(t *testing.T) {
+	filename := "file-on-rotate.log"
+
+	rotated := make(chan string, 1)
+	w := &FileWriter{
+		Filename:   filename,
+		MaxBackups: 10,
+		OnRotate: func(oldPath string) {
+			rotated <- oldPath
+		},
+	}
+	_, err := wlprintf(w, InfoLevel, "hello file writer!\n")
+	if err != nil {
+		t.Fatalf("file writer error: %+v", err)
+	}
+	oldname := w.file.Name()
+	time.Sleep(1100 * time.Millisecond)
+	_ = w.Rotate()
+	w.Close()
+
+	select {
+	case name := <-rotated:
+		if name != oldname {
+			t.Errorf("on rotate expected: %q, actual: %q", oldname, name)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("on rotate must be called")
+	}
+
+	matches, _ := filepath.Glob("file-on-rotate.*.log")
+	for i := range matches {
+		os.Remove(matches[i])
+	}
+	os.Remove(filename)
+}
+
+func TestFileWriterTemplate(t *testing.T) {
+	filename := "file-template.log"
+
+	w := &FileWriter{
+		Filename:   filename,
+		Template:   "{name}.{pid}.{2006-01-02T15-04-05}.log",
+		MaxBackups: 1,
+	}
+	_, err := wlprintf(w, InfoLevel, "hello file writer!\n")
+	if err != nil {
+		t.Fatalf("file writer error: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		time.Sleep(1100 * time.Millisecond)
+		_ = w.Rotate()
+	}
+	time.Sleep(100 * time.Millisecond)
+	w.Close()
+
+	matches, _ := filepath.Glob("file-template." + strconv.Itoa(pid) + ".*.log")
+	if len(matches) != 2 {
+		t.Errorf("filepath glob return %+v number mismath", matches)
+	}
+	for i := range matches {
+		os.Remove(matches[i])
+	}
+	os.Remove(filename)
+}
+
+func TestFileWriterFooter(t *testing.T) {
+	filename := "file-footer.log"
+	text := "hello file writer!\n"
+
+	w := &FileWriter{
+		Filename:   filename,
+		MaxBackups: 10,
+		Header: func(_ os.FileInfo) []byte {
+			return []byte("# header\n")
+		},
+		Footer: func(_ os.FileInfo) []byte {
+			return []byte("# footer\n")
+		},
+	}
+	_, err := wlprintf(w, InfoLevel, text)
+	if err != nil {
+		t.Fatalf("file writer error: %+v", err)
+	}
+	oldname := w.file.Name()
+	time.Sleep(1100 * time.Millisecond)
+	_ = w.Rotate()
+	newname := w.file.Name()
+	w.Close()
+
+	for _, name := range []string{oldname, newname} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read file error: %+v", err)
+		}
+		expected := "# header\n# footer\n"
+		if name == oldname {
+			expected = "# header\n" + text + "# footer\n"
+		}
+		if string(data) != expected {
+			t.Errorf("read file content mismath: data=[%s], expected=[%s]", data, expected)
+		}
+		os.Remove(name)
+	}
+	os.Remove(filename)
+}
+
+func TestFileWriterFallback(t *testing.T) {
+	text := "hello file writer!\n"
+
+	var errs []error
+	var buf bytes.Buffer
+	w := &FileWriter{
+		Filename: "/nonexists/output.log",
+		ErrorHandler: func(err error) {
+			errs = append(errs, err)
+		},
+		Fallback: &buf,
+	}
+
+	_, err := wlprintf(w, InfoLevel, text)
+	if err != nil {
+		t.Fatalf("file writer with fallback should not return error: %+v", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("error handler must be called once, got %+v", errs)
+	}
+	if buf.String() != text {
+		t.Errorf("fallback content mismath: data=[%s], text=[%s]", buf.String(), text)
+	}
+}
+
+func TestFileWriterSyncPolicy(t *testing.T) {
+	filename := "file-sync.log"
+	text := "hello file writer!\n"
+
+	cases := []struct {
+		policy   SyncPolicy
+		unsynced int64
+	}{
+		{SyncNever, int64(len(text)) * 3},
+		{SyncEveryWrite, 0},
+		{SyncEveryBytes, int64(len(text))},
+		{SyncEveryInterval, 0},
+	}
+	for _, c := range cases {
+		w := &FileWriter{
+			Filename:     filename,
+			SyncPolicy:   c.policy,
+			SyncBytes:    int64(len(text)) * 2,
+			SyncInterval: 10 * time.Millisecond,
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := wlprintf(w, InfoLevel, text); err != nil {
+				t.Fatalf("file writer error: %+v", err)
+			}
+		}
+		if c.policy == SyncEveryInterval {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.mu.Lock()
+		unsynced := w.unsynced
+		w.mu.Unlock()
+		if unsynced != c.unsynced {
+			t.Errorf("sync policy %d unsynced expected: %d, actual: %d", c.policy, c.unsynced, unsynced)
+		}
+		if err := w.Sync(); err != nil {
+			t.Errorf("file writer sync error: %+v", err)
+		}
+		w.Close()
+	}
+
+	matches, _ := filepath.Glob("file-sync.*.log")
+	for i := range matches {
+		os.Remove(matches[i])
+	}
+	os.Remove(filename)
+}
+
+func TestFileWriterShared(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows does not rename the opened files")
+	}
+
+	filename := "file-shared.log"
+	text := "hello file writer!\n"
+
+	w1 := &FileWriter{Filename: filename, MaxBackups: 10, Shared: true}
+	w2 := &FileWriter{Filename: filename, MaxBackups: 10, Shared: true}
+
+	for _, w := range []*FileWriter{w1, w2} {
+		if _, err := wlprintf(w, InfoLevel, text); err != nil {
+			t.Fatalf("file writer error: %+v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read shared file error: %+v", err)
+	}
+	if string(data) != text+text {
+		t.Errorf("read shared file content mismath: data=[%s]", data)
+	}
+
+	if err := w1.Rotate(); err != nil {
+		t.Fatalf("file writer rotate error: %+v", err)
+	}
+	// w2 checks the rename in next write
+	w2.checked = time.Time{}
+	for _, w := range []*FileWriter{w2, w1, w2} {
+		if _, err := wlprintf(w, InfoLevel, text); err != nil {
+			t.Fatalf("file writer error: %+v", err)
+		}
+	}
+	w1.Close()
+	w2.Close()
+
+	matches, _ := filepath.Glob("file-shared.*.log")
+	if len(matches) != 1 {
+		t.Fatalf("filepath glob return %+v number mismath", matches)
+	}
+	data, _ = os.ReadFile(matches[0])
+	if string(data) != text+text+text {
+		t.Errorf("read backup file content mismath: data=[%s]", data)
+	}
+	data, _ = os.ReadFile(filename)
+	if string(data) != text+text {
+		t.Errorf("read shared file content mismath: data=[%s]", data)
+	}
+
+	for i := range matches {
+		os.Remove(matches[i])
+	}
+	os.Remove(filename)
+	os.Remove(filename + ".lock")
+}
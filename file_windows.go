@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	lockFileEx   = kernel32.NewProc("LockFileEx").Call
+	unlockFileEx = kernel32.NewProc("UnlockFileEx").Call
+)
+
+// lockFile acquires the exclusive lock of the file by LockFileEx.
+func lockFile(name string) (unlock func(), err error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+	const LOCKFILE_EXCLUSIVE_LOCK = 0x2
+	var ol syscall.Overlapped
+	if r, _, e := lockFileEx(file.Fd(), LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, uintptr(unsafe.Pointer(&ol))); r == 0 {
+		file.Close()
+		return nil, e
+	}
+	return func() {
+		var ol syscall.Overlapped
+		_, _, _ = unlockFileEx(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,318 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// FluentWriter is a Writer that forwards entries in batches to Fluentd/Fluent Bit by
+// the forward protocol, with optional shared key authentication and ack mode.
+type FluentWriter struct {
+	// Network specifies the network of forward input, uses "tcp" if empty.
+	Network string
+
+	// Address specifies the address of forward input, uses "localhost:24224" if empty.
+	Address string
+
+	// Tag specifies the tag of events.
+	Tag string
+
+	// RequireAck determines if waits the ack of each forwarded chunk.
+	RequireAck bool
+
+	// SharedKey specifies the shared key of secure forward, it enables the handshake if not empty.
+	SharedKey string
+
+	// Username and Password specifies the user authentication of secure forward.
+	Username string
+	Password string
+
+	// Hostname specifies the self hostname of handshake, uses hostname if empty.
+	Hostname string
+
+	// TLSConfig specifies the tls config of connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Timeout specifies the dial and request timeout, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a chunk, uses 100 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed chunk, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Dial specifies the dial function for creating connections, uses net.DialTimeout if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	once    sync.Once
+	batcher batcher
+	conn    net.Conn
+	resp    []byte
+}
+
+func (w *FluentWriter) init() {
+	w.once.Do(func() {
+		w.batcher.size = w.BatchSize
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and closes the connection.
+func (w *FluentWriter) Close() (err error) {
+	w.init()
+	err = w.batcher.close()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *FluentWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *FluentWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (w *FluentWriter) send(b *batch) (err error) {
+	if w.conn == nil {
+		if err = w.connect(); err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil && w.conn != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	// Forward Mode: [tag, [[time, record], ...], option]
+	dst := buf.B[:0]
+	if w.RequireAck {
+		dst = msgpackAppendArrayHeader(dst, 3)
+	} else {
+		dst = msgpackAppendArrayHeader(dst, 2)
+	}
+	dst = msgpackAppendString(dst, w.Tag)
+	dst = msgpackAppendArrayHeader(dst, b.Len())
+	for i := 0; i < b.Len(); i++ {
+		entry := b.Entry(i)
+		ts, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+		if !ok {
+			ts = b.times[i]
+		}
+		dst = msgpackAppendArrayHeader(dst, 2)
+		dst = msgpackAppendEventTime(dst, ts)
+		dst = msgpackAppendJSON(dst, bytes.TrimRight(entry, "\n"))
+	}
+	var chunk string
+	if w.RequireAck {
+		var id [16]byte
+		for i := 0; i < len(id); i += 4 {
+			n := Fastrandn(math.MaxUint32)
+			id[i], id[i+1], id[i+2], id[i+3] = byte(n), byte(n>>8), byte(n>>16), byte(n>>24)
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		dst = msgpackAppendMapHeader(dst, 2)
+		dst = msgpackAppendString(dst, "size")
+		dst = msgpackAppendInt(dst, int64(b.Len()))
+		dst = msgpackAppendString(dst, "chunk")
+		dst = msgpackAppendString(dst, chunk)
+	}
+	buf.B = dst
+
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	if _, err = w.conn.Write(buf.B); err != nil {
+		return
+	}
+
+	if w.RequireAck {
+		var v interface{}
+		if v, err = w.read(); err != nil {
+			return
+		}
+		if m, _ := v.(map[string]interface{}); m["ack"] != chunk {
+			return errors.New("log: fluent ack mismatch")
+		}
+	}
+
+	return nil
+}
+
+func (w *FluentWriter) connect() (err error) {
+	network, address := w.Network, w.Address
+	if network == "" {
+		network = "tcp"
+	}
+	if address == "" {
+		address = "localhost:24224"
+	}
+
+	if w.Dial != nil {
+		w.conn, err = w.Dial(network, address)
+	} else {
+		w.conn, err = net.DialTimeout(network, address, w.timeout())
+	}
+	if err != nil {
+		return
+	}
+	w.resp = w.resp[:0]
+
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(address)
+		}
+		conn := tls.Client(w.conn, config)
+		_ = conn.SetDeadline(timeNow().Add(w.timeout()))
+		if err = conn.Handshake(); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			return
+		}
+		w.conn = conn
+	}
+
+	if w.SharedKey != "" {
+		if err = w.handshake(); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			return
+		}
+	}
+
+	return
+}
+
+// handshake performs the HELO/PING/PONG handshake of secure forward.
+func (w *FluentWriter) handshake() error {
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+
+	// HELO: ["HELO", {"nonce": nonce, "auth": salt, "keepalive": bool}]
+	v, err := w.read()
+	if err != nil {
+		return err
+	}
+	helo, _ := v.([]interface{})
+	if len(helo) < 2 || helo[0] != "HELO" {
+		return errors.New("log: fluent invalid HELO message")
+	}
+	options, _ := helo[1].(map[string]interface{})
+	nonce, auth := msgpackBytes(options["nonce"]), msgpackBytes(options["auth"])
+
+	self := w.Hostname
+	if self == "" {
+		self = hostname
+	}
+	var salt [16]byte
+	for i := range salt {
+		salt[i] = byte(Fastrandn(256))
+	}
+	digest := func(parts ...[]byte) string {
+		h := sha512.New()
+		for _, p := range parts {
+			h.Write(p)
+		}
+		return hexEncode(h.Sum(nil))
+	}
+
+	// PING: ["PING", self_hostname, shared_key_salt, sha512_hex(salt + hostname + nonce + key), username, password_digest]
+	ping := msgpackAppendArrayHeader(nil, 6)
+	ping = msgpackAppendString(ping, "PING")
+	ping = msgpackAppendString(ping, self)
+	ping = msgpackAppendBytes(ping, salt[:])
+	ping = msgpackAppendString(ping, digest(salt[:], []byte(self), nonce, []byte(w.SharedKey)))
+	ping = msgpackAppendString(ping, w.Username)
+	if len(auth) != 0 {
+		ping = msgpackAppendString(ping, digest(auth, []byte(w.Username), []byte(w.Password)))
+	} else {
+		ping = msgpackAppendString(ping, "")
+	}
+	if _, err = w.conn.Write(ping); err != nil {
+		return err
+	}
+
+	// PONG: ["PONG", auth_result, reason, server_hostname, sha512_hex(salt + server_hostname + nonce + key)]
+	if v, err = w.read(); err != nil {
+		return err
+	}
+	pong, _ := v.([]interface{})
+	if len(pong) < 5 || pong[0] != "PONG" {
+		return errors.New("log: fluent invalid PONG message")
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		reason, _ := pong[2].(string)
+		return errors.New("log: fluent authentication failed: " + reason)
+	}
+	server, _ := pong[3].(string)
+	if pong[4] != digest(salt[:], []byte(server), nonce, []byte(w.SharedKey)) {
+		return errors.New("log: fluent shared key mismatch of server")
+	}
+
+	return nil
+}
+
+// read reads a msgpack value from connection.
+func (w *FluentWriter) read() (v interface{}, err error) {
+	var buf [4096]byte
+	for {
+		if len(w.resp) > 0 {
+			var rest []byte
+			v, rest, err = msgpackDecode(w.resp)
+			if err == nil {
+				w.resp = append(w.resp[:0], rest...)
+				return
+			}
+			if err != errMsgpackShort {
+				return
+			}
+		}
+		var n int
+		n, err = w.conn.Read(buf[:])
+		if err != nil {
+			return
+		}
+		w.resp = append(w.resp, buf[:n]...)
+	}
+}
+
+func msgpackBytes(v interface{}) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+var _ Writer = (*FluentWriter)(nil)
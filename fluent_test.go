@@ -0,0 +1,200 @@
+package log
+
+import (
+	"crypto/sha512"
+	"net"
+	"testing"
+	"time"
+)
+
+// fluentServer accepts one connection and decodes forward messages of it.
+func fluentServer(t *testing.T, sharedKey string, ack bool) (net.Listener, chan []interface{}) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	messages := make(chan []interface{}, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var data []byte
+		read := func() (v interface{}, err error) {
+			buf := make([]byte, 4096)
+			for {
+				if len(data) > 0 {
+					var rest []byte
+					if v, rest, err = msgpackDecode(data); err == nil {
+						data = rest
+						return
+					} else if err != errMsgpackShort {
+						return
+					}
+				}
+				var n int
+				if n, err = conn.Read(buf); err != nil {
+					return
+				}
+				data = append(data, buf[:n]...)
+			}
+		}
+		digest := func(parts ...[]byte) string {
+			h := sha512.New()
+			for _, p := range parts {
+				h.Write(p)
+			}
+			return hexEncode(h.Sum(nil))
+		}
+
+		if sharedKey != "" {
+			nonce := []byte("0123456789abcdef")
+			helo := msgpackAppendArrayHeader(nil, 2)
+			helo = msgpackAppendString(helo, "HELO")
+			helo = msgpackAppendMapHeader(helo, 3)
+			helo = msgpackAppendString(helo, "nonce")
+			helo = msgpackAppendBytes(helo, nonce)
+			helo = msgpackAppendString(helo, "auth")
+			helo = msgpackAppendBytes(helo, nil)
+			helo = msgpackAppendString(helo, "keepalive")
+			helo = msgpackAppendBool(helo, true)
+			_, _ = conn.Write(helo)
+
+			v, err := read()
+			if err != nil {
+				return
+			}
+			ping, _ := v.([]interface{})
+			if len(ping) != 6 || ping[0] != "PING" {
+				return
+			}
+			host, _ := ping[1].(string)
+			salt := msgpackBytes(ping[2])
+			ok := ping[3] == digest(salt, []byte(host), nonce, []byte(sharedKey))
+			pong := msgpackAppendArrayHeader(nil, 5)
+			pong = msgpackAppendString(pong, "PONG")
+			pong = msgpackAppendBool(pong, ok)
+			pong = msgpackAppendString(pong, "shared key mismatch")
+			pong = msgpackAppendString(pong, "server")
+			pong = msgpackAppendString(pong, digest(salt, []byte("server"), nonce, []byte(sharedKey)))
+			_, _ = conn.Write(pong)
+			if !ok {
+				return
+			}
+		}
+
+		for {
+			v, err := read()
+			if err != nil {
+				return
+			}
+			msg, _ := v.([]interface{})
+			messages <- msg
+			if ack && len(msg) == 3 {
+				option, _ := msg[2].(map[string]interface{})
+				chunk, _ := option["chunk"].(string)
+				resp := msgpackAppendMapHeader(nil, 1)
+				resp = msgpackAppendString(resp, "ack")
+				resp = msgpackAppendString(resp, chunk)
+				_, _ = conn.Write(resp)
+			}
+		}
+	}()
+	return ln, messages
+}
+
+func TestFluentWriter(t *testing.T) {
+	ln, messages := fluentServer(t, "", false)
+	defer ln.Close()
+
+	w := &FluentWriter{
+		Address:       ln.Addr().String(),
+		Tag:           "app.test",
+		FlushInterval: 10 * time.Millisecond,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Int("n", 42).Msg("hello fluent")
+	logger.Warn().Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("fluent writer close error: %+v", err)
+	}
+
+	var records []map[string]interface{}
+	for len(records) < 2 {
+		select {
+		case msg := <-messages:
+			if len(msg) != 2 || msg[0] != "app.test" {
+				t.Fatalf("fluent writer invalid message: %#v", msg)
+			}
+			entries, _ := msg[1].([]interface{})
+			for _, entry := range entries {
+				pair, _ := entry.([]interface{})
+				if ts, _ := pair[0].([]byte); len(ts) != 9 || ts[0] != 0 {
+					t.Errorf("fluent writer invalid event time: %#v", pair[0])
+				}
+				record, _ := pair[1].(map[string]interface{})
+				records = append(records, record)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fluent writer timeout, got %d records", len(records))
+		}
+	}
+
+	if r := records[0]; r["message"] != "hello fluent" || r["foo"] != "bar" || r["level"] != "info" {
+		t.Errorf("fluent writer record mismatch: %#v", r)
+	}
+	if r := records[1]; r["message"] != "second" || r["level"] != "warn" {
+		t.Errorf("fluent writer record mismatch: %#v", r)
+	}
+}
+
+func TestFluentWriterSecureAck(t *testing.T) {
+	ln, messages := fluentServer(t, "secret", true)
+	defer ln.Close()
+
+	w := &FluentWriter{
+		Address:    ln.Addr().String(),
+		Tag:        "app.secure",
+		SharedKey:  "secret",
+		Hostname:   "client",
+		RequireAck: true,
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello secure")
+	if err := w.Close(); err != nil {
+		t.Fatalf("fluent writer close error: %+v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		if len(msg) != 3 || msg[0] != "app.secure" {
+			t.Fatalf("fluent writer invalid message: %#v", msg)
+		}
+		option, _ := msg[2].(map[string]interface{})
+		if chunk, _ := option["chunk"].(string); chunk == "" {
+			t.Errorf("fluent writer missing chunk option: %#v", option)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fluent writer timeout")
+	}
+}
+
+func TestFluentWriterSharedKeyMismatch(t *testing.T) {
+	ln, _ := fluentServer(t, "secret", false)
+	defer ln.Close()
+
+	w := &FluentWriter{
+		Address:    ln.Addr().String(),
+		Tag:        "app.secure",
+		SharedKey:  "wrong",
+		MaxRetries: -1,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello secure")
+	if err := w.Close(); err == nil {
+		t.Errorf("fluent writer should fail with mismatched shared key")
+	}
+}
@@ -120,6 +120,101 @@ func parseFormatterArgs(json []byte, args *FormatterArgs) {
 	}
 }
 
+// parseJSONLevel extracts the level from the given top-level field of json input,
+// returns noLevel if the field is absent or unknown.
+func parseJSONLevel(json []byte, field string) Level {
+	if value := jsonGetField(json, field); len(value) >= 2 && value[0] == '"' {
+		return ParseLevel(b2s(value[1 : len(value)-1]))
+	}
+	return noLevel
+}
+
+// jsonGetField returns the raw value of the given top-level field of json input.
+func jsonGetField(json []byte, field string) []byte {
+	if len(json) == 0 || json[0] != '{' {
+		return nil
+	}
+	var key, str []byte
+	var ok bool
+	for i := 1; i < len(json); i++ {
+		if json[i] != '"' {
+			continue
+		}
+		i, str, _, ok = jsonParseString(json, i+1)
+		if !ok {
+			break
+		}
+		key = str[1 : len(str)-1]
+		for ; i < len(json); i++ {
+			if json[i] <= ' ' || json[i] == ':' {
+				continue
+			}
+			break
+		}
+		i, _, str, ok = jsonParseAny(json, i, true)
+		if !ok {
+			break
+		}
+		if b2s(key) == field {
+			return str
+		}
+	}
+	return nil
+}
+
+// jsonRange calls fn on the raw key and value of each top-level field of json input,
+// it stops if fn returns false. The key is not unescaped.
+func jsonRange(json []byte, fn func(key, value []byte) bool) {
+	if len(json) == 0 || json[0] != '{' {
+		return
+	}
+	var key, str []byte
+	var ok bool
+	for i := 1; i < len(json); i++ {
+		if json[i] != '"' {
+			continue
+		}
+		i, str, _, ok = jsonParseString(json, i+1)
+		if !ok {
+			break
+		}
+		key = str[1 : len(str)-1]
+		for ; i < len(json); i++ {
+			if json[i] <= ' ' || json[i] == ':' {
+				continue
+			}
+			break
+		}
+		i, _, str, ok = jsonParseAny(json, i, true)
+		if !ok || !fn(key, str) {
+			break
+		}
+	}
+}
+
+// jsonRangeArray calls fn on the raw value of each element of json array input,
+// it stops if fn returns false.
+func jsonRangeArray(json []byte, fn func(value []byte) bool) {
+	if len(json) == 0 || json[0] != '[' {
+		return
+	}
+	var value []byte
+	var ok bool
+	for i := 1; i < len(json); i++ {
+		if json[i] <= ' ' || json[i] == ',' {
+			continue
+		}
+		if json[i] == ']' {
+			break
+		}
+		i, _, value, ok = jsonParseAny(json, i, true)
+		if !ok || !fn(value) {
+			break
+		}
+		i--
+	}
+}
+
 func jsonParseString(json []byte, i int) (int, []byte, bool, bool) {
 	var s = i
 	_ = json[len(json)-1] // remove bounds check
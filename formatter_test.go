@@ -29,3 +29,22 @@ func TestFormatterDefault(t *testing.T) {
 
 	Info().Msg("aaaa 'b' cccc")
 }
+
+func TestFormatterParseLevel(t *testing.T) {
+	cases := []struct {
+		JSON  string
+		Level Level
+	}{
+		{`{"time":"2019-07-10T05:35:54.277Z","level":"warn","message":"hello"}`, WarnLevel},
+		{`{"ts":1234567890, "obj":{"level":"debug"}, "level" : "error"}`, ErrorLevel},
+		{`{"message":"level\":\"info","level":"trace"}`, TraceLevel},
+		{`{"time":"2019-07-10T05:35:54.277Z","message":"no level"}`, noLevel},
+		{`a plain text line`, noLevel},
+	}
+
+	for _, c := range cases {
+		if level := parseJSONLevel([]byte(c.JSON), "level"); level != c.Level {
+			t.Errorf("parse level of %s got %s, want %s", c.JSON, level, c.Level)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// GCPWriter is a Writer that writes entries as the structured logging of Google Cloud Logging,
+// which is picked up from stdout by GKE, Cloud Run and Cloud Functions. The level is mapped
+// to "severity", the caller is mapped to "logging.googleapis.com/sourceLocation", and the
+// "trace_id"/"span_id"/"trace_flags" fields are mapped to "logging.googleapis.com/trace",
+// "logging.googleapis.com/spanId" and "logging.googleapis.com/trace_sampled".
+type GCPWriter struct {
+	// ProjectID specifies the project id of trace resource name, uses GOOGLE_CLOUD_PROJECT
+	// environment variable if empty.
+	ProjectID string
+
+	// Writer specifies the writer of output, uses os.Stdout if nil.
+	Writer io.Writer
+
+	once    sync.Once
+	project string
+	mu      sync.Mutex
+}
+
+// WriteEntry implements Writer.
+func (w *GCPWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.once.Do(func() {
+		w.project = w.ProjectID
+		if w.project == "" {
+			w.project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+		}
+	})
+
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	defer bbpool.Put(b)
+
+	b.B = append(b.B, '{')
+	jsonRange(e.buf, func(key, value []byte) bool {
+		if len(b.B) > 1 {
+			b.B = append(b.B, ',')
+		}
+		switch b2s(key) {
+		case "level":
+			b.B = append(b.B, "\"severity\":"...)
+			level := e.Level
+			if level == noLevel && len(value) >= 2 {
+				level = ParseLevel(b2s(value[1 : len(value)-1]))
+			}
+			b.B = append(b.B, '"')
+			b.B = append(b.B, gcpSeverity(level)...)
+			b.B = append(b.B, '"')
+		case "caller":
+			if len(value) < 2 || value[0] != '"' {
+				goto raw
+			}
+			file, line := value[1:len(value)-1], []byte(nil)
+			if i := bytes.LastIndexByte(file, ':'); i > 0 {
+				file, line = file[:i], file[i+1:]
+			}
+			b.B = append(b.B, "\"logging.googleapis.com/sourceLocation\":{\"file\":\""...)
+			b.B = append(b.B, file...)
+			b.B = append(b.B, '"')
+			if len(line) != 0 {
+				b.B = append(b.B, ",\"line\":\""...)
+				b.B = append(b.B, line...)
+				b.B = append(b.B, '"')
+			}
+			b.B = append(b.B, '}')
+		case "trace_id":
+			if len(value) < 2 || value[0] != '"' {
+				goto raw
+			}
+			b.B = append(b.B, "\"logging.googleapis.com/trace\":\""...)
+			if w.project != "" {
+				b.B = append(b.B, "projects/"...)
+				b.B = append(b.B, w.project...)
+				b.B = append(b.B, "/traces/"...)
+			}
+			b.B = append(b.B, value[1:]...)
+		case "span_id":
+			b.B = append(b.B, "\"logging.googleapis.com/spanId\":"...)
+			b.B = append(b.B, value...)
+		case "trace_flags":
+			if len(value) < 2 || value[0] != '"' {
+				goto raw
+			}
+			b.B = append(b.B, "\"logging.googleapis.com/trace_sampled\":"...)
+			if unhex(value[len(value)-2])&1 == 1 {
+				b.B = append(b.B, "true"...)
+			} else {
+				b.B = append(b.B, "false"...)
+			}
+		default:
+			goto raw
+		}
+		return true
+	raw:
+		b.B = append(b.B, '"')
+		b.B = append(b.B, key...)
+		b.B = append(b.B, '"', ':')
+		b.B = append(b.B, value...)
+		return true
+	})
+	b.B = append(b.B, '}', '\n')
+
+	w.mu.Lock()
+	if w.Writer != nil {
+		n, err = w.Writer.Write(b.B)
+	} else {
+		n, err = os.Stdout.Write(b.B)
+	}
+	w.mu.Unlock()
+
+	return
+}
+
+// gcpSeverity converts Level to the LogSeverity of Google Cloud Logging.
+func gcpSeverity(level Level) string {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARNING"
+	case ErrorLevel:
+		return "ERROR"
+	case FatalLevel:
+		return "CRITICAL"
+	case PanicLevel:
+		return "ALERT"
+	}
+	return "DEFAULT"
+}
+
+var _ Writer = (*GCPWriter)(nil)
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestGCPWriter(t *testing.T) {
+	var b bytes.Buffer
+	logger := Logger{
+		Caller: 1,
+		Writer: &GCPWriter{ProjectID: "myproject", Writer: &b},
+	}
+
+	logger.Warn().Str("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736").Str("span_id", "00f067aa0ba902b7").Str("trace_flags", "01").RawJSON("obj", []byte(`{"a":1}`)).Msg("hello gcp")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatalf("gcp writer json error: %+v: %s", err, b.String())
+	}
+
+	if entry["severity"] != "WARNING" || entry["message"] != "hello gcp" || entry["time"] == nil {
+		t.Errorf("gcp writer severity mismatch: %s", b.String())
+	}
+	if entry["logging.googleapis.com/trace"] != "projects/myproject/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("gcp writer trace mismatch: %s", b.String())
+	}
+	if entry["logging.googleapis.com/spanId"] != "00f067aa0ba902b7" || entry["logging.googleapis.com/trace_sampled"] != true {
+		t.Errorf("gcp writer span mismatch: %s", b.String())
+	}
+	location, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if location["file"] != "gcp_test.go" || location["line"] == "" {
+		t.Errorf("gcp writer source location mismatch: %s", b.String())
+	}
+	if obj, _ := entry["obj"].(map[string]interface{}); obj["a"] != float64(1) {
+		t.Errorf("gcp writer should keep the rest fields: %s", b.String())
+	}
+	for _, key := range []string{"level", "caller", "trace_id", "span_id", "trace_flags"} {
+		if _, ok := entry[key]; ok {
+			t.Errorf("gcp writer should map %s field: %s", key, b.String())
+		}
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	cases := map[Level]string{
+		TraceLevel: "DEBUG",
+		DebugLevel: "DEBUG",
+		InfoLevel:  "INFO",
+		WarnLevel:  "WARNING",
+		ErrorLevel: "ERROR",
+		FatalLevel: "CRITICAL",
+		PanicLevel: "ALERT",
+		noLevel:    "DEFAULT",
+	}
+	for level, severity := range cases {
+		if s := gcpSeverity(level); s != severity {
+			t.Errorf("gcpSeverity(%v) = %s, want %s", level, s, severity)
+		}
+	}
+}
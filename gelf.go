@@ -0,0 +1,269 @@
+package log
+
+import (
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// GelfWriter is a Writer that sends entries to Graylog in GELF 1.1 over UDP or TCP.
+// The fields of entry are converted to the additional fields with underscore prefix,
+// and the level is mapped to syslog severity. UDP messages larger than ChunkSize are
+// sent in chunks, and TCP messages are delimited by null byte.
+type GelfWriter struct {
+	// Network specifies the network of GELF input, "udp" or "tcp", uses "udp" if empty.
+	Network string
+
+	// Address specifies the address of GELF input, uses "localhost:12201" if empty.
+	Address string
+
+	// Host specifies the host field of messages, uses hostname if empty.
+	Host string
+
+	// ChunkSize specifies the maximum size of udp datagrams, uses 8192 if zero.
+	ChunkSize int
+
+	// Gzip determines if compresses the udp messages by gzip.
+	Gzip bool
+
+	// TLSConfig specifies the tls config of tcp connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Dial specifies the dial function for creating connections, uses net.Dial if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Close closes the connection of writer.
+func (w *GelfWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+func (w *GelfWriter) network() string {
+	if w.Network == "" {
+		return "udp"
+	}
+	return w.Network
+}
+
+func (w *GelfWriter) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	address := w.Address
+	if address == "" {
+		address = "localhost:12201"
+	}
+	dial := w.Dial
+	if dial == nil {
+		dial = net.Dial
+	}
+
+	w.conn, err = dial(w.network(), address)
+	if err != nil {
+		return
+	}
+
+	if w.TLSConfig != nil && w.network() != "udp" {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(address)
+		}
+		conn := tls.Client(w.conn, config)
+		if err = conn.Handshake(); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			return
+		}
+		w.conn = conn
+	}
+
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *GelfWriter) WriteEntry(e *Entry) (n int, err error) {
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	defer bbpool.Put(b)
+
+	b.B = w.gelf(b.B, e)
+
+	udp := w.network() == "udp"
+	if udp && w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], b.B)
+		b.B, z.B = z.B, b.B
+	} else if !udp {
+		b.B = append(b.B, 0)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		if err = w.connect(); err != nil {
+			return
+		}
+	}
+
+	if udp {
+		n, err = w.writeChunks(b.B)
+	} else {
+		n, err = w.conn.Write(b.B)
+	}
+	if err != nil && !udp {
+		// reconnect and retry once
+		if err = w.connect(); err == nil {
+			n, err = w.conn.Write(b.B)
+		}
+	}
+
+	return
+}
+
+// writeChunks writes the udp message, in chunks if it is larger than ChunkSize.
+func (w *GelfWriter) writeChunks(msg []byte) (n int, err error) {
+	size := w.ChunkSize
+	if size <= 0 {
+		size = 8192
+	}
+	if len(msg) <= size {
+		return w.conn.Write(msg)
+	}
+
+	const header = 12
+	payload := size - header
+	count := (len(msg) + payload - 1) / payload
+	if count > 128 {
+		return 0, errors.New("log: gelf message too large: " + strconv.Itoa(len(msg)) + " bytes")
+	}
+
+	id := uint64(Fastrandn(math.MaxUint32))<<32 | uint64(Fastrandn(math.MaxUint32))
+	chunk := make([]byte, 0, size)
+	for i := 0; i < count; i++ {
+		chunk = append(chunk[:0], 0x1e, 0x0f,
+			byte(id>>56), byte(id>>48), byte(id>>40), byte(id>>32), byte(id>>24), byte(id>>16), byte(id>>8), byte(id),
+			byte(i), byte(count))
+		end := (i + 1) * payload
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk, msg[i*payload:end]...)
+		if _, err = w.conn.Write(chunk); err != nil {
+			return
+		}
+	}
+	return len(msg), nil
+}
+
+// gelf appends the GELF message of entry to dst.
+func (w *GelfWriter) gelf(dst []byte, e *Entry) []byte {
+	host := w.Host
+	if host == "" {
+		host = hostname
+	}
+
+	dst = append(dst, "{\"version\":\"1.1\",\"host\":"...)
+	dst = jsonAppendString(dst, host)
+
+	var short bool
+	level := e.Level
+	jsonRange(e.buf, func(key, value []byte) bool {
+		switch b2s(key) {
+		case "message", "msg":
+			if !short && len(value) > 0 && value[0] == '"' {
+				short = true
+				dst = append(dst, ",\"short_message\":"...)
+				dst = append(dst, value...)
+				return true
+			}
+		case "time":
+			if len(value) > 0 && value[0] == '"' {
+				value = value[1 : len(value)-1]
+			}
+			if ts, ok := parseEntryTime(b2s(value)); ok {
+				dst = append(dst, ",\"timestamp\":"...)
+				dst = strconv.AppendFloat(dst, float64(ts/1e6)/1000, 'f', 3, 64)
+				return true
+			}
+		case "level":
+			if level == noLevel && len(value) > 2 {
+				level = ParseLevel(b2s(value[1 : len(value)-1]))
+			}
+			return true
+		case "stack":
+			if len(value) > 0 && value[0] == '"' {
+				dst = append(dst, ",\"full_message\":"...)
+				dst = append(dst, value...)
+				return true
+			}
+		}
+
+		// additional field
+		if len(value) == 0 || value[0] == 'n' {
+			return true
+		}
+		dst = append(dst, ',', '"', '_')
+		if b2s(key) == "id" {
+			dst = append(dst, '_')
+		}
+		for _, c := range key {
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '.' || c == '-') {
+				c = '_'
+			}
+			dst = append(dst, c)
+		}
+		dst = append(dst, '"', ':')
+		switch value[0] {
+		case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			dst = append(dst, value...)
+		default:
+			dst = jsonAppendBytes(dst, value)
+		}
+		return true
+	})
+
+	if !short {
+		dst = append(dst, ",\"short_message\":\"-\""...)
+	}
+
+	var severity byte
+	switch level {
+	case TraceLevel, DebugLevel:
+		severity = 7
+	case InfoLevel:
+		severity = 6
+	case WarnLevel:
+		severity = 4
+	case ErrorLevel:
+		severity = 3
+	case FatalLevel:
+		severity = 2
+	case PanicLevel:
+		severity = 1
+	default:
+		severity = 6
+	}
+	dst = append(dst, ",\"level\":"...)
+	dst = append(dst, '0'+severity)
+
+	return append(dst, '}')
+}
+
+var _ Writer = (*GelfWriter)(nil)
@@ -0,0 +1,139 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGelfWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer conn.Close()
+
+	w := &GelfWriter{
+		Address: conn.LocalAddr().String(),
+		Host:    "myhost",
+	}
+	defer w.Close()
+	logger := Logger{Writer: w}
+
+	logger.Warn().Str("foo", "bar").Int("id", 42).Bool("ok", true).Str("a b", "c").Msg("hello gelf")
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read error: %+v", err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &msg); err != nil {
+		t.Fatalf("gelf writer json error: %+v: %s", err, buf[:n])
+	}
+	if msg["version"] != "1.1" || msg["host"] != "myhost" || msg["short_message"] != "hello gelf" || msg["level"] != float64(4) {
+		t.Errorf("gelf writer message mismatch: %s", buf[:n])
+	}
+	if ts, _ := msg["timestamp"].(float64); time.Since(time.Unix(int64(ts), 0)) > time.Minute {
+		t.Errorf("gelf writer timestamp mismatch: %s", buf[:n])
+	}
+	if msg["_foo"] != "bar" || msg["__id"] != float64(42) || msg["_ok"] != "true" || msg["_a_b"] != "c" {
+		t.Errorf("gelf writer additional fields mismatch: %s", buf[:n])
+	}
+	for _, key := range []string{"message", "time", "_level", "_message", "_time", "_id"} {
+		if _, ok := msg[key]; ok {
+			t.Errorf("gelf writer should not contain %s: %s", key, buf[:n])
+		}
+	}
+
+	// chunked and compressed
+	w.Gzip = true
+	w.ChunkSize = 1024
+	var sb strings.Builder
+	for i := 0; i < 10000; i++ {
+		sb.WriteByte(byte('a' + Fastrandn(26)))
+	}
+	message := sb.String()
+	logger.Info().Msg(message)
+
+	chunks := make(map[byte][]byte)
+	var count byte
+	for count == 0 || len(chunks) < int(count) {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read error: %+v", err)
+		}
+		if n > 1024 || buf[0] != 0x1e || buf[1] != 0x0f {
+			t.Fatalf("gelf writer chunk mismatch: %d %x", n, buf[:12])
+		}
+		count = buf[11]
+		chunks[buf[10]] = append([]byte(nil), buf[12:n]...)
+	}
+	var data []byte
+	for i := byte(0); i < count; i++ {
+		data = append(data, chunks[i]...)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gelf writer gzip error: %+v", err)
+	}
+	data, _ = io.ReadAll(zr)
+	msg = nil
+	if err := json.Unmarshal(data, &msg); err != nil || msg["short_message"] != message {
+		t.Errorf("gelf writer chunked message mismatch: %+v %s", err, data)
+	}
+}
+
+func TestGelfWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			s, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- s
+		}
+	}()
+
+	w := &GelfWriter{
+		Network: "tcp",
+		Address: ln.Addr().String(),
+	}
+	defer w.Close()
+	logger := Logger{Writer: w}
+
+	logger.Error().Msg("first")
+	logger.Info().Str("stack", "goroutine 1").Msg("second")
+
+	for _, want := range []string{`"short_message":"first","level":3}`, `"full_message":"goroutine 1","short_message":"second","level":6}`} {
+		select {
+		case s := <-messages:
+			if !strings.HasSuffix(s, want+"\x00") || !strings.Contains(s, `"host":"`+hostname+`"`) {
+				t.Errorf("gelf writer tcp message mismatch: %q", s)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("gelf writer tcp timeout")
+		}
+	}
+}
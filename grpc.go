@@ -0,0 +1,209 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+)
+
+// GRPCCall describes a completed rpc, which is logged by GRPCInterceptor.Log.
+type GRPCCall struct {
+	// Method specifies the full method of rpc, e.g. "/helloworld.Greeter/SayHello".
+	Method string
+
+	// Code specifies the name of status code, e.g. status.Code(err).String().
+	Code string
+
+	// Duration specifies the duration of rpc.
+	Duration time.Duration
+
+	// Peer specifies the address of peer, e.g. p.Addr.String() of peer.FromContext.
+	Peer string
+
+	// Metadata specifies the metadata of rpc, a metadata.MD is assignable.
+	Metadata map[string][]string
+
+	// Request and Response specify the payloads of unary rpc, which are logged as JSON.
+	Request, Response interface{}
+
+	// Err specifies the error of rpc.
+	Err error
+
+	// Client determines if the rpc is of a client.
+	Client bool
+
+	// Stream determines if the rpc is a stream.
+	Stream bool
+}
+
+// GRPCInterceptor logs the rpcs of gRPC clients and servers. To keep zero dependencies, it
+// does not implement the interceptors of google.golang.org/grpc, which are a few lines by
+// Context and Log, e.g. the unary server interceptor
+//
+//	func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+//		start := time.Now()
+//		resp, err := handler(i.Context(ctx), req)
+//		md, _ := metadata.FromIncomingContext(ctx)
+//		i.Log(ctx, &log.GRPCCall{Method: info.FullMethod, Code: status.Code(err).String(), Duration: time.Since(start),
+//			Metadata: md, Request: req, Response: resp, Err: err})
+//		return resp, err
+//	}
+type GRPCInterceptor struct {
+	// Logger specifies the logger of rpcs, uses DefaultLogger if nil.
+	Logger *Logger
+
+	// Levels specifies the levels of status codes, the absent codes use DefaultGRPCLevels.
+	Levels map[string]Level
+
+	// RedactMetadata specifies the key patterns of metadata to redact, which are matched
+	// by path.Match case-insensitively, uses DefaultRedactKeys if nil.
+	RedactMetadata []string
+
+	// PayloadLevel specifies the level of logger to log the payloads, the payloads are not
+	// logged if zero or the logger is not enabled at PayloadLevel.
+	PayloadLevel Level
+
+	// MaxPayloadSize specifies the max bytes of payloads, uses 1024 if zero.
+	MaxPayloadSize int
+}
+
+// DefaultGRPCLevels is the default levels of status codes of GRPCInterceptor.
+var DefaultGRPCLevels = map[string]Level{
+	"OK":                 InfoLevel,
+	"Canceled":           InfoLevel,
+	"Unknown":            ErrorLevel,
+	"InvalidArgument":    InfoLevel,
+	"DeadlineExceeded":   WarnLevel,
+	"NotFound":           InfoLevel,
+	"AlreadyExists":      InfoLevel,
+	"PermissionDenied":   WarnLevel,
+	"ResourceExhausted":  WarnLevel,
+	"FailedPrecondition": WarnLevel,
+	"Aborted":            WarnLevel,
+	"OutOfRange":         WarnLevel,
+	"Unimplemented":      ErrorLevel,
+	"Internal":           ErrorLevel,
+	"Unavailable":        WarnLevel,
+	"DataLoss":           ErrorLevel,
+	"Unauthenticated":    InfoLevel,
+}
+
+func (i *GRPCInterceptor) logger(ctx context.Context) *Logger {
+	if i.Logger != nil {
+		return i.Logger
+	}
+	return Ctx(ctx)
+}
+
+// Context returns a copy of ctx with the logger associated, so that the handlers can log
+// by Ctx.
+func (i *GRPCInterceptor) Context(ctx context.Context) context.Context {
+	return i.logger(ctx).WithContext(ctx)
+}
+
+// Log logs the completed rpc.
+func (i *GRPCInterceptor) Log(ctx context.Context, call *GRPCCall) {
+	logger := i.logger(ctx)
+
+	code := call.Code
+	if code == "" {
+		code = "OK"
+		if call.Err != nil {
+			code = "Unknown"
+		}
+	}
+	level, ok := i.Levels[code]
+	if !ok {
+		if level, ok = DefaultGRPCLevels[code]; !ok {
+			level = ErrorLevel
+		}
+	}
+
+	e := logger.WithLevel(level)
+	if e == nil {
+		return
+	}
+
+	kind := "server"
+	if call.Client {
+		kind = "client"
+	}
+	if call.Stream {
+		kind += "_stream"
+	} else {
+		kind += "_unary"
+	}
+	service, method := call.Method, ""
+	if j := strings.LastIndexByte(call.Method, '/'); j >= 0 {
+		service, method = strings.TrimPrefix(call.Method[:j], "/"), call.Method[j+1:]
+	}
+
+	e = e.Str("grpc.kind", kind).
+		Str("grpc.service", service).
+		Str("grpc.method", method).
+		Str("grpc.code", code).
+		Dur("duration", call.Duration)
+	if call.Peer != "" {
+		e = e.Str("peer", call.Peer)
+	}
+	if len(call.Metadata) != 0 {
+		e = e.Dict("metadata", i.metadata(call.Metadata))
+	}
+	if i.PayloadLevel != 0 && !logger.disabled(i.PayloadLevel) {
+		if call.Request != nil {
+			e = i.payload(e, "request", call.Request)
+		}
+		if call.Response != nil {
+			e = i.payload(e, "response", call.Response)
+		}
+	}
+	if call.Err != nil {
+		e = e.Err(call.Err)
+	}
+	e.Msg("")
+}
+
+// metadata returns the redacted metadata.
+func (i *GRPCInterceptor) metadata(md map[string][]string) Context {
+	patterns := i.RedactMetadata
+	if patterns == nil {
+		patterns = DefaultRedactKeys
+	}
+	e := NewContext(nil)
+	for key, values := range md {
+		lower := strings.ToLower(key)
+		redacted := false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, lower); ok {
+				redacted = true
+				break
+			}
+		}
+		if redacted {
+			e = e.Str(key, "***")
+		} else if len(values) == 1 {
+			e = e.Str(key, values[0])
+		} else {
+			e = e.Strs(key, values)
+		}
+	}
+	return e.Value()
+}
+
+// payload appends the payload as JSON, or the truncated string of JSON if it is too large.
+func (i *GRPCInterceptor) payload(e *Entry, key string, v interface{}) *Entry {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return e.Str(key, "marshaling error: "+err.Error())
+	}
+	max := i.MaxPayloadSize
+	if max <= 0 {
+		max = 1024
+	}
+	if len(data) > max {
+		return e.Str(key, string(data[:max])+"...")
+	}
+	return e.RawJSON(key, data)
+}
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGRPCInterceptor(t *testing.T) {
+	var b bytes.Buffer
+	logger := &Logger{Level: DebugLevel, Writer: IOWriter{&b}}
+	i := &GRPCInterceptor{Logger: logger, PayloadLevel: DebugLevel, MaxPayloadSize: 16}
+
+	ctx := i.Context(context.Background())
+	if Ctx(ctx) != logger {
+		t.Errorf("grpc interceptor must associate the logger with context")
+	}
+
+	i.Log(ctx, &GRPCCall{
+		Method:   "/helloworld.Greeter/SayHello",
+		Duration: 1500 * time.Microsecond,
+		Peer:     "127.0.0.1:5000",
+		Metadata: map[string][]string{"authorization": {"Bearer xyz"}, "x-request-id": {"abc"}},
+		Request:  map[string]string{"name": "alice"},
+		Response: map[string]string{"message": "hello alice, nice to meet you"},
+	})
+	s := b.String()
+	for _, v := range []string{`"level":"info"`, `"grpc.kind":"server_unary"`, `"grpc.service":"helloworld.Greeter"`, `"grpc.method":"SayHello"`, `"grpc.code":"OK"`, `"duration":1.5`, `"peer":"127.0.0.1:5000"`, `"authorization":"***"`, `"x-request-id":"abc"`, `"request":{"name":"alice"}`, `"response":"{\"message\":\"hell..."`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("grpc interceptor log must contain %s: %s", v, s)
+		}
+	}
+
+	b.Reset()
+	logger.Level = InfoLevel
+	i.Log(ctx, &GRPCCall{Method: "/helloworld.Greeter/SayHello", Code: "Internal", Client: true, Stream: true, Request: "x", Err: errors.New("boom")})
+	s = b.String()
+	for _, v := range []string{`"level":"error"`, `"grpc.kind":"client_stream"`, `"grpc.code":"Internal"`, `"error":"boom"`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("grpc interceptor log must contain %s: %s", v, s)
+		}
+	}
+	if strings.Contains(s, `"request"`) {
+		t.Errorf("grpc interceptor must not log payloads below PayloadLevel: %s", s)
+	}
+}
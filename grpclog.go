@@ -0,0 +1,125 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GRPCLogger implements grpclog.LoggerV2 and grpclog.DepthLoggerV2 of google.golang.org/grpc
+// without importing it, so that the logging of gRPC internals flows into the logger, e.g.
+//
+//	grpclog.SetLoggerV2(&log.GRPCLogger{Logger: &log.DefaultLogger})
+//
+// The verbosity of V is mapped to the levels, 0 is InfoLevel, 1 is DebugLevel and 2 or more
+// is TraceLevel.
+type GRPCLogger struct {
+	// Logger specifies the logger of gRPC, uses DefaultLogger if nil. The caller of entries
+	// is reported from the callers of gRPC when its Caller is not zero.
+	Logger *Logger
+}
+
+func (g *GRPCLogger) log(depth int, level Level, msg string) {
+	logger := g.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+	if logger.silent(level) {
+		return
+	}
+	e := logger.header(level)
+	if e == nil {
+		return
+	}
+	// skips the frames of log and the method of GRPCLogger
+	if caller := logger.Caller; caller > 0 {
+		e = e.Caller(caller + 2 + depth)
+	} else if caller < 0 {
+		e = e.Caller(caller - 2 - depth)
+	}
+	e.Msg(msg)
+}
+
+// Info logs to INFO log. Arguments are handled in the manner of fmt.Print.
+func (g *GRPCLogger) Info(args ...interface{}) { g.log(0, InfoLevel, fmt.Sprint(args...)) }
+
+// Infoln logs to INFO log. Arguments are handled in the manner of fmt.Println.
+func (g *GRPCLogger) Infoln(args ...interface{}) { g.log(0, InfoLevel, sprintln(args...)) }
+
+// Infof logs to INFO log. Arguments are handled in the manner of fmt.Printf.
+func (g *GRPCLogger) Infof(format string, args ...interface{}) {
+	g.log(0, InfoLevel, fmt.Sprintf(format, args...))
+}
+
+// InfoDepth logs to INFO log at the specified depth.
+func (g *GRPCLogger) InfoDepth(depth int, args ...interface{}) {
+	g.log(depth, InfoLevel, fmt.Sprint(args...))
+}
+
+// Warning logs to WARNING log. Arguments are handled in the manner of fmt.Print.
+func (g *GRPCLogger) Warning(args ...interface{}) { g.log(0, WarnLevel, fmt.Sprint(args...)) }
+
+// Warningln logs to WARNING log. Arguments are handled in the manner of fmt.Println.
+func (g *GRPCLogger) Warningln(args ...interface{}) { g.log(0, WarnLevel, sprintln(args...)) }
+
+// Warningf logs to WARNING log. Arguments are handled in the manner of fmt.Printf.
+func (g *GRPCLogger) Warningf(format string, args ...interface{}) {
+	g.log(0, WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// WarningDepth logs to WARNING log at the specified depth.
+func (g *GRPCLogger) WarningDepth(depth int, args ...interface{}) {
+	g.log(depth, WarnLevel, fmt.Sprint(args...))
+}
+
+// Error logs to ERROR log. Arguments are handled in the manner of fmt.Print.
+func (g *GRPCLogger) Error(args ...interface{}) { g.log(0, ErrorLevel, fmt.Sprint(args...)) }
+
+// Errorln logs to ERROR log. Arguments are handled in the manner of fmt.Println.
+func (g *GRPCLogger) Errorln(args ...interface{}) { g.log(0, ErrorLevel, sprintln(args...)) }
+
+// Errorf logs to ERROR log. Arguments are handled in the manner of fmt.Printf.
+func (g *GRPCLogger) Errorf(format string, args ...interface{}) {
+	g.log(0, ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// ErrorDepth logs to ERROR log at the specified depth.
+func (g *GRPCLogger) ErrorDepth(depth int, args ...interface{}) {
+	g.log(depth, ErrorLevel, fmt.Sprint(args...))
+}
+
+// Fatal logs to FATAL log and exits. Arguments are handled in the manner of fmt.Print.
+func (g *GRPCLogger) Fatal(args ...interface{}) { g.log(0, FatalLevel, fmt.Sprint(args...)) }
+
+// Fatalln logs to FATAL log and exits. Arguments are handled in the manner of fmt.Println.
+func (g *GRPCLogger) Fatalln(args ...interface{}) { g.log(0, FatalLevel, sprintln(args...)) }
+
+// Fatalf logs to FATAL log and exits. Arguments are handled in the manner of fmt.Printf.
+func (g *GRPCLogger) Fatalf(format string, args ...interface{}) {
+	g.log(0, FatalLevel, fmt.Sprintf(format, args...))
+}
+
+// FatalDepth logs to FATAL log at the specified depth and exits.
+func (g *GRPCLogger) FatalDepth(depth int, args ...interface{}) {
+	g.log(depth, FatalLevel, fmt.Sprint(args...))
+}
+
+// V reports whether verbosity level l is enabled, 0 is InfoLevel, 1 is DebugLevel and 2 or
+// more is TraceLevel.
+func (g *GRPCLogger) V(l int) bool {
+	logger := g.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+	level := InfoLevel
+	switch {
+	case l >= 2:
+		level = TraceLevel
+	case l == 1:
+		level = DebugLevel
+	}
+	return !logger.disabled(level)
+}
+
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// grpcDepthLoggerV2 is the method set of grpclog.DepthLoggerV2.
+type grpcDepthLoggerV2 interface {
+	Info(args ...interface{})
+	Infoln(args ...interface{})
+	Infof(format string, args ...interface{})
+	Warning(args ...interface{})
+	Warningln(args ...interface{})
+	Warningf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorln(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+	Fatalln(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	V(l int) bool
+	InfoDepth(depth int, args ...interface{})
+	WarningDepth(depth int, args ...interface{})
+	ErrorDepth(depth int, args ...interface{})
+	FatalDepth(depth int, args ...interface{})
+}
+
+func TestGRPCLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := &Logger{Level: DebugLevel, Caller: 1, Writer: IOWriter{&b}}
+	var g grpcDepthLoggerV2 = &GRPCLogger{Logger: logger}
+
+	g.Info("hello ", "grpc")
+	g.Warningln("hello", "grpc")
+	g.Errorf("hello %s", "grpc")
+	func() { g.InfoDepth(1, "hello depth") }()
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("grpc logger must log 4 entries: %s", b.String())
+	}
+	for i, v := range []string{`"level":"info","caller":"grpclog_test.go:35"`, `"level":"warn"`, `"level":"error"`, `"caller":"grpclog_test.go:38"`} {
+		if !strings.Contains(lines[i], v) || !strings.Contains(lines[i], `"message":"hello`) {
+			t.Errorf("grpc logger entry %d must contain %s: %s", i, v, lines[i])
+		}
+	}
+	if !strings.Contains(lines[1], `"message":"hello grpc"`) {
+		t.Errorf("grpc logger must trim the newline of Warningln: %s", lines[1])
+	}
+
+	if !g.V(0) || !g.V(1) || g.V(2) {
+		t.Errorf("grpc logger verbosity must map to info, debug and trace levels")
+	}
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by HTTPWriter when the circuit breaker is open.
+var ErrCircuitOpen = errors.New("log: http circuit breaker is open")
+
+// HTTPWriter is a Writer that posts entries in batches of NDJSON to an http endpoint,
+// the batches are retried with exponential backoff on 429 and 5xx responses.
+//
+// The circuit breaker opens after BreakerThreshold consecutive failed requests, the
+// batches are sent to Fallback without requests during BreakerTimeout, then a single
+// request is tried to close the circuit breaker.
+type HTTPWriter struct {
+	// URL specifies the url of endpoint.
+	URL string
+
+	// Method specifies the method of requests, uses "POST" if empty.
+	Method string
+
+	// Headers specifies the extra headers of requests.
+	Headers http.Header
+
+	// ContentType specifies the content type of requests, uses "application/x-ndjson" if empty.
+	ContentType string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 100 if zero.
+	BatchSize int
+
+	// BatchBytes specifies the maximum bytes of a request, zero means no limit.
+	BatchBytes int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// BreakerThreshold specifies the consecutive failed requests to open the circuit breaker,
+	// uses 5 if zero, and negative value disables the circuit breaker.
+	BreakerThreshold int
+
+	// BreakerTimeout specifies the open duration of circuit breaker, uses 30 seconds if zero.
+	BreakerTimeout time.Duration
+
+	// Fallback specifies the fallback writer of the entries failed to send.
+	Fallback Writer
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once    sync.Once
+	batcher batcher
+	header  http.Header
+
+	// the states below are owned by the goroutine of batcher.
+	failures  int
+	openUntil time.Time
+}
+
+func (w *HTTPWriter) init() {
+	w.once.Do(func() {
+		w.header = make(http.Header, len(w.Headers)+2)
+		for k, v := range w.Headers {
+			w.header[k] = v
+		}
+		if w.ContentType != "" {
+			w.header.Set("Content-Type", w.ContentType)
+		} else if w.header.Get("Content-Type") == "" {
+			w.header.Set("Content-Type", "application/x-ndjson")
+		}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+
+		w.batcher.size = w.BatchSize
+		w.batcher.bytes = w.BatchBytes
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.batcher.fail = func(b *batch, err error) {
+			if w.Fallback == nil {
+				return
+			}
+			for i := 0; i < b.Len(); i++ {
+				_, _ = w.Fallback.WriteEntry(&Entry{buf: b.Entry(i), Level: b.levels[i]})
+			}
+		}
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *HTTPWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *HTTPWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *HTTPWriter) send(b *batch) (err error) {
+	threshold := w.BreakerThreshold
+	if threshold == 0 {
+		threshold = 5
+	}
+	if threshold > 0 && w.failures >= threshold && timeNow().Before(w.openUntil) {
+		return permanentError{ErrCircuitOpen}
+	}
+
+	body := b.buf
+	delimited := true
+	for i := 0; i < b.Len() && delimited; i++ {
+		entry := b.Entry(i)
+		delimited = len(entry) > 0 && entry[len(entry)-1] == '\n'
+	}
+	if !delimited {
+		// ensures the entries of batch are delimited by newlines.
+		buf := bbpool.Get().(*bb)
+		defer bbpool.Put(buf)
+		buf.B = buf.B[:0]
+		for i := 0; i < b.Len(); i++ {
+			buf.B = append(buf.B, bytes.TrimRight(b.Entry(i), "\n")...)
+			buf.B = append(buf.B, '\n')
+		}
+		body = buf.B
+	}
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], body)
+		body = z.B
+	}
+
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	err = httpSend(w.Client, method, w.URL, w.header, body)
+
+	// the permanent errors e.g. 400 are not failures of endpoint.
+	var perm permanentError
+	switch {
+	case err == nil:
+		w.failures = 0
+	case threshold > 0 && !errors.As(err, &perm):
+		if w.failures++; w.failures >= threshold {
+			timeout := w.BreakerTimeout
+			if timeout <= 0 {
+				timeout = 30 * time.Second
+			}
+			w.openUntil = timeNow().Add(timeout)
+		}
+	}
+	return
+}
+
+var _ Writer = (*HTTPWriter)(nil)
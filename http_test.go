@@ -0,0 +1,112 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPWriter(t *testing.T) {
+	bodies := make(chan string, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/x-ndjson" || req.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("http writer headers mismatch: %v", req.Header)
+		}
+		var r io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			r, _ = gzip.NewReader(req.Body)
+		}
+		body, _ := io.ReadAll(r)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	w := &HTTPWriter{
+		URL:     server.URL,
+		Headers: http.Header{"X-Api-Key": []string{"secret"}},
+		Gzip:    true,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Msg("hello http")
+	_, _ = w.WriteEntry(&Entry{buf: []byte(`{"message":"no newline"}`)})
+	if err := w.Close(); err != nil {
+		t.Fatalf("http writer close error: %+v", err)
+	}
+
+	lines := strings.Split(<-bodies, "\n")
+	if len(lines) != 3 || lines[2] != "" {
+		t.Fatalf("http writer ndjson mismatch: %q", lines)
+	}
+	if !strings.Contains(lines[0], `"message":"hello http"`) || lines[1] != `{"message":"no newline"}` {
+		t.Errorf("http writer entries mismatch: %q", lines)
+	}
+}
+
+func TestHTTPWriterCircuitBreaker(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var fallback bytes.Buffer
+	w := &HTTPWriter{
+		URL:              server.URL,
+		BatchSize:        1,
+		MaxRetries:       -1,
+		BreakerThreshold: 2,
+		BreakerTimeout:   time.Hour,
+		Fallback:         IOWriter{&fallback},
+	}
+	logger := Logger{Writer: w}
+	for i := 0; i < 4; i++ {
+		logger.Info().Int("i", i).Msg("hello http")
+	}
+	err := w.Close()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("http writer want ErrCircuitOpen got %+v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("http writer want 2 requests before circuit open got %d", n)
+	}
+	if n := strings.Count(fallback.String(), "\n"); n != 4 {
+		t.Errorf("http writer want 4 fallback entries got %d: %s", n, fallback.String())
+	}
+}
+
+func TestHTTPWriterCircuitHalfOpen(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	w := &HTTPWriter{
+		URL:              server.URL,
+		BatchSize:        1,
+		MaxRetries:       -1,
+		BreakerThreshold: 1,
+		BreakerTimeout:   time.Millisecond,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("first")
+	time.Sleep(10 * time.Millisecond)
+	logger.Info().Msg("second")
+	_ = w.Close()
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("http writer want 2 requests after circuit half open got %d", n)
+	}
+	if w.failures != 0 {
+		t.Errorf("http writer circuit should be closed, failures=%d", w.failures)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -19,6 +21,9 @@ type JournalWriter struct {
 	// JournalSocket specifies socket name, using `/run/systemd/journal/socket` if empty.
 	JournalSocket string
 
+	// Identifier specifies the SYSLOG_IDENTIFIER field, using the program name if empty.
+	Identifier string
+
 	once sync.Once
 	addr *net.UnixAddr
 	conn *net.UnixConn
@@ -101,19 +106,44 @@ func (w *JournalWriter) WriteEntry(e *Entry) (n int, err error) {
 		priority = "0" // Emergency
 	default:
 		priority = "5" // Notice
+		if c := customLevelOf(e.Level); c != nil {
+			priority = strconv.Itoa(c.Syslog)
+		}
 	}
 	print(b, "PRIORITY", priority)
 
+	// identifier
+	identifier := w.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	print(b, "SYSLOG_IDENTIFIER", identifier)
+
 	// message
 	print(b, "MESSAGE", args.Message)
 
+	// caller
+	if i := strings.LastIndexByte(args.Caller, ':'); i > 0 {
+		print(b, "CODE_FILE", args.Caller[:i])
+		print(b, "CODE_LINE", args.Caller[i+1:])
+	}
+	if args.Goid != "" {
+		print(b, "GOID", args.Goid)
+	}
+	if args.Stack != "" {
+		print(b, "STACK", args.Stack)
+	}
+
 	// fields
 	for _, kv := range args.KeyValues {
-		print(b, strings.ToUpper(kv.Key), kv.Value)
+		name := journalFieldName(kv.Key)
+		switch name {
+		case "", "PRIORITY", "SYSLOG_IDENTIFIER", "MESSAGE", "CODE_FILE", "CODE_LINE", "GOID", "STACK":
+			continue
+		}
+		print(b, name, kv.Value)
 	}
 
-	print(b, "JSON", b2s(e.buf))
-
 	// write
 	n, _, err = w.conn.WriteMsgUnix(b.B, nil, w.addr)
 	if err == nil {
@@ -157,4 +187,30 @@ func (w *JournalWriter) WriteEntry(e *Entry) (n int, err error) {
 	return
 }
 
+// journalFieldName converts key to a valid journald field name, which consists of
+// uppercase letters, digits and underscores, does not start with an underscore or
+// a digit, and is at most 64 characters. It returns empty if no valid characters.
+func journalFieldName(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(b) < 64; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' || c == '_':
+			if len(b) == 0 {
+				continue
+			}
+		default:
+			if len(b) == 0 {
+				continue
+			}
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
 var _ Writer = (*JournalWriter)(nil)
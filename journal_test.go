@@ -6,6 +6,7 @@ package log
 import (
 	"net"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -59,3 +60,60 @@ func TestJournalWriterError(t *testing.T) {
 	_, _ = wlprintf(w, InfoLevel, "a long long long long message.\n")
 	w.Close()
 }
+
+func TestJournalWriterFields(t *testing.T) {
+	const sockname = "/tmp/go-tmp-fields.sock"
+	os.Remove(sockname)
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sockname, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer os.Remove(sockname)
+	defer conn.Close()
+
+	w := &JournalWriter{
+		JournalSocket: sockname,
+		Identifier:    "test",
+	}
+	defer w.Close()
+
+	_, err = wlprintf(w, ErrorLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"error","caller":"test.go:42","goid":5,"user.id":"1","_trusted":"x","9lives":"y","priority":"z","text":"a\nb","message":"hello journal writer"}`+"\n")
+	if err != nil {
+		t.Fatalf("write error: %+v", err)
+	}
+
+	var data [4096]byte
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFromUnix(data[:])
+	if err != nil {
+		t.Fatalf("read error: %+v", err)
+	}
+
+	want := "PRIORITY=3\nSYSLOG_IDENTIFIER=test\nMESSAGE=hello journal writer\nCODE_FILE=test.go\nCODE_LINE=42\nGOID=5\n" +
+		"USER_ID=1\nTRUSTED=x\nLIVES=y\nTEXT\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if got := string(data[:n]); got != want {
+		t.Errorf("journal writer fields: got %q, want %q", got, want)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	cases := []struct {
+		Key  string
+		Name string
+	}{
+		{"foo", "FOO"},
+		{"Foo.Bar-baz", "FOO_BAR_BAZ"},
+		{"_foo", "FOO"},
+		{"123", ""},
+		{"1a2", "A2"},
+		{"-", ""},
+		{"a" + strings.Repeat("b", 100), "A" + strings.Repeat("B", 63)},
+	}
+
+	for _, c := range cases {
+		if name := journalFieldName(c.Key); name != c.Name {
+			t.Errorf("journal field name of %q: got %q, want %q", c.Key, name, c.Name)
+		}
+	}
+}
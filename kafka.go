@@ -0,0 +1,645 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// KafkaAcks specifies the acknowledgments of kafka producer.
+type KafkaAcks int
+
+const (
+	// KafkaAckLeader waits for the leader to write the record.
+	KafkaAckLeader KafkaAcks = iota
+	// KafkaAckAll waits for all in-sync replicas to write the record.
+	KafkaAckAll
+	// KafkaAckNone does not wait for any acknowledgment.
+	KafkaAckNone
+)
+
+// KafkaWriter is a Writer that produces entries in batches to a kafka topic, it speaks
+// the kafka protocol natively (metadata v1, produce v3 with record batch v2).
+// The entries of same key field value are produced to same partition as the default
+// partitioner of java client, and the entries without key are produced to a sticky
+// partition per batch.
+type KafkaWriter struct {
+	// Brokers specifies the bootstrap brokers, e.g. "localhost:9092".
+	Brokers []string
+
+	// Topic specifies the topic of records.
+	Topic string
+
+	// KeyField specifies the entry field used as the key of records, e.g. "request_id".
+	KeyField string
+
+	// Acks specifies the acknowledgments of produce requests.
+	Acks KafkaAcks
+
+	// ClientID specifies the client id of requests, uses "phuslu-log" if empty.
+	ClientID string
+
+	// TLSConfig specifies the tls config of connections, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// SASLUsername and SASLPassword specifies the credential of SASL/PLAIN authentication.
+	SASLUsername string
+	SASLPassword string
+
+	// Timeout specifies the dial and request timeout, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a batch, uses 1000 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Fallback specifies the fallback writer of the entries failed to produce.
+	Fallback Writer
+
+	once    sync.Once
+	batcher batcher
+
+	// the states below are owned by the goroutine of batcher.
+	correlation int32
+	sticky      int
+	brokers     map[int32]string
+	leaders     []int32
+	conns       map[int32]net.Conn
+}
+
+func (w *KafkaWriter) init() {
+	w.once.Do(func() {
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 1000
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.batcher.fail = func(b *batch, err error) {
+			if w.Fallback == nil {
+				return
+			}
+			for i := 0; i < b.Len(); i++ {
+				_, _ = w.Fallback.WriteEntry(&Entry{buf: b.Entry(i), Level: b.levels[i]})
+			}
+		}
+		w.conns = make(map[int32]net.Conn)
+	})
+}
+
+// Close flushes the pending entries and closes the connections.
+func (w *KafkaWriter) Close() (err error) {
+	w.init()
+	err = w.batcher.close()
+	for id, conn := range w.conns {
+		_ = conn.Close()
+		delete(w.conns, id)
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *KafkaWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *KafkaWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (w *KafkaWriter) send(b *batch) error {
+	if w.leaders == nil {
+		if err := w.metadata(); err != nil {
+			return err
+		}
+	}
+
+	// assign the entries to partitions
+	partitions := make([]int32, b.Len())
+	sticky := int32(w.sticky % len(w.leaders))
+	w.sticky++
+	for i := range partitions {
+		partitions[i] = sticky
+		if w.KeyField != "" {
+			if key := jsonFieldString(b.Entry(i), w.KeyField); len(key) != 0 {
+				partitions[i] = (murmur2(key) & 0x7fffffff) % int32(len(w.leaders))
+			}
+		}
+	}
+
+	// produce to the leader of partitions
+	failed := make(map[int32]bool)
+	var lasterr error
+	for _, leader := range w.leaders {
+		if _, ok := failed[leader]; ok {
+			continue
+		}
+		failed[leader] = false
+		if err := w.produce(leader, b, partitions); err != nil {
+			failed[leader] = true
+			lasterr = err
+			if conn := w.conns[leader]; conn != nil {
+				_ = conn.Close()
+				delete(w.conns, leader)
+			}
+		}
+	}
+
+	if lasterr == nil {
+		return nil
+	}
+
+	// keeps the failed entries for retrying.
+	leaders := w.leaders
+	w.leaders = nil
+	b.filter(func(i int, _ []byte) bool {
+		return failed[leaders[partitions[i]]]
+	})
+
+	return lasterr
+}
+
+func (w *KafkaWriter) produce(leader int32, b *batch, partitions []int32) error {
+	acks := int16(1)
+	switch w.Acks {
+	case KafkaAckAll:
+		acks = -1
+	case KafkaAckNone:
+		acks = 0
+	}
+
+	req := bbpool.Get().(*bb)
+	defer func() {
+		if cap(req.B) <= bbcap {
+			bbpool.Put(req)
+		}
+	}()
+
+	// ProduceRequest v3
+	dst := w.appendHeader(req.B[:0], 0, 3)
+	dst = kafkaAppendInt16(dst, -1) // transactional_id
+	dst = kafkaAppendInt16(dst, acks)
+	dst = kafkaAppendInt32(dst, int32(w.timeout()/time.Millisecond))
+	dst = kafkaAppendInt32(dst, 1) // topic_data
+	dst = kafkaAppendString(dst, w.Topic)
+	count, mark := 0, len(dst)
+	dst = kafkaAppendInt32(dst, 0) // partition_data
+	for p := range w.leaders {
+		if w.leaders[p] != leader {
+			continue
+		}
+		n := 0
+		for _, partition := range partitions {
+			if partition == int32(p) {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		count++
+		dst = kafkaAppendInt32(dst, int32(p))
+		dst = kafkaAppendRecordBatch(dst, b, partitions, int32(p), w.KeyField)
+	}
+	if count == 0 {
+		return nil
+	}
+	kafkaPutInt32(dst[mark:], int32(count))
+	req.B = dst
+
+	resp, err := w.roundtrip(leader, req.B, acks != 0)
+	if err != nil || acks == 0 {
+		return err
+	}
+
+	// ProduceResponse v3
+	r := kafkaReader{b: resp}
+	for i, n := 0, r.int32(); i < int(n) && r.ok(); i++ {
+		_ = r.string()
+		for j, m := 0, r.int32(); j < int(m) && r.ok(); j++ {
+			partition := r.int32()
+			code := r.int16()
+			_ = r.int64()
+			_ = r.int64()
+			if code != 0 {
+				return errors.New("log: kafka produce partition " + strconv.Itoa(int(partition)) + " error code " + strconv.Itoa(int(code)))
+			}
+		}
+	}
+	if !r.ok() {
+		return errors.New("log: kafka malformed produce response")
+	}
+
+	return nil
+}
+
+// metadata refreshes the brokers and partition leaders of topic.
+func (w *KafkaWriter) metadata() (err error) {
+	var resp []byte
+	for _, addr := range w.Brokers {
+		var conn net.Conn
+		conn, err = w.dial(addr)
+		if err != nil {
+			continue
+		}
+		// MetadataRequest v1
+		req := w.appendHeader(nil, 3, 1)
+		req = kafkaAppendInt32(req, 1)
+		req = kafkaAppendString(req, w.Topic)
+		resp, err = kafkaRoundtrip(conn, req, w.timeout())
+		_ = conn.Close()
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("log: kafka no brokers")
+	}
+
+	// MetadataResponse v1
+	r := kafkaReader{b: resp}
+	brokers := make(map[int32]string)
+	for i, n := 0, r.int32(); i < int(n) && r.ok(); i++ {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		_ = r.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	_ = r.int32() // controller_id
+	var leaders []int32
+	for i, n := 0, r.int32(); i < int(n) && r.ok(); i++ {
+		code := r.int16()
+		name := r.string()
+		_ = r.int8() // is_internal
+		for j, m := 0, r.int32(); j < int(m) && r.ok(); j++ {
+			_ = r.int16()
+			index := r.int32()
+			leader := r.int32()
+			r.skipArray(4) // replica_nodes
+			r.skipArray(4) // isr_nodes
+			if name == w.Topic && index >= 0 && index < 1<<16 {
+				for int(index) >= len(leaders) {
+					leaders = append(leaders, -1)
+				}
+				leaders[index] = leader
+			}
+		}
+		if name == w.Topic && code != 0 {
+			return errors.New("log: kafka topic " + strconv.Quote(name) + " error code " + strconv.Itoa(int(code)))
+		}
+	}
+	if !r.ok() {
+		return errors.New("log: kafka malformed metadata response")
+	}
+	if len(leaders) == 0 {
+		return errors.New("log: kafka topic " + strconv.Quote(w.Topic) + " has no partitions")
+	}
+	for _, leader := range leaders {
+		if _, ok := brokers[leader]; !ok {
+			return errors.New("log: kafka topic " + strconv.Quote(w.Topic) + " has partitions without leader")
+		}
+	}
+
+	w.brokers, w.leaders = brokers, leaders
+	return nil
+}
+
+func (w *KafkaWriter) roundtrip(leader int32, req []byte, response bool) (resp []byte, err error) {
+	conn := w.conns[leader]
+	if conn == nil {
+		conn, err = w.dial(w.brokers[leader])
+		if err != nil {
+			return
+		}
+		w.conns[leader] = conn
+	}
+	if !response {
+		kafkaPutInt32(req, int32(len(req)-4))
+		_ = conn.SetWriteDeadline(timeNow().Add(w.timeout()))
+		_, err = conn.Write(req)
+		return
+	}
+	return kafkaRoundtrip(conn, req, w.timeout())
+}
+
+func (w *KafkaWriter) dial(addr string) (conn net.Conn, err error) {
+	conn, err = net.DialTimeout("tcp", addr, w.timeout())
+	if err != nil {
+		return
+	}
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" && !config.InsecureSkipVerify {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		_ = tlsConn.SetDeadline(timeNow().Add(w.timeout()))
+		if err = tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if w.SASLUsername != "" {
+		if err = w.authenticate(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return
+}
+
+// authenticate performs the SASL/PLAIN authentication on conn.
+func (w *KafkaWriter) authenticate(conn net.Conn) error {
+	// SaslHandshakeRequest v1
+	req := w.appendHeader(nil, 17, 1)
+	req = kafkaAppendString(req, "PLAIN")
+	resp, err := kafkaRoundtrip(conn, req, w.timeout())
+	if err != nil {
+		return err
+	}
+	if r := (kafkaReader{b: resp}); r.int16() != 0 {
+		return errors.New("log: kafka sasl mechanism PLAIN is not enabled")
+	}
+
+	// SaslAuthenticateRequest v0
+	req = w.appendHeader(req[:0], 36, 0)
+	req = kafkaAppendInt32(req, int32(2+len(w.SASLUsername)+len(w.SASLPassword)))
+	req = append(req, 0)
+	req = append(req, w.SASLUsername...)
+	req = append(req, 0)
+	req = append(req, w.SASLPassword...)
+	resp, err = kafkaRoundtrip(conn, req, w.timeout())
+	if err != nil {
+		return err
+	}
+	r := kafkaReader{b: resp}
+	if code := r.int16(); code != 0 {
+		return errors.New("log: kafka sasl authentication failed: " + r.string())
+	}
+	return nil
+}
+
+// appendHeader appends the size placeholder and request header v1, the size is filled by kafkaRoundtrip.
+func (w *KafkaWriter) appendHeader(dst []byte, key, version int16) []byte {
+	clientID := w.ClientID
+	if clientID == "" {
+		clientID = "phuslu-log"
+	}
+	w.correlation++
+	dst = kafkaAppendInt32(dst, 0)
+	dst = kafkaAppendInt16(dst, key)
+	dst = kafkaAppendInt16(dst, version)
+	dst = kafkaAppendInt32(dst, w.correlation)
+	return kafkaAppendString(dst, clientID)
+}
+
+// kafkaRoundtrip writes the request and reads the response body after correlation id.
+func kafkaRoundtrip(conn net.Conn, req []byte, timeout time.Duration) ([]byte, error) {
+	kafkaPutInt32(req, int32(len(req)-4))
+	_ = conn.SetDeadline(timeNow().Add(timeout))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	n := int(uint32(size[0])<<24 | uint32(size[1])<<16 | uint32(size[2])<<8 | uint32(size[3]))
+	if n < 4 || n > 64*1024*1024 {
+		return nil, errors.New("log: kafka invalid response size " + strconv.Itoa(n))
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(resp[:4], req[8:12]) {
+		return nil, errors.New("log: kafka correlation id mismatch")
+	}
+	return resp[4:], nil
+}
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaAppendRecordBatch appends the records of partition as RecordBatch v2.
+func kafkaAppendRecordBatch(dst []byte, b *batch, partitions []int32, partition int32, keyField string) []byte {
+	mark := len(dst)
+	dst = kafkaAppendInt32(dst, 0) // size of records
+
+	start := len(dst)
+	dst = kafkaAppendInt64(dst, 0) // base_offset
+	dst = kafkaAppendInt32(dst, 0) // batch_length
+	dst = kafkaAppendInt32(dst, -1)
+	dst = append(dst, 2) // magic
+	crc := len(dst)
+	dst = kafkaAppendInt32(dst, 0)
+	dst = kafkaAppendInt16(dst, 0) // attributes
+	lastOffsetDelta := len(dst)
+	dst = kafkaAppendInt32(dst, 0)
+	var base int64
+	for i, p := range partitions {
+		if p == partition {
+			base = b.times[i] / int64(time.Millisecond)
+			break
+		}
+	}
+	dst = kafkaAppendInt64(dst, base) // base_timestamp
+	maxTimestamp := len(dst)
+	dst = kafkaAppendInt64(dst, base)
+	dst = kafkaAppendInt64(dst, -1) // producer_id
+	dst = kafkaAppendInt16(dst, -1) // producer_epoch
+	dst = kafkaAppendInt32(dst, -1) // base_sequence
+	count := len(dst)
+	dst = kafkaAppendInt32(dst, 0)
+
+	var n int32
+	var max int64 = base
+	var record []byte
+	for i, p := range partitions {
+		if p != partition {
+			continue
+		}
+		entry := bytes.TrimRight(b.Entry(i), "\n")
+		ts := b.times[i] / int64(time.Millisecond)
+		if ts > max {
+			max = ts
+		}
+		var key []byte
+		if keyField != "" {
+			if key = jsonFieldString(entry, keyField); len(key) == 0 {
+				key = nil
+			}
+		}
+		record = record[:0]
+		record = append(record, 0) // attributes
+		record = kafkaAppendVarint(record, ts-base)
+		record = kafkaAppendVarint(record, int64(n))
+		if key == nil {
+			record = kafkaAppendVarint(record, -1)
+		} else {
+			record = kafkaAppendVarint(record, int64(len(key)))
+			record = append(record, key...)
+		}
+		record = kafkaAppendVarint(record, int64(len(entry)))
+		record = append(record, entry...)
+		record = kafkaAppendVarint(record, 0) // headers
+		dst = kafkaAppendVarint(dst, int64(len(record)))
+		dst = append(dst, record...)
+		n++
+	}
+
+	kafkaPutInt32(dst[lastOffsetDelta:], n-1)
+	kafkaPutInt64(dst[maxTimestamp:], max)
+	kafkaPutInt32(dst[count:], n)
+	kafkaPutInt32(dst[start+8:], int32(len(dst)-start-12))
+	kafkaPutInt32(dst[crc:], int32(crc32.Checksum(dst[crc+4:], crc32c)))
+	kafkaPutInt32(dst[mark:], int32(len(dst)-start))
+
+	return dst
+}
+
+// murmur2 is the hash function of default partitioner of kafka java client.
+func murmur2(data []byte) int32 {
+	const m = 0x5bd1e995
+	length := len(data)
+	h := uint32(0x9747b28c) ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+func kafkaAppendInt16(dst []byte, v int16) []byte {
+	return append(dst, byte(v>>8), byte(v))
+}
+
+func kafkaAppendInt32(dst []byte, v int32) []byte {
+	return append(dst, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func kafkaAppendInt64(dst []byte, v int64) []byte {
+	return append(dst, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func kafkaAppendString(dst []byte, s string) []byte {
+	dst = kafkaAppendInt16(dst, int16(len(s)))
+	return append(dst, s...)
+}
+
+func kafkaAppendVarint(dst []byte, v int64) []byte {
+	u := uint64(v<<1) ^ uint64(v>>63)
+	for u >= 0x80 {
+		dst = append(dst, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(dst, byte(u))
+}
+
+func kafkaPutInt32(dst []byte, v int32) {
+	_ = dst[3]
+	dst[0], dst[1], dst[2], dst[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
+
+func kafkaPutInt64(dst []byte, v int64) {
+	kafkaPutInt32(dst, int32(v>>32))
+	kafkaPutInt32(dst[4:], int32(v))
+}
+
+// kafkaReader reads the fields of kafka response.
+type kafkaReader struct {
+	b   []byte
+	bad bool
+}
+
+func (r *kafkaReader) ok() bool {
+	return !r.bad
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.bad || len(r.b) < n {
+		r.bad = true
+		return make([]byte, n)
+	}
+	p := r.b[:n]
+	r.b = r.b[n:]
+	return p
+}
+
+func (r *kafkaReader) int8() int8 {
+	return int8(r.next(1)[0])
+}
+
+func (r *kafkaReader) int16() int16 {
+	p := r.next(2)
+	return int16(uint16(p[0])<<8 | uint16(p[1]))
+}
+
+func (r *kafkaReader) int32() int32 {
+	p := r.next(4)
+	return int32(uint32(p[0])<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3]))
+}
+
+func (r *kafkaReader) int64() int64 {
+	return int64(r.int32())<<32 | int64(uint32(r.int32()))
+}
+
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) skipArray(size int) {
+	if n := r.int32(); n > 0 {
+		r.next(int(n) * size)
+	}
+}
+
+var _ Writer = (*KafkaWriter)(nil)
@@ -0,0 +1,278 @@
+package log
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type kafkaRecord struct {
+	Partition int32
+	Key       string
+	Value     string
+}
+
+type kafkaBroker struct {
+	t        *testing.T
+	ln       net.Listener
+	mu       sync.Mutex
+	records  []kafkaRecord
+	auth     string
+	acks     int16
+	failOnce bool
+}
+
+func (b *kafkaBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *kafkaBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, int(size[0])<<24|int(size[1])<<16|int(size[2])<<8|int(size[3]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		r := kafkaReader{b: req}
+		key, _ := r.int16(), r.int16()
+		correlation := r.int32()
+		_ = r.string()
+
+		resp := kafkaAppendInt32(nil, 0)
+		resp = kafkaAppendInt32(resp, correlation)
+		switch key {
+		case 17: // SaslHandshake
+			if mechanism := r.string(); mechanism != "PLAIN" {
+				b.t.Errorf("kafka unexpected sasl mechanism: %s", mechanism)
+			}
+			resp = kafkaAppendInt16(resp, 0)
+			resp = kafkaAppendInt32(resp, 1)
+			resp = kafkaAppendString(resp, "PLAIN")
+		case 36: // SaslAuthenticate
+			b.mu.Lock()
+			b.auth = string(r.next(int(r.int32())))
+			b.mu.Unlock()
+			resp = kafkaAppendInt16(resp, 0)
+			resp = kafkaAppendInt16(resp, -1)
+			resp = kafkaAppendInt32(resp, 0)
+		case 3: // Metadata
+			host, port, _ := net.SplitHostPort(b.ln.Addr().String())
+			n, _ := strconv.Atoi(port)
+			resp = kafkaAppendInt32(resp, 1)
+			resp = kafkaAppendInt32(resp, 0)
+			resp = kafkaAppendString(resp, host)
+			resp = kafkaAppendInt32(resp, int32(n))
+			resp = kafkaAppendInt16(resp, -1)
+			resp = kafkaAppendInt32(resp, 0)
+			resp = kafkaAppendInt32(resp, 1)
+			resp = kafkaAppendInt16(resp, 0)
+			resp = kafkaAppendString(resp, "logs")
+			resp = append(resp, 0)
+			resp = kafkaAppendInt32(resp, 2)
+			for p := int32(0); p < 2; p++ {
+				resp = kafkaAppendInt16(resp, 0)
+				resp = kafkaAppendInt32(resp, p)
+				resp = kafkaAppendInt32(resp, 0)
+				resp = kafkaAppendInt32(resp, 1)
+				resp = kafkaAppendInt32(resp, 0)
+				resp = kafkaAppendInt32(resp, 1)
+				resp = kafkaAppendInt32(resp, 0)
+			}
+		case 0: // Produce
+			_ = r.int16()
+			acks := r.int16()
+			_ = r.int32()
+			b.mu.Lock()
+			b.acks = acks
+			code := int16(0)
+			if b.failOnce {
+				b.failOnce, code = false, 6 // NOT_LEADER_FOR_PARTITION
+			}
+			var partitions []int32
+			for i, n := 0, r.int32(); i < int(n); i++ {
+				if topic := r.string(); topic != "logs" {
+					b.t.Errorf("kafka unexpected topic: %s", topic)
+				}
+				for j, m := 0, r.int32(); j < int(m); j++ {
+					partition := r.int32()
+					partitions = append(partitions, partition)
+					records := b.decode(partition, r.next(int(r.int32())))
+					if code == 0 {
+						b.records = append(b.records, records...)
+					}
+				}
+			}
+			b.mu.Unlock()
+			if acks == 0 {
+				continue
+			}
+			resp = kafkaAppendInt32(resp, 1)
+			resp = kafkaAppendString(resp, "logs")
+			resp = kafkaAppendInt32(resp, int32(len(partitions)))
+			for _, p := range partitions {
+				resp = kafkaAppendInt32(resp, p)
+				resp = kafkaAppendInt16(resp, code)
+				resp = kafkaAppendInt64(resp, 0)
+				resp = kafkaAppendInt64(resp, -1)
+			}
+			resp = kafkaAppendInt32(resp, 0)
+		default:
+			b.t.Errorf("kafka unexpected api key: %d", key)
+			return
+		}
+		kafkaPutInt32(resp, int32(len(resp)-4))
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (b *kafkaBroker) decode(partition int32, data []byte) (records []kafkaRecord) {
+	varint := func(r *kafkaReader) int64 {
+		var u uint64
+		for shift := 0; r.ok(); shift += 7 {
+			c := r.next(1)[0]
+			u |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				break
+			}
+		}
+		return int64(u>>1) ^ -int64(u&1)
+	}
+
+	r := kafkaReader{b: data}
+	_ = r.int64()
+	if n := r.int32(); int(n) != len(data)-12 {
+		b.t.Errorf("kafka batch length mismatch: %d != %d", n, len(data)-12)
+	}
+	_ = r.int32()
+	if magic := r.int8(); magic != 2 {
+		b.t.Errorf("kafka unexpected magic: %d", magic)
+	}
+	if crc := uint32(r.int32()); crc != crc32.Checksum(r.b, crc32.MakeTable(crc32.Castagnoli)) {
+		b.t.Errorf("kafka crc mismatch")
+	}
+	_, _, _, _, _, _, _ = r.int16(), r.int32(), r.int64(), r.int64(), r.int64(), r.int16(), r.int32()
+	for i, n := 0, r.int32(); i < int(n); i++ {
+		size := varint(&r)
+		rr := kafkaReader{b: r.next(int(size))}
+		_ = rr.int8()
+		_ = varint(&rr)
+		if delta := varint(&rr); delta != int64(i) {
+			b.t.Errorf("kafka offset delta mismatch: %d != %d", delta, i)
+		}
+		var record = kafkaRecord{Partition: partition}
+		if n := varint(&rr); n >= 0 {
+			record.Key = string(rr.next(int(n)))
+		}
+		record.Value = string(rr.next(int(varint(&rr))))
+		records = append(records, record)
+	}
+	if !r.ok() || len(r.b) != 0 {
+		b.t.Errorf("kafka malformed record batch")
+	}
+	return
+}
+
+func newKafkaBroker(t *testing.T) *kafkaBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	b := &kafkaBroker{t: t, ln: ln}
+	go b.serve()
+	return b
+}
+
+func TestKafkaWriter(t *testing.T) {
+	broker := newKafkaBroker(t)
+	defer broker.ln.Close()
+	broker.failOnce = true
+
+	w := &KafkaWriter{
+		Brokers:      []string{broker.ln.Addr().String()},
+		Topic:        "logs",
+		KeyField:     "request_id",
+		Acks:         KafkaAckAll,
+		SASLUsername: "user",
+		SASLPassword: "pass",
+		BatchSize:    4,
+	}
+	w.batcher.backoff = 1
+	logger := Logger{Writer: w}
+
+	for i := 0; i < 4; i++ {
+		logger.Info().Str("request_id", "req-"+strconv.Itoa(i%2)).Int("i", i).Msg("hello kafka")
+	}
+	logger.Info().Msg("without key")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("kafka writer close error: %+v", err)
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if broker.auth != "\x00user\x00pass" {
+		t.Errorf("kafka writer sasl mismatch: %q", broker.auth)
+	}
+	if broker.acks != -1 {
+		t.Errorf("kafka writer acks mismatch: %d", broker.acks)
+	}
+	if len(broker.records) != 5 {
+		t.Fatalf("kafka writer should produce 5 records, got %d: %+v", len(broker.records), broker.records)
+	}
+	partitions := make(map[string]int32)
+	for _, record := range broker.records {
+		if record.Key == "" {
+			if !bytes.Contains([]byte(record.Value), []byte(`"message":"without key"`)) {
+				t.Errorf("kafka writer record without key mismatch: %+v", record)
+			}
+			continue
+		}
+		if p, ok := partitions[record.Key]; ok && p != record.Partition {
+			t.Errorf("kafka writer should produce same key to same partition: %+v", broker.records)
+		}
+		partitions[record.Key] = record.Partition
+		if want := (murmur2([]byte(record.Key)) & 0x7fffffff) % 2; record.Partition != want {
+			t.Errorf("kafka writer partition mismatch: %d != %d", record.Partition, want)
+		}
+		if record.Value[len(record.Value)-1] != '}' {
+			t.Errorf("kafka writer record value should be trimmed: %q", record.Value)
+		}
+	}
+}
+
+func TestMurmur2(t *testing.T) {
+	// the values are from kafka java client
+	cases := []struct {
+		Key  string
+		Hash int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, c := range cases {
+		if h := murmur2([]byte(c.Key)); h != c.Hash {
+			t.Errorf("murmur2(%q) = %d, want %d", c.Key, h, c.Hash)
+		}
+	}
+}
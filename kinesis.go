@@ -0,0 +1,228 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// KinesisWriter is a Writer that puts entries in batches to an Amazon Kinesis data stream
+// by PutRecords, or to an Amazon Data Firehose delivery stream by PutRecordBatch if Firehose
+// is set. The records failed by throttling are retried with exponential backoff.
+type KinesisWriter struct {
+	// Region specifies the aws region, uses AWS_REGION environment variable if empty.
+	Region string
+
+	// StreamName specifies the name of data stream, or delivery stream of firehose.
+	StreamName string
+
+	// Firehose determines if puts the records to a delivery stream of firehose, the records
+	// are kept newline delimited for the destinations.
+	Firehose bool
+
+	// PartitionKeyField specifies the entry field used as the partition key of data stream,
+	// uses a random partition key if empty or absent.
+	PartitionKeyField string
+
+	// Endpoint specifies the endpoint url, uses "https://kinesis.{region}.amazonaws.com/"
+	// or "https://firehose.{region}.amazonaws.com/" if empty.
+	Endpoint string
+
+	// Credentials specifies the credentials provider, uses AWSEnvCredentials if nil.
+	Credentials func() (AWSCredentials, error)
+
+	// BatchSize specifies the maximum entries of a batch, uses 500 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once     sync.Once
+	batcher  batcher
+	signer   awsSigner
+	endpoint string
+}
+
+const (
+	// kinesisMaxRecords is the maximum records of PutRecords and PutRecordBatch.
+	kinesisMaxRecords = 500
+	// kinesisMaxBytes and kinesisMaxRecordSize are the limits of PutRecords.
+	kinesisMaxBytes      = 5 * 1024 * 1024
+	kinesisMaxRecordSize = 1024 * 1024
+	// firehoseMaxBytes and firehoseMaxRecordSize are the limits of PutRecordBatch.
+	firehoseMaxBytes      = 4 * 1024 * 1024
+	firehoseMaxRecordSize = 1000 * 1024
+)
+
+func (w *KinesisWriter) init() {
+	w.once.Do(func() {
+		region := w.Region
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		service := "kinesis"
+		if w.Firehose {
+			service = "firehose"
+		}
+		w.endpoint = w.Endpoint
+		if w.endpoint == "" {
+			w.endpoint = "https://" + service + "." + region + ".amazonaws.com/"
+		}
+
+		w.signer.region = region
+		w.signer.service = service
+		w.signer.credentials = w.Credentials
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 || w.batcher.size > kinesisMaxRecords {
+			w.batcher.size = kinesisMaxRecords
+		}
+		// keeps a headroom for the partition keys.
+		w.batcher.bytes = kinesisMaxBytes - kinesisMaxRecords*256
+		if w.Firehose {
+			w.batcher.bytes = firehoseMaxBytes
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *KinesisWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *KinesisWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *KinesisWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	action, limit := "Kinesis_20131202.PutRecords", kinesisMaxRecordSize
+	dst := append(buf.B[:0], "{\"StreamName\":"...)
+	if w.Firehose {
+		action, limit = "Firehose_20150804.PutRecordBatch", firehoseMaxRecordSize
+		dst = append(buf.B[:0], "{\"DeliveryStreamName\":"...)
+	}
+	dst = jsonAppendString(dst, w.StreamName)
+	dst = append(dst, ",\"Records\":["...)
+	for i := 0; i < b.Len(); i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		data := b.Entry(i)
+		if !w.Firehose {
+			data = bytes.TrimRight(data, "\n")
+		}
+		if len(data) > limit {
+			data = data[:limit]
+		}
+		dst = append(dst, "{\"Data\":\""...)
+		n := len(dst)
+		dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(len(data)))...)
+		base64.StdEncoding.Encode(dst[n:], data)
+		dst = append(dst, '"')
+		if !w.Firehose {
+			dst = append(dst, ",\"PartitionKey\":"...)
+			dst = jsonAppendBytes(dst, w.partitionKey(data))
+		}
+		dst = append(dst, '}')
+	}
+	dst = append(dst, "]}"...)
+	buf.B = dst
+
+	req, err := http.NewRequest(http.MethodPost, w.endpoint, bytes.NewReader(buf.B))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", action)
+	if err = w.signer.sign(req, buf.B); err != nil {
+		return err
+	}
+
+	data, err := httpDo(w.Client, req, 1024*1024)
+	if err != nil {
+		switch awsErrorType(err) {
+		case "ProvisionedThroughputExceededException", "ThrottlingException", "ServiceUnavailableException", "LimitExceededException":
+			// unwraps permanentError to retry
+			var perm permanentError
+			if errors.As(err, &perm) {
+				err = perm.error
+			}
+		}
+		return err
+	}
+
+	// the records are failed individually, e.g. by the throughput of shards.
+	var resp struct {
+		FailedRecordCount int `json:"FailedRecordCount"`
+		FailedPutCount    int `json:"FailedPutCount"`
+		Records           []struct {
+			ErrorCode    string `json:"ErrorCode"`
+			ErrorMessage string `json:"ErrorMessage"`
+		} `json:"Records"`
+		RequestResponses []struct {
+			ErrorCode    string `json:"ErrorCode"`
+			ErrorMessage string `json:"ErrorMessage"`
+		} `json:"RequestResponses"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return permanentError{err}
+	}
+	failed, results := resp.FailedRecordCount, resp.Records
+	if w.Firehose {
+		failed, results = resp.FailedPutCount, resp.RequestResponses
+	}
+	if failed == 0 {
+		return nil
+	}
+
+	var message string
+	b.filter(func(i int, _ []byte) bool {
+		if i >= len(results) || results[i].ErrorCode == "" {
+			return false
+		}
+		message = results[i].ErrorCode + ": " + results[i].ErrorMessage
+		return true
+	})
+	return errors.New("log: kinesis failed to put " + strconv.Itoa(b.Len()) + " records: " + message)
+}
+
+// partitionKey returns the partition key of data, it is random if the field is empty or absent.
+func (w *KinesisWriter) partitionKey(data []byte) []byte {
+	if w.PartitionKeyField != "" {
+		if key := jsonFieldString(data, w.PartitionKeyField); len(key) != 0 {
+			if len(key) > 256 {
+				key = key[:256]
+			}
+			return key
+		}
+	}
+	return strconv.AppendUint(nil, uint64(Fastrandn(math.MaxUint32)), 10)
+}
+
+var _ Writer = (*KinesisWriter)(nil)
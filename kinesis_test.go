@@ -0,0 +1,155 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKinesisWriter(t *testing.T) {
+	type record struct {
+		Data         string `json:"Data"`
+		PartitionKey string `json:"PartitionKey"`
+	}
+
+	var mu sync.Mutex
+	var requests [][]record
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "Kinesis_20131202.PutRecords" || !strings.Contains(r.Header.Get("Authorization"), "/kinesis/aws4_request") {
+			t.Errorf("kinesis writer headers mismatch: %v", r.Header)
+		}
+		var req struct {
+			StreamName string   `json:"StreamName"`
+			Records    []record `json:"Records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StreamName != "mystream" {
+			t.Errorf("kinesis writer request mismatch: %+v %+v", err, req)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, req.Records)
+		if len(requests) == 1 {
+			// the second record is throttled
+			_, _ = rw.Write([]byte(`{"FailedRecordCount":1,"Records":[{"SequenceNumber":"1","ShardId":"shardId-0"},{"ErrorCode":"ProvisionedThroughputExceededException","ErrorMessage":"Rate exceeded"}]}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"FailedRecordCount":0,"Records":[{"SequenceNumber":"2","ShardId":"shardId-0"}]}`))
+	}))
+	defer server.Close()
+
+	w := &KinesisWriter{
+		Region:            "us-east-1",
+		StreamName:        "mystream",
+		PartitionKeyField: "user",
+		Endpoint:          server.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		},
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Str("user", "alice").Msg("first")
+	logger.Info().Str("user", "bob").Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("kinesis writer close error: %+v", err)
+	}
+
+	if len(requests) != 2 || len(requests[0]) != 2 || len(requests[1]) != 1 {
+		t.Fatalf("kinesis writer requests mismatch: %+v", requests)
+	}
+	if requests[0][0].PartitionKey != "alice" || requests[1][0].PartitionKey != "bob" {
+		t.Errorf("kinesis writer partition keys mismatch: %+v", requests)
+	}
+	data, _ := base64.StdEncoding.DecodeString(requests[1][0].Data)
+	if !strings.Contains(string(data), `"message":"second"`) || strings.HasSuffix(string(data), "\n") {
+		t.Errorf("kinesis writer record data mismatch: %q", data)
+	}
+}
+
+func TestKinesisWriterFirehose(t *testing.T) {
+	var mu sync.Mutex
+	var datas []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "Firehose_20150804.PutRecordBatch" || !strings.Contains(r.Header.Get("Authorization"), "/firehose/aws4_request") {
+			t.Errorf("firehose writer headers mismatch: %v", r.Header)
+		}
+		var req struct {
+			DeliveryStreamName string `json:"DeliveryStreamName"`
+			Records            []struct {
+				Data         string `json:"Data"`
+				PartitionKey string `json:"PartitionKey"`
+			} `json:"Records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeliveryStreamName != "mydelivery" {
+			t.Errorf("firehose writer request mismatch: %+v %+v", err, req)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range req.Records {
+			if r.PartitionKey != "" {
+				t.Errorf("firehose writer should not send partition key: %+v", r)
+			}
+			data, _ := base64.StdEncoding.DecodeString(r.Data)
+			datas = append(datas, string(data))
+		}
+		_, _ = rw.Write([]byte(`{"FailedPutCount":0,"RequestResponses":[{"RecordId":"1"}]}`))
+	}))
+	defer server.Close()
+
+	w := &KinesisWriter{
+		Region:     "us-east-1",
+		StreamName: "mydelivery",
+		Firehose:   true,
+		Endpoint:   server.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		},
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello firehose")
+	if err := w.Close(); err != nil {
+		t.Fatalf("firehose writer close error: %+v", err)
+	}
+	if len(datas) != 1 || !strings.HasSuffix(datas[0], "\"message\":\"hello firehose\"}\n") {
+		t.Errorf("firehose writer records mismatch: %q", datas)
+	}
+}
+
+func TestKinesisWriterThrottling(t *testing.T) {
+	var mu sync.Mutex
+	var n int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if n++; n == 1 {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"__type":"ProvisionedThroughputExceededException","message":"Rate exceeded"}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"FailedRecordCount":0,"Records":[{"SequenceNumber":"1","ShardId":"shardId-0"}]}`))
+	}))
+	defer server.Close()
+
+	w := &KinesisWriter{
+		StreamName: "mystream",
+		Endpoint:   server.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		},
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello kinesis")
+	if err := w.Close(); err != nil {
+		t.Fatalf("kinesis writer should retry throttled request: %+v", err)
+	}
+	if n != 2 {
+		t.Errorf("kinesis writer want 2 requests got %d", n)
+	}
+}
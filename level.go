@@ -1,5 +1,11 @@
 package log
 
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
 // Level defines log levels.
 type Level uint32
 
@@ -40,7 +46,11 @@ func (l Level) String() (s string) {
 	case PanicLevel:
 		s = "panic"
 	default:
-		s = "????"
+		if c := customLevelOf(l); c != nil {
+			s = c.Name
+		} else {
+			s = "????"
+		}
 	}
 	return
 }
@@ -64,6 +74,175 @@ func ParseLevel(s string) (level Level) {
 		level = PanicLevel
 	default:
 		level = noLevel
+		if c := customLevelByName(s); c != nil {
+			level = c.level
+		}
 	}
 	return
 }
+
+// LevelSpec describes a custom level, see RegisterLevel.
+type LevelSpec struct {
+	// Name specifies the lower case name of level, e.g. "notice".
+	Name string
+
+	// Short specifies the three letters name of level in ConsoleWriter, uses the upper case of
+	// first three letters of Name if empty, e.g. "NOT".
+	Short string
+
+	// Above specifies the level right below the custom level, e.g. InfoLevel for notice.
+	// The level is also used by the writers which recognize only the builtin levels.
+	Above Level
+
+	// Color specifies the color of level in ConsoleWriter, uses gray if empty, e.g. "\x1b[36m".
+	Color string
+
+	// Syslog specifies the syslog severity of level, from 1 (alert) to 7 (debug), uses the
+	// severity of Above level if zero.
+	Syslog int
+}
+
+type customLevel struct {
+	LevelSpec
+	level Level
+	base  Level
+	rank  uint32
+}
+
+// customLevelMin is the value of first custom level.
+const customLevelMin Level = 16
+
+var customLevels struct {
+	mu     sync.Mutex
+	levels atomic.Value // []*customLevel
+}
+
+// RegisterLevel registers a custom level and returns it, the level is ordered right above
+// the Above level of spec, and can be used as the builtin levels, e.g.
+//
+//	var NoticeLevel = log.RegisterLevel(log.LevelSpec{Name: "notice", Above: log.InfoLevel, Syslog: 5})
+//
+//	log.DefaultLogger.WithLevel(NoticeLevel).Msg("hello world")
+//
+// It panics if the name is empty or registered, and should be called before logging.
+func RegisterLevel(spec LevelSpec) Level {
+	if spec.Name == "" || ParseLevel(spec.Name) != noLevel {
+		panic("log: level " + spec.Name + " is empty or already registered")
+	}
+	if spec.Short == "" {
+		spec.Short = spec.Name
+		if len(spec.Short) > 3 {
+			spec.Short = spec.Short[:3]
+		}
+		spec.Short = strings.ToUpper(spec.Short)
+	}
+
+	customLevels.mu.Lock()
+	defer customLevels.mu.Unlock()
+
+	levels, _ := customLevels.levels.Load().([]*customLevel)
+	if customLevelMin+Level(len(levels)) > 0xff {
+		panic("log: too many custom levels")
+	}
+
+	c := &customLevel{
+		LevelSpec: spec,
+		level:     customLevelMin + Level(len(levels)),
+		base:      levelBase(spec.Above),
+		rank:      levelRank(spec.Above) + 1,
+	}
+	if c.rank&0xff == 0xff {
+		panic("log: too many custom levels above " + spec.Above.String())
+	}
+	if c.Syslog == 0 {
+		c.Syslog = levelSyslog(c.base)
+	}
+
+	// the levels are copied on write, and shifted up if ordered above the new level.
+	table := make([]*customLevel, 0, len(levels)+1)
+	for _, x := range levels {
+		if x.rank >= c.rank && x.rank>>8 == c.rank>>8 {
+			y := *x
+			y.rank++
+			x = &y
+		}
+		table = append(table, x)
+	}
+	table = append(table, c)
+	customLevels.levels.Store(table)
+
+	return c.level
+}
+
+// customLevelOf returns the custom level of l, or nil if l is not a custom level.
+func customLevelOf(l Level) *customLevel {
+	if l < customLevelMin {
+		return nil
+	}
+	levels, _ := customLevels.levels.Load().([]*customLevel)
+	if i := int(l - customLevelMin); i < len(levels) {
+		return levels[i]
+	}
+	return nil
+}
+
+// customLevelByName returns the custom level of name or short name, ignoring case.
+func customLevelByName(name string) *customLevel {
+	levels, _ := customLevels.levels.Load().([]*customLevel)
+	for _, c := range levels {
+		if strings.EqualFold(c.Name, name) || c.Short == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// levelRank returns the order of level, the builtin levels are ranked by level<<8, and
+// the custom levels are ranked between them.
+func levelRank(l Level) uint32 {
+	if l <= noLevel {
+		return uint32(l) << 8
+	}
+	if c := customLevelOf(l); c != nil {
+		return c.rank
+	}
+	return uint32(noLevel) << 8
+}
+
+// levelBase returns the builtin level of l, which is the builtin level right below a custom level.
+func levelBase(l Level) Level {
+	if c := customLevelOf(l); c != nil {
+		return c.base
+	}
+	return l
+}
+
+// levelLess reports whether the level a is ordered below the level b.
+func levelLess(a, b Level) bool {
+	if a <= noLevel && b <= noLevel {
+		return a < b
+	}
+	return levelRank(a) < levelRank(b)
+}
+
+// levelSyslog returns the syslog severity of level.
+func levelSyslog(l Level) int {
+	switch l {
+	case TraceLevel, DebugLevel:
+		return 7 // LOG_DEBUG
+	case InfoLevel:
+		return 6 // LOG_INFO
+	case WarnLevel:
+		return 4 // LOG_WARNING
+	case ErrorLevel:
+		return 3 // LOG_ERR
+	case FatalLevel:
+		return 2 // LOG_CRIT
+	case PanicLevel:
+		return 1 // LOG_ALERT
+	}
+	if c := customLevelOf(l); c != nil {
+		return c.Syslog
+	}
+	return 6 // LOG_INFO
+}
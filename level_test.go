@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +30,67 @@ func TestLevelParse(t *testing.T) {
 		}
 	}
 }
+
+func TestLevelRegister(t *testing.T) {
+	notice := RegisterLevel(LevelSpec{Name: "notice", Above: InfoLevel, Color: "\x1b[36m", Syslog: 5})
+	verbose := RegisterLevel(LevelSpec{Name: "verbose", Above: InfoLevel})
+	audit := RegisterLevel(LevelSpec{Name: "audit", Short: "AUD", Above: ErrorLevel})
+
+	if s := notice.String(); s != "notice" {
+		t.Errorf("custom level String() must return notice, not %#v", s)
+	}
+	for _, name := range []string{"notice", "NOTICE", "NOT"} {
+		if l := ParseLevel(name); l != notice {
+			t.Errorf("ParseLevel(%#v) must return %#v, not %#v", name, notice, l)
+		}
+	}
+	if l := ParseLevel("AUD"); l != audit {
+		t.Errorf("ParseLevel(AUD) must return %#v, not %#v", audit, l)
+	}
+
+	// info < verbose < notice < warn < error < audit < fatal
+	order := []Level{InfoLevel, verbose, notice, WarnLevel, ErrorLevel, audit, FatalLevel}
+	for i := 0; i < len(order)-1; i++ {
+		if !levelLess(order[i], order[i+1]) || levelLess(order[i+1], order[i]) {
+			t.Errorf("level %s must be ordered below %s", order[i], order[i+1])
+		}
+	}
+
+	if s := levelSyslog(notice); s != 5 {
+		t.Errorf("syslog severity of notice must be 5, not %d", s)
+	}
+	if s := levelSyslog(audit); s != 3 {
+		t.Errorf("syslog severity of audit must be 3, not %d", s)
+	}
+	if l := levelBase(audit); l != ErrorLevel {
+		t.Errorf("base level of audit must be error, not %s", l)
+	}
+
+	var buf bytes.Buffer
+	logger := Logger{Level: notice, Writer: &IOWriter{&buf}}
+	logger.Info().Msg("info")
+	logger.WithLevel(verbose).Msg("verbose")
+	logger.WithLevel(notice).Msg("notice")
+	logger.Warn().Msg("warn")
+	logger.WithLevel(audit).Msg("audit")
+	if s := buf.String(); strings.Contains(s, `"info"`) || strings.Contains(s, `"verbose"`) ||
+		!strings.Contains(s, `"level":"notice"`) || !strings.Contains(s, `"level":"warn"`) || !strings.Contains(s, `"level":"audit"`) {
+		t.Errorf("logger with custom level got %s", s)
+	}
+
+	buf.Reset()
+	logger = Logger{Level: notice, Writer: &ConsoleWriter{Writer: &buf, ColorOutput: true}}
+	logger.WithLevel(notice).Msg("hello")
+	if s := buf.String(); !strings.Contains(s, "\x1b[36mNOT") {
+		t.Errorf("console writer with custom level got %q", s)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("RegisterLevel with registered name must panic")
+			}
+		}()
+		RegisterLevel(LevelSpec{Name: "notice"})
+	}()
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelHandler is an http.Handler that reports and changes the level of logger, it follows
+// the convention of zap.AtomicLevel, e.g.
+//
+//	curl -s http://localhost:8080/log/level
+//	{"level":"info"}
+//	curl -s -X PUT -d '{"level":"debug"}' http://localhost:8080/log/level
+//	{"level":"debug"}
+//
+// The requests with the `name` query or field change the level of named loggers by SetLevel.
+type LevelHandler struct {
+	// Logger specifies the logger to change, uses DefaultLogger if nil.
+	Logger *Logger
+}
+
+type levelPayload struct {
+	Name  string `json:"name,omitempty"`
+	Level string `json:"level"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+
+	reply := func(code int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	fail := func(code int, msg string) {
+		reply(code, struct {
+			Error string `json:"error"`
+		}{msg})
+	}
+
+	var req levelPayload
+	req.Name = r.URL.Query().Get("name")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			req.Level = r.FormValue("level")
+			if name := r.FormValue("name"); name != "" {
+				req.Name = name
+			}
+		} else {
+			var body levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				fail(http.StatusBadRequest, "Request body must be well-formed JSON: "+err.Error())
+				return
+			}
+			req.Level = body.Level
+			if body.Name != "" {
+				req.Name = body.Name
+			}
+		}
+		if req.Level == "" {
+			fail(http.StatusBadRequest, "Must specify a logging level.")
+			return
+		}
+		level := ParseLevel(req.Level)
+		if level == noLevel {
+			fail(http.StatusBadRequest, "Unrecognized level: \""+req.Level+"\".")
+			return
+		}
+		if req.Name == "" {
+			logger.SetLevel(level)
+		} else if err := SetLevel(req.Name, level); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		fail(http.StatusMethodNotAllowed, "Only GET and PUT are supported.")
+		return
+	}
+
+	level := Level(atomic.LoadUint32((*uint32)(&logger.Level)))
+	if req.Name != "" {
+		var ok bool
+		if level, ok = GetLevel(req.Name); !ok {
+			fail(http.StatusNotFound, "No level of name: \""+req.Name+"\".")
+			return
+		}
+	}
+	reply(http.StatusOK, levelPayload{Name: req.Name, Level: level.String()})
+}
+
+var _ http.Handler = (*LevelHandler)(nil)
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	logger := Logger{Level: InfoLevel}
+	h := &LevelHandler{Logger: &logger}
+	defer UnsetLevel("db")
+
+	cases := []struct {
+		Method      string
+		URL         string
+		ContentType string
+		Body        string
+		Code        int
+		Response    string
+	}{
+		{"GET", "/", "", "", 200, `{"level":"info"}`},
+		{"PUT", "/", "application/json", `{"level":"debug"}`, 200, `{"level":"debug"}`},
+		{"PUT", "/", "application/x-www-form-urlencoded", `level=warn`, 200, `{"level":"warn"}`},
+		{"PUT", "/", "application/json", `{"level":"loud"}`, 400, `{"error":"Unrecognized level: \"loud\"."}`},
+		{"PUT", "/", "application/json", `{`, 400, ``},
+		{"PUT", "/", "application/json", `{}`, 400, `{"error":"Must specify a logging level."}`},
+		{"PUT", "/?name=db", "application/json", `{"level":"trace"}`, 200, `{"name":"db","level":"trace"}`},
+		{"GET", "/?name=db", "", "", 200, `{"name":"db","level":"trace"}`},
+		{"GET", "/?name=http", "", "", 404, `{"error":"No level of name: \"http\"."}`},
+		{"POST", "/", "", "", 405, `{"error":"Only GET and PUT are supported."}`},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.Method, c.URL, strings.NewReader(c.Body))
+		if c.ContentType != "" {
+			req.Header.Set("Content-Type", c.ContentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.Code {
+			t.Errorf("%s %s %s got code %d, want %d", c.Method, c.URL, c.Body, rec.Code, c.Code)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); c.Response != "" && got != c.Response {
+			t.Errorf("%s %s %s got %s, want %s", c.Method, c.URL, c.Body, got, c.Response)
+		}
+	}
+
+	if logger.Level != WarnLevel {
+		t.Errorf("logger level must be warn, not %s", logger.Level)
+	}
+	if level, _ := GetLevel("db"); level != TraceLevel {
+		t.Errorf("level of db must be trace, not %s", level)
+	}
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,9 +30,27 @@ var DefaultLogger = Logger{
 
 // Entry represents a log entry. It is instanced by one of the level method of Logger and finalized by the Msg or Msgf method.
 type Entry struct {
-	buf   []byte
-	Level Level
-	w     Writer
+	buf    []byte
+	Level  Level
+	name   string
+	w      Writer
+	durfmt string
+	hooks  []Hook
+}
+
+// Hook defines an interface to inspect, modify or veto the entries of Logger.
+type Hook interface {
+	// Run is called with the entry and message before the message is appended, it may
+	// append fields to e or change e.Level, and vetoes the entry if returns false.
+	Run(e *Entry, msg string) bool
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as Hook.
+type HookFunc func(e *Entry, msg string) bool
+
+// Run implements Hook, it calls f(e, msg).
+func (f HookFunc) Run(e *Entry, msg string) bool {
+	return f(e, msg)
 }
 
 // Writer defines an entry writer interface.
@@ -74,6 +94,10 @@ type Logger struct {
 	// Level defines log levels.
 	Level Level
 
+	// Name specifies an optional name of logger, e.g. "db" or "http.client". The level set
+	// by SetLevel with a matching pattern takes precedence over Level.
+	Name string
+
 	// Caller determines if adds the file:line of the "caller" key.
 	// If Caller is negative, adds the full /path/to/file:line of the "caller" key.
 	Caller int
@@ -88,9 +112,25 @@ type Logger struct {
 	// TimeUTC specifices that the timestamps should be UTC instead of system local time.
 	TimeUTC bool
 
+	// DurationFormat specifies the format of duration fields. It uses milliseconds with
+	// the fraction if empty, e.g. 1500.500000, see DurationFormatSeconds.
+	DurationFormat string
+
 	// Context specifies an optional context of logger.
 	Context Context
 
+	// StackTrace specifies an optional structured stack trace of entries, e.g. on ErrorLevel and above.
+	StackTrace *StackTrace
+
+	// Hooks specifies the optional hooks of entries, which are run in order before the
+	// message is appended, see Hook.
+	Hooks []Hook
+
+	// Sampler specifies an optional sampler of logger, the entries of which
+	// Sample returns false are dropped as if they are filtered by level.
+	// It is consulted once per entry, the level checks of grpc V and slog Enabled do not sample.
+	Sampler Sampler
+
 	// Writer specifies the writer of output. It uses a wrapped os.Stderr Writer in if empty.
 	Writer Writer
 }
@@ -107,6 +147,24 @@ const TimeFormatUnixMs = "\x02"
 // serialized as Unix timestamp timestamp floats.
 const TimeFormatUnixWithMs = "\x03"
 
+const (
+	// DurationFormatSeconds defines a duration format that makes duration fields to be
+	// serialized as float seconds, e.g. 1.5.
+	DurationFormatSeconds = "s"
+
+	// DurationFormatMillis defines a duration format that makes duration fields to be
+	// serialized as integer milliseconds, e.g. 1500.
+	DurationFormatMillis = "ms"
+
+	// DurationFormatNanos defines a duration format that makes duration fields to be
+	// serialized as integer nanoseconds, e.g. 1500000000.
+	DurationFormatNanos = "ns"
+
+	// DurationFormatString defines a duration format that makes duration fields to be
+	// serialized as strings of time.Duration, e.g. "1.5s".
+	DurationFormatString = "string"
+)
+
 // Trace starts a new message with trace level.
 func Trace() (e *Entry) {
 	if DefaultLogger.silent(TraceLevel) {
@@ -451,8 +509,22 @@ var timeOffset, timeZone = func() (int64, string) {
 	return int64(n), s
 }()
 
+// silent reports whether the entry of level is filtered by level or dropped by sampler.
 func (l *Logger) silent(level Level) bool {
-	return uint32(level) < atomic.LoadUint32((*uint32)(&l.Level))
+	return l.disabled(level) || (l.Sampler != nil && !l.Sampler.Sample(level))
+}
+
+// disabled reports whether the level is filtered by level of logger, it does not sample.
+func (l *Logger) disabled(level Level) bool {
+	min := Level(atomic.LoadUint32((*uint32)(&l.Level)))
+	if l.Name != "" {
+		if table, _ := moduleLevels.table.Load().(*moduleLevelTable); table != nil {
+			if v, ok := table.lookup(l.Name); ok {
+				min = v
+			}
+		}
+	}
+	return levelLess(level, min)
 }
 
 func (l *Logger) header(level Level) *Entry {
@@ -467,6 +539,9 @@ func (l *Logger) header(level Level) *Entry {
 	e := epool.Get().(*Entry)
 	e.buf = e.buf[:0]
 	e.Level = level
+	e.name = l.Name
+	e.durfmt = l.DurationFormat
+	e.hooks = l.Hooks
 	if l.Writer != nil {
 		e.w = l.Writer
 	} else {
@@ -659,11 +734,21 @@ func (l *Logger) header(level Level) *Entry {
 		e.buf = append(e.buf, ",\"level\":\"fatal\""...)
 	case PanicLevel:
 		e.buf = append(e.buf, ",\"level\":\"panic\""...)
+	default:
+		if c := customLevelOf(level); c != nil {
+			e.buf = append(e.buf, ",\"level\":\""...)
+			e.buf = append(e.buf, c.Name...)
+			e.buf = append(e.buf, '"')
+		}
 	}
 	// context
 	if l.Context != nil {
 		e.buf = append(e.buf, l.Context...)
 	}
+	// stack trace
+	if l.StackTrace != nil && l.StackTrace.enabled(level) {
+		l.StackTrace.append(e)
+	}
 	return e
 }
 
@@ -796,6 +881,52 @@ func (e *Entry) Dur(key string, d time.Duration) *Entry {
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
+	e.dur(d)
+	return e
+}
+
+// DurSeconds adds the field key with duration d as float seconds to the entry, e.g. 1.5.
+func (e *Entry) DurSeconds(key string, d time.Duration) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	e.buf = strconv.AppendFloat(e.buf, d.Seconds(), 'f', -1, 64)
+	return e
+}
+
+// DurMillis adds the field key with duration d as integer milliseconds to the entry.
+func (e *Entry) DurMillis(key string, d time.Duration) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	e.buf = strconv.AppendInt(e.buf, int64(d/time.Millisecond), 10)
+	return e
+}
+
+// dur appends the duration d in the DurationFormat of logger.
+func (e *Entry) dur(d time.Duration) {
+	switch e.durfmt {
+	case DurationFormatSeconds:
+		e.buf = strconv.AppendFloat(e.buf, d.Seconds(), 'f', -1, 64)
+		return
+	case DurationFormatMillis:
+		e.buf = strconv.AppendInt(e.buf, int64(d/time.Millisecond), 10)
+		return
+	case DurationFormatNanos:
+		e.buf = strconv.AppendInt(e.buf, int64(d), 10)
+		return
+	case DurationFormatString:
+		e.buf = append(e.buf, '"')
+		e.buf = append(e.buf, d.String()...)
+		e.buf = append(e.buf, '"')
+		return
+	}
 	if d < 0 {
 		d = -d
 		e.buf = append(e.buf, '-')
@@ -817,7 +948,6 @@ func (e *Entry) Dur(key string, d time.Duration) *Entry {
 		tmp[0] = '.'
 		e.buf = append(e.buf, tmp[:]...)
 	}
-	return e
 }
 
 // TimeDiff adds the field key with positive duration between time t and start.
@@ -834,23 +964,7 @@ func (e *Entry) TimeDiff(key string, t time.Time, start time.Time) *Entry {
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
-	e.buf = strconv.AppendInt(e.buf, int64(d/time.Millisecond), 10)
-	if n := (d % time.Millisecond); n != 0 {
-		var tmp [7]byte
-		b := n % 100 * 2
-		n /= 100
-		tmp[6] = smallsString[b+1]
-		tmp[5] = smallsString[b]
-		b = n % 100 * 2
-		n /= 100
-		tmp[4] = smallsString[b+1]
-		tmp[3] = smallsString[b]
-		b = n % 100 * 2
-		tmp[2] = smallsString[b+1]
-		tmp[1] = smallsString[b]
-		tmp[0] = '.'
-		e.buf = append(e.buf, tmp[:]...)
-	}
+	e.dur(d)
 	return e
 }
 
@@ -866,27 +980,7 @@ func (e *Entry) Durs(key string, d []time.Duration) *Entry {
 		if i != 0 {
 			e.buf = append(e.buf, ',')
 		}
-		if a < 0 {
-			a = -a
-			e.buf = append(e.buf, '-')
-		}
-		e.buf = strconv.AppendInt(e.buf, int64(a/time.Millisecond), 10)
-		if n := (a % time.Millisecond); n != 0 {
-			var tmp [7]byte
-			b := n % 100 * 2
-			n /= 100
-			tmp[6] = smallsString[b+1]
-			tmp[5] = smallsString[b]
-			b = n % 100 * 2
-			n /= 100
-			tmp[4] = smallsString[b+1]
-			tmp[3] = smallsString[b]
-			b = n % 100 * 2
-			tmp[2] = smallsString[b+1]
-			tmp[1] = smallsString[b]
-			tmp[0] = '.'
-			e.buf = append(e.buf, tmp[:]...)
-		}
+		e.dur(a)
 	}
 	e.buf = append(e.buf, ']')
 	return e
@@ -948,6 +1042,59 @@ func (e *Entry) Errs(key string, errs []error) *Entry {
 	return e
 }
 
+// errChainMax is the max number of links of ErrChain.
+const errChainMax = 64
+
+// ErrChain adds the field key with the chain of err as an array of the type and message of
+// each link, the chain is walked by the Unwrap methods of errors, including the joined errors
+// of errors.Join in depth-first order, e.g.
+//
+//	"error_chain":[{"type":"*fmt.wrapError","message":"open: no such file"},{"type":"syscall.Errno","message":"no such file"}]
+func (e *Entry) ErrChain(key string, err error) *Entry {
+	if e == nil {
+		return nil
+	}
+
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if err == nil {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	e.buf = append(e.buf, '[')
+	n := 0
+	e.errChain(err, &n)
+	e.buf = append(e.buf, ']')
+	return e
+}
+
+func (e *Entry) errChain(err error, n *int) {
+	for err != nil && *n < errChainMax {
+		if *n != 0 {
+			e.buf = append(e.buf, ',')
+		}
+		*n++
+		e.buf = append(e.buf, "{\"type\":\""...)
+		e.string(reflect.TypeOf(err).String())
+		e.buf = append(e.buf, "\",\"message\":\""...)
+		e.string(err.Error())
+		e.buf = append(e.buf, '"', '}')
+
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				e.errChain(err, n)
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
 // Float64 adds the field key with f as a float64 to the entry.
 func (e *Entry) Float64(key string, f float64) *Entry {
 	if e == nil {
@@ -1338,6 +1485,60 @@ func (e *Entry) GoStringer(key string, val fmt.GoStringer) *Entry {
 	return e
 }
 
+// TextMarshaler adds the field key with val.MarshalText() as a string to the entry,
+// or the marshaling error if val fails.
+func (e *Entry) TextMarshaler(key string, val encoding.TextMarshaler) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if val == nil || (*[2]uintptr)(unsafe.Pointer(&val))[1] == 0 {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	e.buf = append(e.buf, '"')
+	if b, err := val.MarshalText(); err != nil {
+		e.string("marshaling error: " + err.Error())
+	} else {
+		e.bytes(b)
+	}
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// JSONMarshaler adds the field key with val.MarshalJSON() as raw json to the entry,
+// or the marshaling error as a string if val fails or returns invalid json.
+func (e *Entry) JSONMarshaler(key string, val json.Marshaler) *Entry {
+	if e == nil {
+		return nil
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+	if val == nil || (*[2]uintptr)(unsafe.Pointer(&val))[1] == 0 {
+		e.buf = append(e.buf, "null"...)
+		return e
+	}
+	b, err := val.MarshalJSON()
+	switch {
+	case err != nil:
+		e.buf = append(e.buf, '"')
+		e.string("marshaling error: " + err.Error())
+		e.buf = append(e.buf, '"')
+	case !json.Valid(b):
+		e.buf = append(e.buf, "\"marshaling error: invalid json\""...)
+	case bytes.IndexAny(b, "\r\n") >= 0:
+		var dst bytes.Buffer
+		_ = json.Compact(&dst, b)
+		e.buf = append(e.buf, dst.Bytes()...)
+	default:
+		e.buf = append(e.buf, b...)
+	}
+	return e
+}
+
 // Strs adds the field key with vals as a []string to the entry.
 func (e *Entry) Strs(key string, vals []string) *Entry {
 	if e == nil {
@@ -1463,17 +1664,7 @@ func (e *Entry) IPAddr(key string, ip net.IP) *Entry {
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
-	if ip4 := ip.To4(); ip4 != nil {
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[0]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[1]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[2]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[3]), 10)
-	} else {
-		e.buf = append(e.buf, ip.String()...)
-	}
+	e.buf = appendIP(e.buf, ip)
 	e.buf = append(e.buf, '"')
 	return e
 }
@@ -1486,11 +1677,46 @@ func (e *Entry) IPPrefix(key string, pfx net.IPNet) *Entry {
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
-	e.buf = append(e.buf, pfx.String()...)
+	e.buf = appendIPNet(e.buf, pfx)
 	e.buf = append(e.buf, '"')
 	return e
 }
 
+// appendIP appends the IPv4 or IPv6 address without allocations.
+func appendIP(dst []byte, ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		dst = strconv.AppendInt(dst, int64(ip4[0]), 10)
+		dst = append(dst, '.')
+		dst = strconv.AppendInt(dst, int64(ip4[1]), 10)
+		dst = append(dst, '.')
+		dst = strconv.AppendInt(dst, int64(ip4[2]), 10)
+		dst = append(dst, '.')
+		dst = strconv.AppendInt(dst, int64(ip4[3]), 10)
+		return dst
+	}
+	if addr, ok := netip.AddrFromSlice(ip); ok {
+		return addr.AppendTo(dst)
+	}
+	return append(dst, ip.String()...)
+}
+
+// appendIPNet appends the IPv4 or IPv6 prefix without allocations, the non-canonical
+// masks are appended as net.IPNet.String does.
+func appendIPNet(dst []byte, pfx net.IPNet) []byte {
+	ones, bits := pfx.Mask.Size()
+	ip4 := pfx.IP.To4() != nil
+	switch {
+	case bits == 32 && ip4, bits == 128 && !ip4 && len(pfx.IP) == net.IPv6len:
+	case bits == 128 && ip4 && ones >= 96:
+		ones -= 96
+	default:
+		return append(dst, pfx.String()...)
+	}
+	dst = appendIP(dst, pfx.IP)
+	dst = append(dst, '/')
+	return strconv.AppendInt(dst, int64(ones), 10)
+}
+
 // MACAddr adds MAC address to the entry.
 func (e *Entry) MACAddr(key string, ha net.HardwareAddr) *Entry {
 	if e == nil {
@@ -1609,6 +1835,23 @@ func (e *Entry) Msg(msg string) {
 	if e == nil {
 		return
 	}
+	e.write(msg)
+	if (e.Level == FatalLevel) && notTest {
+		os.Exit(255)
+	}
+	if (e.Level == PanicLevel) && notTest {
+		panic(msg)
+	}
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+}
+
+// write runs the hooks, appends the message and writes the entry, it does not exit or panic.
+func (e *Entry) write(msg string) {
+	if e.hooks != nil && e.hook(msg) {
+		return
+	}
 	if msg != "" {
 		e.buf = append(e.buf, ",\"message\":\""...)
 		e.string(msg)
@@ -1617,6 +1860,22 @@ func (e *Entry) Msg(msg string) {
 		e.buf = append(e.buf, '}', '\n')
 	}
 	_, _ = e.w.WriteEntry(e)
+}
+
+// hook runs the hooks of entry once, and reports whether the entry is vetoed.
+func (e *Entry) hook(msg string) bool {
+	hooks := e.hooks
+	e.hooks = nil
+	for _, h := range hooks {
+		if !h.Run(e, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// drop drops the vetoed entry, the fatal and panic entries still exit and panic.
+func (e *Entry) drop(msg string) {
 	if (e.Level == FatalLevel) && notTest {
 		os.Exit(255)
 	}
@@ -1650,8 +1909,15 @@ func (e *Entry) Msgf(format string, v ...interface{}) {
 	}
 	b := bbpool.Get().(*bb)
 	b.B = b.B[:0]
-	e.buf = append(e.buf, ",\"message\":\""...)
 	fmt.Fprintf(b, format, v...)
+	if e.hooks != nil && e.hook(b2s(b.B)) {
+		e.drop(b2s(b.B))
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+		return
+	}
+	e.buf = append(e.buf, ",\"message\":\""...)
 	e.bytes(b.B)
 	e.buf = append(e.buf, '"')
 	if cap(b.B) <= bbcap {
@@ -1667,8 +1933,15 @@ func (e *Entry) Msgs(args ...interface{}) {
 	}
 	b := bbpool.Get().(*bb)
 	b.B = b.B[:0]
-	e.buf = append(e.buf, ",\"message\":\""...)
 	fmt.Fprint(b, args...)
+	if e.hooks != nil && e.hook(b2s(b.B)) {
+		e.drop(b2s(b.B))
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+		return
+	}
+	e.buf = append(e.buf, ",\"message\":\""...)
 	e.bytes(b.B)
 	e.buf = append(e.buf, '"')
 	if cap(b.B) <= bbcap {
@@ -1902,6 +2175,47 @@ func (e *Entry) Object(key string, obj ObjectMarshaler) *Entry {
 	return e
 }
 
+// ObjectFunc is an adapter to allow the use of ordinary functions as ObjectMarshaler, e.g.
+//
+//	log.Info().Object("http", log.ObjectFunc(func(e *log.Entry) {
+//		e.Str("method", "GET").Int("status", 200)
+//	})).Msg("")
+type ObjectFunc func(e *Entry)
+
+// MarshalObject implements ObjectMarshaler, it calls f(e).
+func (f ObjectFunc) MarshalObject(e *Entry) {
+	f(e)
+}
+
+// Group adds the fields appended by f as a nested object of key, which is an empty object
+// if f appends nothing, e.g.
+//
+//	log.Info().Group("http", func(e *log.Entry) {
+//		e.Str("method", "GET").Int("status", 200)
+//	}).Msg("")
+//
+//	// Output: {"time":"2020-07-12T05:03:43.949Z","level":"info","http":{"method":"GET","status":200},"message":""}
+func (e *Entry) Group(key string, f func(e *Entry)) *Entry {
+	if e == nil {
+		return nil
+	}
+
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':')
+
+	n := len(e.buf)
+	f(e)
+	if n < len(e.buf) {
+		e.buf[n] = '{'
+		e.buf = append(e.buf, '}')
+	} else {
+		e.buf = append(e.buf, '{', '}')
+	}
+
+	return e
+}
+
 // Func allows an anonymous func to run only if the entry is enabled.
 func (e *Entry) Func(f func(e *Entry)) *Entry {
 	if e != nil {
@@ -1910,6 +2224,24 @@ func (e *Entry) Func(f func(e *Entry)) *Entry {
 	return e
 }
 
+// Lazy adds the field key with the value returned by f, f is called only if the entry
+// is enabled, so the expensive values cost nothing if the level is filtered or sampled.
+func (e *Entry) Lazy(key string, f func() interface{}) *Entry {
+	if e == nil {
+		return nil
+	}
+	return e.Any(key, f())
+}
+
+// LazyStr adds the field key with the string returned by f, f is called only if the
+// entry is enabled.
+func (e *Entry) LazyStr(key string, f func() string) *Entry {
+	if e == nil {
+		return nil
+	}
+	return e.Str(key, f())
+}
+
 // EmbedObject marshals and Embeds an object that implement the ObjectMarshaler interface.
 func (e *Entry) EmbedObject(obj ObjectMarshaler) *Entry {
 	if e == nil {
@@ -9,69 +9,186 @@ import (
 )
 
 type slogHandler struct {
-	Logger
+	logger Logger
+
+	// context specifies the encoded attrs of WithAttrs.
+	context []byte
+	// grouping specifies the groups of WithGroup which have no attrs yet.
+	grouping []string
+	// depth specifies the number of opened groups in context.
+	depth int
 }
 
-func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
-	switch level {
-	case slog.LevelDebug:
-		return h.Logger.Level <= DebugLevel
-	case slog.LevelInfo:
-		return h.Logger.Level <= InfoLevel
-	case slog.LevelWarn:
-		return h.Logger.Level <= WarnLevel
-	case slog.LevelError:
-		return h.Logger.Level <= ErrorLevel
+// slogLevel converts slog.Level to Level.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
 	}
-	return false
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return !h.logger.disabled(slogLevel(level))
 }
 
 func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
-	var e *Entry
-	switch r.Level {
-	case slog.LevelDebug:
-		e = h.Logger.Debug()
-	case slog.LevelInfo:
-		e = h.Logger.Info()
-	case slog.LevelWarn:
-		e = h.Logger.Warn()
-	case slog.LevelError:
-		e = h.Logger.Error()
-	default:
-		e = h.Logger.Log()
+	if h.logger.Sampler != nil && !h.logger.Sampler.Sample(slogLevel(r.Level)) {
+		return nil
+	}
+	e := h.logger.header(slogLevel(r.Level))
+
+	if caller, full := h.logger.Caller, false; caller != 0 && r.PC != 0 {
+		if caller < 0 {
+			full = true
+		}
+		var rpc = [1]uintptr{r.PC}
+		e.caller(1, rpc[:], full)
 	}
+
+	e.buf = append(e.buf, h.context...)
+
+	depth := h.depth
+	grouping := h.grouping
 	r.Attrs(func(attr slog.Attr) bool {
-		e = e.Any(attr.Key, attr.Value)
+		if slogAppendGroups(e, grouping, attr) {
+			depth += len(grouping)
+			grouping = nil
+		}
 		return true
 	})
+	for i := 0; i < depth; i++ {
+		e.buf = append(e.buf, '}')
+	}
+
 	e.Msg(r.Message)
 	return nil
 }
 
 func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	e := NewContext(nil)
-	for _, attr := range attrs {
-		e = e.Any(attr.Key, attr.Value)
+	if len(attrs) == 0 {
+		return h
 	}
 
-	l := h.Logger
-	l.Context = e.Value()
+	h2 := *h
+	e := NewContext(append([]byte(nil), h.context...))
+	for _, attr := range attrs {
+		if slogAppendGroups(e, h2.grouping, attr) {
+			h2.depth += len(h2.grouping)
+			h2.grouping = nil
+		}
+	}
+	h2.context = e.buf
 
-	return &slogHandler{l}
+	return &h2
 }
 
 func (h *slogHandler) WithGroup(name string) slog.Handler {
-	return h
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.grouping = append(h.grouping[:len(h.grouping):len(h.grouping)], name)
+
+	return &h2
+}
+
+// slogAppendGroups opens the pending groups and appends attr to entry,
+// it reports whether the groups were opened.
+func slogAppendGroups(e *Entry, grouping []string, attr slog.Attr) bool {
+	if len(grouping) == 0 {
+		slogAppendAttr(e, attr)
+		return false
+	}
+
+	// each group opens with a leading comma of next field, e.g.
+	// `,"a":,"b":,"c":1` became `,"a":{"b":{"c":1`
+	mark := len(e.buf)
+	for _, name := range grouping {
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, name...)
+		e.buf = append(e.buf, '"', ':')
+	}
+	n := len(e.buf)
+	slogAppendAttr(e, attr)
+	if len(e.buf) == n {
+		e.buf = e.buf[:mark]
+		return false
+	}
+	for i, j := mark, 0; j < len(grouping); j++ {
+		i += len(grouping[j]) + 4
+		e.buf[i] = '{'
+	}
+	return true
+}
+
+// slogAppendAttr appends attr to entry without allocations for the common kinds.
+func slogAppendAttr(e *Entry, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		e.Str(attr.Key, attr.Value.String())
+	case slog.KindInt64:
+		e.Int64(attr.Key, attr.Value.Int64())
+	case slog.KindUint64:
+		e.Uint64(attr.Key, attr.Value.Uint64())
+	case slog.KindFloat64:
+		e.Float64(attr.Key, attr.Value.Float64())
+	case slog.KindBool:
+		e.Bool(attr.Key, attr.Value.Bool())
+	case slog.KindDuration:
+		e.Dur(attr.Key, attr.Value.Duration())
+	case slog.KindTime:
+		e.Time(attr.Key, attr.Value.Time())
+	case slog.KindGroup:
+		attrs := attr.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		if attr.Key == "" {
+			// inline the group attrs
+			for _, a := range attrs {
+				slogAppendAttr(e, a)
+			}
+			return
+		}
+		mark := len(e.buf)
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, attr.Key...)
+		e.buf = append(e.buf, '"', ':')
+		n := len(e.buf)
+		for _, a := range attrs {
+			slogAppendAttr(e, a)
+		}
+		if len(e.buf) == n {
+			e.buf = e.buf[:mark]
+			return
+		}
+		e.buf[n] = '{'
+		e.buf = append(e.buf, '}')
+	default:
+		e.Any(attr.Key, attr.Value.Any())
+	}
+}
+
+// SlogHandler wraps the Logger to provide slog.Handler, the caller of
+// entries is reported from slog.Record when Caller is not zero.
+func (l *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{logger: *l}
 }
 
 // Slog wraps the Logger to provide *slog.Logger
 func (l *Logger) Slog() *slog.Logger {
-	logger := *l
-	switch {
-	case logger.Caller > 0:
-		logger.Caller += 3
-	case logger.Caller < 0:
-		logger.Caller -= 3
-	}
-	return slog.New(&slogHandler{logger})
+	return slog.New(l.SlogHandler())
 }
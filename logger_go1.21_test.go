@@ -0,0 +1,119 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var b bytes.Buffer
+	logger := (&Logger{
+		Level:  InfoLevel,
+		Caller: 1,
+		Writer: IOWriter{&b},
+	}).Slog()
+
+	logger.Debug("hello from slog debug")
+	if b.Len() != 0 {
+		t.Errorf("slog debug should be filtered out: %s", b.String())
+	}
+
+	logger.With("a", 1).
+		WithGroup("g").
+		WithGroup("empty").
+		Info("hello from slog info",
+			"str", "foo",
+			"int", -42,
+			"uint", uint64(42),
+			"float", 1.5,
+			"bool", true,
+			"dur", time.Second,
+			"err", errors.New("test error"),
+			slog.Group("sub", "x", 1, slog.Group("none")),
+			slog.Group("", "inline", "yes"),
+		)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("slog handler output invalid json %s: %+v", b.String(), err)
+	}
+	if m["level"] != "info" || m["message"] != "hello from slog info" || m["a"] != float64(1) {
+		t.Errorf("slog handler output mismatched: %s", b.String())
+	}
+	if !strings.HasPrefix(m["caller"].(string), "logger_go1.21_test.go:") {
+		t.Errorf("slog handler caller should be reported from record: %s", b.String())
+	}
+	want := `"g":{"empty":{"str":"foo","int":-42,"uint":42,"float":1.5,"bool":true,"dur":1000,"err":"test error","sub":{"x":1},"inline":"yes"}}`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("slog handler output %s should contains %s", b.String(), want)
+	}
+}
+
+func TestSlogHandlerGroup(t *testing.T) {
+	var b bytes.Buffer
+	logger := (&Logger{
+		Level:  TraceLevel,
+		Writer: IOWriter{&b},
+	}).Slog()
+
+	for _, c := range []struct {
+		Logger *slog.Logger
+		Level  slog.Level
+		Want   string
+	}{
+		{logger.WithGroup("g"), slog.LevelWarn, `{"level":"warn","message":"empty group"}`},
+		{logger.WithGroup("g").With("a", 1).WithGroup("h"), slog.LevelError, `{"level":"error","g":{"a":1},"message":"empty group"}`},
+		{logger.WithGroup("g").With("a", 1).WithGroup("h").With("b", 2), slog.LevelDebug - 1, `{"level":"trace","g":{"a":1,"h":{"b":2}},"message":"empty group"}`},
+	} {
+		b.Reset()
+		c.Logger.Log(context.Background(), c.Level, "empty group")
+		if s := b.String(); s[strings.Index(s, `,"level"`)+1:] != c.Want[1:]+"\n" {
+			t.Errorf("slog handler output got %s want %s", s, c.Want)
+		}
+	}
+}
+
+func BenchmarkSlogHandler(b *testing.B) {
+	logger := (&Logger{
+		Level:  InfoLevel,
+		Writer: IOWriter{io.Discard},
+	}).Slog().With("a", 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("hello from slog", "foo", "bar", "n", 42)
+	}
+}
+
+func TestSlogHandlerSampler(t *testing.T) {
+	var sampler countSampler
+	var b bytes.Buffer
+	h := (&Logger{
+		Level:   InfoLevel,
+		Sampler: &sampler,
+		Writer:  IOWriter{&b},
+	}).SlogHandler()
+
+	for i := 0; i < 3; i++ {
+		_ = h.Enabled(context.Background(), slog.LevelInfo)
+	}
+	if sampler != 0 {
+		t.Errorf("slog handler enabled must not sample, got %d samples", sampler)
+	}
+
+	_ = h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hello sampler", 0))
+	if sampler != 1 || !strings.Contains(b.String(), `"message":"hello sampler"`) {
+		t.Errorf("slog handler handle must sample once, got %d samples: %s", sampler, b.String())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	stdLog "log"
 	"net"
+	"net/netip"
 	"os"
 	"strings"
 	"testing"
@@ -251,6 +252,252 @@ func TestLoggerObject(t *testing.T) {
 	logger.Info().EmbedObject(nilIface).Msg("this is a null_object_2 test")
 }
 
+func TestLoggerGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:      ParseLevel("debug"),
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	logger.Info().Group("http", func(e *Entry) {
+		e.Str("method", "GET").Int("status", 200).Group("client", func(e *Entry) {
+			e.Str("ip", "1.2.3.4")
+		})
+	}).Group("empty", func(e *Entry) {}).Object("obj", ObjectFunc(func(e *Entry) {
+		e.Bool("ok", true)
+	})).Msg("group")
+
+	want := `"level":"info","http":{"method":"GET","status":200,"client":{"ip":"1.2.3.4"}},"empty":{},"obj":{"ok":true},"message":"group"}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("group output got %s, want %s", got, want)
+	}
+
+	var entry *Entry
+	if entry.Group("nil", func(e *Entry) { t.Errorf("group of nil entry must not be called") }) != nil {
+		t.Errorf("group of nil entry must return nil")
+	}
+}
+
+func TestLoggerLazy(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:      InfoLevel,
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	called := 0
+	logger.Debug().Lazy("dump", func() interface{} {
+		called++
+		return 1
+	}).LazyStr("str", func() string {
+		called++
+		return "x"
+	}).Msg("filtered")
+	if called != 0 || buf.Len() != 0 {
+		t.Errorf("lazy fields of filtered entry must not be evaluated")
+	}
+
+	logger.Info().Lazy("dump", func() interface{} {
+		called++
+		return 42
+	}).LazyStr("str", func() string {
+		called++
+		return "x"
+	}).Msg("lazy")
+	if want := `"dump":42,"str":"x","message":"lazy"}` + "\n"; called != 2 || !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("lazy output got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestLoggerNetAddrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	_, ipv6Net, _ := net.ParseCIDR("2001:db8::/32")
+	logger.Info().
+		IPAddr("ip6", net.ParseIP("2001:4860:4860::8888")).
+		IPAddr("ip4", net.ParseIP("1.2.3.4")).
+		IPPrefix("ip6_prefix", *ipv6Net).
+		IPPrefix("ip4_prefix", net.IPNet{IP: net.IP{1, 2, 3, 0}, Mask: net.CIDRMask(120, 128)}).
+		IPPrefix("ip4_mask", net.IPNet{IP: net.IP{1, 2, 3, 0}, Mask: net.IPMask{255, 0, 255, 0}}).
+		MACAddr("mac", net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x53, 0x01}).
+		Msg("")
+
+	want := `"ip6":"2001:4860:4860::8888","ip4":"1.2.3.4","ip6_prefix":"2001:db8::/32","ip4_prefix":"1.2.3.0/24","ip4_mask":"1.2.3.0/ff00ff00","mac":"00:00:5e:00:53:01"}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("net addrs output got %s, want %s", got, want)
+	}
+
+	logger.Writer = IOWriter{io.Discard}
+	ip := net.ParseIP("2001:4860:4860::8888")
+	if n := testing.AllocsPerRun(100, func() {
+		logger.Info().IPAddr("ip", ip).IPPrefix("prefix", *ipv6Net).Msg("")
+	}); n != 0 {
+		t.Errorf("net addrs must be zero allocation, got %v", n)
+	}
+}
+
+func TestLoggerDurationFormat(t *testing.T) {
+	d := 1500*time.Millisecond + 500*time.Microsecond
+	cases := []struct {
+		Format string
+		Want   string
+	}{
+		{"", `"d":1500.500000,"ds":[1500.500000,-1000],"diff":1500.500000,"secs":1.5005,"millis":1500}`},
+		{DurationFormatSeconds, `"d":1.5005,"ds":[1.5005,-1],"diff":1.5005,"secs":1.5005,"millis":1500}`},
+		{DurationFormatMillis, `"d":1500,"ds":[1500,-1000],"diff":1500,"secs":1.5005,"millis":1500}`},
+		{DurationFormatNanos, `"d":1500500000,"ds":[1500500000,-1000000000],"diff":1500500000,"secs":1.5005,"millis":1500}`},
+		{DurationFormatString, `"d":"1.5005s","ds":["1.5005s","-1s"],"diff":"1.5005s","secs":1.5005,"millis":1500}`},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		logger := Logger{
+			TimeFormat:     TimeFormatUnix,
+			DurationFormat: c.Format,
+			Writer:         IOWriter{&buf},
+		}
+		now := time.Now()
+		logger.Info().
+			Dur("d", d).
+			Durs("ds", []time.Duration{d, -time.Second}).
+			TimeDiff("diff", now.Add(d), now).
+			DurSeconds("secs", d).
+			DurMillis("millis", d).
+			Msg("")
+		if got := buf.String(); !strings.HasSuffix(got, c.Want+"\n") {
+			t.Errorf("DurationFormat=%q got %s, want %s", c.Format, got, c.Want)
+		}
+	}
+}
+
+type testTextMarshaler struct {
+	s   string
+	err error
+}
+
+func (m *testTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte(m.s), m.err
+}
+
+func (m *testTextMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(m.s), m.err
+}
+
+func TestLoggerMarshalers(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	var nilMarshaler *testTextMarshaler
+	logger.Info().
+		TextMarshaler("text", &testTextMarshaler{s: "a\"b"}).
+		TextMarshaler("text_err", &testTextMarshaler{err: errors.New("boom")}).
+		TextMarshaler("text_nil", nilMarshaler).
+		TextMarshaler("ip", netip.MustParseAddr("1.2.3.4")).
+		JSONMarshaler("json", &testTextMarshaler{s: "{\n  \"a\": [1, 2]\n}"}).
+		JSONMarshaler("json_invalid", &testTextMarshaler{s: "{"}).
+		JSONMarshaler("json_err", &testTextMarshaler{err: errors.New("boom")}).
+		JSONMarshaler("json_nil", nil).
+		Msg("")
+
+	want := `"text":"a\"b","text_err":"marshaling error: boom","text_nil":null,"ip":"1.2.3.4","json":{"a":[1,2]},"json_invalid":"marshaling error: invalid json","json_err":"marshaling error: boom","json_nil":null}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("marshalers output got %s, want %s", got, want)
+	}
+}
+
+type testJoinError []error
+
+func (e testJoinError) Error() string   { return "joined" }
+func (e testJoinError) Unwrap() []error { return e }
+
+func TestLoggerErrChain(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     IOWriter{&buf},
+	}
+
+	base := errors.New("base")
+	wrapped := fmt.Errorf("wrap: %w", base)
+	joined := testJoinError{wrapped, errors.New("other")}
+
+	logger.Info().ErrChain("chain", fmt.Errorf("top: %w", joined)).ErrChain("nil", nil).Msg("")
+
+	want := `"chain":[{"type":"*fmt.wrapError","message":"top: joined"},{"type":"log.testJoinError","message":"joined"},` +
+		`{"type":"*fmt.wrapError","message":"wrap: base"},{"type":"*errors.errorString","message":"base"},` +
+		`{"type":"*errors.errorString","message":"other"}],"nil":null}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("error chain output got %s, want %s", got, want)
+	}
+}
+
+func TestLoggerHooks(t *testing.T) {
+	var buf bytes.Buffer
+	var count int
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Hooks: []Hook{
+			HookFunc(func(e *Entry, msg string) bool {
+				count++
+				return msg != "noisy"
+			}),
+			HookFunc(func(e *Entry, msg string) bool {
+				e.Str("host", "localhost")
+				if msg == "upgrade" {
+					e.Level = WarnLevel
+				}
+				return true
+			}),
+		},
+		Writer: IOWriter{&buf},
+	}
+
+	logger.Info().Msg("hello")
+	logger.Info().Msg("noisy")
+	logger.Info().Msgf("noisy")
+	logger.Info().Msgs("hello", "world")
+	logger.Info().Msgf("upgrade")
+	logger.Debug().Msg("")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if count != 6 || len(lines) != 4 {
+		t.Fatalf("hooks must run 6 times and veto 2 entries, got %d runs and output %s", count, buf.String())
+	}
+	for i, want := range []string{
+		`"host":"localhost","message":"hello"}`,
+		`"host":"localhost","message":"helloworld"}`,
+		`"host":"localhost","message":"upgrade"}`,
+		`"host":"localhost"}`,
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("hooked entry %d got %s, want %s", i, lines[i], want)
+		}
+	}
+
+	levels := &MultiLevelWriter{WarnWriter: IOWriter{&buf}}
+	logger.Writer = levels
+	buf.Reset()
+	logger.Info().Msg("upgrade")
+	if buf.Len() == 0 {
+		t.Errorf("hook must change the level of entry to warn")
+	}
+
+	logger.Hooks = nil
+	logger.Writer = IOWriter{io.Discard}
+	if n := testing.AllocsPerRun(100, func() { logger.Info().Str("a", "b").Msg("hello") }); n != 0 {
+		t.Errorf("logger without hooks must be zero allocation, got %v", n)
+	}
+}
+
 func TestLoggerLog(t *testing.T) {
 	logger := Logger{
 		Level: ParseLevel("debug"),
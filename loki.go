@@ -0,0 +1,196 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LokiWriter is a Writer that pushes entries in batches to Grafana Loki via the push api.
+type LokiWriter struct {
+	// URL specifies the push api url, uses "http://localhost:3100/loki/api/v1/push" if empty.
+	URL string
+
+	// Labels specifies the static labels of streams, e.g. "app", "env".
+	Labels map[string]string
+
+	// LabelFields specifies the entry fields used as labels of streams, e.g. "level".
+	LabelFields []string
+
+	// TenantID specifies the tenant of multi-tenant Loki, sent as X-Scope-OrgID header.
+	TenantID string
+
+	// Headers specifies the extra http headers of requests, e.g. authorization.
+	Headers map[string]string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 1000 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once    sync.Once
+	batcher batcher
+	url     string
+	header  http.Header
+	labels  []byte
+}
+
+func (w *LokiWriter) init() {
+	w.once.Do(func() {
+		w.url = w.URL
+		if w.url == "" {
+			w.url = "http://localhost:3100/loki/api/v1/push"
+		}
+
+		w.header = http.Header{"Content-Type": []string{"application/json"}}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+		if w.TenantID != "" {
+			w.header.Set("X-Scope-OrgID", w.TenantID)
+		}
+		for k, v := range w.Headers {
+			w.header.Set(k, v)
+		}
+
+		keys := make([]string, 0, len(w.Labels))
+		for k := range w.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			w.labels = append(w.labels, ',')
+			w.labels = jsonAppendString(w.labels, k)
+			w.labels = append(w.labels, ':')
+			w.labels = jsonAppendString(w.labels, w.Labels[k])
+		}
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 1000
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *LokiWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *LokiWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *LokiWriter) send(b *batch) error {
+	// group entries by the encoded labels of stream
+	var streams [][]byte
+	var members [][]int
+	label := make([]byte, 0, 128)
+	for i := 0; i < b.Len(); i++ {
+		entry := b.Entry(i)
+		label = append(label[:0], w.labels...)
+		for _, field := range w.LabelFields {
+			value := jsonGetField(entry, field)
+			if len(value) == 0 || string(value) == `""` {
+				continue
+			}
+			label = append(label, ',')
+			label = jsonAppendString(label, field)
+			label = append(label, ':')
+			if value[0] == '"' {
+				// the json string is already escaped, copy it as is.
+				label = append(label, value...)
+			} else {
+				label = jsonAppendBytes(label, value)
+			}
+		}
+		j := 0
+		for ; j < len(streams); j++ {
+			if bytes.Equal(streams[j], label) {
+				break
+			}
+		}
+		if j == len(streams) {
+			streams = append(streams, append([]byte(nil), label...))
+			members = append(members, nil)
+		}
+		members[j] = append(members[j], i)
+	}
+
+	buf := bbpool.Get().(*bb)
+	buf.B = buf.B[:0]
+	defer func() {
+		if cap(buf.B) <= bbcap {
+			bbpool.Put(buf)
+		}
+	}()
+
+	dst := append(buf.B, "{\"streams\":["...)
+	for j, stream := range streams {
+		if j > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, "{\"stream\":{"...)
+		if len(stream) > 0 {
+			dst = append(dst, stream[1:]...)
+		}
+		dst = append(dst, "},\"values\":["...)
+		for k, i := range members[j] {
+			if k > 0 {
+				dst = append(dst, ',')
+			}
+			entry := b.Entry(i)
+			ts, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+			if !ok {
+				ts = b.times[i]
+			}
+			dst = append(dst, '[', '"')
+			dst = strconv.AppendInt(dst, ts, 10)
+			dst = append(dst, '"', ',')
+			dst = jsonAppendBytes(dst, bytes.TrimRight(entry, "\n"))
+			dst = append(dst, ']')
+		}
+		dst = append(dst, "]}"...)
+	}
+	dst = append(dst, "]}"...)
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer func() {
+			if cap(z.B) <= bbcap {
+				bbpool.Put(z)
+			}
+		}()
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	return httpSend(w.Client, http.MethodPost, w.url, w.header, body)
+}
+
+var _ Writer = (*LokiWriter)(nil)
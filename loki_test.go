@@ -0,0 +1,115 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLokiWriter(t *testing.T) {
+	type push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+
+	pushes := make(chan push, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("X-Scope-OrgID") != "tenant1" || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("loki writer unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("loki writer gzip error: %+v", err)
+			return
+		}
+		var p push
+		if err := json.NewDecoder(zr).Decode(&p); err != nil {
+			t.Errorf("loki writer json error: %+v", err)
+		}
+		pushes <- p
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := &LokiWriter{
+		URL:         server.URL + "/loki/api/v1/push",
+		Labels:      map[string]string{"app": "test"},
+		LabelFields: []string{"level"},
+		TenantID:    "tenant1",
+		Gzip:        true,
+	}
+	logger := Logger{Writer: w}
+
+	logger.Info().Str("foo", "bar").Msg("hello loki")
+	logger.Error().Msg("error \"quoted\"")
+	logger.Info().Msg("hello again")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("loki writer close error: %+v", err)
+	}
+
+	p := <-pushes
+	if len(p.Streams) != 2 {
+		t.Fatalf("loki writer should push 2 streams: %+v", p)
+	}
+	info, errs := p.Streams[0], p.Streams[1]
+	if info.Stream["app"] != "test" || info.Stream["level"] != "info" || len(info.Values) != 2 {
+		t.Errorf("loki writer info stream mismatch: %+v", info)
+	}
+	if errs.Stream["level"] != "error" || len(errs.Values) != 1 {
+		t.Errorf("loki writer error stream mismatch: %+v", errs)
+	}
+	if len(info.Values[0][0]) != 19 || !strings.HasSuffix(info.Values[0][0], "000000") {
+		t.Errorf("loki writer timestamp should be nanoseconds of entry time: %s", info.Values[0][0])
+	}
+	if !strings.HasSuffix(info.Values[0][1], `"foo":"bar","message":"hello loki"}`) {
+		t.Errorf("loki writer line mismatch: %s", info.Values[0][1])
+	}
+	if !strings.HasSuffix(errs.Values[0][1], `"message":"error \"quoted\""}`) {
+		t.Errorf("loki writer line mismatch: %s", errs.Values[0][1])
+	}
+}
+
+func TestLokiWriterLabelEscape(t *testing.T) {
+	type push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+		} `json:"streams"`
+	}
+
+	pushes := make(chan push, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var p push
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("loki writer json error: %+v", err)
+		}
+		pushes <- p
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := &LokiWriter{
+		URL:         server.URL,
+		LabelFields: []string{"path", "n"},
+	}
+	logger := Logger{Writer: w}
+
+	logger.Info().Str("path", `C:\tmp\"x"`).Int("n", 42).Msg("hello loki")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("loki writer close error: %+v", err)
+	}
+
+	p := <-pushes
+	if len(p.Streams) != 1 {
+		t.Fatalf("loki writer should push 1 stream: %+v", p)
+	}
+	if s := p.Streams[0].Stream; s["path"] != `C:\tmp\"x"` || s["n"] != "42" {
+		t.Errorf("loki writer label values mismatch: %+v", s)
+	}
+}
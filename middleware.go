@@ -0,0 +1,246 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"time"
+)
+
+// HTTPMiddleware is a middleware of net/http which logs the completion of requests with the
+// fields remote_addr, method, path, proto, status, bytes, referer, user_agent and duration,
+// which are read by AccessLogFormatter, e.g.
+//
+//	http.ListenAndServe(":8080", (&log.HTTPMiddleware{Exclude: []string{"/healthz"}}).Handler(mux))
+//
+// The logger is associated with the request context, so the handlers can log by Ctx(r.Context()).
+// Handler is a standard middleware of chi and echo.WrapMiddleware, and Log is for the others.
+type HTTPMiddleware struct {
+	// Logger specifies the logger of requests, uses DefaultLogger if nil.
+	Logger *Logger
+
+	// Level specifies the level of requests, uses InfoLevel if zero. The 5xx responses are
+	// logged at ErrorLevel at least.
+	Level Level
+
+	// Routes specifies the levels of the paths matching the patterns of path.Match, which
+	// override Level, e.g. {"/api/debug/*": DebugLevel}.
+	Routes map[string]Level
+
+	// Exclude specifies the patterns of path.Match of paths which are not logged, e.g. the
+	// health checks "/healthz" and "/readyz".
+	Exclude []string
+
+	// RequestHeaders specifies the request headers to log in the request_headers field.
+	RequestHeaders []string
+
+	// ResponseHeaders specifies the response headers to log in the response_headers field.
+	ResponseHeaders []string
+
+	// MaxBodySize specifies the max bytes of the request_body and response_body fields,
+	// the bodies are not logged if zero.
+	MaxBodySize int
+}
+
+// Handler returns an http.Handler which logs the requests of next.
+func (m *HTTPMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		logger := m.Logger
+		if logger == nil {
+			logger = &DefaultLogger
+		}
+
+		for _, pattern := range m.Exclude {
+			if ok, _ := path.Match(pattern, r.URL.Path); ok {
+				next.ServeHTTP(rw, r.WithContext(logger.WithContext(r.Context())))
+				return
+			}
+		}
+
+		start := timeNow()
+
+		var reqBody *limitedBuffer
+		if m.MaxBodySize > 0 && r.Body != nil && r.Body != http.NoBody {
+			reqBody = &limitedBuffer{max: m.MaxBodySize}
+			r.Body = &teeReadCloser{r.Body, reqBody}
+		}
+		w := &middlewareResponseWriter{ResponseWriter: rw}
+		if m.MaxBodySize > 0 {
+			w.body = &limitedBuffer{max: m.MaxBodySize}
+		}
+
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context())))
+
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		var reqb, respb []byte
+		if reqBody != nil {
+			reqb = reqBody.buf
+		}
+		if w.body != nil {
+			respb = w.body.buf
+		}
+		m.log(r, w.Header(), w.status, w.bytes, timeNow().Sub(start), reqb, respb)
+	})
+}
+
+// Log logs the completion of request r with the response header, status and bytes, it lets
+// the middleware of web frameworks share the fields and options of HTTPMiddleware, e.g. gin
+//
+//	router.Use(func(c *gin.Context) {
+//		start := time.Now()
+//		c.Request = c.Request.WithContext(log.DefaultLogger.WithContext(c.Request.Context()))
+//		c.Next()
+//		m.Log(c.Request, c.Writer.Header(), c.Writer.Status(), int64(c.Writer.Size()), time.Since(start))
+//	})
+//
+// The request and response bodies are not logged by Log.
+func (m *HTTPMiddleware) Log(r *http.Request, header http.Header, status int, bytes int64, duration time.Duration) {
+	for _, pattern := range m.Exclude {
+		if ok, _ := path.Match(pattern, r.URL.Path); ok {
+			return
+		}
+	}
+	m.log(r, header, status, bytes, duration, nil, nil)
+}
+
+func (m *HTTPMiddleware) log(r *http.Request, header http.Header, status int, bytes int64, duration time.Duration, reqBody, respBody []byte) {
+	logger := m.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+
+	level := m.level(r.URL.Path)
+	if status >= 500 && levelLess(level, ErrorLevel) {
+		level = ErrorLevel
+	}
+
+	e := logger.WithLevel(level)
+	if e == nil {
+		return
+	}
+	e = e.Str("remote_addr", r.RemoteAddr).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Str("proto", r.Proto).
+		Int("status", status).
+		Int64("bytes", bytes).
+		Str("referer", r.Referer()).
+		Str("user_agent", r.UserAgent()).
+		Dur("duration", duration)
+	if len(m.RequestHeaders) != 0 {
+		e = e.Dict("request_headers", middlewareHeaders(r.Header, m.RequestHeaders))
+	}
+	if len(m.ResponseHeaders) != 0 && header != nil {
+		e = e.Dict("response_headers", middlewareHeaders(header, m.ResponseHeaders))
+	}
+	if reqBody != nil {
+		e = e.Bytes("request_body", reqBody)
+	}
+	if respBody != nil {
+		e = e.Bytes("response_body", respBody)
+	}
+	e.Msg("")
+}
+
+func (m *HTTPMiddleware) level(p string) Level {
+	for pattern, level := range m.Routes {
+		if ok, _ := path.Match(pattern, p); ok {
+			return level
+		}
+	}
+	if m.Level != 0 {
+		return m.Level
+	}
+	return InfoLevel
+}
+
+func middlewareHeaders(header http.Header, names []string) Context {
+	e := NewContext(nil)
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			e = e.Str(http.CanonicalHeaderKey(name), value)
+		}
+	}
+	return e.Value()
+}
+
+// limitedBuffer keeps the first max bytes written.
+type limitedBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.max - len(b.buf); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		b.buf = append(b.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+type teeReadCloser struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+func (r *teeReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = r.w.Write(p[:n])
+	}
+	return
+}
+
+// middlewareResponseWriter records the status and bytes of response.
+type middlewareResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	body   *limitedBuffer
+}
+
+func (w *middlewareResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *middlewareResponseWriter) Write(p []byte) (n int, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err = w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	if w.body != nil {
+		_, _ = w.body.Write(p[:n])
+	}
+	return
+}
+
+// Flush implements http.Flusher.
+func (w *middlewareResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *middlewareResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("log: http.Hijacker is not implemented")
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *middlewareResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,110 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	var b bytes.Buffer
+	logger := &Logger{Level: DebugLevel, Writer: IOWriter{&b}}
+	m := &HTTPMiddleware{
+		Logger:          logger,
+		Routes:          map[string]Level{"/debug/*": DebugLevel},
+		Exclude:         []string{"/healthz"},
+		RequestHeaders:  []string{"x-request-id"},
+		ResponseHeaders: []string{"Content-Type"},
+		MaxBodySize:     4,
+	}
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Ctx(r.Context()) != logger {
+			t.Errorf("http middleware must associate the logger with context")
+		}
+		_, _ = io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello world"))
+		}
+	}))
+
+	for _, path := range []string{"/hello", "/healthz", "/debug/pprof", "/fail"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("request body"))
+		req.Header.Set("X-Request-Id", "abc")
+		req.Header.Set("User-Agent", "test")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("http middleware must log 3 requests, not %d: %s", len(lines), b.String())
+	}
+
+	cases := []struct {
+		Level  string
+		Path   string
+		Status int
+		Bytes  int
+	}{
+		{"info", "/hello", 200, 11},
+		{"debug", "/debug/pprof", 200, 11},
+		{"error", "/fail", 500, 5},
+	}
+	for i, c := range cases {
+		var entry struct {
+			Level           string            `json:"level"`
+			Method          string            `json:"method"`
+			Path            string            `json:"path"`
+			Status          int               `json:"status"`
+			Bytes           int               `json:"bytes"`
+			UserAgent       string            `json:"user_agent"`
+			RequestHeaders  map[string]string `json:"request_headers"`
+			ResponseHeaders map[string]string `json:"response_headers"`
+			RequestBody     string            `json:"request_body"`
+			ResponseBody    string            `json:"response_body"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("http middleware entry %q unmarshal error: %+v", lines[i], err)
+		}
+		if entry.Level != c.Level || entry.Path != c.Path || entry.Status != c.Status || entry.Bytes != c.Bytes {
+			t.Errorf("http middleware entry %d got %+v, want %+v", i, entry, c)
+		}
+		if entry.Method != "POST" || entry.UserAgent != "test" || entry.RequestHeaders["X-Request-Id"] != "abc" {
+			t.Errorf("http middleware entry %d got %+v", i, entry)
+		}
+		if entry.RequestBody != "requ" || entry.ResponseBody != "hell" && entry.ResponseBody != "oops" {
+			t.Errorf("http middleware entry %d bodies got %q and %q", i, entry.RequestBody, entry.ResponseBody)
+		}
+	}
+}
+
+func TestHTTPMiddlewareLog(t *testing.T) {
+	var b bytes.Buffer
+	m := &HTTPMiddleware{
+		Logger:          &Logger{Writer: IOWriter{&b}},
+		Exclude:         []string{"/healthz"},
+		ResponseHeaders: []string{"Content-Type"},
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	m.Log(httptest.NewRequest(http.MethodGet, "/healthz", nil), header, 200, 2, time.Millisecond)
+	m.Log(httptest.NewRequest(http.MethodGet, "/users", nil), header, 404, 9, 1500*time.Microsecond)
+
+	s := b.String()
+	if strings.Count(s, "\n") != 1 {
+		t.Fatalf("http middleware log must exclude the health checks: %s", s)
+	}
+	for _, v := range []string{`"level":"info"`, `"path":"/users"`, `"status":404`, `"bytes":9`, `"duration":1.5`, `"response_headers":{"Content-Type":"application/json"}`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("http middleware log must contain %s: %s", v, s)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// MmapRingWriter is an Writer that writes the entries to a fixed-size memory-mapped
+// circular file like a flight recorder, the oldest entries are overwritten when the file
+// is full. The mapped pages survive the process crashes, use ReadMmapRing to reconstruct
+// the recent entries after a crash.
+//
+// The file is not synced to the disk, so it does not survive the power loss or the kernel
+// panic. It is only supported on unix platforms.
+type MmapRingWriter struct {
+	// Filename is the file to write logs to, it is reused if it has the same size.
+	Filename string
+
+	// FileMode represents the file's mode and permission bits. The default mode is 0644.
+	FileMode os.FileMode
+
+	// Size specifies the size of ring, uses 16MB if zero.
+	Size int64
+
+	mu   sync.Mutex
+	data []byte
+	ring []byte
+}
+
+// The layout of ring file is the header and the circular records, the header has the magic,
+// the size of ring, the absolute positions of the oldest and next records. Each record is
+// the length, the unix nanoseconds and the entry, a record never wraps around and a wrap
+// marker or less than 4 bytes pads to the end of ring.
+const (
+	mmapRingMagic  = "PHLRING1"
+	mmapRingHeader = 64
+	mmapRingRecord = 12
+	mmapRingWrap   = 0xffffffff
+)
+
+// ErrMmapRingEntryTooLarge is returned if an entry is larger than the ring.
+var ErrMmapRingEntryTooLarge = errors.New("log: entry is larger than the mmap ring")
+
+// WriteEntry implements Writer.
+func (w *MmapRingWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer.
+func (w *MmapRingWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.data == nil {
+		if err = w.open(); err != nil {
+			return
+		}
+	}
+
+	size := uint64(len(w.ring))
+	length := uint64(mmapRingRecord + len(p))
+	if length > size {
+		return 0, ErrMmapRingEntryTooLarge
+	}
+
+	tail, head := w.position(16), w.position(24)
+	pos := head
+	if off := pos % size; size-off < length {
+		pos += size - off
+	}
+
+	// advances the tail over the records to be overwritten before writing
+	for pos+length-tail > size {
+		if tail >= head {
+			tail = pos
+			break
+		}
+		tail = mmapRingNext(w.ring, tail)
+	}
+	w.setPosition(16, tail)
+
+	if off := head % size; pos != head && size-off >= 4 {
+		binary.LittleEndian.PutUint32(w.ring[off:], mmapRingWrap)
+	}
+	off := pos % size
+	binary.LittleEndian.PutUint32(w.ring[off:], uint32(len(p)))
+	binary.LittleEndian.PutUint64(w.ring[off+4:], uint64(timeNow().UnixNano()))
+	copy(w.ring[off+mmapRingRecord:], p)
+	w.setPosition(24, pos+length)
+
+	return len(p), nil
+}
+
+func (w *MmapRingWriter) position(i int) uint64 {
+	return binary.LittleEndian.Uint64(w.data[i:])
+}
+
+func (w *MmapRingWriter) setPosition(i int, pos uint64) {
+	binary.LittleEndian.PutUint64(w.data[i:], pos)
+}
+
+// mmapRingNext returns the absolute position of the record next to pos.
+func mmapRingNext(ring []byte, pos uint64) uint64 {
+	size := uint64(len(ring))
+	off := pos % size
+	if size-off < 4 || binary.LittleEndian.Uint32(ring[off:]) == mmapRingWrap {
+		return pos + size - off
+	}
+	return pos + mmapRingRecord + uint64(binary.LittleEndian.Uint32(ring[off:]))
+}
+
+func (w *MmapRingWriter) open() (err error) {
+	perm := w.FileMode
+	if perm == 0 {
+		perm = 0644
+	}
+	size := w.Size
+	if size <= 0 {
+		size = 16 * 1024 * 1024
+	}
+
+	file, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_RDWR, perm)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return
+	}
+	reuse := fi.Size() == mmapRingHeader+size
+	if !reuse {
+		if err = file.Truncate(0); err != nil {
+			return
+		}
+		if err = file.Truncate(mmapRingHeader + size); err != nil {
+			return
+		}
+	}
+
+	data, err := mmapFile(file, int(mmapRingHeader+size))
+	if err != nil {
+		return
+	}
+
+	if !reuse || string(data[:8]) != mmapRingMagic || binary.LittleEndian.Uint64(data[8:]) != uint64(size) {
+		copy(data, mmapRingMagic)
+		binary.LittleEndian.PutUint64(data[8:], uint64(size))
+		binary.LittleEndian.PutUint64(data[16:], 0)
+		binary.LittleEndian.PutUint64(data[24:], 0)
+	}
+
+	w.data = data
+	w.ring = data[mmapRingHeader:]
+
+	return
+}
+
+// Close implements io.Closer, and unmaps the file.
+func (w *MmapRingWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.data != nil {
+		err = munmapFile(w.data)
+		w.data, w.ring = nil, nil
+	}
+	return
+}
+
+// ReadMmapRing reads the ring file of MmapRingWriter, and returns the entries within the
+// duration d before the last entry in order, or all entries if d is not positive.
+func ReadMmapRing(filename string, d time.Duration) (entries [][]byte, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	if len(data) < mmapRingHeader || string(data[:8]) != mmapRingMagic || binary.LittleEndian.Uint64(data[8:]) != uint64(len(data)-mmapRingHeader) {
+		return nil, errors.New("log: invalid mmap ring file: " + filename)
+	}
+
+	ring := data[mmapRingHeader:]
+	size := uint64(len(ring))
+	tail, head := binary.LittleEndian.Uint64(data[16:]), binary.LittleEndian.Uint64(data[24:])
+	if tail > head || head-tail > size {
+		return nil, errors.New("log: corrupted mmap ring file: " + filename)
+	}
+
+	var times []int64
+	for pos := tail; pos < head; pos = mmapRingNext(ring, pos) {
+		off := pos % size
+		if size-off < 4 || binary.LittleEndian.Uint32(ring[off:]) == mmapRingWrap {
+			continue
+		}
+		length := uint64(binary.LittleEndian.Uint32(ring[off:]))
+		if size-off < mmapRingRecord+length {
+			return nil, errors.New("log: corrupted mmap ring file: " + filename)
+		}
+		times = append(times, int64(binary.LittleEndian.Uint64(ring[off+4:])))
+		entries = append(entries, ring[off+mmapRingRecord:off+mmapRingRecord+length])
+	}
+
+	if d > 0 && len(times) > 0 {
+		since := times[len(times)-1] - int64(d)
+		i := 0
+		for i < len(times) && times[i] < since {
+			i++
+		}
+		entries = entries[i:]
+	}
+
+	return
+}
+
+var _ Writer = (*MmapRingWriter)(nil)
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile returns an error on the platforms without mmap.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errors.New("log: mmap is not supported")
+}
+
+// munmapFile is a no-op on the platforms without mmap.
+func munmapFile(data []byte) error {
+	return nil
+}
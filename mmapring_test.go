@@ -0,0 +1,85 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMmapRingWriter(t *testing.T) {
+	filename := "file-mmapring.log"
+	defer os.Remove(filename)
+
+	w := &MmapRingWriter{Filename: filename, Size: 1000}
+	for i := 0; i < 1000; i++ {
+		if _, err := wlprintf(w, InfoLevel, "hello mmap ring %d\n", i); err != nil {
+			t.Fatalf("mmap ring writer write error: %+v", err)
+		}
+	}
+	if _, err := w.Write(make([]byte, 1000)); err != ErrMmapRingEntryTooLarge {
+		t.Errorf("mmap ring writer must reject the large entry, not %+v", err)
+	}
+	// simulates a crash without Close
+	entries, err := ReadMmapRing(filename, 0)
+	if err != nil {
+		t.Fatalf("read mmap ring error: %+v", err)
+	}
+	if len(entries) == 0 || len(entries) > 1000/len("hello mmap ring 999\n") {
+		t.Fatalf("read mmap ring got %d entries", len(entries))
+	}
+	for i, entry := range entries {
+		if want := fmt.Sprintf("hello mmap ring %d\n", 1000-len(entries)+i); string(entry) != want {
+			t.Fatalf("read mmap ring entry %d must be %q, not %q", i, want, entry)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("mmap ring writer close error: %+v", err)
+	}
+
+	// reopens and continues the ring
+	w = &MmapRingWriter{Filename: filename, Size: 1000}
+	_, _ = wlprintf(w, InfoLevel, "hello mmap ring again\n")
+	w.Close()
+	entries, err = ReadMmapRing(filename, 0)
+	if err != nil {
+		t.Fatalf("read mmap ring error: %+v", err)
+	}
+	if got := string(entries[len(entries)-1]); got != "hello mmap ring again\n" {
+		t.Errorf("mmap ring writer must continue the ring, got %q", got)
+	}
+	if got, want := string(entries[len(entries)-2]), "hello mmap ring 999\n"; got != want {
+		t.Errorf("mmap ring writer must keep the previous entries, got %q", got)
+	}
+}
+
+func TestReadMmapRingDuration(t *testing.T) {
+	filename := "file-mmapring-duration.log"
+	defer os.Remove(filename)
+
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := &MmapRingWriter{Filename: filename, Size: 4096}
+	defer w.Close()
+	for i := 0; i < 10; i++ {
+		timeNow = func() time.Time { return base.Add(time.Duration(i) * time.Second) }
+		_, _ = wlprintf(w, InfoLevel, "%d\n", i)
+	}
+
+	entries, err := ReadMmapRing(filename, 3*time.Second)
+	if err != nil {
+		t.Fatalf("read mmap ring error: %+v", err)
+	}
+	if len(entries) != 4 || string(entries[0]) != "6\n" {
+		t.Errorf("read mmap ring must return the last 3 seconds, got %q", entries)
+	}
+
+	if _, err := ReadMmapRing("mmapring_test.go", 0); err == nil {
+		t.Errorf("read mmap ring must reject the invalid file")
+	}
+}
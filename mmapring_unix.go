@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file to the shared memory.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmapFile unmaps the memory of mmapFile.
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// moduleLevelTable is the copy-on-write table of the levels set by SetLevel.
+type moduleLevelTable struct {
+	exact map[string]Level
+	globs []moduleLevelGlob
+	cache sync.Map // name -> moduleLevelResult
+}
+
+type moduleLevelGlob struct {
+	pattern string
+	level   Level
+}
+
+type moduleLevelResult struct {
+	level Level
+	ok    bool
+}
+
+var moduleLevels struct {
+	mu    sync.Mutex
+	table atomic.Value // *moduleLevelTable
+}
+
+// SetLevel sets the level of loggers whose Name matches the pattern, it takes precedence
+// over the Level of loggers. The pattern is the exact name or a glob of path.Match, e.g.
+// "db", "http.*" or "*". The exact names take precedence over the globs, and the later
+// globs take precedence over the former ones.
+func SetLevel(pattern string, level Level) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	moduleLevels.mu.Lock()
+	defer moduleLevels.mu.Unlock()
+
+	old, _ := moduleLevels.table.Load().(*moduleLevelTable)
+	table := &moduleLevelTable{exact: make(map[string]Level)}
+	if old != nil {
+		for name, level := range old.exact {
+			table.exact[name] = level
+		}
+		for _, glob := range old.globs {
+			if glob.pattern != pattern {
+				table.globs = append(table.globs, glob)
+			}
+		}
+	}
+	if moduleLevelIsGlob(pattern) {
+		table.globs = append(table.globs, moduleLevelGlob{pattern, level})
+	} else {
+		table.exact[pattern] = level
+	}
+	moduleLevels.table.Store(table)
+
+	return nil
+}
+
+// UnsetLevel removes the level of pattern set by SetLevel.
+func UnsetLevel(pattern string) {
+	moduleLevels.mu.Lock()
+	defer moduleLevels.mu.Unlock()
+
+	old, _ := moduleLevels.table.Load().(*moduleLevelTable)
+	if old == nil {
+		return
+	}
+	table := &moduleLevelTable{exact: make(map[string]Level)}
+	for name, level := range old.exact {
+		if name != pattern {
+			table.exact[name] = level
+		}
+	}
+	for _, glob := range old.globs {
+		if glob.pattern != pattern {
+			table.globs = append(table.globs, glob)
+		}
+	}
+	moduleLevels.table.Store(table)
+}
+
+// GetLevel returns the level of logger name set by SetLevel, and reports whether it is set.
+func GetLevel(name string) (Level, bool) {
+	table, _ := moduleLevels.table.Load().(*moduleLevelTable)
+	if table == nil {
+		return noLevel, false
+	}
+	return table.lookup(name)
+}
+
+// Levels returns the patterns and levels set by SetLevel, sorted by pattern.
+func Levels() (patterns []string, levels []Level) {
+	table, _ := moduleLevels.table.Load().(*moduleLevelTable)
+	if table == nil {
+		return
+	}
+	m := make(map[string]Level, len(table.exact)+len(table.globs))
+	for name, level := range table.exact {
+		m[name] = level
+	}
+	for _, glob := range table.globs {
+		m[glob.pattern] = glob.level
+	}
+	for pattern := range m {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		levels = append(levels, m[pattern])
+	}
+	return
+}
+
+// lookup returns the level of name, the results of globs are cached in the table.
+func (t *moduleLevelTable) lookup(name string) (Level, bool) {
+	if level, ok := t.exact[name]; ok {
+		return level, true
+	}
+	if len(t.globs) == 0 {
+		return noLevel, false
+	}
+	if v, ok := t.cache.Load(name); ok {
+		r := v.(moduleLevelResult)
+		return r.level, r.ok
+	}
+	var r moduleLevelResult
+	for i := len(t.globs) - 1; i >= 0; i-- {
+		if ok, _ := path.Match(t.globs[i].pattern, name); ok {
+			r = moduleLevelResult{t.globs[i].level, true}
+			break
+		}
+	}
+	t.cache.Store(name, r)
+	return r.level, r.ok
+}
+
+func moduleLevelIsGlob(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"io"
+	"testing"
+)
+
+func TestModuleLevel(t *testing.T) {
+	defer func() {
+		patterns, _ := Levels()
+		for _, pattern := range patterns {
+			UnsetLevel(pattern)
+		}
+	}()
+
+	db := Logger{Name: "db", Level: InfoLevel, Writer: IOWriter{io.Discard}}
+	pool := Logger{Name: "db.pool", Level: InfoLevel, Writer: IOWriter{io.Discard}}
+	http := Logger{Name: "http", Level: InfoLevel, Writer: IOWriter{io.Discard}}
+
+	if db.Debug() != nil || pool.Debug() != nil {
+		t.Fatalf("named loggers must use its level if no pattern is set")
+	}
+
+	if err := SetLevel("db", DebugLevel); err != nil {
+		t.Fatalf("SetLevel(db) error: %+v", err)
+	}
+	if err := SetLevel("db.*", WarnLevel); err != nil {
+		t.Fatalf("SetLevel(db.*) error: %+v", err)
+	}
+	if err := SetLevel("[", DebugLevel); err == nil {
+		t.Errorf("SetLevel with bad pattern must return error")
+	}
+
+	if db.Debug() == nil {
+		t.Errorf("logger db must log debug after SetLevel")
+	}
+	if pool.Info() != nil || pool.Warn() == nil {
+		t.Errorf("logger db.pool must use the level of db.*")
+	}
+	if http.Debug() != nil || http.Info() == nil {
+		t.Errorf("logger http must use its level")
+	}
+
+	if err := SetLevel("*", ErrorLevel); err != nil {
+		t.Fatalf("SetLevel(*) error: %+v", err)
+	}
+	if level, ok := GetLevel("db.pool"); !ok || level != ErrorLevel {
+		t.Errorf("the later glob must take precedence, got %s %v", level, ok)
+	}
+	if level, ok := GetLevel("db"); !ok || level != DebugLevel {
+		t.Errorf("the exact name must take precedence, got %s %v", level, ok)
+	}
+
+	patterns, levels := Levels()
+	if len(patterns) != 3 || patterns[0] != "*" || levels[0] != ErrorLevel || patterns[1] != "db" || patterns[2] != "db.*" {
+		t.Errorf("Levels returns %v %v", patterns, levels)
+	}
+
+	UnsetLevel("*")
+	UnsetLevel("db.*")
+	if _, ok := GetLevel("db.pool"); ok {
+		t.Errorf("logger db.pool must have no level after UnsetLevel")
+	}
+}
+
+func BenchmarkModuleLevel(b *testing.B) {
+	defer UnsetLevel("bench.*")
+	_ = SetLevel("bench.*", InfoLevel)
+	logger := Logger{Name: "bench.module", Writer: IOWriter{io.Discard}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Debug().Msg("hello")
+	}
+}
@@ -0,0 +1,425 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MQTTWriter is a Writer that publishes entries in batches to a mqtt broker by MQTT 3.1.1,
+// it sends keepalive pings in background, so the will message of broker is only published
+// on the unexpected disconnection.
+type MQTTWriter struct {
+	// Addr specifies the address of mqtt broker, uses "localhost:1883" if empty.
+	Addr string
+
+	// ClientID specifies the client identifier of connection, uses a random id if empty.
+	ClientID string
+
+	// Username and Password specifies the credential of connection.
+	Username string
+	Password string
+
+	// Topic specifies the topic of messages.
+	Topic string
+
+	// LevelTopic determines if appends the level of entry to topic, e.g. "devices/1/logs/info".
+	// The entries without level are published to topic as is.
+	LevelTopic bool
+
+	// QoS specifies the qos of messages, the valid values are 0, 1 and 2.
+	QoS byte
+
+	// Retain determines if the broker retains the last message of topic.
+	Retain bool
+
+	// WillTopic and WillMessage specifies the will message published by broker
+	// on the unexpected disconnection.
+	WillTopic   string
+	WillMessage string
+	WillQoS     byte
+	WillRetain  bool
+
+	// KeepAlive specifies the keepalive interval of connection, uses 60 seconds if zero.
+	KeepAlive time.Duration
+
+	// TLSConfig specifies the tls config of connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Timeout specifies the dial and request timeout, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a batch, uses 100 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Dial specifies the dial function for creating connections, uses net.DialTimeout if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	once    sync.Once
+	batcher batcher
+
+	mu     sync.Mutex
+	conn   net.Conn
+	br     *bufio.Reader
+	id     uint16
+	active time.Time
+	done   chan struct{}
+}
+
+func (w *MQTTWriter) init() {
+	w.once.Do(func() {
+		w.batcher.size = w.BatchSize
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.done = make(chan struct{})
+		go w.ping()
+	})
+}
+
+// Close flushes the pending entries and disconnects from broker.
+func (w *MQTTWriter) Close() (err error) {
+	w.init()
+	err = w.batcher.close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+	if w.conn != nil {
+		// the graceful disconnection discards the will message.
+		_, _ = w.conn.Write([]byte{0xe0, 0x00})
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *MQTTWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *MQTTWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (w *MQTTWriter) keepalive() time.Duration {
+	if w.KeepAlive > 0 {
+		return w.KeepAlive
+	}
+	return 60 * time.Second
+}
+
+// ping sends PINGREQ when the connection is idle for half of keepalive interval.
+func (w *MQTTWriter) ping() {
+	interval := w.keepalive() / 2
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		w.mu.Lock()
+		if w.conn != nil && time.Since(w.active) >= interval {
+			_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+			_, err := w.conn.Write([]byte{0xc0, 0x00})
+			for err == nil {
+				var typ byte
+				if typ, _, err = w.read(); typ == 0xd0 {
+					break
+				}
+			}
+			if err != nil {
+				_ = w.conn.Close()
+				w.conn = nil
+			}
+			w.active = time.Now()
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *MQTTWriter) send(b *batch) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		if err = w.connect(); err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil && w.conn != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	qos := w.QoS
+	if qos > 2 {
+		qos = 2
+	}
+
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	dst := buf.B[:0]
+	ids := make([]uint16, b.Len())
+	for i := 0; i < b.Len(); i++ {
+		entry := bytes.TrimRight(b.Entry(i), "\n")
+		topic := len(w.Topic)
+		var level string
+		if w.LevelTopic && b.levels[i] != noLevel {
+			level = b.levels[i].String()
+			topic += 1 + len(level)
+		}
+		n := 2 + topic + len(entry)
+		if qos > 0 {
+			n += 2
+		}
+		header := byte(0x30) | qos<<1
+		if w.Retain {
+			header |= 0x01
+		}
+		dst = append(dst, header)
+		dst = mqttAppendLength(dst, n)
+		dst = append(dst, byte(topic>>8), byte(topic))
+		dst = append(dst, w.Topic...)
+		if level != "" {
+			dst = append(dst, '/')
+			dst = append(dst, level...)
+		}
+		if qos > 0 {
+			w.id++
+			if w.id == 0 {
+				w.id = 1
+			}
+			ids[i] = w.id
+			dst = append(dst, byte(w.id>>8), byte(w.id))
+		}
+		dst = append(dst, entry...)
+	}
+	buf.B = dst
+
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	w.active = time.Now()
+	if _, err = w.conn.Write(buf.B); err != nil {
+		return
+	}
+	if qos == 0 {
+		return nil
+	}
+
+	// waits PUBACK of qos 1, or PUBREC and PUBCOMP of qos 2.
+	acked := make(map[uint16]bool, len(ids))
+	for pending := len(ids); pending > 0; {
+		typ, body, err := w.read()
+		if err != nil {
+			break
+		}
+		if len(body) < 2 {
+			continue
+		}
+		id := uint16(body[0])<<8 | uint16(body[1])
+		switch typ {
+		case 0x40, 0x70: // PUBACK, PUBCOMP
+			if !acked[id] {
+				acked[id] = true
+				pending--
+			}
+		case 0x50: // PUBREC
+			if _, err = w.conn.Write([]byte{0x62, 0x02, body[0], body[1]}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// keeps the unacknowledged messages for retrying.
+	b.filter(func(i int, _ []byte) bool {
+		return !acked[ids[i]]
+	})
+	if b.Len() > 0 {
+		return errors.New("log: mqtt broker did not acknowledge " + strconv.Itoa(b.Len()) + " messages")
+	}
+	return nil
+}
+
+func (w *MQTTWriter) connect() (err error) {
+	addr := w.Addr
+	if addr == "" {
+		addr = "localhost:1883"
+	}
+
+	if w.Dial != nil {
+		w.conn, err = w.Dial("tcp", addr)
+	} else {
+		w.conn, err = net.DialTimeout("tcp", addr, w.timeout())
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		w.conn = tls.Client(w.conn, config)
+	}
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	w.br = bufio.NewReader(w.conn)
+
+	clientID := w.ClientID
+	if clientID == "" {
+		var id [8]byte
+		for i := range id {
+			id[i] = byte(Fastrandn(256))
+		}
+		clientID = "phuslu-log-" + hexEncode(id[:])
+	}
+
+	// CONNECT: protocol name, level 4, flags, keepalive, and payload.
+	flags := byte(0x02) // clean session
+	payload := mqttAppendString(nil, clientID)
+	if w.WillTopic != "" {
+		flags |= 0x04 | (w.WillQoS&0x03)<<3
+		if w.WillRetain {
+			flags |= 0x20
+		}
+		payload = mqttAppendString(payload, w.WillTopic)
+		payload = mqttAppendString(payload, w.WillMessage)
+	}
+	if w.Username != "" {
+		flags |= 0x80
+		payload = mqttAppendString(payload, w.Username)
+	}
+	if w.Password != "" {
+		flags |= 0x40
+		payload = mqttAppendString(payload, w.Password)
+	}
+	keepalive := int(w.keepalive() / time.Second)
+	if keepalive > 65535 {
+		keepalive = 65535
+	}
+
+	dst := []byte{0x10}
+	dst = mqttAppendLength(dst, 10+len(payload))
+	dst = append(dst, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, flags, byte(keepalive>>8), byte(keepalive))
+	dst = append(dst, payload...)
+	if _, err = w.conn.Write(dst); err != nil {
+		return
+	}
+
+	// CONNACK: session present flag, return code.
+	typ, body, err := w.read()
+	if err != nil {
+		return
+	}
+	if typ != 0x20 || len(body) < 2 {
+		return errors.New("log: mqtt invalid CONNACK packet")
+	}
+	if code := body[1]; code != 0 {
+		err = errors.New("log: mqtt connection refused: " + mqttConnackReason(code))
+		if code == 4 || code == 5 {
+			err = permanentError{err}
+		}
+		return
+	}
+	w.active = time.Now()
+
+	return nil
+}
+
+// read reads a control packet from broker, it returns the packet type and body.
+func (w *MQTTWriter) read() (typ byte, body []byte, err error) {
+	if typ, err = w.br.ReadByte(); err != nil {
+		return
+	}
+	var n, shift int
+	for {
+		var c byte
+		if c, err = w.br.ReadByte(); err != nil {
+			return
+		}
+		n |= int(c&0x7f) << shift
+		if c&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("log: mqtt invalid remaining length")
+		}
+	}
+	body = make([]byte, n)
+	_, err = io.ReadFull(w.br, body)
+	return typ & 0xf0, body, err
+}
+
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return "code " + strconv.Itoa(int(code))
+}
+
+func mqttAppendLength(dst []byte, n int) []byte {
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n > 0 {
+			c |= 0x80
+		}
+		dst = append(dst, c)
+		if n == 0 {
+			return dst
+		}
+	}
+}
+
+func mqttAppendString(dst []byte, s string) []byte {
+	dst = append(dst, byte(len(s)>>8), byte(len(s)))
+	return append(dst, s...)
+}
+
+var _ Writer = (*MQTTWriter)(nil)
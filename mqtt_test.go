@@ -0,0 +1,198 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type mqttPacket struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// mqttBroker accepts one connection, acknowledges the packets of client and records them.
+func mqttBroker(t *testing.T) (net.Listener, chan mqttPacket) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	packets := make(chan mqttPacket, 64)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		for {
+			header, err := br.ReadByte()
+			if err != nil {
+				return
+			}
+			var n, shift int
+			for {
+				c, _ := br.ReadByte()
+				n |= int(c&0x7f) << shift
+				shift += 7
+				if c&0x80 == 0 {
+					break
+				}
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(br, body); err != nil {
+				return
+			}
+			packets <- mqttPacket{header & 0xf0, header & 0x0f, body}
+			switch header & 0xf0 {
+			case 0x10: // CONNECT
+				_, _ = conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+			case 0x30: // PUBLISH
+				qos := (header >> 1) & 0x03
+				if qos == 0 {
+					continue
+				}
+				topic := int(body[0])<<8 | int(body[1])
+				id := body[2+topic : 4+topic]
+				if qos == 1 {
+					_, _ = conn.Write([]byte{0x40, 0x02, id[0], id[1]})
+				} else {
+					_, _ = conn.Write([]byte{0x50, 0x02, id[0], id[1]})
+				}
+			case 0x60: // PUBREL
+				_, _ = conn.Write([]byte{0x70, 0x02, body[0], body[1]})
+			case 0xc0: // PINGREQ
+				_, _ = conn.Write([]byte{0xd0, 0x00})
+			}
+		}
+	}()
+	return ln, packets
+}
+
+func mqttTopic(body []byte) string {
+	n := int(body[0])<<8 | int(body[1])
+	return string(body[2 : 2+n])
+}
+
+func TestMQTTWriter(t *testing.T) {
+	ln, packets := mqttBroker(t)
+	defer ln.Close()
+
+	w := &MQTTWriter{
+		Addr:        ln.Addr().String(),
+		ClientID:    "device-1",
+		Username:    "user",
+		Password:    "pass",
+		Topic:       "devices/1/logs",
+		LevelTopic:  true,
+		QoS:         1,
+		WillTopic:   "devices/1/status",
+		WillMessage: "offline",
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Msg("hello mqtt")
+	logger.Error().Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("mqtt writer close error: %+v", err)
+	}
+
+	connect := <-packets
+	if connect.Type != 0x10 || string(connect.Body[2:6]) != "MQTT" {
+		t.Fatalf("mqtt writer invalid connect packet: %x", connect.Body)
+	}
+	if flags := connect.Body[7]; flags != 0x80|0x40|0x04|0x02 {
+		t.Errorf("mqtt writer connect flags mismatch: %08b", flags)
+	}
+	if id := mqttTopic(connect.Body[10:]); id != "device-1" {
+		t.Errorf("mqtt writer client id mismatch: %s", id)
+	}
+
+	for _, topic := range []string{"devices/1/logs/info", "devices/1/logs/error"} {
+		p := <-packets
+		if p.Type != 0x30 || p.Flags != 0x02 {
+			t.Fatalf("mqtt writer invalid publish packet: %x %x", p.Type, p.Flags)
+		}
+		if s := mqttTopic(p.Body); s != topic {
+			t.Errorf("mqtt writer topic want %s got %s", topic, s)
+		}
+		if payload := p.Body[2+len(topic)+2:]; payload[0] != '{' || payload[len(payload)-1] != '}' {
+			t.Errorf("mqtt writer payload mismatch: %s", payload)
+		}
+	}
+
+	if p := <-packets; p.Type != 0xe0 {
+		t.Errorf("mqtt writer should disconnect gracefully: %x", p.Type)
+	}
+}
+
+func TestMQTTWriterQoS2(t *testing.T) {
+	ln, packets := mqttBroker(t)
+	defer ln.Close()
+
+	w := &MQTTWriter{
+		Addr:  ln.Addr().String(),
+		Topic: "logs",
+		QoS:   2,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello mqtt")
+	if err := w.Close(); err != nil {
+		t.Fatalf("mqtt writer close error: %+v", err)
+	}
+
+	var types []byte
+	for i := 0; i < 4; i++ {
+		select {
+		case p := <-packets:
+			types = append(types, p.Type)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("mqtt writer timeout, got packets %x", types)
+		}
+	}
+	if string(types) != string([]byte{0x10, 0x30, 0x60, 0xe0}) {
+		t.Errorf("mqtt writer qos 2 packets mismatch: %x", types)
+	}
+}
+
+func TestMQTTWriterKeepAlive(t *testing.T) {
+	ln, packets := mqttBroker(t)
+	defer ln.Close()
+
+	w := &MQTTWriter{
+		Addr:      ln.Addr().String(),
+		Topic:     "logs",
+		KeepAlive: 20 * time.Millisecond,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello mqtt")
+
+	timeout := time.After(5 * time.Second)
+	for ping := false; !ping; {
+		select {
+		case p := <-packets:
+			ping = p.Type == 0xc0
+		case <-timeout:
+			t.Fatalf("mqtt writer did not send PINGREQ")
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("mqtt writer close error: %+v", err)
+	}
+}
+
+func TestMQTTAppendLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152, 268435455} {
+		dst := mqttAppendLength(nil, n)
+		var m, shift int
+		for _, c := range dst {
+			m |= int(c&0x7f) << shift
+			shift += 7
+		}
+		if m != n {
+			t.Errorf("mqtt remaining length want %d got %d (%x)", n, m, dst)
+		}
+	}
+}
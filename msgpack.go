@@ -0,0 +1,523 @@
+package log
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+func msgpackAppendNil(dst []byte) []byte {
+	return append(dst, 0xc0)
+}
+
+func msgpackAppendBool(dst []byte, b bool) []byte {
+	if b {
+		return append(dst, 0xc3)
+	}
+	return append(dst, 0xc2)
+}
+
+func msgpackAppendInt(dst []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return msgpackAppendUint(dst, uint64(n))
+	case n >= -32:
+		return append(dst, byte(n))
+	case n >= math.MinInt8:
+		return append(dst, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return append(dst, 0xd1, byte(n>>8), byte(n))
+	case n >= math.MinInt32:
+		return append(dst, 0xd2, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(dst, 0xd3, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func msgpackAppendUint(dst []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(dst, byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, 0xcd, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(dst, 0xce, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(dst, 0xcf, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func msgpackAppendFloat(dst []byte, f float64) []byte {
+	n := math.Float64bits(f)
+	return append(dst, 0xcb, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func msgpackAppendStringHeader(dst []byte, n int) []byte {
+	switch {
+	case n <= 31:
+		return append(dst, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, 0xda, byte(n>>8), byte(n))
+	}
+	return append(dst, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func msgpackAppendString(dst []byte, s string) []byte {
+	dst = msgpackAppendStringHeader(dst, len(s))
+	return append(dst, s...)
+}
+
+func msgpackAppendBytes(dst []byte, b []byte) []byte {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		dst = append(dst, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		dst = append(dst, 0xc5, byte(n>>8), byte(n))
+	default:
+		dst = append(dst, 0xc6, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(dst, b...)
+}
+
+func msgpackAppendArrayHeader(dst []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(dst, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, 0xdc, byte(n>>8), byte(n))
+	}
+	return append(dst, 0xdd, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func msgpackAppendMapHeader(dst []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(dst, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(dst, 0xde, byte(n>>8), byte(n))
+	}
+	return append(dst, 0xdf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// msgpackAppendEventTime appends the EventTime extension of fluent forward protocol.
+func msgpackAppendEventTime(dst []byte, ns int64) []byte {
+	sec, nsec := uint32(ns/1e9), uint32(ns%1e9)
+	return append(dst, 0xd7, 0x00,
+		byte(sec>>24), byte(sec>>16), byte(sec>>8), byte(sec),
+		byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+}
+
+// msgpackAppendJSONString appends the escaped json string without quotes as msgpack string.
+func msgpackAppendJSONString(dst []byte, raw []byte) []byte {
+	escaped := false
+	for _, c := range raw {
+		if c == '\\' {
+			escaped = true
+			break
+		}
+	}
+	if !escaped {
+		dst = msgpackAppendStringHeader(dst, len(raw))
+		return append(dst, raw...)
+	}
+	// unescapes to the tail of dst, then moves it after the header.
+	start := len(dst)
+	dst = jsonUnescape(raw, dst)
+	n := len(dst) - start
+	var header [5]byte
+	h := msgpackAppendStringHeader(header[:0], n)
+	dst = append(dst, h...)
+	copy(dst[start+len(h):], dst[start:start+n])
+	copy(dst[start:], h)
+	return dst
+}
+
+// msgpackAppendJSON appends the raw json value as msgpack.
+func msgpackAppendJSON(dst []byte, raw []byte) []byte {
+	if len(raw) == 0 {
+		return msgpackAppendNil(dst)
+	}
+	switch raw[0] {
+	case '"':
+		if len(raw) < 2 {
+			return msgpackAppendString(dst, "")
+		}
+		return msgpackAppendJSONString(dst, raw[1:len(raw)-1])
+	case 't':
+		return msgpackAppendBool(dst, true)
+	case 'f':
+		return msgpackAppendBool(dst, false)
+	case 'n':
+		return msgpackAppendNil(dst)
+	case '{':
+		n := 0
+		jsonRange(raw, func(key, value []byte) bool {
+			n++
+			return true
+		})
+		dst = msgpackAppendMapHeader(dst, n)
+		jsonRange(raw, func(key, value []byte) bool {
+			dst = msgpackAppendJSONString(dst, key)
+			dst = msgpackAppendJSON(dst, value)
+			return true
+		})
+		return dst
+	case '[':
+		n := 0
+		jsonRangeArray(raw, func(value []byte) bool {
+			n++
+			return true
+		})
+		dst = msgpackAppendArrayHeader(dst, n)
+		jsonRangeArray(raw, func(value []byte) bool {
+			dst = msgpackAppendJSON(dst, value)
+			return true
+		})
+		return dst
+	}
+	if n, err := strconv.ParseInt(b2s(raw), 10, 64); err == nil {
+		return msgpackAppendInt(dst, n)
+	}
+	if f, err := strconv.ParseFloat(b2s(raw), 64); err == nil {
+		return msgpackAppendFloat(dst, f)
+	}
+	return msgpackAppendBytes(dst, raw)
+}
+
+var errMsgpackShort = errors.New("log: msgpack short buffer")
+
+// msgpackDecode decodes a msgpack value from data, it returns the value and the rest of data.
+// The maps are decoded as map[string]interface{}, and the extensions are decoded as []byte.
+func msgpackDecode(data []byte) (v interface{}, rest []byte, err error) {
+	if len(data) == 0 {
+		return nil, data, errMsgpackShort
+	}
+	c := data[0]
+	data = data[1:]
+
+	uintn := func(n int) (u uint64) {
+		if len(data) < n {
+			err = errMsgpackShort
+			return
+		}
+		for i := 0; i < n; i++ {
+			u = u<<8 | uint64(data[i])
+		}
+		data = data[n:]
+		return
+	}
+	bytesn := func(n int) (b []byte) {
+		if err != nil {
+			return
+		}
+		if len(data) < n {
+			err = errMsgpackShort
+			return
+		}
+		b, data = data[:n], data[n:]
+		return
+	}
+	array := func(n int) interface{} {
+		a := make([]interface{}, 0, n)
+		for i := 0; i < n && err == nil; i++ {
+			var e interface{}
+			e, data, err = msgpackDecode(data)
+			a = append(a, e)
+		}
+		return a
+	}
+	dict := func(n int) interface{} {
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n && err == nil; i++ {
+			var k, e interface{}
+			if k, data, err = msgpackDecode(data); err != nil {
+				break
+			}
+			e, data, err = msgpackDecode(data)
+			// the non-string keys are ignored
+			switch key := k.(type) {
+			case string:
+				m[key] = e
+			case []byte:
+				m[string(key)] = e
+			}
+		}
+		return m
+	}
+
+	switch {
+	case c <= 0x7f:
+		v = int64(c)
+	case c >= 0xe0:
+		v = int64(int8(c))
+	case c&0xf0 == 0x80:
+		v = dict(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		v = array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		v = string(bytesn(int(c & 0x1f)))
+	default:
+		switch c {
+		case 0xc0:
+			v = nil
+		case 0xc2:
+			v = false
+		case 0xc3:
+			v = true
+		case 0xc4, 0xc5, 0xc6:
+			v = bytesn(int(uintn(1 << (c - 0xc4))))
+		case 0xc7, 0xc8, 0xc9:
+			n := int(uintn(1 << (c - 0xc7)))
+			v = bytesn(n + 1)
+		case 0xca:
+			v = float64(math.Float32frombits(uint32(uintn(4))))
+		case 0xcb:
+			v = math.Float64frombits(uintn(8))
+		case 0xcc, 0xcd, 0xce, 0xcf:
+			v = uintn(1 << (c - 0xcc))
+		case 0xd0:
+			v = int64(int8(uintn(1)))
+		case 0xd1:
+			v = int64(int16(uintn(2)))
+		case 0xd2:
+			v = int64(int32(uintn(4)))
+		case 0xd3:
+			v = int64(uintn(8))
+		case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+			v = bytesn(1 + 1<<(c-0xd4))
+		case 0xd9, 0xda, 0xdb:
+			v = string(bytesn(int(uintn(1 << (c - 0xd9)))))
+		case 0xdc, 0xdd:
+			v = array(int(uintn(2 << (c - 0xdc))))
+		case 0xde, 0xdf:
+			v = dict(int(uintn(2 << (c - 0xde))))
+		default:
+			err = errors.New("log: msgpack invalid type 0x" + strconv.FormatUint(uint64(c), 16))
+		}
+	}
+	return v, data, err
+}
+
+// MsgpackEncoder is an Encoder that encodes entries to MessagePack maps, the entries
+// are concatenated without separators and can be decoded back by DecodeMsgpack.
+type MsgpackEncoder struct{}
+
+// BeginEntry implements Encoder.
+func (MsgpackEncoder) BeginEntry(dst []byte, n int) []byte { return msgpackAppendMapHeader(dst, n) }
+
+// EndEntry implements Encoder.
+func (MsgpackEncoder) EndEntry(dst []byte) []byte { return dst }
+
+// AppendKey implements Encoder.
+func (MsgpackEncoder) AppendKey(dst []byte, key string) []byte { return msgpackAppendString(dst, key) }
+
+// AppendString implements Encoder.
+func (MsgpackEncoder) AppendString(dst []byte, s string) []byte { return msgpackAppendString(dst, s) }
+
+// AppendInt implements Encoder.
+func (MsgpackEncoder) AppendInt(dst []byte, n int64) []byte { return msgpackAppendInt(dst, n) }
+
+// AppendUint implements Encoder.
+func (MsgpackEncoder) AppendUint(dst []byte, n uint64) []byte { return msgpackAppendUint(dst, n) }
+
+// AppendFloat implements Encoder.
+func (MsgpackEncoder) AppendFloat(dst []byte, f float64) []byte { return msgpackAppendFloat(dst, f) }
+
+// AppendBool implements Encoder.
+func (MsgpackEncoder) AppendBool(dst []byte, b bool) []byte { return msgpackAppendBool(dst, b) }
+
+// AppendNull implements Encoder.
+func (MsgpackEncoder) AppendNull(dst []byte) []byte { return msgpackAppendNil(dst) }
+
+// BeginObject implements Encoder.
+func (MsgpackEncoder) BeginObject(dst []byte, n int) []byte { return msgpackAppendMapHeader(dst, n) }
+
+// EndObject implements Encoder.
+func (MsgpackEncoder) EndObject(dst []byte) []byte { return dst }
+
+// BeginArray implements Encoder.
+func (MsgpackEncoder) BeginArray(dst []byte, n int) []byte { return msgpackAppendArrayHeader(dst, n) }
+
+// EndArray implements Encoder.
+func (MsgpackEncoder) EndArray(dst []byte) []byte { return dst }
+
+var _ Encoder = MsgpackEncoder{}
+
+// DecodeMsgpack appends the json entry of the first msgpack value in data to dst, and
+// returns the rest of data. The order of map keys is kept, the binaries are decoded as
+// strings, the timestamp extensions as RFC3339 strings, and the other extensions as null.
+// A newline is appended if the value is a map, as the entries written by logger.
+func DecodeMsgpack(dst, data []byte) (json, rest []byte, err error) {
+	isMap := len(data) > 0 && (data[0]&0xf0 == 0x80 || data[0] == 0xde || data[0] == 0xdf)
+	json, rest, err = msgpackAppendJSONFrom(dst, data)
+	if err == nil && isMap {
+		json = append(json, '\n')
+	}
+	return
+}
+
+// msgpackAppendJSONFrom appends the first msgpack value in data as json to dst.
+func msgpackAppendJSONFrom(dst, data []byte) ([]byte, []byte, error) {
+	if len(data) == 0 {
+		return dst, data, errMsgpackShort
+	}
+	c := data[0]
+	data = data[1:]
+
+	var err error
+	uintn := func(n int) (u uint64) {
+		if len(data) < n {
+			err = errMsgpackShort
+			return
+		}
+		for i := 0; i < n; i++ {
+			u = u<<8 | uint64(data[i])
+		}
+		data = data[n:]
+		return
+	}
+	bytesn := func(n int) (b []byte) {
+		if err != nil {
+			return
+		}
+		if len(data) < n {
+			err = errMsgpackShort
+			return
+		}
+		b, data = data[:n], data[n:]
+		return
+	}
+	array := func(n int) {
+		dst = append(dst, '[')
+		for i := 0; i < n && err == nil; i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst, data, err = msgpackAppendJSONFrom(dst, data)
+		}
+		dst = append(dst, ']')
+	}
+	dict := func(n int) {
+		dst = append(dst, '{')
+		for i := 0; i < n && err == nil; i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			start := len(dst)
+			if dst, data, err = msgpackAppendJSONFrom(dst, data); err != nil {
+				break
+			}
+			// the non-string keys are quoted
+			if dst[start] != '"' {
+				key := string(dst[start:])
+				dst = jsonAppendString(dst[:start], key)
+			}
+			dst = append(dst, ':')
+			dst, data, err = msgpackAppendJSONFrom(dst, data)
+		}
+		dst = append(dst, '}')
+	}
+	float := func(f float64) {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			dst = jsonAppendString(dst, strconv.FormatFloat(f, 'f', -1, 64))
+			return
+		}
+		dst = strconv.AppendFloat(dst, f, 'f', -1, 64)
+	}
+
+	switch {
+	case c <= 0x7f:
+		dst = strconv.AppendInt(dst, int64(c), 10)
+	case c >= 0xe0:
+		dst = strconv.AppendInt(dst, int64(int8(c)), 10)
+	case c&0xf0 == 0x80:
+		dict(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		if b := bytesn(int(c & 0x1f)); err == nil {
+			dst = jsonAppendBytes(dst, b)
+		}
+	default:
+		switch c {
+		case 0xc0:
+			dst = append(dst, "null"...)
+		case 0xc2:
+			dst = append(dst, "false"...)
+		case 0xc3:
+			dst = append(dst, "true"...)
+		case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+			n := 1 << (c - 0xc4)
+			if c >= 0xd9 {
+				n = 1 << (c - 0xd9)
+			}
+			if b := bytesn(int(uintn(n))); err == nil {
+				dst = jsonAppendBytes(dst, b)
+			}
+		case 0xc7, 0xc8, 0xc9, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+			var n int
+			if c <= 0xc9 {
+				n = int(uintn(1 << (c - 0xc7)))
+			} else {
+				n = 1 << (c - 0xd4)
+			}
+			if ext := bytesn(n + 1); err == nil {
+				dst = msgpackAppendExtJSON(dst, ext)
+			}
+		case 0xca:
+			float(float64(math.Float32frombits(uint32(uintn(4)))))
+		case 0xcb:
+			float(math.Float64frombits(uintn(8)))
+		case 0xcc, 0xcd, 0xce, 0xcf:
+			dst = strconv.AppendUint(dst, uintn(1<<(c-0xcc)), 10)
+		case 0xd0:
+			dst = strconv.AppendInt(dst, int64(int8(uintn(1))), 10)
+		case 0xd1:
+			dst = strconv.AppendInt(dst, int64(int16(uintn(2))), 10)
+		case 0xd2:
+			dst = strconv.AppendInt(dst, int64(int32(uintn(4))), 10)
+		case 0xd3:
+			dst = strconv.AppendInt(dst, int64(uintn(8)), 10)
+		case 0xdc, 0xdd:
+			array(int(uintn(2 << (c - 0xdc))))
+		case 0xde, 0xdf:
+			dict(int(uintn(2 << (c - 0xde))))
+		default:
+			err = errors.New("log: msgpack invalid type 0x" + strconv.FormatUint(uint64(c), 16))
+		}
+	}
+	return dst, data, err
+}
+
+// msgpackAppendExtJSON appends the msgpack extension of type and data as json, it decodes
+// the timestamp extension as RFC3339 string, and the others as null.
+func msgpackAppendExtJSON(dst []byte, ext []byte) []byte {
+	if int8(ext[0]) != -1 {
+		return append(dst, "null"...)
+	}
+	var sec, nsec uint64
+	for _, b := range ext[1:] {
+		sec = sec<<8 | uint64(b)
+	}
+	switch len(ext) - 1 {
+	case 4:
+	case 8:
+		sec, nsec = sec&(1<<34-1), sec>>34
+	case 12:
+		for _, b := range ext[1:5] {
+			nsec = nsec<<8 | uint64(b)
+		}
+		sec = 0
+		for _, b := range ext[5:] {
+			sec = sec<<8 | uint64(b)
+		}
+	default:
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '"')
+	dst = time.Unix(int64(sec), int64(nsec)).UTC().AppendFormat(dst, time.RFC3339Nano)
+	return append(dst, '"')
+}
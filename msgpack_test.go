@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackAppendJSON(t *testing.T) {
+	raw := `{"time":"2019-07-10T05:35:54.277Z","level":"info","n":-42,"u":300,"f":1.5,"ok":true,"no":false,"nil":null,"s":"a\"b\né","obj":{"a":[1,"x",{"b":2}]},"arr":[]}`
+	v, rest, err := msgpackDecode(msgpackAppendJSON(nil, []byte(raw)))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("msgpack decode error: %+v rest=%d", err, len(rest))
+	}
+	want := map[string]interface{}{
+		"time":  "2019-07-10T05:35:54.277Z",
+		"level": "info",
+		"n":     int64(-42),
+		"u":     uint64(300),
+		"f":     1.5,
+		"ok":    true,
+		"no":    false,
+		"nil":   nil,
+		"s":     "a\"b\né",
+		"obj": map[string]interface{}{
+			"a": []interface{}{int64(1), "x", map[string]interface{}{"b": int64(2)}},
+		},
+		"arr": []interface{}{},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("msgpack round trip mismatch:\n got %#v\nwant %#v", v, want)
+	}
+}
+
+func TestMsgpackAppendInt(t *testing.T) {
+	for _, n := range []int64{0, 1, 127, 128, 255, 256, 65535, 65536, 1 << 40, -1, -32, -33, -128, -129, -32768, -32769, -1 << 40} {
+		v, _, err := msgpackDecode(msgpackAppendInt(nil, n))
+		if err != nil {
+			t.Fatalf("msgpack decode %d error: %+v", n, err)
+		}
+		switch v := v.(type) {
+		case int64:
+			if v != n {
+				t.Errorf("msgpack int want %d got %d", n, v)
+			}
+		case uint64:
+			if int64(v) != n {
+				t.Errorf("msgpack int want %d got %d", n, v)
+			}
+		default:
+			t.Errorf("msgpack int %d decoded as %T", n, v)
+		}
+	}
+}
+
+func TestMsgpackAppendString(t *testing.T) {
+	for _, n := range []int{0, 31, 32, 255, 256, 65535, 65536} {
+		s := string(make([]byte, n))
+		v, _, err := msgpackDecode(msgpackAppendString(nil, s))
+		if err != nil || v != s {
+			t.Errorf("msgpack string of %d bytes mismatch: %+v", n, err)
+		}
+	}
+}
+
+func TestMsgpackAppendEventTime(t *testing.T) {
+	data := msgpackAppendEventTime(nil, 1562736954277000001)
+	if len(data) != 10 || data[0] != 0xd7 || data[1] != 0x00 {
+		t.Fatalf("msgpack event time header mismatch: %x", data)
+	}
+	if sec, nsec := binary.BigEndian.Uint32(data[2:]), binary.BigEndian.Uint32(data[6:]); sec != 1562736954 || nsec != 277000001 {
+		t.Errorf("msgpack event time mismatch: %d %d", sec, nsec)
+	}
+}
+
+func TestMsgpackDecodeShort(t *testing.T) {
+	data := msgpackAppendJSON(nil, []byte(`{"foo":"bar","n":[1,2,3]}`))
+	for i := 0; i < len(data); i++ {
+		if _, _, err := msgpackDecode(data[:i]); err != errMsgpackShort {
+			t.Errorf("msgpack decode of %d bytes want errMsgpackShort got %+v", i, err)
+		}
+	}
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Writer: &EncodeWriter{Encoder: MsgpackEncoder{}, Writer: &buf},
+	}
+
+	logger.Info().Str("foo", "bar").Int("n", -42).Uint64("u", 1<<63).Float64("f", 1.5).Bool("ok", true).
+		Strs("strs", []string{"a", "b"}).RawJSON("obj", []byte(`{"a":{"b":null}}`)).Msg("hello \"msgpack\"")
+	logger.Warn().Msg("")
+
+	var lines []byte
+	var err error
+	data := buf.Bytes()
+	for len(data) > 0 {
+		if lines, data, err = DecodeMsgpack(lines, data); err != nil {
+			t.Fatalf("decode msgpack error: %+v", err)
+		}
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(lines), []byte("\n")) {
+		var m map[string]interface{}
+		if err := json.Unmarshal(line, &m); err != nil {
+			t.Fatalf("json unmarshal %s error: %+v", line, err)
+		}
+		entries = append(entries, m)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("msgpack encoder entries: got %d, want 2: %s", len(entries), lines)
+	}
+
+	m := entries[0]
+	delete(m, "time")
+	want := map[string]interface{}{
+		"level":   "info",
+		"foo":     "bar",
+		"n":       float64(-42),
+		"u":       float64(1 << 63),
+		"f":       1.5,
+		"ok":      true,
+		"strs":    []interface{}{"a", "b"},
+		"obj":     map[string]interface{}{"a": map[string]interface{}{"b": nil}},
+		"message": "hello \"msgpack\"",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("msgpack encoder: got %+v, want %+v", m, want)
+	}
+	if entries[1]["level"] != "warn" {
+		t.Errorf("msgpack encoder: got %+v", entries[1])
+	}
+	if !bytes.HasPrefix(lines, []byte(`{"time":`)) {
+		t.Errorf("decode msgpack should keep the order of keys: %s", lines)
+	}
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	cases := []struct {
+		Data []byte
+		JSON string
+	}{
+		{[]byte{0xc0}, `null`},
+		{[]byte{0xcb, 0x7f, 0xf8, 0, 0, 0, 0, 0, 1}, `"NaN"`},
+		{[]byte{0x81, 0x01, 0xa1, 'a'}, `{"1":"a"}` + "\n"},
+		{[]byte{0xc4, 0x02, '"', '\n'}, `"\"\n"`},
+		{[]byte{0xd6, 0xff, 0x5d, 0x25, 0x79, 0x3a}, `"2019-07-10T05:35:54Z"`},
+		{append([]byte{0xd7, 0xff}, 0x42, 0x0a, 0xbd, 0x00, 0x5d, 0x25, 0x79, 0x3a), `"2019-07-10T05:35:54.277Z"`},
+		{[]byte{0xd4, 0x01, 0x00}, `null`},
+	}
+
+	for _, c := range cases {
+		got, rest, err := DecodeMsgpack(nil, c.Data)
+		if err != nil || len(rest) != 0 {
+			t.Errorf("decode msgpack %x error: %+v, rest: %x", c.Data, err, rest)
+		}
+		if string(got) != c.JSON {
+			t.Errorf("decode msgpack %x: got %s, want %s", c.Data, got, c.JSON)
+		}
+	}
+
+	if _, _, err := DecodeMsgpack(nil, []byte{0x82, 0xa1, 'a'}); err != errMsgpackShort {
+		t.Errorf("decode short msgpack should be error: %+v", err)
+	}
+}
+
+func BenchmarkMsgpackEncoder(b *testing.B) {
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer:     &EncodeWriter{Encoder: MsgpackEncoder{}, Writer: io.Discard},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("foo", "bar").Int("n", 42).Msg("hello msgpack")
+	}
+}
@@ -2,6 +2,8 @@ package log
 
 import (
 	"io"
+	"path"
+	"reflect"
 )
 
 // MultiWriter is an alias for MultiLevelWriter
@@ -48,7 +50,7 @@ func (w *MultiLevelWriter) Close() (err error) {
 // WriteEntry implements entryWriter.
 func (w *MultiLevelWriter) WriteEntry(e *Entry) (n int, err error) {
 	var err1 error
-	switch e.Level {
+	switch levelBase(e.Level) {
 	case noLevel, PanicLevel, FatalLevel, ErrorLevel:
 		if w.ErrorWriter != nil {
 			n, err1 = w.ErrorWriter.WriteEntry(e)
@@ -74,7 +76,7 @@ func (w *MultiLevelWriter) WriteEntry(e *Entry) (n int, err error) {
 		}
 	}
 
-	if w.ConsoleWriter != nil && e.Level >= w.ConsoleLevel {
+	if w.ConsoleWriter != nil && !levelLess(e.Level, w.ConsoleLevel) {
 		_, _ = w.ConsoleWriter.WriteEntry(e)
 	}
 
@@ -112,6 +114,39 @@ func (w *MultiEntryWriter) WriteEntry(e *Entry) (n int, err error) {
 
 var _ Writer = (*MultiEntryWriter)(nil)
 
+// LeveledWriter is a Writer that log the entries greater than or equal to Level to Writer,
+// it attaches an independent level to the writers of MultiEntryWriter, e.g.
+//
+//	log.DefaultLogger = log.Logger{Level: log.DebugLevel, Writer: &log.MultiEntryWriter{
+//		&log.ConsoleWriter{},
+//		&log.LeveledWriter{Level: log.InfoLevel, Writer: &log.FileWriter{Filename: "main.log"}},
+//	}}
+type LeveledWriter struct {
+	// Level specifies the minimum level of entries writes to
+	Level Level
+
+	// Writer specifies the writer of entries
+	Writer Writer
+}
+
+// Close implements io.Closer, and closes the underlying Writer.
+func (w *LeveledWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+// WriteEntry implements entryWriter.
+func (w *LeveledWriter) WriteEntry(e *Entry) (n int, err error) {
+	if levelLess(e.Level, w.Level) {
+		return len(e.buf), nil
+	}
+	return w.Writer.WriteEntry(e)
+}
+
+var _ Writer = (*LeveledWriter)(nil)
+
 // MultiIOWriter is an array io.Writer that log to different writers
 type MultiIOWriter []io.Writer
 
@@ -138,3 +173,127 @@ func (w *MultiIOWriter) WriteEntry(e *Entry) (n int, err error) {
 
 	return
 }
+
+// RouteRule is a rule of RouteWriter, an entry matches the rule if it matches all the
+// non-zero conditions of rule.
+type RouteRule struct {
+	// MinLevel specifies the minimum level of matched entries.
+	MinLevel Level
+
+	// MaxLevel specifies the maximum level of matched entries.
+	MaxLevel Level
+
+	// Name specifies the pattern of logger name of matched entries, a glob of path.Match,
+	// e.g. "db" or "http.*".
+	Name string
+
+	// Fields specifies the field values of matched entries, the values are compared with
+	// the strings or the raw json of other types, e.g. {"audit": "true", "component": "db"}.
+	Fields map[string]string
+
+	// Writer specifies the writer of matched entries.
+	Writer Writer
+
+	// Continue determines if the matched entries fall through to the next rules.
+	Continue bool
+}
+
+// Match reports whether the entry matches the rule.
+func (r *RouteRule) Match(e *Entry) bool {
+	if r.MinLevel != 0 && levelLess(e.Level, r.MinLevel) {
+		return false
+	}
+	if r.MaxLevel != 0 && levelLess(r.MaxLevel, e.Level) {
+		return false
+	}
+	if r.Name != "" {
+		if ok, _ := path.Match(r.Name, e.name); !ok {
+			return false
+		}
+	}
+	for key, want := range r.Fields {
+		value := jsonGetField(e.buf, key)
+		if len(value) >= 2 && value[0] == '"' {
+			if jsonString(value) != want {
+				return false
+			}
+		} else if value == nil || b2s(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteWriter is a Writer that routes entries by the rules in order, an entry is written to
+// the first matching rule, and to the next matching rules if the rule continues. The entries
+// matching no rules are written to Default.
+type RouteWriter struct {
+	// Rules specifies the rules of routing.
+	Rules []RouteRule
+
+	// Default specifies the writer of entries matching no rules, the entries are dropped if nil.
+	Default Writer
+}
+
+// Close implements io.Closer, and closes the writers of rules and default once.
+func (w *RouteWriter) Close() (err error) {
+	var closed []Writer
+	for i := 0; i <= len(w.Rules); i++ {
+		writer := w.Default
+		if i < len(w.Rules) {
+			writer = w.Rules[i].Writer
+		}
+		if writer == nil || routeContains(closed, writer) {
+			continue
+		}
+		closed = append(closed, writer)
+		if closer, ok := writer.(io.Closer); ok {
+			if err1 := closer.Close(); err1 != nil {
+				err = err1
+			}
+		}
+	}
+	return
+}
+
+// routeContains reports whether the comparable writer is in writers.
+func routeContains(writers []Writer, writer Writer) bool {
+	if !reflect.TypeOf(writer).Comparable() {
+		return false
+	}
+	for _, w := range writers {
+		if reflect.TypeOf(w) == reflect.TypeOf(writer) && w == writer {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteEntry implements entryWriter.
+func (w *RouteWriter) WriteEntry(e *Entry) (n int, err error) {
+	var err1 error
+	matched := false
+	for i := range w.Rules {
+		rule := &w.Rules[i]
+		if !rule.Match(e) {
+			continue
+		}
+		matched = true
+		if rule.Writer != nil {
+			n, err1 = rule.Writer.WriteEntry(e)
+			if err1 != nil && err == nil {
+				err = err1
+			}
+		}
+		if !rule.Continue {
+			break
+		}
+	}
+
+	if !matched && w.Default != nil {
+		n, err = w.Default.WriteEntry(e)
+	}
+	return
+}
+
+var _ Writer = (*RouteWriter)(nil)
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -186,3 +187,115 @@ func TestMultiEntryWriterError(t *testing.T) {
 		t.Errorf("test close error writer error: %+v", err)
 	}
 }
+
+func TestRouteWriter(t *testing.T) {
+	audit, db, errs, other := &Recorder{}, &Recorder{}, &Recorder{}, &Recorder{}
+	w := &RouteWriter{
+		Rules: []RouteRule{
+			{Fields: map[string]string{"audit": "true"}, Writer: audit},
+			{MinLevel: ErrorLevel, Writer: errs, Continue: true},
+			{Name: "db.*", Writer: db},
+			{Fields: map[string]string{"component": "db"}, Writer: db},
+		},
+		Default: other,
+	}
+
+	logger := Logger{Level: TraceLevel, Writer: w}
+	logger.Info().Bool("audit", true).Msg("login")
+	logger.Error().Str("component", "db").Msg("db failed")
+	logger.Error().Msg("failed")
+	logger.Debug().Str("component", "db").Msg("query")
+	logger.Info().Str("component", "http").Msg("request")
+
+	named := Logger{Level: TraceLevel, Name: "db.pool", Writer: w}
+	named.Info().Msg("pool")
+
+	for _, c := range []struct {
+		r    *Recorder
+		msgs []string
+	}{
+		{audit, []string{"login"}},
+		{errs, []string{"db failed", "failed"}},
+		{db, []string{"db failed", "query", "pool"}},
+		{other, []string{"request"}},
+	} {
+		var msgs []string
+		for _, entry := range c.r.Entries() {
+			msgs = append(msgs, entry.Message)
+		}
+		if len(msgs) != len(c.msgs) {
+			t.Errorf("route writer messages got %q want %q", msgs, c.msgs)
+			continue
+		}
+		for i := range msgs {
+			if msgs[i] != c.msgs[i] {
+				t.Errorf("route writer messages got %q want %q", msgs, c.msgs)
+				break
+			}
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("route writer close error: %+v", err)
+	}
+}
+
+func TestRouteWriterClose(t *testing.T) {
+	file := &FileWriter{Filename: "file-route.log"}
+	defer func() {
+		matches, _ := filepath.Glob("file-route*.log")
+		for _, name := range matches {
+			os.Remove(name)
+		}
+	}()
+	w := &RouteWriter{
+		Rules:   []RouteRule{{MaxLevel: DebugLevel, Writer: file}, {MinLevel: WarnLevel, Writer: file}},
+		Default: &MultiEntryWriter{IOWriter{io.Discard}},
+	}
+	logger := Logger{Level: TraceLevel, Writer: w}
+	logger.Debug().Msg("debug")
+	logger.Info().Msg("info")
+	logger.Warn().Msg("warn")
+
+	if err := w.Close(); err != nil {
+		t.Errorf("route writer close error: %+v", err)
+	}
+
+	data, err := os.ReadFile("file-route.log")
+	if err != nil {
+		t.Fatalf("route writer read file error: %+v", err)
+	}
+	if s := string(data); !strings.Contains(s, `"debug"`) || strings.Contains(s, `"info"`) || !strings.Contains(s, `"warn"`) {
+		t.Errorf("route writer file mismatched: %s", s)
+	}
+}
+
+func TestLeveledWriter(t *testing.T) {
+	console, file, syslog := &Recorder{}, &Recorder{}, &Recorder{}
+	w := &MultiEntryWriter{
+		&LeveledWriter{Level: DebugLevel, Writer: console},
+		&LeveledWriter{Level: InfoLevel, Writer: file},
+		&LeveledWriter{Level: WarnLevel, Writer: syslog},
+	}
+
+	logger := Logger{Level: TraceLevel, Writer: w}
+	logger.Trace().Msg("trace")
+	logger.Debug().Msg("debug")
+	logger.Info().Msg("info")
+	logger.Warn().Msg("warn")
+	logger.Error().Msg("error")
+
+	if n := console.Len(); n != 4 {
+		t.Errorf("leveled writer of debug must log 4 entries: %d", n)
+	}
+	if n := file.Len(); n != 3 || file.Has(DebugLevel, "", nil) {
+		t.Errorf("leveled writer of info must log 3 entries: %d", n)
+	}
+	if n := syslog.Len(); n != 2 || !syslog.Has(WarnLevel, "warn", nil) {
+		t.Errorf("leveled writer of warn must log 2 entries: %d", n)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("leveled writer close error: %+v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSWriter is a Writer that publishes entries in batches to a nats subject, it speaks
+// the nats client protocol natively, and waits the publish acks if JetStream is set.
+type NATSWriter struct {
+	// URL specifies the url of nats server, uses "nats://localhost:4222" if empty.
+	// The user info of url is used as the credential, the tls scheme enables tls.
+	URL string
+
+	// Subject specifies the subject template of messages, the {field} placeholders are
+	// replaced by the value of entry fields, e.g. "logs.{level}.{service}".
+	// The missing fields are replaced by "_".
+	Subject string
+
+	// JetStream determines if waits the publish acks of JetStream for each message.
+	JetStream bool
+
+	// Username and Password specifies the credential of user authentication.
+	Username string
+	Password string
+
+	// Token specifies the token of token authentication.
+	Token string
+
+	// Name specifies the client name of connection, uses "phuslu-log" if empty.
+	Name string
+
+	// TLSConfig specifies the tls config of connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Timeout specifies the dial and request timeout, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a batch, uses 100 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Dial specifies the dial function for creating connections, uses net.DialTimeout if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	once    sync.Once
+	batcher batcher
+
+	// the states below are owned by the goroutine of batcher.
+	conn  net.Conn
+	br    *bufio.Reader
+	inbox string
+}
+
+func (w *NATSWriter) init() {
+	w.once.Do(func() {
+		w.batcher.size = w.BatchSize
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and closes the connection.
+func (w *NATSWriter) Close() (err error) {
+	w.init()
+	err = w.batcher.close()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *NATSWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *NATSWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (w *NATSWriter) send(b *batch) (err error) {
+	if w.conn == nil {
+		if err = w.connect(); err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil && w.conn != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	dst := buf.B[:0]
+	for i := 0; i < b.Len(); i++ {
+		entry := bytes.TrimRight(b.Entry(i), "\n")
+		dst = append(dst, "PUB "...)
+		dst = natsAppendSubject(dst, w.Subject, entry)
+		if w.JetStream {
+			dst = append(dst, ' ')
+			dst = append(dst, w.inbox...)
+			dst = append(dst, '.')
+			dst = strconv.AppendInt(dst, int64(i), 10)
+		}
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, int64(len(entry)), 10)
+		dst = append(dst, "\r\n"...)
+		dst = append(dst, entry...)
+		dst = append(dst, "\r\n"...)
+	}
+	if !w.JetStream {
+		// the PONG of PING confirms the messages are processed by server.
+		dst = append(dst, "PING\r\n"...)
+	}
+	buf.B = dst
+
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	if _, err = w.conn.Write(buf.B); err != nil {
+		return
+	}
+
+	if !w.JetStream {
+		for {
+			op, _, _, err := w.read()
+			if err != nil {
+				return err
+			}
+			if op == "PONG" {
+				return nil
+			}
+		}
+	}
+
+	acks := make([]bool, b.Len())
+	var reason string
+	for pending := b.Len(); pending > 0; {
+		op, args, payload, err := w.read()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+			return err
+		}
+		if op != "MSG" || len(args) == 0 || !strings.HasPrefix(args[0], w.inbox+".") {
+			continue
+		}
+		i, _ := strconv.Atoi(args[0][len(w.inbox)+1:])
+		if i < 0 || i >= len(acks) || acks[i] {
+			continue
+		}
+		pending--
+		if value := jsonGetField(payload, "error"); value != nil {
+			reason = string(jsonFieldString(value, "description"))
+			continue
+		}
+		acks[i] = true
+	}
+
+	// keeps the unacknowledged messages for retrying.
+	b.filter(func(i int, _ []byte) bool {
+		return !acks[i]
+	})
+	if b.Len() > 0 {
+		if reason == "" {
+			reason = "ack timeout"
+		}
+		return errors.New("log: nats jetstream failed to publish " + strconv.Itoa(b.Len()) + " messages: " + reason)
+	}
+
+	return nil
+}
+
+func (w *NATSWriter) connect() (err error) {
+	rawurl := w.URL
+	if rawurl == "" {
+		rawurl = "nats://localhost:4222"
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return permanentError{err}
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	if w.Dial != nil {
+		w.conn, err = w.Dial("tcp", address)
+	} else {
+		w.conn, err = net.DialTimeout("tcp", address, w.timeout())
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	w.br = bufio.NewReader(w.conn)
+
+	// INFO {"server_id":"...","tls_required":false,...}
+	op, _, info, err := w.read()
+	if err != nil {
+		return
+	}
+	if op != "INFO" {
+		return errors.New("log: nats invalid INFO message: " + op)
+	}
+
+	config := w.TLSConfig
+	if config == nil && (u.Scheme == "tls" || string(jsonGetField(info, "tls_required")) == "true") {
+		config = &tls.Config{}
+	}
+	if config != nil {
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName = u.Hostname()
+		}
+		conn := tls.Client(w.conn, config)
+		if err = conn.Handshake(); err != nil {
+			return
+		}
+		w.conn = conn
+		w.br = bufio.NewReader(w.conn)
+	}
+
+	username, password, token := w.Username, w.Password, w.Token
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			username, password = u.User.Username(), p
+		} else {
+			token = u.User.Username()
+		}
+	}
+	name := w.Name
+	if name == "" {
+		name = "phuslu-log"
+	}
+
+	dst := append([]byte(nil), `CONNECT {"verbose":false,"pedantic":false,"lang":"go","version":"1.0","protocol":1,"name":`...)
+	dst = jsonAppendString(dst, name)
+	if username != "" {
+		dst = append(dst, `,"user":`...)
+		dst = jsonAppendString(dst, username)
+		dst = append(dst, `,"pass":`...)
+		dst = jsonAppendString(dst, password)
+	}
+	if token != "" {
+		dst = append(dst, `,"auth_token":`...)
+		dst = jsonAppendString(dst, token)
+	}
+	dst = append(dst, "}\r\n"...)
+
+	if w.JetStream {
+		var id [8]byte
+		for i := range id {
+			id[i] = byte(Fastrandn(256))
+		}
+		w.inbox = "_INBOX." + hexEncode(id[:])
+		dst = append(dst, "SUB "...)
+		dst = append(dst, w.inbox...)
+		dst = append(dst, ".* 1\r\n"...)
+	}
+
+	// the PONG of PING confirms the connection is authorized.
+	dst = append(dst, "PING\r\n"...)
+	if _, err = w.conn.Write(dst); err != nil {
+		return
+	}
+	for {
+		if op, _, _, err = w.read(); err != nil {
+			return
+		}
+		if op == "PONG" {
+			return nil
+		}
+	}
+}
+
+// read reads a protocol message from server, it answers the PING of server, and
+// returns the -ERR of server as error.
+func (w *NATSWriter) read() (op string, args []string, payload []byte, err error) {
+	for {
+		var line string
+		if line, err = w.br.ReadString('\n'); err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, line, _ = strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PING":
+			if _, err = w.conn.Write([]byte("PONG\r\n")); err != nil {
+				return
+			}
+			continue
+		case "+OK":
+			continue
+		case "-ERR":
+			err = errors.New("log: nats server error: " + strings.Trim(line, "'"))
+			if strings.Contains(line, "Authorization") || strings.Contains(line, "Permissions") {
+				err = permanentError{err}
+			}
+			return
+		case "INFO":
+			op, payload = "INFO", []byte(line)
+			return
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			op, args = "MSG", strings.Fields(line)
+			if len(args) < 3 {
+				err = errors.New("log: nats invalid MSG message: " + line)
+				return
+			}
+			var n int
+			if n, err = strconv.Atoi(args[len(args)-1]); err != nil {
+				return
+			}
+			payload = make([]byte, n+2)
+			if _, err = io.ReadFull(w.br, payload); err != nil {
+				return
+			}
+			payload = payload[:n]
+			return
+		default:
+			op = strings.ToUpper(op)
+			return
+		}
+	}
+}
+
+// natsAppendSubject appends the subject of template with the values of entry fields to dst.
+func natsAppendSubject(dst []byte, template string, entry []byte) []byte {
+	for {
+		i := strings.IndexByte(template, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(template[i:], '}')
+		if j < 0 {
+			break
+		}
+		dst = append(dst, template[:i]...)
+		value := jsonFieldString(entry, template[i+1:i+j])
+		if len(value) == 0 {
+			dst = append(dst, '_')
+		}
+		for _, c := range value {
+			// the subject tokens of nats cannot contain whitespaces, dots and wildcards.
+			if c <= ' ' || c == '.' || c == '*' || c == '>' || c == '"' || c == '\\' {
+				c = '_'
+			}
+			dst = append(dst, c)
+		}
+		template = template[i+j+1:]
+	}
+	return append(dst, template...)
+}
+
+var _ Writer = (*NATSWriter)(nil)
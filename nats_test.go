@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type natsMessage struct {
+	Subject string
+	Payload string
+}
+
+// natsServer accepts one connection and records the published messages, it acks the
+// messages with reply subject as JetStream unless the payload contains "reject".
+func natsServer(t *testing.T) (net.Listener, chan natsMessage, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	messages := make(chan natsMessage, 16)
+	connects := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n"))
+		br := bufio.NewReader(conn)
+		sid := ""
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args := strings.Fields(line)
+			switch args[0] {
+			case "CONNECT":
+				connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))
+			case "SUB":
+				sid = args[2]
+			case "PING":
+				_, _ = conn.Write([]byte("PING\r\nPONG\r\n"))
+			case "PUB":
+				n, _ := strconv.Atoi(args[len(args)-1])
+				payload := make([]byte, n+2)
+				if _, err := io.ReadFull(br, payload); err != nil {
+					return
+				}
+				messages <- natsMessage{args[1], string(payload[:n])}
+				if len(args) == 4 {
+					ack := `{"stream":"LOGS","seq":1}`
+					if strings.Contains(string(payload), "reject") {
+						ack = `{"error":{"code":503,"description":"stream unavailable"}}`
+					}
+					_, _ = conn.Write([]byte("MSG " + args[2] + " " + sid + " " + strconv.Itoa(len(ack)) + "\r\n" + ack + "\r\n"))
+				}
+			}
+		}
+	}()
+	return ln, messages, connects
+}
+
+func TestNATSWriter(t *testing.T) {
+	ln, messages, connects := natsServer(t)
+	defer ln.Close()
+
+	w := &NATSWriter{
+		URL:           "nats://alice:secret@" + ln.Addr().String(),
+		Subject:       "logs.{level}.{service}",
+		FlushInterval: 10 * time.Millisecond,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("service", "api.v1").Msg("hello nats")
+	logger.Warn().Msg("no service")
+	if err := w.Close(); err != nil {
+		t.Fatalf("nats writer close error: %+v", err)
+	}
+
+	if connect := <-connects; !strings.Contains(connect, `"user":"alice"`) || !strings.Contains(connect, `"pass":"secret"`) {
+		t.Errorf("nats writer connect mismatch: %s", connect)
+	}
+	for _, want := range []string{"logs.info.api_v1", "logs.warn._"} {
+		select {
+		case msg := <-messages:
+			if msg.Subject != want {
+				t.Errorf("nats writer subject want %s got %s", want, msg.Subject)
+			}
+			if !strings.HasPrefix(msg.Payload, "{") || strings.HasSuffix(msg.Payload, "\n") {
+				t.Errorf("nats writer payload mismatch: %q", msg.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("nats writer timeout")
+		}
+	}
+}
+
+func TestNATSWriterJetStream(t *testing.T) {
+	ln, messages, _ := natsServer(t)
+	defer ln.Close()
+
+	w := &NATSWriter{
+		URL:        "nats://" + ln.Addr().String(),
+		Subject:    "logs",
+		JetStream:  true,
+		MaxRetries: -1,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello jetstream")
+	logger.Info().Msg("reject me")
+	err := w.Close()
+	if err == nil || !strings.Contains(err.Error(), "stream unavailable") {
+		t.Errorf("nats writer should fail with rejected message: %+v", err)
+	}
+	if n := len(messages); n != 2 {
+		t.Errorf("nats writer want 2 published messages got %d", n)
+	}
+}
+
+func TestNATSAppendSubject(t *testing.T) {
+	cases := []struct {
+		Template string
+		Entry    string
+		Subject  string
+	}{
+		{"logs", `{"level":"info"}`, "logs"},
+		{"logs.{level}", `{"level":"info"}`, "logs.info"},
+		{"{app}.{level}", `{"level":"info","app":"a b*"}`, "a_b_.info"},
+		{"logs.{n}.{x}", `{"n":42}`, "logs.42._"},
+		{"logs.{level", `{"level":"info"}`, "logs.{level"},
+	}
+	for _, c := range cases {
+		if s := string(natsAppendSubject(nil, c.Template, []byte(c.Entry))); s != c.Subject {
+			t.Errorf("nats subject of %s want %s got %s", c.Template, c.Subject, s)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package log
+
+// raceEnabled reports whether the race detector is enabled, which adds allocations.
+const raceEnabled = false
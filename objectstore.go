@@ -0,0 +1,442 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectUploader uploads an object to object storage.
+type ObjectUploader interface {
+	Upload(key string, data []byte) error
+}
+
+// ObjectStoreWriter is a Writer that spools entries as gzip compressed chunks in a local
+// directory, and uploads the chunks to object storage by Uploader when the chunk reaches
+// MaxSize or MaxAge. The chunks failed to upload are kept in directory and retried with
+// exponential backoff, until they are uploaded by this or next process.
+type ObjectStoreWriter struct {
+	// Dir specifies the spool directory of chunks, uses "spool" in the temporary directory if empty.
+	Dir string
+
+	// Key specifies the object key template of chunks, uses "{yyyy}/{MM}/{dd}/{HH}/{host}-{unix}.json.gz" if empty.
+	// The placeholders are the {yyyy}, {MM}, {dd}, {HH}, {mm} and {ss} of chunk start time in UTC,
+	// the {unix} of chunk start time in nanoseconds, and the {host} of hostname.
+	Key string
+
+	// MaxSize specifies the maximum uncompressed bytes of a chunk, uses 64MB if zero.
+	MaxSize int64
+
+	// MaxAge specifies the maximum duration of a chunk, uses 5 minutes if zero.
+	MaxAge time.Duration
+
+	// Uploader specifies the uploader of chunks.
+	Uploader ObjectUploader
+
+	mu    sync.Mutex
+	file  *os.File
+	bw    *bufio.Writer
+	zw    *gzip.Writer
+	size  int64
+	timer *time.Timer
+	err   error
+
+	once    sync.Once
+	dir     string
+	ready   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func (w *ObjectStoreWriter) init() {
+	w.once.Do(func() {
+		w.dir = w.Dir
+		if w.dir == "" {
+			w.dir = filepath.Join(os.TempDir(), "spool")
+		}
+		w.ready = make(chan struct{}, 1)
+		w.done = make(chan struct{})
+		w.stopped = make(chan struct{})
+
+		// the partial chunks of previous process are uploaded as is.
+		if _, err := os.Stat(w.dir); err == nil {
+			parts, _ := filepath.Glob(filepath.Join(w.dir, "*.json.gz.part"))
+			for _, part := range parts {
+				_ = os.Rename(part, strings.TrimSuffix(part, ".part"))
+			}
+			w.ready <- struct{}{}
+		}
+
+		go w.upload()
+	})
+}
+
+// Close closes the current chunk, and uploads the pending chunks.
+func (w *ObjectStoreWriter) Close() (err error) {
+	w.init()
+
+	w.mu.Lock()
+	err = w.rotate()
+	w.mu.Unlock()
+
+	close(w.done)
+	<-w.stopped
+
+	if err == nil {
+		err = w.err
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *ObjectStoreWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.init()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err = w.create(); err != nil {
+			return
+		}
+	}
+	if n, err = w.zw.Write(e.buf); err != nil {
+		return
+	}
+	w.size += int64(n)
+
+	maxSize := w.MaxSize
+	if maxSize <= 0 {
+		maxSize = 64 * 1024 * 1024
+	}
+	if w.size >= maxSize {
+		err = w.rotate()
+	}
+	return
+}
+
+// create creates a new chunk, it is named by the unix nanoseconds of start time.
+func (w *ObjectStoreWriter) create() (err error) {
+	if err = os.MkdirAll(w.dir, 0755); err != nil {
+		return
+	}
+
+	name := filepath.Join(w.dir, strconv.FormatInt(timeNow().UnixNano(), 10)+".json.gz.part")
+	w.file, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return
+	}
+	if w.bw == nil {
+		w.bw = bufio.NewWriterSize(w.file, 64*1024)
+		w.zw = gzip.NewWriter(w.bw)
+	} else {
+		w.bw.Reset(w.file)
+		w.zw.Reset(w.bw)
+	}
+	w.size = 0
+
+	maxAge := w.MaxAge
+	if maxAge <= 0 {
+		maxAge = 5 * time.Minute
+	}
+	file := w.file
+	w.timer = time.AfterFunc(maxAge, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.file == file {
+			if err := w.rotate(); err != nil {
+				w.err = err
+			}
+		}
+	})
+	return
+}
+
+// rotate closes the current chunk, and notifies the uploader.
+func (w *ObjectStoreWriter) rotate() (err error) {
+	if w.file == nil {
+		return nil
+	}
+	w.timer.Stop()
+
+	if err = w.zw.Close(); err == nil {
+		err = w.bw.Flush()
+	}
+	if err1 := w.file.Close(); err == nil {
+		err = err1
+	}
+	name := w.file.Name()
+	w.file = nil
+	if err != nil {
+		return
+	}
+	if err = os.Rename(name, strings.TrimSuffix(name, ".part")); err != nil {
+		return
+	}
+
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// upload uploads the ready chunks in order of start time.
+func (w *ObjectStoreWriter) upload() {
+	defer close(w.stopped)
+
+	var retry <-chan time.Time
+	delay := time.Second
+	for {
+		var closing bool
+		select {
+		case <-w.ready:
+		case <-retry:
+		case <-w.done:
+			closing = true
+		}
+		retry = nil
+
+		names, _ := filepath.Glob(filepath.Join(w.dir, "*.json.gz"))
+		sort.Strings(names)
+		var err error
+		for _, name := range names {
+			if err = w.put(name); err != nil {
+				break
+			}
+		}
+
+		w.mu.Lock()
+		w.err = err
+		w.mu.Unlock()
+
+		if closing {
+			return
+		}
+		if err != nil {
+			retry = time.After(delay + time.Duration(Fastrandn(uint32(delay/time.Millisecond)+1))*time.Millisecond)
+			if delay *= 2; delay > 5*time.Minute {
+				delay = 5 * time.Minute
+			}
+		} else {
+			delay = time.Second
+		}
+	}
+}
+
+func (w *ObjectStoreWriter) put(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return os.Remove(name)
+	}
+
+	unix, _ := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), ".json.gz"), 10, 64)
+	if err = w.Uploader.Upload(objectStoreKey(w.Key, time.Unix(0, unix)), data); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// objectStoreKey returns the object key of template with the chunk start time.
+func objectStoreKey(template string, start time.Time) string {
+	if template == "" {
+		template = "{yyyy}/{MM}/{dd}/{HH}/{host}-{unix}.json.gz"
+	}
+	start = start.UTC()
+	pad := func(n int) string {
+		if n < 10 {
+			return "0" + strconv.Itoa(n)
+		}
+		return strconv.Itoa(n)
+	}
+	return strings.NewReplacer(
+		"{yyyy}", strconv.Itoa(start.Year()),
+		"{MM}", pad(int(start.Month())),
+		"{dd}", pad(start.Day()),
+		"{HH}", pad(start.Hour()),
+		"{mm}", pad(start.Minute()),
+		"{ss}", pad(start.Second()),
+		"{unix}", strconv.FormatInt(start.UnixNano(), 10),
+		"{host}", hostname,
+	).Replace(template)
+}
+
+// S3Uploader is an ObjectUploader of Amazon S3 and the compatible storages.
+type S3Uploader struct {
+	// Region specifies the aws region, uses AWS_REGION environment variable if empty.
+	Region string
+
+	// Bucket specifies the bucket of objects.
+	Bucket string
+
+	// Endpoint specifies the endpoint url of path style, e.g. "http://localhost:9000" of minio,
+	// uses "https://{bucket}.s3.{region}.amazonaws.com" if empty.
+	Endpoint string
+
+	// Credentials specifies the credentials provider, uses AWSEnvCredentials if nil.
+	Credentials func() (AWSCredentials, error)
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once   sync.Once
+	signer awsSigner
+}
+
+// Upload implements ObjectUploader.
+func (u *S3Uploader) Upload(key string, data []byte) error {
+	u.once.Do(func() {
+		u.signer.region = u.Region
+		if u.signer.region == "" {
+			u.signer.region = os.Getenv("AWS_REGION")
+		}
+		u.signer.service = "s3"
+		u.signer.credentials = u.Credentials
+	})
+
+	endpoint, path := u.Endpoint, "/"+key
+	if endpoint == "" {
+		endpoint = "https://" + u.Bucket + ".s3." + u.signer.region + ".amazonaws.com"
+	} else {
+		path = "/" + u.Bucket + path
+	}
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.URL.Path, req.URL.RawPath = path, awsURIEncode(path, false)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	if err = u.signer.sign(req, data); err != nil {
+		return err
+	}
+	_, err = httpDo(u.Client, req, 0)
+	return err
+}
+
+// GCSUploader is an ObjectUploader of Google Cloud Storage.
+type GCSUploader struct {
+	// Bucket specifies the bucket of objects.
+	Bucket string
+
+	// Endpoint specifies the endpoint url, uses "https://storage.googleapis.com" if empty.
+	Endpoint string
+
+	// Token specifies the access token provider, uses the token of default service account
+	// from metadata server of GCE/GKE/Cloud Run if nil.
+	Token func() (string, error)
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Upload implements ObjectUploader.
+func (u *GCSUploader) Upload(key string, data []byte) error {
+	token := u.Token
+	if token == nil {
+		token = gcpMetadataToken
+	}
+	access, err := token()
+	if err != nil {
+		return err
+	}
+
+	endpoint := u.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	header := http.Header{
+		"Authorization":    []string{"Bearer " + access},
+		"Content-Type":     []string{"application/x-ndjson"},
+		"Content-Encoding": []string{"gzip"},
+	}
+	return httpSend(u.Client, http.MethodPost, endpoint+"/upload/storage/v1/b/"+url.PathEscape(u.Bucket)+
+		"/o?uploadType=media&name="+url.QueryEscape(key), header, data)
+}
+
+// gcpMetadataToken returns the access token of default service account from metadata server.
+func gcpMetadataToken() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	data, err := httpDo(&http.Client{Timeout: 5 * time.Second}, req, 64*1024)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("log: gcp metadata token response without access_token")
+	}
+	return resp.AccessToken, nil
+}
+
+// AzureBlobUploader is an ObjectUploader of Azure Blob Storage.
+type AzureBlobUploader struct {
+	// ContainerURL specifies the url of container, e.g. "https://myaccount.blob.core.windows.net/logs".
+	ContainerURL string
+
+	// SASToken specifies the shared access signature of container, e.g. "sv=2022-11-02&ss=b&sig=...".
+	SASToken string
+
+	// Token specifies the access token provider if SASToken is empty,
+	// uses AzureEnvToken of "https://storage.azure.com/.default" if nil.
+	Token func() (AzureToken, error)
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once   sync.Once
+	tokens azureTokenSource
+}
+
+// Upload implements ObjectUploader.
+func (u *AzureBlobUploader) Upload(key string, data []byte) error {
+	u.once.Do(func() {
+		u.tokens.scope = "https://storage.azure.com/.default"
+		u.tokens.token = u.Token
+	})
+
+	header := http.Header{
+		"X-Ms-Blob-Type":   []string{"BlockBlob"},
+		"X-Ms-Version":     []string{"2021-08-06"},
+		"Content-Type":     []string{"application/x-ndjson"},
+		"Content-Encoding": []string{"gzip"},
+	}
+	blob := strings.TrimRight(u.ContainerURL, "/") + "/" + awsURIEncode(key, false)
+	if u.SASToken != "" {
+		blob += "?" + strings.TrimPrefix(u.SASToken, "?")
+	} else {
+		token, err := u.tokens.get()
+		if err != nil {
+			return err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return httpSend(u.Client, http.MethodPut, blob, header, data)
+}
+
+var _ Writer = (*ObjectStoreWriter)(nil)
+var _ ObjectUploader = (*S3Uploader)(nil)
+var _ ObjectUploader = (*GCSUploader)(nil)
+var _ ObjectUploader = (*AzureBlobUploader)(nil)
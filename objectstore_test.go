@@ -0,0 +1,211 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type objectTestUploader struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (u *objectTestUploader) Upload(key string, data []byte) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.fail {
+		return errors.New("upload failed")
+	}
+	if u.objects == nil {
+		u.objects = make(map[string][]byte)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	u.objects[key], err = io.ReadAll(r)
+	return err
+}
+
+func TestObjectStoreWriter(t *testing.T) {
+	dir := t.TempDir()
+	uploader := &objectTestUploader{}
+	w := &ObjectStoreWriter{
+		Dir:      dir,
+		Key:      "logs/{host}/{yyyy}-{MM}-{dd}/{unix}.json.gz",
+		MaxSize:  1024,
+		Uploader: uploader,
+	}
+	logger := Logger{Writer: w}
+	for i := 0; i < 40; i++ {
+		logger.Info().Int("i", i).Msg("hello object store")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("object store writer close error: %+v", err)
+	}
+
+	if len(uploader.objects) < 2 {
+		t.Errorf("object store writer should rotate chunks by size, got %d objects", len(uploader.objects))
+	}
+	var lines int
+	prefix := "logs/" + hostname + "/" + time.Now().UTC().Format("2006-01-02") + "/"
+	for key, data := range uploader.objects {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, ".json.gz") {
+			t.Errorf("object store writer key mismatch: %s", key)
+		}
+		lines += bytes.Count(data, []byte("\n"))
+	}
+	if lines != 40 {
+		t.Errorf("object store writer want 40 entries got %d", lines)
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 0 {
+		t.Errorf("object store writer should remove uploaded chunks: %v", names)
+	}
+}
+
+func TestObjectStoreWriterMaxAge(t *testing.T) {
+	uploader := &objectTestUploader{}
+	w := &ObjectStoreWriter{
+		Dir:      t.TempDir(),
+		MaxAge:   10 * time.Millisecond,
+		Uploader: uploader,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello object store")
+
+	for i := 0; i < 500; i++ {
+		uploader.mu.Lock()
+		n := len(uploader.objects)
+		uploader.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	uploader.mu.Lock()
+	n := len(uploader.objects)
+	uploader.mu.Unlock()
+	if n != 1 {
+		t.Errorf("object store writer should upload chunk by age")
+	}
+	_ = w.Close()
+}
+
+func TestObjectStoreWriterSpool(t *testing.T) {
+	dir := t.TempDir()
+	uploader := &objectTestUploader{fail: true}
+	w := &ObjectStoreWriter{Dir: dir, Uploader: uploader}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello spool")
+	if err := w.Close(); err == nil {
+		t.Errorf("object store writer should return the upload error")
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, "*.json.gz")); len(names) != 1 {
+		t.Fatalf("object store writer should keep failed chunks: %v", names)
+	}
+
+	// an unfinished chunk of crashed process
+	if err := os.WriteFile(filepath.Join(dir, "1.json.gz.part"), nil, 0644); err != nil {
+		t.Fatalf("write part error: %+v", err)
+	}
+
+	uploader.fail = false
+	w = &ObjectStoreWriter{Dir: dir, Uploader: uploader}
+	if err := w.Close(); err != nil {
+		t.Fatalf("object store writer close error: %+v", err)
+	}
+	if len(uploader.objects) != 1 {
+		t.Errorf("object store writer should upload spooled chunks: %v", uploader.objects)
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, "*")); len(names) != 0 {
+		t.Errorf("object store writer should remove uploaded chunks: %v", names)
+	}
+}
+
+func TestObjectStoreKey(t *testing.T) {
+	start := time.Date(2024, 3, 5, 7, 8, 9, 10, time.UTC)
+	key := objectStoreKey("{yyyy}/{MM}/{dd}/{HH}{mm}{ss}-{unix}", start)
+	if key != "2024/03/05/070809-1709622489000000010" {
+		t.Errorf("object store key mismatch: %s", key)
+	}
+}
+
+func TestS3Uploader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.EscapedPath() != "/mybucket/2024/a%20b.json.gz" {
+			t.Errorf("s3 uploader request mismatch: %s %s", r.Method, r.URL.EscapedPath())
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/s3/aws4_request") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			t.Errorf("s3 uploader should sign requests: %v", r.Header)
+		}
+		if data, _ := io.ReadAll(r.Body); string(data) != "data" {
+			t.Errorf("s3 uploader body mismatch: %s", data)
+		}
+	}))
+	defer server.Close()
+
+	u := &S3Uploader{
+		Region:   "us-east-1",
+		Bucket:   "mybucket",
+		Endpoint: server.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		},
+	}
+	if err := u.Upload("2024/a b.json.gz", []byte("data")); err != nil {
+		t.Errorf("s3 uploader error: %+v", err)
+	}
+}
+
+func TestGCSUploader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/upload/storage/v1/b/mybucket/o" || r.URL.Query().Get("name") != "2024/a.json.gz" || r.URL.Query().Get("uploadType") != "media" {
+			t.Errorf("gcs uploader request mismatch: %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer TOKEN" {
+			t.Errorf("gcs uploader auth mismatch: %v", r.Header)
+		}
+	}))
+	defer server.Close()
+
+	u := &GCSUploader{
+		Bucket:   "mybucket",
+		Endpoint: server.URL,
+		Token:    func() (string, error) { return "TOKEN", nil },
+	}
+	if err := u.Upload("2024/a.json.gz", []byte("data")); err != nil {
+		t.Errorf("gcs uploader error: %+v", err)
+	}
+}
+
+func TestAzureBlobUploader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/logs/2024/a.json.gz" || r.URL.Query().Get("sig") != "abc" {
+			t.Errorf("azure blob uploader request mismatch: %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			t.Errorf("azure blob uploader headers mismatch: %v", r.Header)
+		}
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	u := &AzureBlobUploader{
+		ContainerURL: server.URL + "/logs",
+		SASToken:     "?sv=2022-11-02&sig=abc",
+	}
+	if err := u.Upload("2024/a.json.gz", []byte("data")); err != nil {
+		t.Errorf("azure blob uploader error: %+v", err)
+	}
+}
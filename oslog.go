@@ -0,0 +1,139 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package log
+
+/*
+#include <os/log.h>
+#include <stdlib.h>
+
+static os_log_t oslog_create(const char *subsystem, const char *category) {
+	return os_log_create(subsystem, category);
+}
+
+static os_log_t oslog_default(void) {
+	return OS_LOG_DEFAULT;
+}
+
+static void oslog_write(os_log_t log, os_log_type_t type, const char *msg) {
+	os_log_with_type(log, type, "%{public}s", msg);
+}
+*/
+import "C"
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// OSLogWriter is an Writer that writes logs to apple unified logging system.
+type OSLogWriter struct {
+	// Subsystem specifies the default subsystem, uses OS_LOG_DEFAULT if empty.
+	Subsystem string
+
+	// Category specifies the default category, uses `default` if empty.
+	Category string
+
+	// SubsystemField specifies the field of subsystem in log event, uses `subsystem` if empty.
+	SubsystemField string
+
+	// CategoryField specifies the field of category in log event, uses `category` if empty.
+	CategoryField string
+
+	mu   sync.Mutex
+	logs map[string]map[string]C.os_log_t
+}
+
+// Close implements io.Closer.
+func (w *OSLogWriter) Close() (err error) {
+	w.mu.Lock()
+	// the os_log_t objects are retained for the lifetime of process by os_log_create.
+	w.logs = nil
+	w.mu.Unlock()
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *OSLogWriter) WriteEntry(e *Entry) (n int, err error) {
+	subsystemField, categoryField := w.SubsystemField, w.CategoryField
+	if subsystemField == "" {
+		subsystemField = "subsystem"
+	}
+	if categoryField == "" {
+		categoryField = "category"
+	}
+
+	// subsystem and category may alias e.buf, they are cloned before caching.
+	subsystem, category := w.Subsystem, w.Category
+	if value := jsonFieldString(e.buf, subsystemField); len(value) != 0 {
+		subsystem = b2s(value)
+	}
+	if value := jsonFieldString(e.buf, categoryField); len(value) != 0 {
+		category = b2s(value)
+	}
+	if category == "" {
+		category = "default"
+	}
+
+	// level
+	var typ C.os_log_type_t
+	switch e.Level {
+	case TraceLevel, DebugLevel:
+		typ = C.OS_LOG_TYPE_DEBUG
+	case InfoLevel:
+		typ = C.OS_LOG_TYPE_INFO
+	case ErrorLevel:
+		typ = C.OS_LOG_TYPE_ERROR
+	case FatalLevel, PanicLevel:
+		typ = C.OS_LOG_TYPE_FAULT
+	default:
+		typ = C.OS_LOG_TYPE_DEFAULT
+	}
+
+	w.mu.Lock()
+	log, ok := w.logs[subsystem][category]
+	if !ok {
+		log = w.create(subsystem, category)
+	}
+	w.mu.Unlock()
+
+	b := bbpool.Get().(*bb)
+	b.B = append(b.B[:0], e.buf...)
+	if len(b.B) != 0 && b.B[len(b.B)-1] == '\n' {
+		b.B = b.B[:len(b.B)-1]
+	}
+	b.B = append(b.B, 0)
+	C.oslog_write(log, typ, (*C.char)(unsafe.Pointer(&b.B[0])))
+	if cap(b.B) <= bbcap {
+		bbpool.Put(b)
+	}
+
+	n = len(e.buf)
+	return
+}
+
+// create makes and caches the os_log_t of subsystem and category, w.mu must be held.
+func (w *OSLogWriter) create(subsystem, category string) (log C.os_log_t) {
+	subsystem, category = strings.Clone(subsystem), strings.Clone(category)
+	if subsystem == "" {
+		log = C.oslog_default()
+	} else {
+		s, c := C.CString(subsystem), C.CString(category)
+		log = C.oslog_create(s, c)
+		C.free(unsafe.Pointer(s))
+		C.free(unsafe.Pointer(c))
+	}
+	if w.logs == nil {
+		w.logs = make(map[string]map[string]C.os_log_t)
+	}
+	m := w.logs[subsystem]
+	if m == nil {
+		m = make(map[string]C.os_log_t)
+		w.logs[subsystem] = m
+	}
+	m[category] = log
+	return
+}
+
+var _ Writer = (*OSLogWriter)(nil)
@@ -0,0 +1,37 @@
+//go:build !darwin || !cgo
+// +build !darwin !cgo
+
+package log
+
+import (
+	"errors"
+)
+
+// OSLogWriter is an Writer that writes logs to apple unified logging system.
+//
+// It requires darwin with cgo enabled, otherwise WriteEntry always returns an error.
+type OSLogWriter struct {
+	// Subsystem specifies the default subsystem, uses OS_LOG_DEFAULT if empty.
+	Subsystem string
+
+	// Category specifies the default category, uses `default` if empty.
+	Category string
+
+	// SubsystemField specifies the field of subsystem in log event, uses `subsystem` if empty.
+	SubsystemField string
+
+	// CategoryField specifies the field of category in log event, uses `category` if empty.
+	CategoryField string
+}
+
+// Close implements io.Closer.
+func (w *OSLogWriter) Close() (err error) {
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *OSLogWriter) WriteEntry(e *Entry) (n int, err error) {
+	return 0, errors.New("log: oslog is not supported")
+}
+
+var _ Writer = (*OSLogWriter)(nil)
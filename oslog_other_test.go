@@ -0,0 +1,20 @@
+//go:build !darwin || !cgo
+// +build !darwin !cgo
+
+package log
+
+import (
+	"testing"
+)
+
+func TestOSLogWriterUnsupported(t *testing.T) {
+	w := &OSLogWriter{
+		Subsystem: "com.github.phuslu.log",
+	}
+	defer w.Close()
+
+	_, err := wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello json oslog writer"}`+"\n")
+	if err == nil {
+		t.Errorf("test oslog writer on unsupported platform: want error")
+	}
+}
@@ -0,0 +1,26 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package log
+
+import (
+	"testing"
+)
+
+func TestOSLogWriter(t *testing.T) {
+	w := &OSLogWriter{
+		Subsystem: "com.github.phuslu.log",
+	}
+	defer w.Close()
+
+	for _, level := range []string{"trace", "debug", "info", "warning", "error", "fatal", "panic", "hahaha"} {
+		_, err := wlprintf(w, ParseLevel(level), `{"time":"2019-07-10T05:35:54.277Z","level":"%s","category":"test","caller":"test.go:42","error":"i am test error","foo":"bar","n":42,"message":"hello json oslog writer"}`+"\n", level)
+		if err != nil {
+			t.Errorf("test json oslog writer error: %+v", err)
+		}
+	}
+
+	if len(w.logs) != 1 {
+		t.Errorf("test oslog writer logs: got %d, want 1", len(w.logs))
+	}
+}
@@ -0,0 +1,377 @@
+package log
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OtlpWriter is a Writer that exports entries in batches to the OpenTelemetry collector
+// via OTLP/HTTP protobuf. The level is mapped to SeverityNumber, the message is mapped
+// to Body, the "trace_id"/"span_id"/"trace_flags" fields are mapped to the trace context
+// of record, and the rest fields are mapped to Attributes.
+type OtlpWriter struct {
+	// Endpoint specifies the http url of OTLP logs, uses "http://localhost:4318/v1/logs" if empty.
+	Endpoint string
+
+	// Headers specifies the extra http headers of requests, e.g. authorization.
+	Headers map[string]string
+
+	// Resource specifies the resource attributes, e.g. "service.name".
+	Resource map[string]string
+
+	// ScopeName specifies the instrumentation scope name, uses "github.com/phuslu/log" if empty.
+	ScopeName string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 512 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once     sync.Once
+	batcher  batcher
+	endpoint string
+	header   http.Header
+	prefix   []byte
+}
+
+func (w *OtlpWriter) init() {
+	w.once.Do(func() {
+		w.endpoint = w.Endpoint
+		if w.endpoint == "" {
+			w.endpoint = "http://localhost:4318/v1/logs"
+		}
+
+		w.header = http.Header{"Content-Type": []string{"application/x-protobuf"}}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+		for k, v := range w.Headers {
+			w.header.Set(k, v)
+		}
+
+		// resource and scope are encoded once.
+		keys := make([]string, 0, len(w.Resource))
+		for k := range w.Resource {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var start int
+		w.prefix, start = pbBegin(w.prefix, 1)
+		for _, k := range keys {
+			w.prefix = otlpAppendKeyValue(w.prefix, otlpResourceAttributes, k, 's', w.Resource[k])
+		}
+		w.prefix = pbEnd(w.prefix, start)
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 512
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *OtlpWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *OtlpWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *OtlpWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	buf.B = buf.B[:0]
+	defer func() {
+		if cap(buf.B) <= bbcap {
+			bbpool.Put(buf)
+		}
+	}()
+
+	// ExportLogsServiceRequest.resource_logs
+	dst, resourceLogs := pbBegin(buf.B, 1)
+	dst = append(dst, w.prefix...)
+	// ResourceLogs.scope_logs
+	dst, scopeLogs := pbBegin(dst, 2)
+	// ScopeLogs.scope
+	scope := w.ScopeName
+	if scope == "" {
+		scope = "github.com/phuslu/log"
+	}
+	dst, start := pbBegin(dst, 1)
+	dst = pbAppendString(dst, 1, scope)
+	dst = pbEnd(dst, start)
+	// ScopeLogs.log_records
+	tmp := bbpool.Get().(*bb)
+	for i := 0; i < b.Len(); i++ {
+		tmp.B = append(tmp.B[:0], b.Entry(i)...)
+		var args FormatterArgs
+		parseFormatterArgs(tmp.B, &args)
+		dst = otlpAppendLogRecord(dst, &args, b.levels[i], b.times[i])
+	}
+	if cap(tmp.B) <= bbcap {
+		bbpool.Put(tmp)
+	}
+	dst = pbEnd(dst, scopeLogs)
+	dst = pbEnd(dst, resourceLogs)
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer func() {
+			if cap(z.B) <= bbcap {
+				bbpool.Put(z)
+			}
+		}()
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	return httpSend(w.Client, http.MethodPost, w.endpoint, w.header, body)
+}
+
+// otlpSeverity converts Level to the SeverityNumber of OpenTelemetry.
+func otlpSeverity(level Level) uint64 {
+	switch level {
+	case TraceLevel:
+		return 1
+	case DebugLevel:
+		return 5
+	case InfoLevel:
+		return 9
+	case WarnLevel:
+		return 13
+	case ErrorLevel:
+		return 17
+	case FatalLevel:
+		return 21
+	case PanicLevel:
+		return 22
+	}
+	return 0
+}
+
+func otlpAppendLogRecord(dst []byte, args *FormatterArgs, level Level, observed int64) []byte {
+	dst, record := pbBegin(dst, 2)
+
+	ts, ok := parseEntryTime(args.Time)
+	if !ok {
+		ts = observed
+	}
+	dst = pbAppendFixed64(dst, 1, uint64(ts))
+	dst = pbAppendFixed64(dst, 11, uint64(observed))
+
+	if level == noLevel {
+		level = ParseLevel(args.Level)
+	}
+	if n := otlpSeverity(level); n != 0 {
+		dst = pbAppendTag(dst, 2, 0)
+		dst = pbAppendVarint(dst, n)
+	}
+	if args.Level != "" && args.Level != "????" {
+		dst = pbAppendString(dst, 3, args.Level)
+	}
+
+	if args.Message != "" {
+		var start int
+		dst, start = pbBegin(dst, 5)
+		dst = pbAppendString(dst, 1, args.Message)
+		dst = pbEnd(dst, start)
+	}
+
+	if args.Caller != "" {
+		file, line := args.Caller, ""
+		if i := strings.LastIndexByte(file, ':'); i > 0 {
+			file, line = file[:i], file[i+1:]
+		}
+		dst = otlpAppendKeyValue(dst, otlpRecordAttributes, "code.filepath", 's', file)
+		if line != "" {
+			dst = otlpAppendKeyValue(dst, otlpRecordAttributes, "code.lineno", 'n', line)
+		}
+	}
+	if args.Goid != "" {
+		dst = otlpAppendKeyValue(dst, otlpRecordAttributes, "thread.id", 'n', args.Goid)
+	}
+	if args.Stack != "" {
+		dst = otlpAppendKeyValue(dst, otlpRecordAttributes, "exception.stacktrace", 's', args.Stack)
+	}
+
+	var traceID [16]byte
+	var spanID [8]byte
+	var flags uint64
+	var traced bool
+	for _, kv := range args.KeyValues {
+		switch kv.Key {
+		case "trace_id":
+			if hexDecode(traceID[:], kv.Value) {
+				traced = true
+				continue
+			}
+		case "span_id":
+			if hexDecode(spanID[:], kv.Value) {
+				continue
+			}
+		case "trace_flags":
+			if n, err := strconv.ParseUint(kv.Value, 16, 8); err == nil {
+				flags = n
+				continue
+			}
+		}
+		dst = otlpAppendKeyValue(dst, otlpRecordAttributes, kv.Key, kv.ValueType, kv.Value)
+	}
+
+	if traced {
+		dst = pbAppendFixed32(dst, 8, uint32(flags))
+		dst = pbAppendBytes(dst, 9, traceID[:])
+		if spanID != ([8]byte{}) {
+			dst = pbAppendBytes(dst, 10, spanID[:])
+		}
+	}
+
+	return pbEnd(dst, record)
+}
+
+// the field numbers of attributes in Resource and LogRecord messages.
+const (
+	otlpResourceAttributes = 1
+	otlpRecordAttributes   = 6
+)
+
+// otlpAppendKeyValue appends the KeyValue of attributes field num, with the json value type of formatter args.
+func otlpAppendKeyValue(dst []byte, num int, key string, typ byte, value string) []byte {
+	if typ == 0 {
+		// null
+		return dst
+	}
+	dst, kv := pbBegin(dst, num)
+	dst = pbAppendString(dst, 1, key)
+	dst, start := pbBegin(dst, 2)
+	switch typ {
+	case 'n':
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			dst = pbAppendTag(dst, 3, 0)
+			dst = pbAppendVarint(dst, uint64(n))
+		} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+			dst = pbAppendFixed64(dst, 4, math.Float64bits(f))
+		} else {
+			dst = pbAppendString(dst, 1, value)
+		}
+	case 't', 'f':
+		dst = pbAppendTag(dst, 2, 0)
+		if typ == 't' {
+			dst = append(dst, 1)
+		} else {
+			dst = append(dst, 0)
+		}
+	default:
+		dst = pbAppendString(dst, 1, value)
+	}
+	dst = pbEnd(dst, start)
+	return pbEnd(dst, kv)
+}
+
+// hexDecode decodes the hex string s to dst, it reports whether s is exactly hex of dst.
+func hexDecode(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) {
+		return false
+	}
+	for i := range dst {
+		hi, lo := unhex(s[2*i]), unhex(s[2*i+1])
+		if hi > 0xf || lo > 0xf {
+			return false
+		}
+		dst[i] = hi<<4 | lo
+	}
+	return true
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10
+	}
+	return 0xff
+}
+
+func pbAppendVarint(dst []byte, v uint64) []byte {
+	for v >= 0x80 {
+		dst = append(dst, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(dst, byte(v))
+}
+
+func pbAppendTag(dst []byte, num int, typ byte) []byte {
+	return pbAppendVarint(dst, uint64(num)<<3|uint64(typ))
+}
+
+func pbAppendString(dst []byte, num int, s string) []byte {
+	dst = pbAppendTag(dst, num, 2)
+	dst = pbAppendVarint(dst, uint64(len(s)))
+	return append(dst, s...)
+}
+
+func pbAppendBytes(dst []byte, num int, b []byte) []byte {
+	dst = pbAppendTag(dst, num, 2)
+	dst = pbAppendVarint(dst, uint64(len(b)))
+	return append(dst, b...)
+}
+
+func pbAppendFixed64(dst []byte, num int, v uint64) []byte {
+	dst = pbAppendTag(dst, num, 1)
+	return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24), byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func pbAppendFixed32(dst []byte, num int, v uint32) []byte {
+	dst = pbAppendTag(dst, num, 5)
+	return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// pbBegin begins a length-delimited field, it reserves 5 bytes of length and returns the start of content.
+func pbBegin(dst []byte, num int) ([]byte, int) {
+	dst = pbAppendTag(dst, num, 2)
+	dst = append(dst, 0, 0, 0, 0, 0)
+	return dst, len(dst)
+}
+
+// pbEnd ends the length-delimited field, it writes the length and moves the content forward.
+func pbEnd(dst []byte, start int) []byte {
+	n := len(dst) - start
+	var tmp [5]byte
+	size := pbAppendVarint(tmp[:0], uint64(n))
+	i := start - 5
+	i += copy(dst[i:], size)
+	copy(dst[i:], dst[start:])
+	return dst[:i+n]
+}
+
+var _ Writer = (*OtlpWriter)(nil)
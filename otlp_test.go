@@ -0,0 +1,186 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type pbField struct {
+	Num   int
+	Type  byte
+	Value uint64
+	Bytes []byte
+}
+
+func pbDecode(t *testing.T, b []byte) (fields []pbField) {
+	varint := func() (v uint64) {
+		for shift := 0; len(b) > 0; shift += 7 {
+			c := b[0]
+			b = b[1:]
+			v |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				break
+			}
+		}
+		return
+	}
+	for len(b) > 0 {
+		tag := varint()
+		f := pbField{Num: int(tag >> 3), Type: byte(tag & 7)}
+		switch f.Type {
+		case 0:
+			f.Value = varint()
+		case 1:
+			for i := 0; i < 8; i++ {
+				f.Value |= uint64(b[i]) << (8 * i)
+			}
+			b = b[8:]
+		case 2:
+			n := varint()
+			f.Bytes, b = b[:n], b[n:]
+		case 5:
+			for i := 0; i < 4; i++ {
+				f.Value |= uint64(b[i]) << (8 * i)
+			}
+			b = b[4:]
+		default:
+			t.Fatalf("pbDecode unknown wire type %d", f.Type)
+		}
+		fields = append(fields, f)
+	}
+	return
+}
+
+func pbGet(t *testing.T, b []byte, num int) (fields []pbField) {
+	for _, f := range pbDecode(t, b) {
+		if f.Num == num {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
+
+// pbAttrs decodes the attributes field num, which is 1 of Resource and 6 of LogRecord in
+// opentelemetry/proto/resource/v1/resource.proto and opentelemetry/proto/logs/v1/logs.proto.
+func pbAttrs(t *testing.T, msg []byte, num int) map[string]pbField {
+	attrs := make(map[string]pbField)
+	for _, kv := range pbGet(t, msg, num) {
+		key := pbGet(t, kv.Bytes, 1)[0].Bytes
+		value := pbDecode(t, pbGet(t, kv.Bytes, 2)[0].Bytes)[0]
+		attrs[string(key)] = value
+	}
+	return attrs
+}
+
+func TestOtlpWriter(t *testing.T) {
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("otlp writer unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("otlp writer should send headers: %v", r.Header)
+		}
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, _ = gzip.NewReader(r.Body)
+		}
+		data, _ := io.ReadAll(reader)
+		bodies <- data
+	}))
+	defer server.Close()
+
+	w := &OtlpWriter{
+		Endpoint:  server.URL + "/v1/logs",
+		Headers:   map[string]string{"Authorization": "Bearer token"},
+		Resource:  map[string]string{"service.name": "test"},
+		Gzip:      true,
+		BatchSize: 2,
+	}
+	logger := Logger{Caller: 1, Writer: w}
+
+	logger.Info().Str("foo", "bar").Int("n", 42).Float64("f", 1.5).Bool("ok", true).Msg("hello otlp")
+	logger.Error().Str("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736").Str("span_id", "00f067aa0ba902b7").Str("trace_flags", "01").Msg("with trace")
+	logger.Warn().Msg("pending")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("otlp writer close error: %+v", err)
+	}
+
+	var records [][]byte
+	for len(bodies) > 0 {
+		data := <-bodies
+		resourceLogs := pbGet(t, data, 1)[0].Bytes
+		resource := pbGet(t, resourceLogs, 1)[0].Bytes
+		if attrs := pbAttrs(t, resource, 1); string(attrs["service.name"].Bytes) != "test" {
+			t.Errorf("otlp writer resource mismatch: %v", attrs)
+		}
+		if fields := pbGet(t, resource, 6); len(fields) != 0 {
+			t.Errorf("otlp writer resource must not have field 6: %v", fields)
+		}
+		scopeLogs := pbGet(t, resourceLogs, 2)[0].Bytes
+		scope := pbGet(t, scopeLogs, 1)[0].Bytes
+		if name := pbGet(t, scope, 1)[0].Bytes; string(name) != "github.com/phuslu/log" {
+			t.Errorf("otlp writer scope mismatch: %s", name)
+		}
+		for _, f := range pbGet(t, scopeLogs, 2) {
+			records = append(records, f.Bytes)
+		}
+	}
+	if len(records) != 3 {
+		t.Fatalf("otlp writer should export 3 records, got %d", len(records))
+	}
+
+	record := records[0]
+	if n := pbGet(t, record, 2)[0].Value; n != 9 {
+		t.Errorf("otlp writer severity number mismatch: %d", n)
+	}
+	if s := pbGet(t, record, 3)[0].Bytes; string(s) != "info" {
+		t.Errorf("otlp writer severity text mismatch: %s", s)
+	}
+	if body := pbGet(t, pbGet(t, record, 5)[0].Bytes, 1)[0].Bytes; string(body) != "hello otlp" {
+		t.Errorf("otlp writer body mismatch: %s", body)
+	}
+	if ts, observed := pbGet(t, record, 1)[0].Value, pbGet(t, record, 11)[0].Value; ts == 0 || observed < ts {
+		t.Errorf("otlp writer timestamp mismatch: %d %d", ts, observed)
+	}
+	attrs := pbAttrs(t, record, 6)
+	if string(attrs["foo"].Bytes) != "bar" || attrs["n"].Value != 42 || attrs["f"].Type != 1 || attrs["ok"].Value != 1 {
+		t.Errorf("otlp writer attributes mismatch: %+v", attrs)
+	}
+	if string(attrs["code.filepath"].Bytes) != "otlp_test.go" || attrs["code.lineno"].Value == 0 {
+		t.Errorf("otlp writer caller mismatch: %+v", attrs)
+	}
+
+	record = records[1]
+	if n := pbGet(t, record, 2)[0].Value; n != 17 {
+		t.Errorf("otlp writer severity number mismatch: %d", n)
+	}
+	if id := pbGet(t, record, 9)[0].Bytes; len(id) != 16 || id[0] != 0x4b {
+		t.Errorf("otlp writer trace id mismatch: %x", id)
+	}
+	if id := pbGet(t, record, 10)[0].Bytes; len(id) != 8 || id[7] != 0xb7 {
+		t.Errorf("otlp writer span id mismatch: %x", id)
+	}
+	if flags := pbGet(t, record, 8)[0].Value; flags != 1 {
+		t.Errorf("otlp writer trace flags mismatch: %d", flags)
+	}
+	if attrs := pbAttrs(t, record, 6); attrs["trace_id"].Num != 0 || attrs["span_id"].Num != 0 || attrs["trace_flags"].Num != 0 {
+		t.Errorf("otlp writer should not export trace fields as attributes: %+v", attrs)
+	}
+}
+
+func TestPbEnd(t *testing.T) {
+	dst, start := pbBegin(nil, 1)
+	for i := 0; i < 200; i++ {
+		dst = append(dst, 'x')
+	}
+	dst = pbEnd(dst, start)
+	fields := pbDecode(t, dst)
+	if len(fields) != 1 || len(fields[0].Bytes) != 200 {
+		t.Errorf("pbEnd mismatch: %+v", fields)
+	}
+}
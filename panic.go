@@ -0,0 +1,65 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// panicStackTrace is the stack trace of PanicValue, which drops the frames of runtime.
+var panicStackTrace = StackTrace{MaxDepth: 32, SkipPrefixes: []string{"runtime."}}
+
+// PanicValue adds the field "panic" with the value of recover(), and the structured stack
+// trace and goroutine id of the panic, e.g.
+//
+//	defer func() {
+//		if v := recover(); v != nil {
+//			log.Error().PanicValue(v).Msg("panic recovered")
+//		}
+//	}()
+//
+// It appends nothing if v is nil.
+func (e *Entry) PanicValue(v interface{}) *Entry {
+	if e == nil || v == nil {
+		return e
+	}
+
+	e.buf = append(e.buf, ",\"panic\":\""...)
+	switch x := v.(type) {
+	case string:
+		e.string(x)
+	case error:
+		e.string(x.Error())
+	case fmt.Stringer:
+		e.string(x.String())
+	default:
+		e.string(fmt.Sprint(x))
+	}
+	e.buf = append(e.buf, '"')
+
+	e.buf = append(e.buf, ",\"goid\":"...)
+	e.buf = strconv.AppendInt(e.buf, int64(goid()), 10)
+
+	panicStackTrace.append(e)
+	return e
+}
+
+// RecoverAndLog recovers the panic and logs the panic value, stack trace and goroutine id
+// in an error entry, and re-panics with the value if repanic is true. It must be deferred
+// directly, e.g.
+//
+//	defer log.DefaultLogger.RecoverAndLog(false)
+func (l *Logger) RecoverAndLog(repanic bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	// the stack trace of logger is replaced by the one of panic.
+	logger := *l
+	logger.StackTrace = nil
+	logger.Error().PanicValue(v).Msg("panic recovered")
+
+	if repanic {
+		panic(v)
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		StackTrace: &StackTrace{},
+		Writer:     IOWriter{&buf},
+	}
+
+	func() {
+		defer logger.RecoverAndLog(false)
+		panic(errors.New("boom"))
+	}()
+
+	var entry struct {
+		Level      string `json:"level"`
+		Panic      string `json:"panic"`
+		Goid       int    `json:"goid"`
+		Message    string `json:"message"`
+		StackTrace []struct {
+			Function string `json:"function"`
+		} `json:"stacktrace"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unmarshal entry %s error: %+v", buf.String(), err)
+	}
+	if entry.Level != "error" || entry.Panic != "boom" || entry.Goid == 0 || entry.Message != "panic recovered" {
+		t.Errorf("recovered entry got %s", buf.String())
+	}
+	if len(entry.StackTrace) == 0 || !strings.Contains(entry.StackTrace[0].Function, "TestRecoverAndLog.func") {
+		t.Errorf("the first frame must be the panicking function: %s", buf.String())
+	}
+	if n := strings.Count(buf.String(), `"stacktrace"`); n != 1 {
+		t.Errorf("recovered entry must have one stack trace, not %d", n)
+	}
+
+	buf.Reset()
+	func() {
+		defer func() {
+			if v := recover(); v != 42 {
+				t.Errorf("RecoverAndLog must re-panic with 42, not %v", v)
+			}
+		}()
+		defer logger.RecoverAndLog(true)
+		panic(42)
+	}()
+	if !strings.Contains(buf.String(), `"panic":"42"`) {
+		t.Errorf("re-panic entry got %s", buf.String())
+	}
+
+	buf.Reset()
+	func() {
+		defer logger.RecoverAndLog(true)
+	}()
+	if buf.Len() != 0 {
+		t.Errorf("RecoverAndLog without panic must log nothing: %s", buf.String())
+	}
+}
@@ -0,0 +1,259 @@
+package log
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ParsedEntry is a json entry of logger decoded by Parse.
+type ParsedEntry struct {
+	// Time is the time of entry, it is zero if absent or neither RFC3339 nor unix timestamp.
+	Time time.Time
+
+	// Level is the level of entry, it is the absent level if absent or unknown.
+	Level Level
+
+	// Caller is the caller of entry, e.g. "prog.go:42".
+	Caller string
+
+	// Goid is the goroutine id of entry.
+	Goid int64
+
+	// Stack is the stack of entry.
+	Stack string
+
+	// Message is the message of entry.
+	Message string
+
+	// Fields is the other fields of entry in order.
+	Fields []ParsedField
+}
+
+// ParsedField is a field of ParsedEntry.
+type ParsedField struct {
+	// Key is the unescaped key of field.
+	Key string
+
+	// Value is the unescaped string, or the raw json of other types.
+	Value string
+
+	// Type is the json type of field, 's' for string, 'n' for number, 't' for true,
+	// 'f' for false, 'o' for object, 'a' for array and 0 for null.
+	Type byte
+}
+
+// ErrParse is returned by Parse if the input is not a json object.
+var ErrParse = errors.New("log: invalid json entry")
+
+// Parse decodes a json entry of logger, the well-known time, level, caller, goid, stack
+// and message fields are typed, the others are kept as parsed strings in order. It scans
+// the input by the json parser of FormatterArgs, without using encoding/json.
+func Parse(data []byte) (entry ParsedEntry, err error) {
+	// the strings of entry refer to the copy of data, unescaped in place.
+	json := append([]byte(nil), data...)
+
+	i := jsonSkip(json, 0)
+	if i >= len(json) || json[i] != '{' {
+		return entry, ErrParse
+	}
+	entry.Level = noLevel
+	if i = jsonSkip(json, i+1); i < len(json) && json[i] == '}' {
+		if jsonSkip(json, i+1) != len(json) {
+			return entry, ErrParse
+		}
+		return
+	}
+
+	var key, val []byte
+	var esc, ok bool
+	var typ byte
+	for {
+		if i >= len(json) || json[i] != '"' {
+			return entry, ErrParse
+		}
+		i, key, esc, ok = jsonParseString(json, i+1)
+		if !ok {
+			return entry, ErrParse
+		}
+		key = key[1 : len(key)-1]
+		if esc {
+			key = jsonUnescape(key, key[:0])
+		}
+
+		if i = jsonSkip(json, i); i >= len(json) || json[i] != ':' {
+			return entry, ErrParse
+		}
+		if i = jsonSkip(json, i+1); i >= len(json) || !jsonValueStart(json[i]) {
+			return entry, ErrParse
+		}
+		i, typ, val, ok = jsonParseAny(json, i, true)
+		if !ok || !jsonValid(typ, val) {
+			return entry, ErrParse
+		}
+		switch typ {
+		case 's':
+			val = val[1 : len(val)-1]
+		case 'S':
+			val = jsonUnescape(val[1:len(val)-1], val[:0])
+			typ = 's'
+		case 'o':
+			if val[0] == '[' {
+				typ = 'a'
+			}
+		}
+		entry.set(b2s(key), b2s(val), typ)
+
+		if i = jsonSkip(json, i); i >= len(json) {
+			return entry, ErrParse
+		}
+		if json[i] == '}' {
+			break
+		}
+		if json[i] != ',' {
+			return entry, ErrParse
+		}
+		i = jsonSkip(json, i+1)
+	}
+
+	if jsonSkip(json, i+1) != len(json) {
+		return entry, ErrParse
+	}
+	return
+}
+
+func (entry *ParsedEntry) set(key, value string, typ byte) {
+	switch {
+	case key == "time" && entry.Time.IsZero() && (typ == 's' || typ == 'n'):
+		if typ == 'n' {
+			entry.Time = glogTime(value, "")
+		} else {
+			entry.Time, _ = time.Parse(time.RFC3339Nano, value)
+		}
+		return
+	case key == "level" && typ == 's':
+		entry.Level = ParseLevel(value)
+		return
+	case key == "caller" && typ == 's':
+		entry.Caller = value
+		return
+	case key == "goid" && typ == 'n':
+		entry.Goid, _ = strconv.ParseInt(value, 10, 64)
+		return
+	case key == "stack" && typ == 's':
+		entry.Stack = value
+		return
+	case (key == "message" || key == "msg") && typ == 's' && entry.Message == "":
+		entry.Message = value
+		return
+	}
+	entry.Fields = append(entry.Fields, ParsedField{Key: key, Value: value, Type: typ})
+}
+
+// Get returns the field of key, and reports whether it exists.
+func (entry *ParsedEntry) Get(key string) (field ParsedField, ok bool) {
+	for _, field = range entry.Fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return ParsedField{}, false
+}
+
+// Interface returns the value of field in the types of encoding/json, i.e. string, float64,
+// bool, nil, map[string]interface{} and []interface{}.
+func (field ParsedField) Interface() interface{} {
+	switch field.Type {
+	case 's':
+		return field.Value
+	case 'n':
+		f, _ := strconv.ParseFloat(field.Value, 64)
+		return f
+	case 't':
+		return true
+	case 'f':
+		return false
+	case 'o', 'a':
+		return jsonValue([]byte(field.Value))
+	}
+	return nil
+}
+
+// jsonValue decodes the raw json value in the types of encoding/json.
+func jsonValue(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	switch raw[0] {
+	case '"':
+		return jsonString(raw)
+	case '{':
+		m := make(map[string]interface{})
+		jsonRange(raw, func(key, value []byte) bool {
+			m[jsonString(key)] = jsonValue(value)
+			return true
+		})
+		return m
+	case '[':
+		a := make([]interface{}, 0)
+		jsonRangeArray(raw, func(value []byte) bool {
+			a = append(a, jsonValue(value))
+			return true
+		})
+		return a
+	case 't':
+		return true
+	case 'f':
+		return false
+	case 'n':
+		return nil
+	}
+	f, _ := strconv.ParseFloat(b2s(raw), 64)
+	return f
+}
+
+// jsonString unescapes the json string with or without the quotes.
+func jsonString(s []byte) string {
+	if len(s) >= 2 && s[0] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	for _, c := range s {
+		if c == '\\' {
+			return string(jsonUnescape(s, nil))
+		}
+	}
+	return string(s)
+}
+
+func jsonSkip(json []byte, i int) int {
+	for i < len(json) && (json[i] == ' ' || json[i] == '\t' || json[i] == '\n' || json[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func jsonValueStart(c byte) bool {
+	switch c {
+	case '{', '[', '"', '-', 't', 'f', 'n':
+		return true
+	}
+	return c >= '0' && c <= '9'
+}
+
+// jsonValid reports whether the value parsed by jsonParseAny is complete.
+func jsonValid(typ byte, val []byte) bool {
+	switch typ {
+	case 'o':
+		return len(val) >= 2 && (val[0] == '{' && val[len(val)-1] == '}' || val[0] == '[' && val[len(val)-1] == ']')
+	case 'n':
+		_, err := strconv.ParseFloat(b2s(val), 64)
+		return err == nil
+	case 't':
+		return b2s(val) == "true"
+	case 'f':
+		return b2s(val) == "false"
+	case 0:
+		return b2s(val) == "null"
+	}
+	return true
+}
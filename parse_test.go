@@ -0,0 +1,88 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	line := `{"time":"2019-07-10T05:35:54.277Z","level":"warn","caller":"prog.go:42","goid":123,"foo":"b\"ar","n":-1.5e3,"ok":true,"no":false,"nil":null,"obj":{"a":[1,"x",{"b\\n":null}]},"arr":[],"message":"hello 世界"}` + "\n"
+	entry, err := Parse([]byte(line))
+	if err != nil {
+		t.Fatalf("parse entry error: %+v", err)
+	}
+
+	if want := time.Date(2019, 7, 10, 5, 35, 54, 277000000, time.UTC); !entry.Time.Equal(want) {
+		t.Errorf("parse entry time got %v want %v", entry.Time, want)
+	}
+	if entry.Level != WarnLevel || entry.Caller != "prog.go:42" || entry.Goid != 123 || entry.Message != "hello 世界" {
+		t.Errorf("parse entry mismatched: %+v", entry)
+	}
+
+	want := []ParsedField{
+		{"foo", `b"ar`, 's'},
+		{"n", "-1.5e3", 'n'},
+		{"ok", "true", 't'},
+		{"no", "false", 'f'},
+		{"nil", "null", 0},
+		{"obj", `{"a":[1,"x",{"b\\n":null}]}`, 'o'},
+		{"arr", "[]", 'a'},
+	}
+	if !reflect.DeepEqual(entry.Fields, want) {
+		t.Errorf("parse entry fields got %+v want %+v", entry.Fields, want)
+	}
+
+	field, ok := entry.Get("obj")
+	if !ok || !reflect.DeepEqual(field.Interface(), map[string]interface{}{"a": []interface{}{float64(1), "x", map[string]interface{}{`b\n`: nil}}}) {
+		t.Errorf("parse entry obj mismatched: %#v", field.Interface())
+	}
+	if field, _ := entry.Get("n"); field.Interface() != -1500.0 {
+		t.Errorf("parse entry number mismatched: %#v", field.Interface())
+	}
+	if _, ok := entry.Get("missing"); ok {
+		t.Errorf("parse entry must not get missing field")
+	}
+
+	entry, err = Parse([]byte(`{"time":1562736954.277,"message":"unix"}`))
+	if err != nil || entry.Time.Sub(time.UnixMilli(1562736954277)) > time.Microsecond || entry.Time.Sub(time.UnixMilli(1562736954277)) < -time.Microsecond || entry.Level != noLevel {
+		t.Errorf("parse entry unix time mismatched: %+v %+v", entry, err)
+	}
+
+	if entry, err = Parse([]byte(" {} ")); err != nil || len(entry.Fields) != 0 {
+		t.Errorf("parse empty entry mismatched: %+v %+v", entry, err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`plain text`,
+		`[1,2]`,
+		`{`,
+		`{"a"}`,
+		`{"a":}`,
+		`{"a":1,}`,
+		`{"a":1 "b":2}`,
+		`{"a":"x`,
+		`{"a":{"b":1}`,
+		`{"a":tru}`,
+		`{"a":x}`,
+		`{"a":1} trailing`,
+	} {
+		if _, err := Parse([]byte(s)); err != ErrParse {
+			t.Errorf("parse %q must return ErrParse: %+v", s, err)
+		}
+	}
+}
+
+func TestParseLogger(t *testing.T) {
+	r := &Recorder{}
+	logger := Logger{Level: TraceLevel, Caller: 1, Writer: r}
+	logger.Info().Str("s", "a\tb").Int("i", 42).Floats64("fs", []float64{1, 2}).Msg("hello")
+
+	last, _ := r.Last()
+	if last.Message != "hello" || !reflect.DeepEqual(last.Fields, map[string]interface{}{"s": "a\tb", "i": float64(42), "fs": []interface{}{float64(1), float64(2)}}) {
+		t.Errorf("recorder parsed entry mismatched: %+v", last)
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitWriter is a Writer that limits the entries of same key to Burst per Window,
+// and writes a summary entry with a "suppressed" field when the window closes. The entries
+// with an empty key are keyed by the level and caller, or not limited if without caller.
+type RateLimitWriter struct {
+	// Burst specifies the maximum entries of same key per window, uses 1 if zero.
+	Burst int
+
+	// Window specifies the time window of rate limiting, uses 1 minute if zero.
+	Window time.Duration
+
+	// KeyField specifies the json field name as the key of entries, uses "message" if empty.
+	KeyField string
+
+	// Writer specifies the writer of output.
+	Writer Writer
+
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+	sweepAt int64
+	timer   *time.Timer
+}
+
+type rateLimitBucket struct {
+	resetAt    int64
+	count      int
+	suppressed int
+	level      Level
+	last       []byte
+}
+
+// Close writes the pending summary entries and closes the underlying writer.
+func (w *RateLimitWriter) Close() (err error) {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	for key, b := range w.buckets {
+		if err1 := w.summary(b); err1 != nil {
+			err = err1
+		}
+		delete(w.buckets, key)
+	}
+	w.mu.Unlock()
+
+	if closer, ok := w.Writer.(io.Closer); ok {
+		if err1 := closer.Close(); err1 != nil {
+			err = err1
+		}
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *RateLimitWriter) WriteEntry(e *Entry) (n int, err error) {
+	field := w.KeyField
+	if field == "" {
+		field = "message"
+	}
+	window := w.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	burst := w.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	key := jsonGetField(e.buf, field)
+	if len(key) == 0 || string(key) == `""` {
+		caller := jsonGetField(e.buf, "caller")
+		if caller == nil {
+			return w.Writer.WriteEntry(e)
+		}
+		key = append([]byte{0, byte(e.Level)}, caller...)
+	}
+	now := timeNow().UnixNano()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buckets == nil {
+		w.buckets = make(map[string]*rateLimitBucket)
+	}
+
+	if now >= w.sweepAt {
+		w.sweepAt = now + int64(window)
+		for k, b := range w.buckets {
+			if now >= b.resetAt {
+				err = w.summary(b)
+				delete(w.buckets, k)
+			}
+		}
+	}
+
+	b, ok := w.buckets[string(key)]
+	if !ok {
+		b = &rateLimitBucket{resetAt: now + int64(window)}
+		w.buckets[string(key)] = b
+	} else if now >= b.resetAt {
+		err = w.summary(b)
+		b.resetAt, b.count = now+int64(window), 0
+	}
+
+	b.count++
+	if b.count > burst {
+		b.suppressed++
+		b.level = e.Level
+		b.last = append(b.last[:0], e.buf...)
+		n = len(e.buf)
+		if w.timer == nil {
+			w.timer = time.AfterFunc(time.Duration(b.resetAt-now), w.expire)
+		}
+		return
+	}
+
+	var err1 error
+	n, err1 = w.Writer.WriteEntry(e)
+	if err1 != nil {
+		err = err1
+	}
+	return
+}
+
+// expire writes the summary entries of closed windows, and re-arms the timer for the
+// earliest window which has suppressed entries.
+func (w *RateLimitWriter) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := timeNow().UnixNano()
+	next := int64(0)
+	for k, b := range w.buckets {
+		if now >= b.resetAt {
+			_ = w.summary(b)
+			delete(w.buckets, k)
+		} else if b.suppressed > 0 && (next == 0 || b.resetAt < next) {
+			next = b.resetAt
+		}
+	}
+
+	w.timer = nil
+	if next != 0 {
+		w.timer = time.AfterFunc(time.Duration(next-now), w.expire)
+	}
+}
+
+// summary writes the last suppressed entry of bucket with a "suppressed" field.
+func (w *RateLimitWriter) summary(b *rateLimitBucket) (err error) {
+	if b.suppressed == 0 {
+		return
+	}
+
+	i := bytes.LastIndexByte(b.last, '}')
+	if i < 0 {
+		b.suppressed = 0
+		return
+	}
+
+	e := epool.Get().(*Entry)
+	e.Level = b.level
+	e.name = ""
+	e.buf = append(e.buf[:0], b.last[:i]...)
+	e.buf = append(e.buf, ",\"suppressed\":"...)
+	e.buf = strconv.AppendInt(e.buf, int64(b.suppressed), 10)
+	e.buf = append(e.buf, b.last[i:]...)
+
+	_, err = w.Writer.WriteEntry(e)
+
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+
+	b.suppressed = 0
+	return
+}
+
+var _ Writer = (*RateLimitWriter)(nil)
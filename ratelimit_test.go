@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &RateLimitWriter{
+		Burst:  2,
+		Window: 50 * time.Millisecond,
+		Writer: IOWriter{&b},
+	}
+	logger := Logger{Writer: w}
+	output := func() string {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return b.String()
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Error().Int("i", i).Msg("hot error loop")
+		logger.Info().Msg("other message")
+	}
+
+	if n := strings.Count(output(), "hot error loop"); n != 2 {
+		t.Errorf("rate limit writer should write 2 entries, got %d: %s", n, output())
+	}
+	if strings.Contains(output(), "suppressed") {
+		t.Errorf("rate limit writer should not write summary before window closes: %s", output())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if s := output(); !strings.Contains(s, `"i":9,"message":"hot error loop","suppressed":8}`) {
+		t.Errorf("rate limit writer should write summary when window closes: %s", s)
+	}
+	logger.Error().Int("i", 10).Msg("hot error loop")
+
+	if !strings.Contains(output(), `"i":9,"message":"hot error loop","suppressed":8}`) {
+		t.Errorf("rate limit writer should write summary of hot error loop: %s", output())
+	}
+	if !strings.Contains(output(), `"message":"other message","suppressed":8}`) {
+		t.Errorf("rate limit writer should write summary of other message: %s", output())
+	}
+	if !strings.Contains(output(), `"i":10,"message":"hot error loop"}`) {
+		t.Errorf("rate limit writer should write entry of new window: %s", output())
+	}
+}
+
+func TestRateLimitWriterKeyField(t *testing.T) {
+	var b bytes.Buffer
+	w := &RateLimitWriter{
+		KeyField: "code",
+		Writer:   IOWriter{&b},
+	}
+	logger := Logger{Writer: w}
+
+	for i := 0; i < 5; i++ {
+		logger.Warn().Int("code", i%2).Msgf("warning %d", i)
+	}
+
+	if n := strings.Count(b.String(), "\n"); n != 2 {
+		t.Errorf("rate limit writer should write 2 entries, got %d: %s", n, b.String())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("rate limit writer close error: %+v", err)
+	}
+
+	if !strings.Contains(b.String(), `"message":"warning 4","suppressed":2}`) {
+		t.Errorf("rate limit writer should write summary on close: %s", b.String())
+	}
+	if !strings.Contains(b.String(), `"message":"warning 3","suppressed":1}`) {
+		t.Errorf("rate limit writer should write summary on close: %s", b.String())
+	}
+}
+
+func TestRateLimitWriterEmptyKey(t *testing.T) {
+	var b bytes.Buffer
+	w := &RateLimitWriter{Writer: IOWriter{&b}}
+	logger := Logger{Writer: w}
+	caller := Logger{Caller: 1, Writer: w}
+
+	for i := 0; i < 3; i++ {
+		logger.Info().Int("i", i).Msg("")
+		caller.Info().Int("j", i).Msg("")
+		caller.Warn().Int("k", i).Msg("")
+	}
+	_ = w.Close()
+
+	s := b.String()
+	for _, v := range []string{`"i":0`, `"i":1`, `"i":2`, `"j":0`, `"k":0`, `"j":2,"suppressed":2`, `"k":2,"suppressed":2`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("rate limit writer must key the empty messages by level and caller, %s: %s", v, s)
+		}
+	}
+	if strings.Contains(s, `"j":1`) {
+		t.Errorf("rate limit writer must limit the empty messages of same caller: %s", s)
+	}
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// RecordedEntry is an entry parsed by Recorder.
+type RecordedEntry struct {
+	// Time is the time of entry, it is zero if the time is not in RFC3339 format.
+	Time time.Time
+
+	// Level is the level of entry.
+	Level Level
+
+	// Message is the message of entry.
+	Message string
+
+	// Fields is the fields of entry except time, level, caller, goid, stack and message,
+	// the values are in the types of encoding/json, see ParsedField.Interface.
+	Fields map[string]interface{}
+}
+
+// Recorder is a Writer which records the parsed entries in memory, for the tests to verify
+// the logging without matching the json lines, e.g.
+//
+//	r := &log.Recorder{}
+//	logger := log.Logger{Level: log.TraceLevel, Writer: r}
+//	logger.Info().Str("user", "x").Msg("login")
+//	r.Has(log.InfoLevel, "login", log.Fields{"user": "x"}) // true
+type Recorder struct {
+	mu      sync.Mutex
+	entries []RecordedEntry
+}
+
+// WriteEntry implements Writer.
+func (r *Recorder) WriteEntry(e *Entry) (int, error) {
+	parsed, err := Parse(e.buf)
+	if err != nil {
+		return 0, err
+	}
+
+	entry := RecordedEntry{Time: parsed.Time, Level: e.Level, Message: parsed.Message}
+	entry.Fields = make(map[string]interface{}, len(parsed.Fields))
+	for _, field := range parsed.Fields {
+		entry.Fields[field.Key] = field.Interface()
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return len(e.buf), nil
+}
+
+// Match reports whether the entry has the level, message and fields. The zero level and
+// empty message match any, the field values are compared in their json forms.
+func (entry *RecordedEntry) Match(level Level, msg string, fields Fields) bool {
+	if level != 0 && entry.Level != level {
+		return false
+	}
+	if msg != "" && entry.Message != msg {
+		return false
+	}
+	for key, value := range fields {
+		v, ok := entry.Fields[key]
+		if !ok || !reflect.DeepEqual(v, recorderValue(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// recorderValue converts v to the value decoded by encoding/json.
+func recorderValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var value interface{}
+	if json.Unmarshal(data, &value) != nil {
+		return v
+	}
+	return value
+}
+
+// Has reports whether any entry matches the level, message and fields.
+func (r *Recorder) Has(level Level, msg string, fields Fields) bool {
+	return r.Count(level, msg, fields) != 0
+}
+
+// Count returns the number of entries matching the level, message and fields.
+func (r *Recorder) Count(level Level, msg string, fields Fields) (n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.entries {
+		if r.entries[i].Match(level, msg, fields) {
+			n++
+		}
+	}
+	return
+}
+
+// Find returns the entries matching the level, message and fields.
+func (r *Recorder) Find(level Level, msg string, fields Fields) (entries []RecordedEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.entries {
+		if r.entries[i].Match(level, msg, fields) {
+			entries = append(entries, r.entries[i])
+		}
+	}
+	return
+}
+
+// Entries returns a copy of the recorded entries.
+func (r *Recorder) Entries() []RecordedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEntry(nil), r.entries...)
+}
+
+// Len returns the number of recorded entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Last returns the last recorded entry, and reports whether it exists.
+func (r *Recorder) Last() (entry RecordedEntry, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return
+	}
+	return r.entries[len(r.entries)-1], true
+}
+
+// Reset discards the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+var _ Writer = (*Recorder)(nil)
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{}
+	logger := Logger{Level: TraceLevel, Writer: r}
+
+	logger.Info().Str("user", "x").Int("n", 42).Msg("login")
+	logger.Info().Str("user", "y").Msg("login")
+	logger.Error().Err(errNotFound).Dict("req", NewContext(nil).Str("id", "1").Value()).Msg("failed")
+
+	if n := r.Len(); n != 3 {
+		t.Fatalf("recorder must record 3 entries: %d", n)
+	}
+	if !r.Has(InfoLevel, "login", Fields{"user": "x", "n": 42}) {
+		t.Errorf("recorder must have the login entry of x: %+v", r.Entries())
+	}
+	if r.Has(WarnLevel, "login", nil) || r.Has(InfoLevel, "login", Fields{"user": "z"}) || r.Has(InfoLevel, "login", Fields{"missing": 1}) {
+		t.Errorf("recorder must not have the mismatched entries")
+	}
+	if n := r.Count(InfoLevel, "login", nil); n != 2 {
+		t.Errorf("recorder count of login must be 2: %d", n)
+	}
+	if n := r.Count(0, "", nil); n != 3 {
+		t.Errorf("recorder count of any must be 3: %d", n)
+	}
+	if entries := r.Find(0, "", Fields{"user": "y"}); len(entries) != 1 || entries[0].Fields["user"] != "y" {
+		t.Errorf("recorder find of y mismatched: %+v", entries)
+	}
+	if !r.Has(ErrorLevel, "failed", Fields{"error": "not found", "req": map[string]string{"id": "1"}}) {
+		t.Errorf("recorder must match the nested fields: %+v", r.Entries())
+	}
+
+	last, ok := r.Last()
+	if !ok || last.Level != ErrorLevel || last.Message != "failed" || time.Since(last.Time) > time.Minute {
+		t.Errorf("recorder last entry mismatched: %+v", last)
+	}
+	if _, ok := last.Fields["time"]; ok {
+		t.Errorf("recorder fields must not contain time: %+v", last.Fields)
+	}
+
+	r.Reset()
+	if _, ok := r.Last(); ok || r.Len() != 0 {
+		t.Errorf("recorder must be empty after reset")
+	}
+}
+
+type recorderError string
+
+func (e recorderError) Error() string { return string(e) }
+
+const errNotFound = recorderError("not found")
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"crypto/sha256"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactKeys is the default key patterns of RedactWriter.
+var DefaultRedactKeys = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"*token*",
+	"*api_key*",
+	"*apikey*",
+	"authorization",
+	"cookie",
+	"set-cookie",
+	"ssn",
+}
+
+// RedactWriter is an Writer that masks or hashes the secrets and PII of entries before
+// writing, the values of matched keys are replaced at any depth of nested objects, and the
+// substrings of string values which match the regexps are replaced, e.g.
+//
+//	{"level":"info","user":"alice","password":"***","card":"card ***","message":"login"}
+type RedactWriter struct {
+	// Keys specifies the patterns of keys to redact, which are matched by path.Match
+	// case-insensitively, uses DefaultRedactKeys if nil.
+	Keys []string
+
+	// Values specifies the regexps of string values to redact, e.g. credit card numbers.
+	Values []*regexp.Regexp
+
+	// Mask specifies the replacement of redacted values, uses "***" if empty.
+	Mask string
+
+	// Hash determines if replaces the redacted values with the "sha256:" prefixed first
+	// 16 hex digits of sha256 of values instead of Mask, so the equal values can be correlated.
+	Hash bool
+
+	// Writer specifies the writer of output.
+	Writer Writer
+
+	once sync.Once
+	keys []string
+}
+
+// Close implements io.Closer, will closes the underlying Writer if not empty.
+func (w *RedactWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *RedactWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.once.Do(func() {
+		keys := w.Keys
+		if keys == nil {
+			keys = DefaultRedactKeys
+		}
+		for _, key := range keys {
+			w.keys = append(w.keys, strings.ToLower(key))
+		}
+	})
+
+	if len(e.buf) == 0 || e.buf[0] != '{' {
+		return w.Writer.WriteEntry(e)
+	}
+
+	b := bbpool.Get().(*bb)
+	t := bbpool.Get().(*bb)
+	defer func() {
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+		if cap(t.B) <= bbcap {
+			bbpool.Put(t)
+		}
+	}()
+
+	b.B = w.object(b.B[:0], e.buf, t)
+	if e.buf[len(e.buf)-1] == '\n' {
+		b.B = append(b.B, '\n')
+	}
+
+	e.buf, b.B = b.B, e.buf
+	n, err = w.Writer.WriteEntry(e)
+	e.buf, b.B = b.B, e.buf
+
+	return
+}
+
+func (w *RedactWriter) object(dst, json []byte, t *bb) []byte {
+	dst = append(dst, '{')
+	first := true
+	jsonRange(json, func(key, value []byte) bool {
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst = append(dst, '"')
+		dst = append(dst, key...)
+		dst = append(dst, '"', ':')
+		if w.match(key) {
+			dst = w.mask(dst, value, t)
+		} else {
+			dst = w.value(dst, value, t)
+		}
+		return true
+	})
+	return append(dst, '}')
+}
+
+func (w *RedactWriter) value(dst, value []byte, t *bb) []byte {
+	switch {
+	case len(value) == 0:
+		return dst
+	case value[0] == '{':
+		return w.object(dst, value, t)
+	case value[0] == '[':
+		dst = append(dst, '[')
+		first := true
+		jsonRangeArray(value, func(value []byte) bool {
+			if !first {
+				dst = append(dst, ',')
+			}
+			first = false
+			dst = w.value(dst, value, t)
+			return true
+		})
+		return append(dst, ']')
+	case value[0] == '"' && len(w.Values) != 0 && len(value) > 2:
+		s := encodeUnescape(value[1:len(value)-1], t)
+		matched := false
+		for _, re := range w.Values {
+			if re.MatchString(s) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return append(dst, value...)
+		}
+		for _, re := range w.Values {
+			s = re.ReplaceAllStringFunc(s, w.replace)
+		}
+		return jsonAppendString(dst, s)
+	}
+	return append(dst, value...)
+}
+
+// mask appends the redacted value.
+func (w *RedactWriter) mask(dst, value []byte, t *bb) []byte {
+	if !w.Hash {
+		return jsonAppendString(dst, w.replace(""))
+	}
+	s := b2s(value)
+	if len(value) >= 2 && value[0] == '"' {
+		s = encodeUnescape(value[1:len(value)-1], t)
+	}
+	return jsonAppendString(dst, w.replace(s))
+}
+
+// replace returns the mask or hash of s.
+func (w *RedactWriter) replace(s string) string {
+	if w.Hash {
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hexEncode(sum[:8])
+	}
+	if w.Mask != "" {
+		return w.Mask
+	}
+	return "***"
+}
+
+// match reports whether the key is redacted.
+func (w *RedactWriter) match(key []byte) bool {
+	lower := strings.ToLower(b2s(key))
+	for _, pattern := range w.keys {
+		if ok, _ := path.Match(pattern, lower); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var _ Writer = (*RedactWriter)(nil)
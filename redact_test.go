@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		TimeFormat: TimeFormatUnix,
+		Writer: &RedactWriter{
+			Values: []*regexp.Regexp{regexp.MustCompile(`\b\d{4}-\d{4}-\d{4}-\d{4}\b`)},
+			Writer: IOWriter{&buf},
+		},
+	}
+
+	logger.Info().
+		Str("user", "alice").
+		Str("Password", "hunter2").
+		Int("api_key", 42).
+		Group("http", func(e *Entry) {
+			e.Str("Authorization", "Bearer xyz").Strs("cookies", []string{"a"})
+		}).
+		Array("items", func(a *Array) {
+			a.Object(func(e *Entry) { e.Str("access_token", "t") }).Str("card 1234-5678-9012-3456 used")
+		}).
+		Str("card", "paid by 1234-5678-9012-3456\t").
+		Msg("login")
+
+	want := `"user":"alice","Password":"***","api_key":"***","http":{"Authorization":"***","cookies":["a"]},"items":[{"access_token":"***"},"card *** used"],"card":"paid by ***\t","message":"login"}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("redacted output got %s, want %s", got, want)
+	}
+
+	buf.Reset()
+	logger.Writer = &RedactWriter{
+		Keys:   []string{"email"},
+		Hash:   true,
+		Writer: IOWriter{&buf},
+	}
+	logger.Info().Str("email", "alice@example.com").Str("password", "x").Msg("")
+	want = `"email":"sha256:ff8d9819fc0e12bf","password":"x"}` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("hashed output got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,304 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisWriter is a Writer that appends entries in batches to a redis stream by pipelined
+// XADD commands, it reconnects to server automatically after connection errors.
+type RedisWriter struct {
+	// Addr specifies the address of redis server, uses "localhost:6379" if empty.
+	Addr string
+
+	// Username and Password specifies the credential of AUTH command.
+	Username string
+	Password string
+
+	// DB specifies the database of SELECT command.
+	DB int
+
+	// Stream specifies the key of stream, uses "logs" if empty.
+	Stream string
+
+	// Field specifies the field name of entries in stream, uses "log" if empty.
+	Field string
+
+	// MaxLen specifies the approximate maximum length of stream trimmed by XADD,
+	// zero value disables trimming.
+	MaxLen int64
+
+	// TLSConfig specifies the tls config of connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Timeout specifies the dial and request timeout, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a pipeline, uses 100 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Dial specifies the dial function for creating connections, uses net.DialTimeout if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	once    sync.Once
+	batcher batcher
+
+	// the states below are owned by the goroutine of batcher.
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func (w *RedisWriter) init() {
+	w.once.Do(func() {
+		w.batcher.size = w.BatchSize
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and closes the connection.
+func (w *RedisWriter) Close() (err error) {
+	w.init()
+	err = w.batcher.close()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// WriteEntry implements Writer.
+func (w *RedisWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *RedisWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (w *RedisWriter) send(b *batch) (err error) {
+	if w.conn == nil {
+		if err = w.connect(); err != nil {
+			return
+		}
+	}
+	defer func() {
+		// the connection is still usable after error replies.
+		var re redisError
+		if err != nil && w.conn != nil && !errors.As(err, &re) {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	stream, field := w.Stream, w.Field
+	if stream == "" {
+		stream = "logs"
+	}
+	if field == "" {
+		field = "log"
+	}
+	var maxlen string
+	if w.MaxLen > 0 {
+		maxlen = strconv.FormatInt(w.MaxLen, 10)
+	}
+
+	buf := bbpool.Get().(*bb)
+	defer bbpool.Put(buf)
+
+	// XADD stream [MAXLEN ~ n] * field entry
+	dst := buf.B[:0]
+	for i := 0; i < b.Len(); i++ {
+		entry := bytes.TrimRight(b.Entry(i), "\n")
+		if maxlen != "" {
+			dst = append(dst, "*8\r\n$4\r\nXADD\r\n"...)
+			dst = redisAppendBulk(dst, stream)
+			dst = append(dst, "$6\r\nMAXLEN\r\n$1\r\n~\r\n"...)
+			dst = redisAppendBulk(dst, maxlen)
+		} else {
+			dst = append(dst, "*5\r\n$4\r\nXADD\r\n"...)
+			dst = redisAppendBulk(dst, stream)
+		}
+		dst = append(dst, "$1\r\n*\r\n"...)
+		dst = redisAppendBulk(dst, field)
+		dst = redisAppendBulk(dst, b2s(entry))
+	}
+	buf.B = dst
+
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	if _, err = w.conn.Write(buf.B); err != nil {
+		return
+	}
+
+	// reads the replies of pipeline, and keeps the failed entries for retrying.
+	failed := make([]bool, b.Len())
+	var rerr error
+	for i := 0; i < b.Len(); i++ {
+		if _, err = w.read(); err != nil {
+			if _, ok := err.(redisError); !ok {
+				return
+			}
+			failed[i], rerr = true, err
+		}
+	}
+	b.filter(func(i int, _ []byte) bool {
+		return failed[i]
+	})
+	if rerr != nil && !rerr.(redisError).temporary() {
+		return permanentError{rerr}
+	}
+	return rerr
+}
+
+func (w *RedisWriter) connect() (err error) {
+	addr := w.Addr
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	if w.Dial != nil {
+		w.conn, err = w.Dial("tcp", addr)
+	} else {
+		w.conn, err = net.DialTimeout("tcp", addr, w.timeout())
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		w.conn = tls.Client(w.conn, config)
+	}
+	_ = w.conn.SetDeadline(timeNow().Add(w.timeout()))
+	w.br = bufio.NewReader(w.conn)
+
+	var dst []byte
+	var n int
+	if w.Password != "" {
+		if w.Username != "" {
+			dst = append(dst, "*3\r\n$4\r\nAUTH\r\n"...)
+			dst = redisAppendBulk(dst, w.Username)
+		} else {
+			dst = append(dst, "*2\r\n$4\r\nAUTH\r\n"...)
+		}
+		dst = redisAppendBulk(dst, w.Password)
+		n++
+	}
+	if w.DB != 0 {
+		dst = append(dst, "*2\r\n$6\r\nSELECT\r\n"...)
+		dst = redisAppendBulk(dst, strconv.Itoa(w.DB))
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	if _, err = w.conn.Write(dst); err != nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		if _, err = w.read(); err != nil {
+			if _, ok := err.(redisError); ok {
+				err = permanentError{err}
+			}
+			return
+		}
+	}
+	return
+}
+
+// read reads a reply from server, the error reply is returned as redisError.
+func (w *RedisWriter) read() (reply []byte, err error) {
+	line, err := w.br.ReadSlice('\n')
+	if err != nil {
+		return
+	}
+	if len(line) < 3 {
+		return nil, errors.New("log: redis invalid reply")
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		reply = line[1:]
+	case '-':
+		err = redisError(line[1:])
+	case '$':
+		var n int
+		if n, err = strconv.Atoi(b2s(line[1:])); err != nil || n < 0 {
+			return
+		}
+		reply = make([]byte, n+2)
+		_, err = io.ReadFull(w.br, reply)
+		reply = reply[:n]
+	case '*':
+		var n int
+		if n, err = strconv.Atoi(b2s(line[1:])); err != nil {
+			return
+		}
+		for i := 0; i < n && err == nil; i++ {
+			_, err = w.read()
+		}
+	default:
+		err = errors.New("log: redis invalid reply: " + string(line))
+	}
+	return
+}
+
+// redisError is an error reply of redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "log: redis error: " + string(e)
+}
+
+// temporary reports whether the error is expected to be resolved by retrying.
+func (e redisError) temporary() bool {
+	for _, prefix := range []string{"LOADING", "BUSY", "TRYAGAIN", "OOM", "CLUSTERDOWN", "MASTERDOWN"} {
+		if strings.HasPrefix(string(e), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func redisAppendBulk(dst []byte, s string) []byte {
+	dst = append(dst, '$')
+	dst = strconv.AppendInt(dst, int64(len(s)), 10)
+	dst = append(dst, '\r', '\n')
+	dst = append(dst, s...)
+	return append(dst, '\r', '\n')
+}
+
+var _ Writer = (*RedisWriter)(nil)
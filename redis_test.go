@@ -0,0 +1,132 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redisServer serves the given number of connections, and records the commands. The
+// first connection is closed without replies if drop is set.
+func redisServer(t *testing.T, conns int, drop bool) (net.Listener, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	commands := make(chan []string, 64)
+	go func() {
+		for c := 0; c < conns; c++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			br := bufio.NewReader(conn)
+			for {
+				line, err := br.ReadString('\n')
+				if err != nil || line[0] != '*' {
+					break
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				args := make([]string, n)
+				for i := range args {
+					line, _ = br.ReadString('\n')
+					size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					arg := make([]byte, size+2)
+					_, _ = io.ReadFull(br, arg)
+					args[i] = string(arg[:size])
+				}
+				if drop && c == 0 {
+					break
+				}
+				commands <- args
+				switch {
+				case args[0] == "XADD" && strings.Contains(args[len(args)-1], "wrongtype"):
+					_, _ = conn.Write([]byte("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"))
+				case args[0] == "XADD":
+					_, _ = conn.Write([]byte("$15\r\n1526919030474-0\r\n"))
+				default:
+					_, _ = conn.Write([]byte("+OK\r\n"))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return ln, commands
+}
+
+func TestRedisWriter(t *testing.T) {
+	ln, commands := redisServer(t, 1, false)
+	defer ln.Close()
+
+	w := &RedisWriter{
+		Addr:          ln.Addr().String(),
+		Password:      "secret",
+		DB:            2,
+		Stream:        "app:logs",
+		MaxLen:        10000,
+		FlushInterval: 10 * time.Millisecond,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Msg("hello redis")
+	logger.Info().Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("redis writer close error: %+v", err)
+	}
+
+	want := []string{"AUTH secret", "SELECT 2", "XADD app:logs MAXLEN ~ 10000 * log", "XADD app:logs MAXLEN ~ 10000 * log"}
+	for i, prefix := range want {
+		if len(commands) == 0 {
+			t.Fatalf("redis writer want %d commands got %d", len(want), i)
+		}
+		args := <-commands
+		if cmd := strings.Join(args, " "); !strings.HasPrefix(cmd, prefix) {
+			t.Errorf("redis writer command want %s got %s", prefix, cmd)
+		}
+		if args[0] == "XADD" && !strings.HasPrefix(args[len(args)-1], "{") {
+			t.Errorf("redis writer entry mismatch: %s", args[len(args)-1])
+		}
+	}
+}
+
+func TestRedisWriterReconnect(t *testing.T) {
+	ln, commands := redisServer(t, 2, true)
+	defer ln.Close()
+
+	w := &RedisWriter{
+		Addr: ln.Addr().String(),
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello redis")
+	if err := w.Close(); err != nil {
+		t.Fatalf("redis writer close error: %+v", err)
+	}
+	if args := <-commands; strings.Join(args[:4], " ") != "XADD logs * log" {
+		t.Errorf("redis writer command mismatch: %v", args)
+	}
+}
+
+func TestRedisWriterErrorReply(t *testing.T) {
+	ln, commands := redisServer(t, 1, false)
+	defer ln.Close()
+
+	w := &RedisWriter{
+		Addr: ln.Addr().String(),
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+	logger.Info().Msg("wrongtype")
+	logger.Info().Msg("hello redis")
+	err := w.Close()
+	if err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("redis writer should fail with WRONGTYPE: %+v", err)
+	}
+	// the permanent error reply is not retried.
+	if n := len(commands); n != 2 {
+		t.Errorf("redis writer want 2 commands got %d", n)
+	}
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Sampler defines an interface to a log sampler.
+type Sampler interface {
+	// Sample returns true if the entry of level should be part of the sample,
+	// false if the entry should be dropped.
+	Sample(level Level) bool
+}
+
+// BasicSampler is a sampler that will send every Nth entries, regardless of their level.
+type BasicSampler struct {
+	// N specifies the sample rate, all entries are sent if N is less than 2.
+	N uint32
+
+	counter uint32
+}
+
+// Sample implements Sampler.
+func (s *BasicSampler) Sample(level Level) bool {
+	n := s.N
+	if n <= 1 {
+		return true
+	}
+	return (atomic.AddUint32(&s.counter, 1)-1)%n == 0
+}
+
+// RandomSampler is a sampler that will send 1/N entries randomly, regardless of their level.
+type RandomSampler uint32
+
+// Sample implements Sampler.
+func (s RandomSampler) Sample(level Level) bool {
+	if s <= 1 {
+		return true
+	}
+	return Fastrandn(uint32(s)) == 0
+}
+
+// BurstSampler is a sampler that lets Burst entries pass per Period, then send
+// the rest entries to NextSampler.
+type BurstSampler struct {
+	// Burst specifies the maximum number of entries per period.
+	Burst uint32
+
+	// Period specifies the burst period, the entries are always sent to NextSampler if zero.
+	Period time.Duration
+
+	// NextSampler specifies the sampler used after the burst is reached,
+	// all the exceeded entries are dropped if it is nil.
+	NextSampler Sampler
+
+	counter uint32
+	resetAt int64
+}
+
+// Sample implements Sampler.
+func (s *BurstSampler) Sample(level Level) bool {
+	if s.Burst > 0 && s.Period > 0 && s.inc() <= s.Burst {
+		return true
+	}
+	if s.NextSampler == nil {
+		return false
+	}
+	return s.NextSampler.Sample(level)
+}
+
+func (s *BurstSampler) inc() uint32 {
+	now := timeNow().UnixNano()
+	resetAt := atomic.LoadInt64(&s.resetAt)
+	if now > resetAt {
+		if atomic.CompareAndSwapInt64(&s.resetAt, resetAt, now+int64(s.Period)) {
+			atomic.StoreUint32(&s.counter, 1)
+			return 1
+		}
+	}
+	return atomic.AddUint32(&s.counter, 1)
+}
+
+// LevelSampler is a sampler applies different sampler for each level,
+// the entries are always sent if the sampler of level is nil.
+type LevelSampler struct {
+	TraceSampler Sampler
+	DebugSampler Sampler
+	InfoSampler  Sampler
+	WarnSampler  Sampler
+	ErrorSampler Sampler
+}
+
+// Sample implements Sampler.
+func (s *LevelSampler) Sample(level Level) bool {
+	var sampler Sampler
+	switch level {
+	case TraceLevel:
+		sampler = s.TraceSampler
+	case DebugLevel:
+		sampler = s.DebugSampler
+	case InfoLevel:
+		sampler = s.InfoSampler
+	case WarnLevel:
+		sampler = s.WarnSampler
+	case ErrorLevel:
+		sampler = s.ErrorSampler
+	}
+	if sampler == nil {
+		return true
+	}
+	return sampler.Sample(level)
+}
+
+var _ Sampler = (*BasicSampler)(nil)
+var _ Sampler = RandomSampler(0)
+var _ Sampler = (*BurstSampler)(nil)
+var _ Sampler = (*LevelSampler)(nil)
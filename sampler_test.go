@@ -0,0 +1,140 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBasicSampler(t *testing.T) {
+	var b bytes.Buffer
+	logger := Logger{
+		Sampler: &BasicSampler{N: 3},
+		Writer:  IOWriter{&b},
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Info().Int("i", i).Msg("hello basic sampler")
+	}
+
+	if n := strings.Count(b.String(), "\n"); n != 4 {
+		t.Errorf("basic sampler should send 4 entries, got %d: %s", n, b.String())
+	}
+	if !strings.Contains(b.String(), `"i":9,`) {
+		t.Errorf("basic sampler should send every 3rd entry: %s", b.String())
+	}
+}
+
+func TestRandomSampler(t *testing.T) {
+	var count int
+	for i := 0; i < 1000; i++ {
+		if RandomSampler(10).Sample(InfoLevel) {
+			count++
+		}
+	}
+	if count == 0 || count > 300 {
+		t.Errorf("random sampler sent unexpected %d entries", count)
+	}
+	if !RandomSampler(0).Sample(InfoLevel) {
+		t.Errorf("random sampler with zero rate should send all entries")
+	}
+}
+
+func TestBurstSampler(t *testing.T) {
+	s := &BurstSampler{
+		Burst:       2,
+		Period:      50 * time.Millisecond,
+		NextSampler: &BasicSampler{N: 4},
+	}
+
+	var count int
+	for i := 0; i < 10; i++ {
+		if s.Sample(InfoLevel) {
+			count++
+		}
+	}
+	if count != 2+2 {
+		t.Errorf("burst sampler should send 4 entries, got %d", count)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !s.Sample(InfoLevel) {
+		t.Errorf("burst sampler should reset after period")
+	}
+
+	if (&BurstSampler{}).Sample(InfoLevel) {
+		t.Errorf("empty burst sampler should drop all entries")
+	}
+}
+
+func TestLevelSampler(t *testing.T) {
+	var b bytes.Buffer
+	logger := Logger{
+		Level: TraceLevel,
+		Sampler: &LevelSampler{
+			DebugSampler: &BasicSampler{N: 5},
+			InfoSampler:  &BurstSampler{Burst: 1, Period: time.Minute},
+		},
+		Writer: IOWriter{&b},
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Trace().Msg("trace")
+		logger.Debug().Msg("debug")
+		logger.Info().Msg("info")
+		logger.Warn().Msg("warn")
+	}
+
+	for level, want := range map[string]int{"trace": 10, "debug": 2, "info": 1, "warn": 10} {
+		if n := strings.Count(b.String(), `"level":"`+level+`"`); n != want {
+			t.Errorf("level sampler should send %d %s entries, got %d", want, level, n)
+		}
+	}
+}
+
+type countSampler int
+
+func (s *countSampler) Sample(level Level) bool {
+	*s++
+	return true
+}
+
+func TestSamplerLevelCheck(t *testing.T) {
+	var sampler countSampler
+	logger := Logger{
+		Level:   InfoLevel,
+		Sampler: &sampler,
+		Writer:  IOWriter{io.Discard},
+	}
+
+	g := &GRPCLogger{Logger: &logger}
+	for i := 0; i < 10; i++ {
+		_ = g.V(0)
+	}
+	logger.Debug().Msg("hello debug")
+	if sampler != 0 {
+		t.Errorf("level checks must not sample, got %d samples", sampler)
+	}
+
+	logger.Info().Msg("hello info")
+	if sampler != 1 {
+		t.Errorf("an emitted entry must sample once, got %d samples", sampler)
+	}
+}
+
+func BenchmarkBasicSampler(b *testing.B) {
+	logger := Logger{
+		Sampler: &BasicSampler{N: 10},
+		Writer:  IOWriter{io.Discard},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info().Str("foo", "bar").Msg("hello sampler")
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedWriter is an Writer that buffers the entries in shards by goroutine affinity,
+// and merges the shards in time order to the underlying writer, so the hot loggers of
+// many cores do not contend on a global writer mutex.
+//
+// The entries are written when a shard is full, periodically, and on Flush or Close.
+type ShardedWriter struct {
+	// Shards specifies the number of shards, uses runtime.GOMAXPROCS(0) if zero.
+	Shards int
+
+	// ShardSize specifies the buffered bytes of a shard before flushing, uses 64KB if zero.
+	ShardSize int
+
+	// FlushInterval specifies the interval of flushing, uses 1 second if zero, and
+	// flushes only on full shards and Close if negative.
+	FlushInterval time.Duration
+
+	// Writer specifies the writer of merged output.
+	Writer io.Writer
+
+	closed uint32
+	once   sync.Once
+	shards []writerShard
+	mu     sync.Mutex
+	buf    []byte
+	heads  []int
+	timer  *time.Timer
+}
+
+type writerShard struct {
+	mu    sync.Mutex
+	buf   []byte
+	recs  []shardRecord
+	spare []byte
+	srecs []shardRecord
+	_     [64]byte // avoids false sharing
+}
+
+// shardRecord is the monotonic time and end offset of an entry in the shard buffer.
+type shardRecord struct {
+	mono int64
+	end  int
+}
+
+// WriteEntry implements Writer.
+func (w *ShardedWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.once.Do(w.start)
+
+	s := &w.shards[uint(goid())%uint(len(w.shards))]
+	s.mu.Lock()
+	if atomic.LoadUint32(&w.closed) != 0 {
+		s.mu.Unlock()
+		return 0, ErrClosed
+	}
+	_, _, mono := now()
+	s.buf = append(s.buf, e.buf...)
+	s.recs = append(s.recs, shardRecord{mono, len(s.buf)})
+	full := len(s.buf) >= w.size()
+	s.mu.Unlock()
+
+	if full {
+		err = w.Flush()
+	}
+
+	return len(e.buf), err
+}
+
+func (w *ShardedWriter) size() int {
+	if w.ShardSize > 0 {
+		return w.ShardSize
+	}
+	return 64 * 1024
+}
+
+func (w *ShardedWriter) start() {
+	shards := w.Shards
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	w.shards = make([]writerShard, shards)
+	w.heads = make([]int, shards)
+
+	if w.FlushInterval >= 0 {
+		interval := w.FlushInterval
+		if interval == 0 {
+			interval = time.Second
+		}
+		w.mu.Lock()
+		w.timer = time.AfterFunc(interval, func() {
+			_ = w.Flush()
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.timer != nil {
+				w.timer.Reset(interval)
+			}
+		})
+		w.mu.Unlock()
+	}
+}
+
+// Flush merges the buffered entries of shards in time order and writes them to the
+// underlying writer.
+func (w *ShardedWriter) Flush() (err error) {
+	w.once.Do(w.start)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flush()
+}
+
+func (w *ShardedWriter) flush() (err error) {
+	total := 0
+	for i := range w.shards {
+		s := &w.shards[i]
+		s.mu.Lock()
+		s.buf, s.spare = s.spare[:0], s.buf
+		s.recs, s.srecs = s.srecs[:0], s.recs
+		s.mu.Unlock()
+		w.heads[i] = 0
+		total += len(s.srecs)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	// merges the shards by the monotonic time of entries
+	w.buf = w.buf[:0]
+	for ; total > 0; total-- {
+		k := -1
+		for i := range w.shards {
+			s := &w.shards[i]
+			if w.heads[i] < len(s.srecs) && (k < 0 || s.srecs[w.heads[i]].mono < w.shards[k].srecs[w.heads[k]].mono) {
+				k = i
+			}
+		}
+		s, j := &w.shards[k], w.heads[k]
+		start := 0
+		if j > 0 {
+			start = s.srecs[j-1].end
+		}
+		w.buf = append(w.buf, s.spare[start:s.srecs[j].end]...)
+		w.heads[k]++
+	}
+
+	_, err = w.Writer.Write(w.buf)
+	return
+}
+
+// Close implements io.Closer, it flushes the buffered entries, and closes the underlying
+// Writer if it is an io.Closer. The writes after Close return ErrClosed.
+func (w *ShardedWriter) Close() (err error) {
+	w.once.Do(w.start)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !atomic.CompareAndSwapUint32(&w.closed, 0, 1) {
+		return nil
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	err = w.flush()
+	if closer, ok := w.Writer.(io.Closer); ok {
+		if err1 := closer.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return
+}
+
+var _ Writer = (*ShardedWriter)(nil)
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestShardedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &ShardedWriter{Shards: 4, ShardSize: 256, Writer: &buf}
+
+	// the entries of successive goroutines must keep the order across shards
+	for i := 0; i < 100; i++ {
+		done := make(chan struct{})
+		go func(i int) {
+			_, _ = wlprintf(w, InfoLevel, "%03d\n", i)
+			close(done)
+		}(i)
+		<-done
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("sharded writer close error: %+v", err)
+	}
+	if _, err := wlprintf(w, InfoLevel, "after close\n"); err != ErrClosed {
+		t.Errorf("sharded writer must return ErrClosed after close, not %+v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 100 {
+		t.Fatalf("sharded writer must write 100 entries, not %d", len(lines))
+	}
+	for i, line := range lines {
+		if want := fmt.Sprintf("%03d", i); line != want {
+			t.Fatalf("sharded writer entry %d must be %s, not %s", i, want, line)
+		}
+	}
+}
+
+func TestShardedWriterParallel(t *testing.T) {
+	var buf compressBuffer
+	w := &ShardedWriter{ShardSize: 1024, Writer: &buf}
+	logger := Logger{Writer: w}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info().Int("g", i).Int("j", j).Msg("hello sharded writer")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("sharded writer close error: %+v", err)
+	}
+	if n := strings.Count(buf.buf.String(), "hello sharded writer"); n != 800 {
+		t.Errorf("sharded writer must write 800 entries, not %d", n)
+	}
+}
+
+func BenchmarkShardedWriter(b *testing.B) {
+	logger := Logger{
+		Writer: &ShardedWriter{
+			Writer: io.Discard,
+		},
+	}
+	b.SetParallelism(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(b *testing.PB) {
+		for b.Next() {
+			logger.Info().Msg("hello sharded writer")
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// SignWriter is an Writer that appends a signature field to each entry for tamper-evident
+// audit logs, the signature is the HMAC-SHA256 of entry with Key, or SHA256 if Key is empty.
+// If Chain is true, the signature covers the signature of previous entry, so that deleting,
+// reordering or inserting entries breaks the chain, e.g.
+//
+//	{"time":"2020-07-12T05:03:43.949Z","level":"info","message":"login","sig":"5c2a...9f"}
+//
+// Use Verify to audit the output.
+type SignWriter struct {
+	// Key specifies the key of HMAC-SHA256, uses SHA256 without key if empty.
+	Key []byte
+
+	// Field specifies the field of signature, uses "sig" if empty.
+	Field string
+
+	// Chain determines if chains the signature of entry with the previous one.
+	Chain bool
+
+	// Prev specifies the signature of previous entry in hex when Chain is true, e.g. the last
+	// signature of the appended file, the chain starts from empty if empty.
+	Prev string
+
+	// Writer specifies the writer of output.
+	Writer Writer
+
+	mu   sync.Mutex
+	prev []byte
+	h    hash.Hash
+}
+
+// ErrSignature is returned by SignWriter.Verify if an entry is tampered.
+var ErrSignature = errors.New("log: invalid signature")
+
+// Close implements io.Closer, will closes the underlying Writer if not empty.
+func (w *SignWriter) Close() (err error) {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return
+}
+
+func (w *SignWriter) field() string {
+	if w.Field == "" {
+		return "sig"
+	}
+	return w.Field
+}
+
+func (w *SignWriter) hash() hash.Hash {
+	if len(w.Key) != 0 {
+		return hmac.New(sha256.New, w.Key)
+	}
+	return sha256.New()
+}
+
+// sign appends the hex signature of data chained with prev.
+func sign(dst []byte, h hash.Hash, prev, data []byte) []byte {
+	h.Reset()
+	h.Write(prev)
+	h.Write(data)
+	var sum [sha256.Size]byte
+	for _, c := range h.Sum(sum[:0]) {
+		dst = append(dst, hex[c>>4], hex[c&0xf])
+	}
+	return dst
+}
+
+// WriteEntry implements Writer.
+func (w *SignWriter) WriteEntry(e *Entry) (n int, err error) {
+	i := bytes.LastIndexByte(e.buf, '}')
+	if i < 0 {
+		return w.Writer.WriteEntry(e)
+	}
+
+	b := bbpool.Get().(*bb)
+	defer func() {
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+	}()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.h == nil {
+		w.h = w.hash()
+		w.prev = append(w.prev[:0], w.Prev...)
+	}
+
+	b.B = append(b.B[:0], e.buf[:i]...)
+	if i > 1 {
+		b.B = append(b.B, ',')
+	}
+	b.B = append(b.B, '"')
+	b.B = append(b.B, w.field()...)
+	b.B = append(b.B, '"', ':', '"')
+	start := len(b.B)
+	var prev []byte
+	if w.Chain {
+		prev = w.prev
+	}
+	b.B = sign(b.B, w.h, prev, e.buf[:i])
+	if w.Chain {
+		w.prev = append(w.prev[:0], b.B[start:]...)
+	}
+	b.B = append(b.B, '"')
+	b.B = append(b.B, e.buf[i:]...)
+
+	e.buf, b.B = b.B, e.buf
+	n, err = w.Writer.WriteEntry(e)
+	e.buf, b.B = b.B, e.buf
+
+	return
+}
+
+// Verify verifies the signatures of entries written by the writer with the same Key, Field,
+// Chain and Prev, it returns the number of verified entries, and ErrSignature with the
+// ordinal of first tampered entry.
+func (w *SignWriter) Verify(r io.Reader) (n int, err error) {
+	h := w.hash()
+	field := []byte(`"` + w.field() + `":"`)
+	prev := []byte(w.Prev)
+	var sig []byte
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		i := bytes.LastIndex(line, field)
+		j := i + len(field) + 2*sha256.Size
+		if i < 1 || j+2 != len(line) || line[j] != '"' || line[j+1] != '}' {
+			return n, &signatureError{n + 1}
+		}
+		var data []byte
+		switch line[i-1] {
+		case ',':
+			data = line[:i-1]
+		case '{':
+			data = line[:i]
+		default:
+			return n, &signatureError{n + 1}
+		}
+		if !w.Chain {
+			prev = nil
+		}
+		sig = sign(sig[:0], h, prev, data)
+		if !hmac.Equal(sig, line[i+len(field):j]) {
+			return n, &signatureError{n + 1}
+		}
+		prev = append(prev[:0], sig...)
+		n++
+	}
+	return n, scanner.Err()
+}
+
+type signatureError struct {
+	line int
+}
+
+func (e *signatureError) Error() string {
+	return ErrSignature.Error() + " at entry " + strconv.Itoa(e.line)
+}
+
+func (e *signatureError) Unwrap() error {
+	return ErrSignature
+}
+
+var _ Writer = (*SignWriter)(nil)
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignWriter(t *testing.T) {
+	for _, c := range []struct {
+		Key   []byte
+		Chain bool
+	}{
+		{nil, false},
+		{[]byte("secret"), false},
+		{[]byte("secret"), true},
+		{nil, true},
+	} {
+		var buf bytes.Buffer
+		w := &SignWriter{Key: c.Key, Chain: c.Chain, Writer: IOWriter{&buf}}
+		logger := Logger{Writer: w}
+		logger.Info().Str("user", "alice").Msg("login")
+		logger.Info().Msg("")
+		logger.Log().Msg("")
+		_, _ = wlprintf(w, InfoLevel, "{}\n")
+
+		lines := strings.SplitAfter(buf.String(), "\n")
+		lines = lines[:len(lines)-1]
+		if !strings.Contains(lines[0], `"message":"login","sig":"`) || !strings.HasPrefix(lines[3], `{"sig":"`) {
+			t.Errorf("signed output got %s", buf.String())
+		}
+
+		if n, err := w.Verify(strings.NewReader(buf.String())); n != 4 || err != nil {
+			t.Errorf("Verify(%+v) must return 4 and nil, not %d and %+v", c, n, err)
+		}
+
+		tampered := strings.Replace(buf.String(), "alice", "bob", 1)
+		if n, err := w.Verify(strings.NewReader(tampered)); n != 0 || !errors.Is(err, ErrSignature) {
+			t.Errorf("Verify(%+v) of tampered must return 0 and ErrSignature, not %d and %+v", c, n, err)
+		}
+
+		removed := lines[0] + lines[2] + lines[3]
+		n, err := w.Verify(strings.NewReader(removed))
+		if c.Chain && (n != 1 || err == nil || err.Error() != "log: invalid signature at entry 2") {
+			t.Errorf("Verify(%+v) of removed must return 1 and error, not %d and %+v", c, n, err)
+		}
+		if !c.Chain && (n != 3 || err != nil) {
+			t.Errorf("Verify(%+v) of removed must return 3 and nil, not %d and %+v", c, n, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	w1 := &SignWriter{Key: []byte("secret"), Chain: true, Writer: IOWriter{&buf}}
+	_, _ = wlprintf(w1, InfoLevel, `{"n":1}`+"\n")
+	prev := buf.String()[len(buf.String())-67 : len(buf.String())-3]
+	w2 := &SignWriter{Key: []byte("secret"), Chain: true, Prev: prev, Writer: IOWriter{&buf}}
+	_, _ = wlprintf(w2, InfoLevel, `{"n":2}`+"\n")
+	if n, err := w1.Verify(strings.NewReader(buf.String())); n != 2 || err != nil {
+		t.Errorf("Verify of resumed chain must return 2 and nil, not %d and %+v", n, err)
+	}
+}
@@ -0,0 +1,104 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// SignalHandler handles the signals which operators send to classic daemons, it changes
+// the level of Logger to Level on SIGUSR1 and restores it on SIGUSR2, and rotates the
+// Rotators on SIGHUP, e.g. the FileWriters after logrotate moved the files.
+type SignalHandler struct {
+	// Logger specifies the logger changed by SIGUSR1 and SIGUSR2, uses DefaultLogger if nil.
+	Logger *Logger
+
+	// Level specifies the level of SIGUSR1, uses DebugLevel if zero.
+	Level Level
+
+	// Rotators specifies the writers rotated by SIGHUP, e.g. *FileWriter.
+	Rotators []interface{ Rotate() error }
+
+	// OnError specifies an optional callback of the errors of rotation.
+	OnError func(err error)
+
+	mu    sync.Mutex
+	ch    chan os.Signal
+	done  chan struct{}
+	saved Level
+}
+
+// Start starts handling the signals in a goroutine, it returns immediately.
+func (h *SignalHandler) Start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.ch != nil {
+		return
+	}
+	h.ch = make(chan os.Signal, 1)
+	h.done = make(chan struct{})
+	signal.Notify(h.ch, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func(ch chan os.Signal, done chan struct{}) {
+		defer close(done)
+		for sig := range ch {
+			h.handle(sig)
+		}
+	}(h.ch, h.done)
+}
+
+// Stop stops handling the signals, and waits for the goroutine to exit.
+func (h *SignalHandler) Stop() {
+	h.mu.Lock()
+	ch, done := h.ch, h.done
+	h.ch, h.done = nil, nil
+	h.mu.Unlock()
+
+	if ch == nil {
+		return
+	}
+	signal.Stop(ch)
+	close(ch)
+	<-done
+}
+
+func (h *SignalHandler) handle(sig os.Signal) {
+	logger := h.Logger
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+
+	switch sig {
+	case syscall.SIGUSR1:
+		level := h.Level
+		if level == 0 {
+			level = DebugLevel
+		}
+		old := Level(atomic.SwapUint32((*uint32)(&logger.Level), uint32(level)))
+		h.mu.Lock()
+		if h.saved == 0 {
+			h.saved = old
+		}
+		h.mu.Unlock()
+	case syscall.SIGUSR2:
+		h.mu.Lock()
+		saved := h.saved
+		h.saved = 0
+		h.mu.Unlock()
+		if saved != 0 {
+			logger.SetLevel(saved)
+		}
+	case syscall.SIGHUP:
+		for _, r := range h.Rotators {
+			if err := r.Rotate(); err != nil && h.OnError != nil {
+				h.OnError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package log
+
+import (
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type signalRotator struct {
+	n int32
+}
+
+func (r *signalRotator) Rotate() error {
+	atomic.AddInt32(&r.n, 1)
+	return nil
+}
+
+func TestSignalHandler(t *testing.T) {
+	logger := Logger{Level: WarnLevel}
+	rotator := &signalRotator{}
+	h := &SignalHandler{Logger: &logger, Rotators: []interface{ Rotate() error }{rotator}}
+	h.Start()
+	defer h.Stop()
+
+	wait := func(cond func() bool) bool {
+		for i := 0; i < 200; i++ {
+			if cond() {
+				return true
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return false
+	}
+	level := func() Level { return Level(atomic.LoadUint32((*uint32)(&logger.Level))) }
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	if !wait(func() bool { return level() == DebugLevel }) {
+		t.Errorf("SIGUSR1 must change level to debug, not %s", level())
+	}
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	if !wait(func() bool { return level() == WarnLevel }) {
+		t.Errorf("SIGUSR2 must restore level to warn, not %s", level())
+	}
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	if !wait(func() bool { return atomic.LoadInt32(&rotator.n) == 1 }) {
+		t.Errorf("SIGHUP must rotate the writers")
+	}
+}
@@ -0,0 +1,246 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// SocketFraming defines the framing of SocketWriter.
+type SocketFraming int
+
+const (
+	// SocketFramingNewline frames the entries by newline, as json lines.
+	SocketFramingNewline SocketFraming = iota
+	// SocketFramingLength frames the entries by a 4-byte big-endian length prefix.
+	SocketFramingLength
+)
+
+// SocketWriter is a Writer that writes raw json entries to a TCP/UDP/Unix endpoint with the
+// framing, it reconnects to the endpoint after connection errors. The entries are spilled to
+// SpillFile when the endpoint is unreachable, and replayed after reconnection.
+type SocketWriter struct {
+	// Network specifies the network of endpoint, e.g. "tcp", "udp", "unix", uses "tcp" if empty.
+	Network string
+
+	// Address specifies the address of endpoint.
+	Address string
+
+	// Framing specifies the framing of entries.
+	Framing SocketFraming
+
+	// TLSConfig specifies the tls config of connection, it enables tls if not nil.
+	TLSConfig *tls.Config
+
+	// Timeout specifies the dial and write timeout, uses 5 seconds if zero.
+	Timeout time.Duration
+
+	// ReconnectInterval specifies the minimum interval of dials, uses 1 second if zero.
+	ReconnectInterval time.Duration
+
+	// SpillFile specifies the file of entries when the endpoint is unreachable, it disables spilling if empty.
+	SpillFile string
+
+	// MaxSpillSize specifies the maximum bytes of SpillFile, uses 64MB if zero.
+	// The entries are dropped if SpillFile is full.
+	MaxSpillSize int64
+
+	// Dial specifies the dial function for creating connections, uses net.DialTimeout if nil.
+	Dial func(network, address string) (net.Conn, error)
+
+	mu        sync.Mutex
+	conn      net.Conn
+	dialAt    time.Time
+	spill     *os.File
+	spillSize int64
+	frame     []byte
+}
+
+// errSocketUnreachable is returned by SocketWriter when the endpoint is unreachable.
+var errSocketUnreachable = errors.New("log: socket endpoint is unreachable")
+
+// WriteEntry implements Writer.
+func (w *SocketWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.frame = socketAppendFrame(w.frame[:0], w.Framing, e.buf)
+
+	if w.conn == nil {
+		err = w.connect()
+	}
+	if err == nil {
+		if err = w.replay(); err == nil {
+			err = w.write(w.frame)
+		}
+	}
+	if err != nil && w.SpillFile != "" {
+		// spills the entry with length prefix to keep the boundaries.
+		w.frame = socketAppendFrame(w.frame[:0], SocketFramingLength, bytes.TrimRight(e.buf, "\n"))
+		err = w.spillWrite(w.frame)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *SocketWriter) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 5 * time.Second
+}
+
+func (w *SocketWriter) connect() (err error) {
+	interval := w.ReconnectInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	now := timeNow()
+	if now.Sub(w.dialAt) < interval {
+		return errSocketUnreachable
+	}
+	w.dialAt = now
+
+	network := w.Network
+	if network == "" {
+		network = "tcp"
+	}
+	if w.Dial != nil {
+		w.conn, err = w.Dial(network, w.Address)
+	} else {
+		w.conn, err = net.DialTimeout(network, w.Address, w.timeout())
+	}
+	if err != nil {
+		w.conn = nil
+		return
+	}
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(w.Address)
+		}
+		w.conn = tls.Client(w.conn, config)
+	}
+	return
+}
+
+func (w *SocketWriter) write(data []byte) (err error) {
+	_ = w.conn.SetWriteDeadline(timeNow().Add(w.timeout()))
+	if _, err = w.conn.Write(data); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return
+}
+
+// replay writes the spilled entries to connection, and truncates SpillFile.
+func (w *SocketWriter) replay() error {
+	if w.SpillFile == "" {
+		return nil
+	}
+	if w.spill == nil {
+		// the spilled entries of previous process are replayed too.
+		if info, err := os.Stat(w.SpillFile); err != nil || info.Size() == 0 {
+			return nil
+		}
+	} else if w.spillSize == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(w.SpillFile)
+	if err != nil {
+		return nil
+	}
+	var frame []byte
+	for len(data) >= 4 {
+		n := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		if len(data) < 4+n {
+			break
+		}
+		frame = socketAppendFrame(frame[:0], w.Framing, data[4:4+n])
+		if err = w.write(frame); err != nil {
+			// keeps the rest entries in SpillFile.
+			_ = w.spillReset(data)
+			return err
+		}
+		data = data[4+n:]
+	}
+	return w.spillReset(nil)
+}
+
+func (w *SocketWriter) spillWrite(frame []byte) (err error) {
+	if w.spill == nil {
+		if w.spill, err = os.OpenFile(w.SpillFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			w.spill = nil
+			return
+		}
+		if info, err := w.spill.Stat(); err == nil {
+			w.spillSize = info.Size()
+		}
+	}
+	limit := w.MaxSpillSize
+	if limit <= 0 {
+		limit = 64 * 1024 * 1024
+	}
+	if w.spillSize+int64(len(frame)) > limit {
+		return errors.New("log: socket spill file is full")
+	}
+	n, err := w.spill.Write(frame)
+	w.spillSize += int64(n)
+	return
+}
+
+// spillReset replaces the content of SpillFile with data.
+func (w *SocketWriter) spillReset(data []byte) (err error) {
+	if w.spill != nil {
+		_ = w.spill.Close()
+		w.spill = nil
+	}
+	if err = os.WriteFile(w.SpillFile, data, 0644); err == nil {
+		w.spillSize = int64(len(data))
+	}
+	return
+}
+
+// Close closes the connection and SpillFile.
+func (w *SocketWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	if w.spill != nil {
+		if err1 := w.spill.Close(); err == nil {
+			err = err1
+		}
+		w.spill = nil
+	}
+	return
+}
+
+// socketAppendFrame appends the framed entry to dst.
+func socketAppendFrame(dst []byte, framing SocketFraming, entry []byte) []byte {
+	switch framing {
+	case SocketFramingLength:
+		entry = bytes.TrimRight(entry, "\n")
+		n := len(entry)
+		dst = append(dst, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		dst = append(dst, entry...)
+	default:
+		dst = append(dst, entry...)
+		if len(entry) == 0 || entry[len(entry)-1] != '\n' {
+			dst = append(dst, '\n')
+		}
+	}
+	return dst
+}
+
+var _ Writer = (*SocketWriter)(nil)
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketWriterNewline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	w := &SocketWriter{Address: ln.Addr().String()}
+	defer w.Close()
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello socket")
+	_, _ = w.WriteEntry(&Entry{buf: []byte(`{"message":"no newline"}`)})
+
+	for _, want := range []string{`"message":"hello socket"`, `{"message":"no newline"}`} {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, want) {
+				t.Errorf("socket writer line want %s got %s", want, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("socket writer timeout")
+		}
+	}
+}
+
+func TestSocketWriterLength(t *testing.T) {
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "socket"))
+	if err != nil {
+		t.Skipf("listen unix error: %+v", err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			frame := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(conn, frame); err != nil {
+				return
+			}
+			frames <- string(frame)
+		}
+	}()
+
+	w := &SocketWriter{Network: "unix", Address: ln.Addr().String(), Framing: SocketFramingLength}
+	defer w.Close()
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello length")
+
+	select {
+	case frame := <-frames:
+		if !strings.HasPrefix(frame, "{") || !strings.HasSuffix(frame, `"message":"hello length"}`) {
+			t.Errorf("socket writer frame mismatch: %q", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("socket writer timeout")
+	}
+}
+
+func TestSocketWriterSpill(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := &SocketWriter{
+		Address:           addr,
+		ReconnectInterval: time.Millisecond,
+		SpillFile:         filepath.Join(t.TempDir(), "spill"),
+	}
+	defer w.Close()
+	logger := Logger{Writer: w}
+	logger.Info().Int("i", 1).Msg("spilled")
+	logger.Info().Int("i", 2).Msg("spilled")
+	if w.spillSize == 0 {
+		t.Fatalf("socket writer should spill entries when unreachable")
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("relisten error: %+v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	time.Sleep(2 * time.Millisecond)
+	logger.Info().Int("i", 3).Msg("direct")
+	for _, want := range []string{`"i":1`, `"i":2`, `"i":3`} {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, want) {
+				t.Errorf("socket writer line want %s got %s", want, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("socket writer timeout")
+		}
+	}
+	if w.spillSize != 0 {
+		t.Errorf("socket writer should truncate spill file after replay, size=%d", w.spillSize)
+	}
+}
+
+func TestSocketWriterUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := &SocketWriter{Address: addr}
+	defer w.Close()
+	if _, err := w.WriteEntry(&Entry{buf: []byte("{}\n")}); err == nil {
+		t.Errorf("socket writer should fail without spill file")
+	}
+	// the reconnection is throttled by interval
+	if _, err := w.WriteEntry(&Entry{buf: []byte("{}\n")}); err != errSocketUnreachable {
+		t.Errorf("socket writer want errSocketUnreachable got %+v", err)
+	}
+}
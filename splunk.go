@@ -0,0 +1,145 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SplunkWriter is a Writer that sends entries in batches to Splunk HTTP Event Collector,
+// the batches are retried with exponential backoff on 429 and 5xx responses.
+type SplunkWriter struct {
+	// URL specifies the event endpoint, uses "https://localhost:8088/services/collector/event" if empty.
+	URL string
+
+	// Token specifies the token of HTTP Event Collector.
+	Token string
+
+	// Host specifies the host metadata of events, uses hostname if empty.
+	Host string
+
+	// Source specifies the source metadata of events.
+	Source string
+
+	// SourceType specifies the sourcetype metadata of events, uses "_json" if empty.
+	SourceType string
+
+	// Index specifies the index metadata of events.
+	Index string
+
+	// Gzip determines if compresses the requests by gzip.
+	Gzip bool
+
+	// BatchSize specifies the maximum entries of a request, uses 100 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed request, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Client specifies the http client, uses http.DefaultClient if nil.
+	Client *http.Client
+
+	once     sync.Once
+	batcher  batcher
+	url      string
+	header   http.Header
+	metadata []byte
+}
+
+func (w *SplunkWriter) init() {
+	w.once.Do(func() {
+		w.url = w.URL
+		if w.url == "" {
+			w.url = "https://localhost:8088/services/collector/event"
+		}
+
+		w.header = http.Header{
+			"Content-Type":  []string{"application/json"},
+			"Authorization": []string{"Splunk " + w.Token},
+		}
+		if w.Gzip {
+			w.header.Set("Content-Encoding", "gzip")
+		}
+
+		host := w.Host
+		if host == "" {
+			host = hostname
+		}
+		sourcetype := w.SourceType
+		if sourcetype == "" {
+			sourcetype = "_json"
+		}
+		w.metadata = append(w.metadata, ",\"host\":"...)
+		w.metadata = jsonAppendString(w.metadata, host)
+		if w.Source != "" {
+			w.metadata = append(w.metadata, ",\"source\":"...)
+			w.metadata = jsonAppendString(w.metadata, w.Source)
+		}
+		w.metadata = append(w.metadata, ",\"sourcetype\":"...)
+		w.metadata = jsonAppendString(w.metadata, sourcetype)
+		if w.Index != "" {
+			w.metadata = append(w.metadata, ",\"index\":"...)
+			w.metadata = jsonAppendString(w.metadata, w.Index)
+		}
+
+		w.batcher.size = w.BatchSize
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *SplunkWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *SplunkWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *SplunkWriter) send(b *batch) error {
+	buf := bbpool.Get().(*bb)
+	buf.B = buf.B[:0]
+	defer bbpool.Put(buf)
+
+	dst := buf.B
+	for i := 0; i < b.Len(); i++ {
+		entry := b.Entry(i)
+		ts, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+		if !ok {
+			ts = b.times[i]
+		}
+		dst = append(dst, "{\"time\":"...)
+		dst = strconv.AppendFloat(dst, float64(ts/int64(time.Millisecond))/1000, 'f', 3, 64)
+		dst = append(dst, w.metadata...)
+		dst = append(dst, ",\"event\":"...)
+		dst = append(dst, bytes.TrimRight(entry, "\n")...)
+		dst = append(dst, '}', '\n')
+	}
+	buf.B = dst
+
+	body := buf.B
+	if w.Gzip {
+		z := bbpool.Get().(*bb)
+		defer bbpool.Put(z)
+		z.B = gzipAppend(z.B[:0], buf.B)
+		body = z.B
+	}
+
+	return httpSend(w.Client, http.MethodPost, w.url, w.header, body)
+}
+
+var _ Writer = (*SplunkWriter)(nil)
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplunkWriter(t *testing.T) {
+	type event struct {
+		Time       float64                `json:"time"`
+		Host       string                 `json:"host"`
+		Source     string                 `json:"source"`
+		SourceType string                 `json:"sourcetype"`
+		Index      string                 `json:"index"`
+		Event      map[string]interface{} `json:"event"`
+	}
+
+	var calls int32
+	events := make(chan event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk token1" || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("splunk writer unexpected request: %v", r.Header)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		zr, _ := gzip.NewReader(r.Body)
+		decoder := json.NewDecoder(zr)
+		for decoder.More() {
+			var e event
+			if err := decoder.Decode(&e); err != nil {
+				t.Errorf("splunk writer json error: %+v", err)
+				return
+			}
+			events <- e
+		}
+		_, _ = rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	w := &SplunkWriter{
+		URL:        server.URL + "/services/collector/event",
+		Token:      "token1",
+		Source:     "myapp",
+		Index:      "main",
+		Gzip:       true,
+		MaxRetries: 2,
+	}
+	w.batcher.backoff = time.Millisecond
+	logger := Logger{Writer: w}
+
+	logger.Info().Str("foo", "bar").Msg("hello splunk")
+	logger.Warn().Msg("hello again")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("splunk writer close error: %+v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("splunk writer should retry on 503, calls=%d", n)
+	}
+	if len(events) != 2 {
+		t.Fatalf("splunk writer should send 2 events, got %d", len(events))
+	}
+
+	e := <-events
+	if e.Host != hostname || e.Source != "myapp" || e.SourceType != "_json" || e.Index != "main" {
+		t.Errorf("splunk writer metadata mismatch: %+v", e)
+	}
+	if e.Event["message"] != "hello splunk" || e.Event["foo"] != "bar" {
+		t.Errorf("splunk writer event mismatch: %+v", e.Event)
+	}
+	if d := time.Since(time.Unix(0, int64(e.Time*1e9))); d < 0 || d > time.Minute {
+		t.Errorf("splunk writer time mismatch: %v", e.Time)
+	}
+}
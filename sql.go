@@ -0,0 +1,216 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLWriter is a Writer that inserts entries in batches to a table of sql database, the
+// time, level and message of entries are extracted to columns, and the entries are stored
+// in the payload column, e.g. for PostgreSQL
+//
+//	CREATE TABLE logs (time timestamptz, level text, message text, payload jsonb);
+//
+// The batches are inserted by multi-row INSERT statements, or by COPY FROM STDIN if Copy
+// is set, which is supported by the drivers implementing COPY as prepared statement, e.g. lib/pq.
+type SQLWriter struct {
+	// DB specifies the database of table.
+	DB *sql.DB
+
+	// Table specifies the table name, uses "logs" if empty.
+	Table string
+
+	// TimeColumn specifies the column of entry time, uses "time" if empty.
+	TimeColumn string
+
+	// LevelColumn specifies the column of entry level, uses "level" if empty.
+	LevelColumn string
+
+	// MessageColumn specifies the column of entry message, uses "message" if empty.
+	MessageColumn string
+
+	// PayloadColumn specifies the column of entry itself, uses "payload" if empty.
+	PayloadColumn string
+
+	// Placeholder specifies the placeholder of statements, uses "$" (as $1, $2) if empty,
+	// the "?" is for MySQL and SQLite.
+	Placeholder string
+
+	// Copy determines if inserts the batches by COPY FROM STDIN of PostgreSQL.
+	Copy bool
+
+	// Timeout specifies the timeout of a batch, uses 10 seconds if zero.
+	Timeout time.Duration
+
+	// BatchSize specifies the maximum entries of a batch, uses 500 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	// Fallback specifies the fallback writer of the entries failed to insert.
+	Fallback Writer
+
+	once    sync.Once
+	batcher batcher
+	columns string
+}
+
+func (w *SQLWriter) init() {
+	w.once.Do(func() {
+		if w.Table == "" {
+			w.Table = "logs"
+		}
+		column := func(name, value string) string {
+			if name == "" {
+				return value
+			}
+			return name
+		}
+		w.columns = column(w.TimeColumn, "time") + ", " + column(w.LevelColumn, "level") + ", " +
+			column(w.MessageColumn, "message") + ", " + column(w.PayloadColumn, "payload")
+
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 500
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+		w.batcher.fail = func(b *batch, err error) {
+			if w.Fallback == nil {
+				return
+			}
+			for i := 0; i < b.Len(); i++ {
+				_, _ = w.Fallback.WriteEntry(&Entry{buf: b.Entry(i), Level: b.levels[i]})
+			}
+		}
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *SQLWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *SQLWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+// sqlRow returns the column values of i-th entry of batch.
+func sqlRow(b *batch, i int) (ts time.Time, level, message, payload string) {
+	entry := bytes.TrimRight(b.Entry(i), "\n")
+	ns, ok := parseEntryTime(b2s(jsonFieldString(entry, "time")))
+	if !ok {
+		ns = b.times[i]
+	}
+	level = b.levels[i].String()
+	if b.levels[i] == noLevel {
+		level = ""
+	}
+	if value := jsonGetField(entry, "message"); len(value) >= 2 && value[0] == '"' {
+		var m []byte
+		if bytes.IndexByte(value, '\\') >= 0 {
+			m = jsonUnescape(value[1:len(value)-1], nil)
+		} else {
+			m = value[1 : len(value)-1]
+		}
+		message = string(m)
+	}
+	return time.Unix(0, ns).UTC(), level, message, string(entry)
+}
+
+func (w *SQLWriter) send(b *batch) (err error) {
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if w.Copy {
+		return w.copy(ctx, b)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(w.Table)
+	sb.WriteString(" (")
+	sb.WriteString(w.columns)
+	sb.WriteString(") VALUES ")
+	args := make([]interface{}, 0, 4*b.Len())
+	for i := 0; i < b.Len(); i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('(')
+		for j := 1; j <= 4; j++ {
+			if j > 1 {
+				sb.WriteByte(',')
+			}
+			if w.Placeholder == "?" {
+				sb.WriteByte('?')
+			} else {
+				sb.WriteByte('$')
+				sb.WriteString(strconv.Itoa(4*i + j))
+			}
+		}
+		sb.WriteByte(')')
+		ts, level, message, payload := sqlRow(b, i)
+		args = append(args, ts, level, message, payload)
+	}
+
+	_, err = w.DB.ExecContext(ctx, sb.String(), args...)
+	return
+}
+
+// copy inserts the batch by COPY FROM STDIN in a transaction.
+func (w *SQLWriter) copy(ctx context.Context, b *batch) (err error) {
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, "COPY "+w.Table+" ("+w.columns+") FROM STDIN")
+	if err != nil {
+		return
+	}
+	for i := 0; i < b.Len(); i++ {
+		ts, level, message, payload := sqlRow(b, i)
+		if _, err = stmt.ExecContext(ctx, ts, level, message, payload); err != nil {
+			_ = stmt.Close()
+			return
+		}
+	}
+	// the exec without args flushes the buffered rows.
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return
+	}
+	if err = stmt.Close(); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+var _ Writer = (*SQLWriter)(nil)
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sqlTestDriver records the executed statements of connections.
+type sqlTestDriver struct{}
+
+// sqlTestState is the executed statements of a dsn.
+type sqlTestState struct {
+	mu    sync.Mutex
+	execs []sqlTestExec
+	fail  bool
+}
+
+type sqlTestExec struct {
+	Query string
+	Args  []driver.Value
+}
+
+var sqlTestStates sync.Map
+
+func (sqlTestDriver) Open(dsn string) (driver.Conn, error) {
+	d, _ := sqlTestStates.Load(dsn)
+	return &sqlTestConn{d.(*sqlTestState)}, nil
+}
+
+type sqlTestConn struct{ d *sqlTestState }
+
+func (c *sqlTestConn) Prepare(query string) (driver.Stmt, error) {
+	return &sqlTestStmt{c.d, query}, nil
+}
+func (c *sqlTestConn) Close() error              { return nil }
+func (c *sqlTestConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *sqlTestConn) Commit() error             { return c.record("COMMIT", nil) }
+func (c *sqlTestConn) Rollback() error           { return c.record("ROLLBACK", nil) }
+
+func (c *sqlTestConn) record(query string, args []driver.Value) error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if c.d.fail && strings.HasPrefix(query, "INSERT") {
+		return errors.New("relation does not exist")
+	}
+	c.d.execs = append(c.d.execs, sqlTestExec{query, args})
+	return nil
+}
+
+type sqlTestStmt struct {
+	d     *sqlTestState
+	query string
+}
+
+func (s *sqlTestStmt) Close() error  { return nil }
+func (s *sqlTestStmt) NumInput() int { return -1 }
+func (s *sqlTestStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), (&sqlTestConn{s.d}).record(s.query, args)
+}
+func (s *sqlTestStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var sqlTestOnce sync.Once
+
+func sqlTestDB(t *testing.T) (*sql.DB, *sqlTestState) {
+	sqlTestOnce.Do(func() {
+		sql.Register("phuslu-log-sqltest", sqlTestDriver{})
+	})
+	d := &sqlTestState{}
+	dsn := t.Name() + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	sqlTestStates.Store(dsn, d)
+	db, err := sql.Open("phuslu-log-sqltest", dsn)
+	if err != nil {
+		t.Fatalf("sql open error: %+v", err)
+	}
+	return db, d
+}
+
+func TestSQLWriter(t *testing.T) {
+	db, d := sqlTestDB(t)
+	defer db.Close()
+
+	w := &SQLWriter{
+		DB:            db,
+		Table:         "app_logs",
+		PayloadColumn: "data",
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Str("foo", "bar").Msg("hello \"sql\"")
+	logger.Warn().Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("sql writer close error: %+v", err)
+	}
+
+	if len(d.execs) != 1 {
+		t.Fatalf("sql writer want 1 statement got %d: %+v", len(d.execs), d.execs)
+	}
+	exec := d.execs[0]
+	if want := "INSERT INTO app_logs (time, level, message, data) VALUES ($1,$2,$3,$4),($5,$6,$7,$8)"; exec.Query != want {
+		t.Errorf("sql writer query want %s got %s", want, exec.Query)
+	}
+	if len(exec.Args) != 8 {
+		t.Fatalf("sql writer want 8 args got %d", len(exec.Args))
+	}
+	if ts, _ := exec.Args[0].(time.Time); time.Since(ts) > time.Minute {
+		t.Errorf("sql writer time mismatch: %v", exec.Args[0])
+	}
+	if exec.Args[1] != "info" || exec.Args[2] != `hello "sql"` || exec.Args[5] != "warn" || exec.Args[6] != "second" {
+		t.Errorf("sql writer args mismatch: %v", exec.Args)
+	}
+	if payload, _ := exec.Args[3].(string); !strings.HasPrefix(payload, "{") || !strings.HasSuffix(payload, "}") {
+		t.Errorf("sql writer payload mismatch: %q", payload)
+	}
+}
+
+func TestSQLWriterCopy(t *testing.T) {
+	db, d := sqlTestDB(t)
+	defer db.Close()
+
+	w := &SQLWriter{
+		DB:   db,
+		Copy: true,
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("first")
+	logger.Info().Msg("second")
+	if err := w.Close(); err != nil {
+		t.Fatalf("sql writer close error: %+v", err)
+	}
+
+	var queries []string
+	for _, exec := range d.execs {
+		queries = append(queries, exec.Query+"/"+string(rune('0'+len(exec.Args))))
+	}
+	copy := "COPY logs (time, level, message, payload) FROM STDIN"
+	if want := []string{copy + "/4", copy + "/4", copy + "/0", "COMMIT/0"}; strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sql writer copy statements mismatch: %q", queries)
+	}
+}
+
+func TestSQLWriterFallback(t *testing.T) {
+	db, d := sqlTestDB(t)
+	defer db.Close()
+	d.fail = true
+
+	var fallback bytes.Buffer
+	w := &SQLWriter{
+		DB:          db,
+		Placeholder: "?",
+		MaxRetries:  -1,
+		Fallback:    IOWriter{&fallback},
+	}
+	logger := Logger{Writer: w}
+	logger.Info().Msg("hello fallback")
+	if err := w.Close(); err == nil {
+		t.Errorf("sql writer should fail")
+	}
+	if !strings.Contains(fallback.String(), `"message":"hello fallback"`) {
+		t.Errorf("sql writer fallback mismatch: %s", fallback.String())
+	}
+}
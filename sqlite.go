@@ -0,0 +1,154 @@
+package log
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLiteWriter is a Writer that stores entries to a table of embedded SQLite database, it keeps
+// the last MaxEntries entries or MaxAge of entries by pruning the table after each batch.
+// The database is opened by the sqlite driver of application, e.g. mattn/go-sqlite3 or
+// modernc.org/sqlite, and the table is created if not exists as
+//
+//	CREATE TABLE logs (time INTEGER, level TEXT, message TEXT, payload TEXT);
+//	CREATE INDEX logs_time ON logs (time);
+//
+// The time column is the unix milliseconds of entry, e.g. it is queried by
+//
+//	SELECT datetime(time/1000, 'unixepoch'), level, message FROM logs WHERE level = 'error';
+type SQLiteWriter struct {
+	// DB specifies the database of table.
+	DB *sql.DB
+
+	// Table specifies the table name, uses "logs" if empty.
+	Table string
+
+	// MaxEntries specifies the maximum entries of table, zero value means no limit.
+	MaxEntries int64
+
+	// MaxAge specifies the maximum age of entries in table, zero value means no limit.
+	MaxAge time.Duration
+
+	// BatchSize specifies the maximum entries of a batch, uses 500 if zero.
+	BatchSize int
+
+	// FlushInterval specifies the flush interval of pending entries, uses 1 second if zero.
+	FlushInterval time.Duration
+
+	// MaxRetries specifies the maximum retries of a failed batch, uses 3 if zero,
+	// and negative value disables retries.
+	MaxRetries int
+
+	once    sync.Once
+	batcher batcher
+	created bool
+}
+
+func (w *SQLiteWriter) init() {
+	w.once.Do(func() {
+		if w.Table == "" {
+			w.Table = "logs"
+		}
+		w.batcher.size = w.BatchSize
+		if w.batcher.size <= 0 {
+			w.batcher.size = 500
+		}
+		w.batcher.interval = w.FlushInterval
+		w.batcher.retries = batchRetries(w.MaxRetries)
+		w.batcher.send = w.send
+	})
+}
+
+// Close flushes the pending entries and stops the background goroutine.
+func (w *SQLiteWriter) Close() error {
+	w.init()
+	return w.batcher.close()
+}
+
+// WriteEntry implements Writer.
+func (w *SQLiteWriter) WriteEntry(e *Entry) (int, error) {
+	w.init()
+	if err := w.batcher.write(e); err != nil {
+		return 0, err
+	}
+	return len(e.buf), nil
+}
+
+func (w *SQLiteWriter) send(b *batch) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if !w.created {
+		_, err = w.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+w.Table+" (time INTEGER, level TEXT, message TEXT, payload TEXT)")
+		if err != nil {
+			return
+		}
+		_, err = w.DB.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS "+w.Table+"_time ON "+w.Table+" (time)")
+		if err != nil {
+			return
+		}
+		w.created = true
+	}
+
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// the variables of a statement are limited to 999 by old sqlite.
+	const rows = 999 / 4
+	for start := 0; start < b.Len(); start += rows {
+		end := start + rows
+		if end > b.Len() {
+			end = b.Len()
+		}
+		var sb strings.Builder
+		sb.WriteString("INSERT INTO ")
+		sb.WriteString(w.Table)
+		sb.WriteString(" (time, level, message, payload) VALUES ")
+		args := make([]interface{}, 0, 4*(end-start))
+		for i := start; i < end; i++ {
+			if i > start {
+				sb.WriteByte(',')
+			}
+			sb.WriteString("(?,?,?,?)")
+			ts, level, message, payload := sqlRow(b, i)
+			args = append(args, ts.UnixNano()/int64(time.Millisecond), level, message, payload)
+		}
+		if _, err = tx.ExecContext(ctx, sb.String(), args...); err != nil {
+			return
+		}
+	}
+	if err = w.prune(ctx, tx); err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
+
+// prune deletes the entries beyond MaxEntries and MaxAge.
+func (w *SQLiteWriter) prune(ctx context.Context, tx *sql.Tx) (err error) {
+	if w.MaxEntries > 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+w.Table+" WHERE rowid <= (SELECT max(rowid) FROM "+w.Table+") - "+
+			strconv.FormatInt(w.MaxEntries, 10))
+		if err != nil {
+			return
+		}
+	}
+	if w.MaxAge > 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+w.Table+" WHERE time < ?",
+			timeNow().Add(-w.MaxAge).UnixNano()/int64(time.Millisecond))
+	}
+	return
+}
+
+var _ Writer = (*SQLiteWriter)(nil)
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteWriter(t *testing.T) {
+	db, d := sqlTestDB(t)
+	defer db.Close()
+
+	w := &SQLiteWriter{
+		DB:         db,
+		MaxEntries: 1000,
+		MaxAge:     24 * time.Hour,
+		// flushes all entries in one batch at close.
+		FlushInterval: time.Hour,
+	}
+	logger := Logger{Writer: w}
+	for i := 0; i < 300; i++ {
+		logger.Info().Int("i", i).Msg("hello sqlite")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("sqlite writer close error: %+v", err)
+	}
+
+	var queries []string
+	var rows int
+	for _, exec := range d.execs {
+		query := exec.Query
+		if strings.HasPrefix(query, "INSERT") {
+			rows += len(exec.Args) / 4
+			if ms, _ := exec.Args[0].(int64); time.Since(time.Unix(0, ms*int64(time.Millisecond))) > time.Minute {
+				t.Errorf("sqlite writer time mismatch: %v", exec.Args[0])
+			}
+			query = query[:strings.Index(query, " VALUES")]
+		}
+		queries = append(queries, query)
+	}
+	want := []string{
+		"CREATE TABLE IF NOT EXISTS logs (time INTEGER, level TEXT, message TEXT, payload TEXT)",
+		"CREATE INDEX IF NOT EXISTS logs_time ON logs (time)",
+		"INSERT INTO logs (time, level, message, payload)",
+		"INSERT INTO logs (time, level, message, payload)",
+		"DELETE FROM logs WHERE rowid <= (SELECT max(rowid) FROM logs) - 1000",
+		"DELETE FROM logs WHERE time < ?",
+		"COMMIT",
+	}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("sqlite writer statements mismatch:\n%s", strings.Join(queries, "\n"))
+	}
+	if rows != 300 {
+		t.Errorf("sqlite writer want 300 rows got %d", rows)
+	}
+}
@@ -0,0 +1,328 @@
+package log
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SQLLogger logs the sql queries with the args, rows affected and duration, the slow queries
+// over SlowThreshold are logged at WarnLevel. It wraps a database/sql driver by Driver, e.g.
+//
+//	sql.Register("postgres-log", (&log.SQLLogger{SlowThreshold: time.Second}).Driver(&pq.Driver{}))
+//	db, err := sql.Open("postgres-log", dsn)
+//
+// Its Info, Warn, Error and Trace methods match logger.Interface of GORM, to keep zero
+// dependencies, the LogMode method is left to a wrapper, e.g.
+//
+//	type gormLogger struct{ *log.SQLLogger }
+//
+//	func (l gormLogger) LogMode(logger.LogLevel) logger.Interface { return l }
+type SQLLogger struct {
+	// Logger specifies the logger of queries, uses Ctx(ctx) if nil.
+	Logger *Logger
+
+	// Level specifies the level of queries, uses DebugLevel if zero.
+	Level Level
+
+	// SlowThreshold specifies the duration of slow queries, which are logged at WarnLevel.
+	SlowThreshold time.Duration
+
+	// Args determines if logs the args of queries. Otherwise the args are not logged, and the
+	// literals of the interpolated sql of Trace are replaced with "?".
+	Args bool
+}
+
+func (l *SQLLogger) logger(ctx context.Context) *Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return Ctx(ctx)
+}
+
+// Log logs a query, rows is the number of rows affected, -1 if unknown.
+func (l *SQLLogger) Log(ctx context.Context, query string, args []interface{}, rows int64, duration time.Duration, err error) {
+	level := l.Level
+	if level == 0 {
+		level = DebugLevel
+	}
+	switch {
+	case err != nil:
+		level = ErrorLevel
+	case l.SlowThreshold > 0 && duration >= l.SlowThreshold && levelLess(level, WarnLevel):
+		level = WarnLevel
+	}
+
+	e := l.logger(ctx).WithLevel(level)
+	if e == nil {
+		return
+	}
+	e = e.Str("sql", query)
+	if l.Args && len(args) != 0 {
+		if data, err := json.Marshal(args); err == nil {
+			e = e.RawJSON("args", data)
+		} else {
+			e = e.Interface("args", args)
+		}
+	}
+	if rows >= 0 {
+		e = e.Int64("rows", rows)
+	}
+	e = e.Dur("duration", duration)
+	if l.SlowThreshold > 0 && duration >= l.SlowThreshold {
+		e = e.Bool("slow", true)
+	}
+	if err != nil {
+		e = e.Err(err)
+	}
+	e.Msg("")
+}
+
+// Info logs the message at InfoLevel, it matches logger.Interface of GORM.
+func (l *SQLLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.logger(ctx).Info().Msgf(msg, data...)
+}
+
+// Warn logs the message at WarnLevel, it matches logger.Interface of GORM.
+func (l *SQLLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.logger(ctx).Warn().Msgf(msg, data...)
+}
+
+// Error logs the message at ErrorLevel, it matches logger.Interface of GORM.
+func (l *SQLLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.logger(ctx).Error().Msgf(msg, data...)
+}
+
+// Trace logs the interpolated sql of fc, it matches logger.Interface of GORM.
+func (l *SQLLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	query, rows := fc()
+	if !l.Args {
+		query = redactSQL(query)
+	}
+	l.Log(ctx, query, nil, rows, timeNow().Sub(begin), err)
+}
+
+// redactSQL replaces the string and number literals of sql with "?".
+func redactSQL(sql string) string {
+	b := make([]byte, 0, len(sql))
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'':
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b = append(b, '?')
+		case c >= '0' && c <= '9' && (i == 0 || !sqlIdent(sql[i-1])):
+			for i+1 < len(sql) && (sql[i+1] >= '0' && sql[i+1] <= '9' || sql[i+1] == '.') {
+				i++
+			}
+			b = append(b, '?')
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+func sqlIdent(c byte) bool {
+	return c == '_' || c == '$' || c == '"' || c == '`' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Driver returns a driver which logs the queries of d.
+func (l *SQLLogger) Driver(d driver.Driver) driver.Driver {
+	return &sqlDriver{d, l}
+}
+
+type sqlDriver struct {
+	driver.Driver
+	logger *SQLLogger
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{c, d.logger}, nil
+}
+
+type sqlConn struct {
+	driver.Conn
+	logger *SQLLogger
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{stmt, query, c.logger}, nil
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := timeNow()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.logger.Log(ctx, query, sqlArgs(args), sqlRows(result, err), timeNow().Sub(start), err)
+	}
+	return result, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := timeNow()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.logger.Log(ctx, query, sqlArgs(args), -1, timeNow().Sub(start), err)
+	}
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	driver.Stmt
+	query  string
+	logger *SQLLogger
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := timeNow()
+	result, err := s.Stmt.Exec(args) //nolint
+	s.logger.Log(context.Background(), s.query, sqlValues(args), sqlRows(result, err), timeNow().Sub(start), err)
+	return result, err
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := timeNow()
+	rows, err := s.Stmt.Query(args) //nolint
+	s.logger.Log(context.Background(), s.query, sqlValues(args), -1, timeNow().Sub(start), err)
+	return rows, err
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	start := timeNow()
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else if values, err1 := sqlNamedValues(args); err1 != nil {
+		return nil, err1
+	} else {
+		result, err = s.Stmt.Exec(values) //nolint
+	}
+	s.logger.Log(ctx, s.query, sqlArgs(args), sqlRows(result, err), timeNow().Sub(start), err)
+	return
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	start := timeNow()
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else if values, err1 := sqlNamedValues(args); err1 != nil {
+		return nil, err1
+	} else {
+		rows, err = s.Stmt.Query(values) //nolint
+	}
+	s.logger.Log(ctx, s.query, sqlArgs(args), -1, timeNow().Sub(start), err)
+	return
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func sqlRows(result driver.Result, err error) int64 {
+	if err != nil || result == nil {
+		return -1
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
+
+func sqlArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func sqlValues(args []driver.Value) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	return values
+}
+
+func sqlNamedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("log: driver does not support the use of named parameters: " + arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sqlLoggerTestDriver struct{}
+
+func (sqlLoggerTestDriver) Open(name string) (driver.Conn, error) { return sqlLoggerTestConn{}, nil }
+
+type sqlLoggerTestConn struct{}
+
+func (sqlLoggerTestConn) Prepare(query string) (driver.Stmt, error) { return sqlLoggerTestStmt{}, nil }
+func (sqlLoggerTestConn) Close() error                              { return nil }
+func (sqlLoggerTestConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+type sqlLoggerTestStmt struct{}
+
+func (sqlLoggerTestStmt) Close() error  { return nil }
+func (sqlLoggerTestStmt) NumInput() int { return -1 }
+
+func (sqlLoggerTestStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) != 0 && args[0] == "fail" {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func (sqlLoggerTestStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return sqlLoggerTestRows{}, nil
+}
+
+type sqlLoggerTestRows struct{}
+
+func (sqlLoggerTestRows) Columns() []string              { return []string{"id"} }
+func (sqlLoggerTestRows) Close() error                   { return nil }
+func (sqlLoggerTestRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestSQLLoggerDriver(t *testing.T) {
+	var b bytes.Buffer
+	l := &SQLLogger{Logger: &Logger{Level: DebugLevel, Writer: IOWriter{&b}}, Args: true}
+	sql.Register("phuslu-log-test", l.Driver(sqlLoggerTestDriver{}))
+
+	db, err := sql.Open("phuslu-log-test", "")
+	if err != nil {
+		t.Fatalf("open sql logger driver error: %+v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "bob", 42); err != nil {
+		t.Fatalf("exec sql logger driver error: %+v", err)
+	}
+	s := b.String()
+	for _, v := range []string{`"level":"debug"`, `"sql":"UPDATE users SET name = ? WHERE id = ?"`, `"args":["bob",42]`, `"rows":3`, `"duration":`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("sql logger log must contain %s: %s", v, s)
+		}
+	}
+
+	b.Reset()
+	if _, err := db.Exec("DELETE FROM users WHERE name = ?", "fail"); err == nil {
+		t.Errorf("exec sql logger driver must return error")
+	}
+	if s = b.String(); !strings.Contains(s, `"level":"error"`) || !strings.Contains(s, `"error":"boom"`) {
+		t.Errorf("sql logger must log failed queries at error level: %s", s)
+	}
+
+	b.Reset()
+	l.Args = false
+	rows, err := db.Query("SELECT id FROM users WHERE name = ?", "alice")
+	if err != nil {
+		t.Fatalf("query sql logger driver error: %+v", err)
+	}
+	rows.Close()
+	if s = b.String(); !strings.Contains(s, `"sql":"SELECT id FROM users WHERE name = ?"`) || strings.Contains(s, "alice") || strings.Contains(s, `"rows"`) {
+		t.Errorf("sql logger must not log the args of queries: %s", s)
+	}
+}
+
+func TestSQLLoggerTrace(t *testing.T) {
+	var b bytes.Buffer
+	l := &SQLLogger{Logger: &Logger{Level: DebugLevel, Writer: IOWriter{&b}}, SlowThreshold: time.Second}
+
+	l.Trace(context.Background(), timeNow().Add(-2*time.Second), func() (string, int64) {
+		return `SELECT * FROM "users" WHERE name = 'o''neil' AND age > 18 AND t2.id = 3 LIMIT 1`, 1
+	}, nil)
+	s := b.String()
+	for _, v := range []string{`"level":"warn"`, `"sql":"SELECT * FROM \"users\" WHERE name = ? AND age > ? AND t2.id = ? LIMIT ?"`, `"rows":1`, `"slow":true`} {
+		if !strings.Contains(s, v) {
+			t.Errorf("sql logger trace must contain %s: %s", v, s)
+		}
+	}
+
+	b.Reset()
+	l.Info(context.Background(), "migrate %s", "users")
+	if s = b.String(); !strings.Contains(s, `"level":"info"`) || !strings.Contains(s, `"message":"migrate users"`) {
+		t.Errorf("sql logger info must log the message: %s", s)
+	}
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// StackTrace specifies the structured stack traces of entries, which are arrays of the
+// function, file and line of frames, e.g.
+//
+//	"stacktrace":[{"function":"main.handle","file":"main.go","line":42},{"function":"main.main","file":"main.go","line":12}]
+//
+// The leading frames of logger are dropped.
+type StackTrace struct {
+	// Level specifies the min level of entries to capture the stack traces, uses ErrorLevel if zero.
+	Level Level
+
+	// Key specifies the field key of stack traces, uses "stacktrace" if empty.
+	Key string
+
+	// MaxDepth specifies the max number of frames, uses 32 if zero.
+	MaxDepth int
+
+	// SkipPrefixes specifies the prefixes of functions of which frames are dropped, e.g.
+	// "runtime." and "net/http.".
+	SkipPrefixes []string
+
+	// FullPath determines if adds the full /path/to/file of frames.
+	FullPath bool
+}
+
+// stackTracePackage is the function prefix of this package.
+var stackTracePackage = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(stackTraceFunc).Pointer()).Name()
+	return name[:strings.LastIndexByte(name, '.')+1]
+}()
+
+func stackTraceFunc() {}
+
+// enabled reports whether the stack traces of level are captured.
+func (st *StackTrace) enabled(level Level) bool {
+	min := st.Level
+	if min == 0 {
+		min = ErrorLevel
+	}
+	return level != noLevel && !levelLess(level, min)
+}
+
+// append appends the stack trace of caller to the entry.
+func (st *StackTrace) append(e *Entry) {
+	depth := st.MaxDepth
+	if depth <= 0 {
+		depth = 32
+	}
+
+	var rpc [64]uintptr
+	pcs := rpc[:]
+	if n := depth + 16; n > len(pcs) {
+		pcs = make([]uintptr, n)
+	}
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	key := st.Key
+	if key == "" {
+		key = "stacktrace"
+	}
+	e.buf = append(e.buf, ',', '"')
+	e.buf = append(e.buf, key...)
+	e.buf = append(e.buf, '"', ':', '[')
+
+	leading, n := true, 0
+	for n < depth {
+		frame, more := frames.Next()
+		if leading && strings.HasPrefix(frame.Function, stackTracePackage) && !strings.HasSuffix(frame.File, "_test.go") {
+			if !more {
+				break
+			}
+			continue
+		}
+		leading = false
+		if !st.skip(frame.Function) {
+			if n != 0 {
+				e.buf = append(e.buf, ',')
+			}
+			n++
+			file := frame.File
+			if !st.FullPath {
+				if i := strings.LastIndexByte(file, '/'); i >= 0 {
+					file = file[i+1:]
+				}
+			}
+			e.buf = append(e.buf, "{\"function\":\""...)
+			e.string(frame.Function)
+			e.buf = append(e.buf, "\",\"file\":\""...)
+			e.string(file)
+			e.buf = append(e.buf, "\",\"line\":"...)
+			e.buf = strconv.AppendInt(e.buf, int64(frame.Line), 10)
+			e.buf = append(e.buf, '}')
+		}
+		if !more {
+			break
+		}
+	}
+
+	e.buf = append(e.buf, ']')
+}
+
+func (st *StackTrace) skip(function string) bool {
+	for _, prefix := range st.SkipPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStackTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		StackTrace: &StackTrace{MaxDepth: 2, SkipPrefixes: []string{"runtime."}},
+		Writer:     IOWriter{&buf},
+	}
+
+	logger.Info().Msg("no stack trace")
+	if strings.Contains(buf.String(), "stacktrace") {
+		t.Errorf("info entry must have no stack trace: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Error().Msg("stack trace")
+
+	var entry struct {
+		StackTrace []struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"stacktrace"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unmarshal entry %s error: %+v", buf.String(), err)
+	}
+	if len(entry.StackTrace) != 2 {
+		t.Fatalf("stack trace must have 2 frames: %s", buf.String())
+	}
+	if f := entry.StackTrace[0]; !strings.HasSuffix(f.Function, ".TestStackTrace") || f.File != "stacktrace_test.go" || f.Line == 0 {
+		t.Errorf("the first frame must be the caller: %+v", f)
+	}
+	if f := entry.StackTrace[1]; f.Function != "testing.tRunner" {
+		t.Errorf("the second frame must be testing.tRunner: %+v", f)
+	}
+
+	buf.Reset()
+	logger.StackTrace = &StackTrace{Level: WarnLevel, Key: "frames", FullPath: true, SkipPrefixes: []string{"testing."}}
+	logger.Warn().Msg("stack trace")
+	if s := buf.String(); !strings.Contains(s, `"frames":[{"function":"`) || !strings.Contains(s, "/stacktrace_test.go") || strings.Contains(s, "testing.tRunner") {
+		t.Errorf("stack trace with options got %s", s)
+	}
+}
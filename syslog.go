@@ -1,12 +1,40 @@
 package log
 
 import (
+	"crypto/tls"
+	"io"
 	"net"
 	"strconv"
 	"sync"
 	"time"
 )
 
+// SyslogFacility defines syslog facility.
+type SyslogFacility int
+
+const (
+	LOG_KERN     SyslogFacility = 0  // kernel messages
+	LOG_USER     SyslogFacility = 1  // user-level messages
+	LOG_MAIL     SyslogFacility = 2  // mail system
+	LOG_DAEMON   SyslogFacility = 3  // system daemons
+	LOG_AUTH     SyslogFacility = 4  // security/authorization messages
+	LOG_SYSLOG   SyslogFacility = 5  // messages generated internally by syslogd
+	LOG_LPR      SyslogFacility = 6  // line printer subsystem
+	LOG_NEWS     SyslogFacility = 7  // network news subsystem
+	LOG_UUCP     SyslogFacility = 8  // UUCP subsystem
+	LOG_CRON     SyslogFacility = 9  // clock daemon
+	LOG_AUTHPRIV SyslogFacility = 10 // security/authorization messages (private)
+	LOG_FTP      SyslogFacility = 11 // FTP daemon
+	LOG_LOCAL0   SyslogFacility = 16 // local use 0
+	LOG_LOCAL1   SyslogFacility = 17 // local use 1
+	LOG_LOCAL2   SyslogFacility = 18 // local use 2
+	LOG_LOCAL3   SyslogFacility = 19 // local use 3
+	LOG_LOCAL4   SyslogFacility = 20 // local use 4
+	LOG_LOCAL5   SyslogFacility = 21 // local use 5
+	LOG_LOCAL6   SyslogFacility = 22 // local use 6
+	LOG_LOCAL7   SyslogFacility = 23 // local use 7
+)
+
 // SyslogWriter is an Writer that writes logs to a syslog server..
 type SyslogWriter struct {
 	// Network specifies network of the syslog server
@@ -24,12 +52,60 @@ type SyslogWriter struct {
 	// Marker specifies prefix of the syslog message, e.g. `@cee:`
 	Marker string
 
+	// Facility specifies facility of the syslog message, e.g. `LOG_LOCAL0`.
+	// The PRI value of message is computed as facility*8+severity.
+	Facility SyslogFacility
+
+	// RFC5424 determines if the syslog message is formatted by RFC 5424 instead of RFC 3164.
+	RFC5424 bool
+
+	// MsgID specifies MSGID of the RFC 5424 message, uses `-` if empty.
+	MsgID string
+
+	// StructuredDataID specifies SD-ID of the RFC 5424 structured data element which
+	// populated from the json keys of log entry, e.g. `fields@32473`.
+	// If empty, the STRUCTURED-DATA section is `-`.
+	StructuredDataID string
+
+	// OctetCounting determines if frames the syslog message by octet-counting
+	// (`MSG-LEN SP SYSLOG-MSG`) instead of non-transparent newline framing over TCP.
+	// It always be enabled when TLSConfig is set, see RFC 5425.
+	OctetCounting bool
+
+	// TLSConfig specifies the TLS configuration, if not empty, the connection
+	// created by Dial over TCP network is wrapped to a TLS client connection.
+	TLSConfig *tls.Config
+
 	// Dial specifies the dial function for creating TCP/TLS connections.
 	Dial func(network, addr string) (net.Conn, error)
 
-	mu    sync.Mutex
-	conn  net.Conn
-	local bool
+	// ReconnectDelay specifies the initial delay before reconnecting to the syslog
+	// server after a failed connection, it doubles on each consecutive failure.
+	// If zero, the writer reconnects on every write.
+	ReconnectDelay time.Duration
+
+	// MaxReconnectDelay specifies the maximum delay between reconnects, uses 1 minute if zero.
+	MaxReconnectDelay time.Duration
+
+	// ReconnectJitter specifies the maximum random duration added to each reconnect delay.
+	ReconnectJitter time.Duration
+
+	// MaxRetries specifies the maximum number of consecutive failed reconnects,
+	// the writer stops reconnecting until Close is called. Zero means no limit.
+	MaxRetries int
+
+	// BufferSize specifies the maximum number of messages held in memory during
+	// outages, they are flushed on reconnect. The oldest message is dropped if full.
+	BufferSize int
+
+	mu      sync.Mutex
+	conn    net.Conn
+	local   bool
+	retries int
+	delay   time.Duration
+	next    time.Time
+	lasterr error
+	pending [][]byte
 }
 
 // Close closes a connection to the syslog server.
@@ -37,6 +113,9 @@ func (w *SyslogWriter) Close() (err error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	w.retries, w.delay, w.next, w.lasterr = 0, 0, time.Time{}, nil
+	w.pending = nil
+
 	if w.conn != nil {
 		err = w.conn.Close()
 		w.conn = nil
@@ -45,6 +124,62 @@ func (w *SyslogWriter) Close() (err error) {
 	return
 }
 
+// reconnect makes a connection to the syslog server with respecting the reconnect
+// policy, and flushes the buffered messages after connected.
+func (w *SyslogWriter) reconnect() (err error) {
+	policy := w.ReconnectDelay > 0 || w.MaxRetries > 0
+	if policy && w.lasterr != nil {
+		if w.MaxRetries > 0 && w.retries >= w.MaxRetries {
+			return w.lasterr
+		}
+		if timeNow().Before(w.next) {
+			return w.lasterr
+		}
+	}
+
+	err = w.connect()
+	if err != nil {
+		if policy {
+			w.retries++
+			w.lasterr = err
+			switch {
+			case w.delay == 0:
+				w.delay = w.ReconnectDelay
+			default:
+				w.delay *= 2
+			}
+			maxDelay := w.MaxReconnectDelay
+			if maxDelay == 0 {
+				maxDelay = time.Minute
+			}
+			if w.delay > maxDelay {
+				w.delay = maxDelay
+			}
+			delay := w.delay
+			if ms := w.ReconnectJitter / time.Millisecond; ms > 0 {
+				delay += time.Duration(Fastrandn(uint32(ms))) * time.Millisecond
+			}
+			w.next = timeNow().Add(delay)
+		}
+		return
+	}
+
+	w.retries, w.delay, w.next, w.lasterr = 0, 0, time.Time{}, nil
+
+	for len(w.pending) > 0 {
+		if _, err = w.conn.Write(w.pending[0]); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			return
+		}
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+	w.pending = nil
+
+	return
+}
+
 // connect makes a connection to the syslog server.
 func (w *SyslogWriter) connect() (err error) {
 	if w.conn != nil {
@@ -62,6 +197,21 @@ func (w *SyslogWriter) connect() (err error) {
 		return
 	}
 
+	if w.TLSConfig != nil {
+		config := w.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(w.Address)
+		}
+		conn := tls.Client(w.conn, config)
+		if err = conn.Handshake(); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			return
+		}
+		w.conn = conn
+	}
+
 	w.local = w.Address != "" && w.Address[0] == '/'
 
 	if w.Hostname == "" {
@@ -80,8 +230,8 @@ func (w *SyslogWriter) WriteEntry(e *Entry) (n int, err error) {
 	if w.conn == nil {
 		w.mu.Lock()
 		if w.conn == nil {
-			err = w.connect()
-			if err != nil {
+			err = w.reconnect()
+			if err != nil && w.BufferSize <= 0 {
 				w.mu.Unlock()
 				return
 			}
@@ -90,25 +240,8 @@ func (w *SyslogWriter) WriteEntry(e *Entry) (n int, err error) {
 	}
 
 	// convert level to syslog priority
-	var priority byte
-	switch e.Level {
-	case TraceLevel:
-		priority = '7' // LOG_DEBUG
-	case DebugLevel:
-		priority = '7' // LOG_DEBUG
-	case InfoLevel:
-		priority = '6' // LOG_INFO
-	case WarnLevel:
-		priority = '4' // LOG_WARNING
-	case ErrorLevel:
-		priority = '3' // LOG_ERR
-	case FatalLevel:
-		priority = '2' // LOG_CRIT
-	case PanicLevel:
-		priority = '1' // LOG_ALERT
-	default:
-		priority = '6' // LOG_INFO
-	}
+	priority := levelSyslog(e.Level)
+	priority += int(w.Facility&0x1f) * 8
 
 	e1 := epool.Get().(*Entry)
 	defer func(entry *Entry) {
@@ -117,38 +250,162 @@ func (w *SyslogWriter) WriteEntry(e *Entry) (n int, err error) {
 		}
 	}(e1)
 
-	// <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
-	e1.buf = append(e1.buf[:0], '<', priority, '>')
-	if w.local {
-		// Compared to the network form below, the changes are:
-		//	1. Use time.Stamp instead of time.RFC3339.
-		//	2. Drop the hostname field.
-		e1.buf = timeNow().AppendFormat(e1.buf, time.Stamp)
+	// reserves the room of MSG-LEN for octet-counting framing
+	const framesize = 24
+	octet := w.OctetCounting || w.TLSConfig != nil
+	e1.buf = e1.buf[:0]
+	if octet {
+		e1.buf = append(e1.buf, make([]byte, framesize)...)
+	}
+
+	if w.RFC5424 {
+		e1.buf = w.rfc5424(e1.buf, priority, e.buf)
 	} else {
-		e1.buf = timeNow().AppendFormat(e1.buf, time.RFC3339)
+		// <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+		e1.buf = append(e1.buf, '<')
+		e1.buf = strconv.AppendInt(e1.buf, int64(priority), 10)
+		e1.buf = append(e1.buf, '>')
+		if w.local {
+			// Compared to the network form below, the changes are:
+			//	1. Use time.Stamp instead of time.RFC3339.
+			//	2. Drop the hostname field.
+			e1.buf = timeNow().AppendFormat(e1.buf, time.Stamp)
+		} else {
+			e1.buf = timeNow().AppendFormat(e1.buf, time.RFC3339)
+			e1.buf = append(e1.buf, ' ')
+			e1.buf = append(e1.buf, w.Hostname...)
+		}
 		e1.buf = append(e1.buf, ' ')
-		e1.buf = append(e1.buf, w.Hostname...)
+		e1.buf = append(e1.buf, w.Tag...)
+		e1.buf = append(e1.buf, '[')
+		e1.buf = strconv.AppendInt(e1.buf, int64(pid), 10)
+		e1.buf = append(e1.buf, ']', ':', ' ')
+		e1.buf = append(e1.buf, w.Marker...)
+		e1.buf = append(e1.buf, e.buf...)
+	}
+
+	msg := e1.buf
+	if octet {
+		// MSG-LEN SP SYSLOG-MSG
+		if msg[len(msg)-1] == '\n' {
+			msg = msg[:len(msg)-1]
+		}
+		var tmp [framesize]byte
+		size := strconv.AppendInt(tmp[:0], int64(len(msg)-framesize), 10)
+		i := framesize - len(size) - 1
+		copy(msg[i:], size)
+		msg[framesize-1] = ' '
+		msg = msg[i:]
 	}
-	e1.buf = append(e1.buf, ' ')
-	e1.buf = append(e1.buf, w.Tag...)
-	e1.buf = append(e1.buf, '[')
-	e1.buf = strconv.AppendInt(e1.buf, int64(pid), 10)
-	e1.buf = append(e1.buf, ']', ':', ' ')
-	e1.buf = append(e1.buf, w.Marker...)
-	e1.buf = append(e1.buf, e.buf...)
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	if w.conn != nil {
-		if n, err := w.conn.Write(e1.buf); err == nil {
-			return n, err
+	switch {
+	case w.conn != nil:
+		if n, err = w.conn.Write(msg); err == nil {
+			return
+		}
+		err = w.reconnect()
+	case err == nil:
+		err = w.reconnect()
+	}
+	if err == nil {
+		if n, err = w.conn.Write(msg); err == nil {
+			return
+		}
+	}
+
+	if w.BufferSize > 0 {
+		if len(w.pending) >= w.BufferSize {
+			w.pending[0] = nil
+			w.pending = w.pending[1:]
+		}
+		w.pending = append(w.pending, append([]byte(nil), msg...))
+		return len(msg), nil
+	}
+
+	return 0, err
+}
+
+// Write implements io.Writer, sends logs to the syslog server with the priority
+// converted from the level field of json input. Prefer to use it as Writer of Logger,
+// which passes the level of entry directly.
+func (w *SyslogWriter) Write(p []byte) (n int, err error) {
+	return w.WriteEntry(&Entry{Level: parseJSONLevel(p, "level"), buf: p})
+}
+
+// rfc5424 appends a RFC 5424 syslog message to dst.
+//
+//	<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) rfc5424(dst []byte, priority int, msg []byte) []byte {
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(priority), 10)
+	dst = append(dst, '>', '1', ' ')
+	dst = timeNow().AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = appendSyslogName(dst, w.Hostname, 255)
+	dst = append(dst, ' ')
+	dst = appendSyslogName(dst, w.Tag, 48)
+	dst = append(dst, ' ')
+	dst = strconv.AppendInt(dst, int64(pid), 10)
+	dst = append(dst, ' ')
+	dst = appendSyslogName(dst, w.MsgID, 32)
+	dst = append(dst, ' ')
+
+	if w.StructuredDataID == "" || len(msg) == 0 || msg[0] != '{' {
+		dst = append(dst, '-')
+	} else {
+		b := bbpool.Get().(*bb)
+		b.B = append(b.B[:0], msg...)
+
+		var args FormatterArgs
+		parseFormatterArgs(b.B, &args)
+
+		dst = append(dst, '[')
+		dst = appendSyslogName(dst, w.StructuredDataID, 32)
+		for _, kv := range args.KeyValues {
+			dst = append(dst, ' ')
+			dst = appendSyslogName(dst, kv.Key, 32)
+			dst = append(dst, '=', '"')
+			for i := 0; i < len(kv.Value); i++ {
+				switch c := kv.Value[i]; c {
+				case '"', '\\', ']':
+					dst = append(dst, '\\', c)
+				default:
+					dst = append(dst, c)
+				}
+			}
+			dst = append(dst, '"')
+		}
+		dst = append(dst, ']')
+
+		if cap(b.B) <= bbcap {
+			bbpool.Put(b)
+		}
+	}
+
+	dst = append(dst, ' ')
+	dst = append(dst, w.Marker...)
+	dst = append(dst, msg...)
+
+	return dst
+}
+
+// appendSyslogName appends s as a RFC 5424 header or SD-NAME field to dst, it drops
+// the characters not allowed by PRINTUSASCII or SD-NAME, and truncates it to max bytes.
+func appendSyslogName(dst []byte, s string, max int) []byte {
+	n := len(dst)
+	for i := 0; i < len(s) && len(dst)-n < max; i++ {
+		if c := s[i]; '!' <= c && c <= '~' && c != '=' && c != ']' && c != '"' {
+			dst = append(dst, c)
 		}
 	}
-	if err := w.connect(); err != nil {
-		return 0, err
+	if len(dst) == n {
+		dst = append(dst, '-')
 	}
-	return w.conn.Write(e1.buf)
+	return dst
 }
 
 var _ Writer = (*SyslogWriter)(nil)
+var _ io.Writer = (*SyslogWriter)(nil)
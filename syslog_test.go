@@ -4,8 +4,18 @@
 package log
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	stdLog "log"
+	"math/big"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -114,3 +124,343 @@ func TestSyslogWriterUnix(t *testing.T) {
 	_, err = wlprintf(w, InfoLevel, "a long long long long message again.\n")
 	t.Logf("write syslog writer error: %+v", err)
 }
+
+func TestSyslogWriterRFC5424(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer conn.Close()
+
+	w := &SyslogWriter{
+		Network:          "udp",
+		Address:          conn.LocalAddr().String(),
+		Hostname:         "myhost",
+		Tag:              "myapp",
+		MsgID:            "ID47",
+		StructuredDataID: "fields@32473",
+		RFC5424:          true,
+	}
+	defer w.Close()
+
+	_, err = wlprintf(w, WarnLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"warn","foo":"b\"a]r","n":42,"message":"hello rfc5424"}`+"\n")
+	if err != nil {
+		t.Fatalf("write syslog writer error: %+v", err)
+	}
+
+	var data [1024]byte
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(data[:])
+	if err != nil {
+		t.Fatalf("read syslog message error: %+v", err)
+	}
+
+	msg := string(data[:n])
+	if !strings.HasPrefix(msg, "<4>1 ") {
+		t.Errorf("invalid rfc5424 header: %s", msg)
+	}
+	for _, s := range []string{
+		" myhost myapp " + strconv.Itoa(pid) + " ID47 ",
+		`[fields@32473 foo="b\"a\]r" n="42"] {"time":`,
+	} {
+		if !strings.Contains(msg, s) {
+			t.Errorf("rfc5424 message %q should contains %q", msg, s)
+		}
+	}
+}
+
+func TestSyslogWriterOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var data [1024]byte
+		var b []byte
+		for !strings.Contains(string(b), "second") {
+			n, err := conn.Read(data[:])
+			if err != nil {
+				break
+			}
+			b = append(b, data[:n]...)
+		}
+		ch <- string(b)
+	}()
+
+	w := &SyslogWriter{
+		Network:       "tcp",
+		Address:       ln.Addr().String(),
+		Tag:           "myapp",
+		OctetCounting: true,
+	}
+	defer w.Close()
+
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","message":"first\nline"}`+"\n")
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","message":"second"}`+"\n")
+
+	select {
+	case s := <-ch:
+		for i := 0; i < 2; i++ {
+			j := strings.IndexByte(s, ' ')
+			if j <= 0 {
+				t.Fatalf("invalid octet-counting frame: %q", s)
+			}
+			size, err := strconv.Atoi(s[:j])
+			if err != nil || len(s) < j+1+size {
+				t.Fatalf("invalid octet-counting frame size: %q", s)
+			}
+			if msg := s[j+1 : j+1+size]; !strings.HasPrefix(msg, "<6>") || !strings.HasSuffix(msg, "}") {
+				t.Errorf("invalid octet-counting message: %q", msg)
+			}
+			s = s[j+1+size:]
+		}
+		if s != "" {
+			t.Errorf("unexpected trailing data: %q", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("read syslog messages timeout")
+	}
+}
+
+func TestSyslogWriterTLS(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key error: %+v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("create certificate error: %+v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate error: %+v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}},
+	})
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var data [1024]byte
+		n, _ := conn.Read(data[:])
+		ch <- string(data[:n])
+	}()
+
+	w := &SyslogWriter{
+		Network:   "tcp",
+		Address:   ln.Addr().String(),
+		Tag:       "myapp",
+		TLSConfig: &tls.Config{RootCAs: pool},
+	}
+	defer w.Close()
+
+	_, err = wlprintf(w, ErrorLevel, `{"level":"error","message":"hello tls"}`+"\n")
+	if err != nil {
+		t.Fatalf("write syslog writer error: %+v", err)
+	}
+
+	select {
+	case s := <-ch:
+		if !strings.Contains(s, " <3>") || !strings.HasSuffix(s, `"message":"hello tls"}`) {
+			t.Errorf("invalid tls syslog message: %q", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("read syslog messages timeout")
+	}
+}
+
+func TestSyslogWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var data [1024]byte
+		var b []byte
+		for strings.Count(string(b), "\n") < 3 {
+			n, err := conn.Read(data[:])
+			if err != nil {
+				break
+			}
+			b = append(b, data[:n]...)
+		}
+		ch <- string(b)
+	}()
+
+	var dials int
+	var down = true
+	w := &SyslogWriter{
+		Network: "tcp",
+		Address: ln.Addr().String(),
+		Dial: func(network, addr string) (net.Conn, error) {
+			dials++
+			if down {
+				return nil, errors.New("syslog server is down")
+			}
+			return net.Dial(network, addr)
+		},
+		ReconnectDelay:    10 * time.Millisecond,
+		MaxReconnectDelay: 20 * time.Millisecond,
+		BufferSize:        2,
+	}
+	defer w.Close()
+
+	for _, msg := range []string{"one", "two", "three"} {
+		if _, err := wlprintf(w, InfoLevel, `{"message":"%s"}`+"\n", msg); err != nil {
+			t.Errorf("buffered write should not return error: %+v", err)
+		}
+	}
+	if dials != 1 {
+		t.Errorf("reconnect should be delayed by backoff, dials=%d", dials)
+	}
+
+	down = false
+	time.Sleep(30 * time.Millisecond)
+	if _, err := wlprintf(w, InfoLevel, `{"message":"four"}`+"\n"); err != nil {
+		t.Fatalf("write syslog writer error: %+v", err)
+	}
+
+	select {
+	case s := <-ch:
+		if strings.Contains(s, "one") || !strings.Contains(s, "two") || !strings.Contains(s, "three") || !strings.Contains(s, "four") {
+			t.Errorf("unexpected flushed messages: %q", s)
+		}
+		if strings.Index(s, "two") > strings.Index(s, "four") {
+			t.Errorf("buffered messages should be flushed first: %q", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("read syslog messages timeout")
+	}
+}
+
+func TestSyslogWriterMaxRetries(t *testing.T) {
+	var dials int
+	w := &SyslogWriter{
+		Network: "tcp",
+		Address: "127.0.0.1:1",
+		Dial: func(network, addr string) (net.Conn, error) {
+			dials++
+			return nil, errors.New("syslog server is down")
+		},
+		MaxRetries: 2,
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := wlprintf(w, InfoLevel, `{"message":"hello"}`+"\n"); err == nil {
+			t.Errorf("write to a down syslog server should return error")
+		}
+	}
+	if dials != 2 {
+		t.Errorf("writer should stop reconnecting after max retries, dials=%d", dials)
+	}
+
+	w.Close()
+	_, _ = wlprintf(w, InfoLevel, `{"message":"hello"}`+"\n")
+	if dials != 3 {
+		t.Errorf("writer should reconnect after close, dials=%d", dials)
+	}
+}
+
+func TestSyslogWriterIOWriter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer conn.Close()
+
+	w := &SyslogWriter{
+		Network: "udp",
+		Address: conn.LocalAddr().String(),
+	}
+	defer w.Close()
+
+	logger := stdLog.New(w, "", 0)
+	logger.Print(`{"time":"2019-07-10T05:35:54.277Z","message":"hello","level":"error"}`)
+
+	var data [1024]byte
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(data[:])
+	if err != nil {
+		t.Fatalf("read syslog message error: %+v", err)
+	}
+	if msg := string(data[:n]); !strings.HasPrefix(msg, "<3>") {
+		t.Errorf("syslog priority should be sniffed from level field: %s", msg)
+	}
+}
+
+func TestSyslogWriterFacility(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer conn.Close()
+
+	for _, c := range []struct {
+		Facility SyslogFacility
+		Level    Level
+		Prefix   string
+		RFC5424  bool
+	}{
+		{LOG_KERN, InfoLevel, "<6>", false},
+		{LOG_USER, WarnLevel, "<12>", false},
+		{LOG_DAEMON, ErrorLevel, "<27>", true},
+		{LOG_LOCAL0, InfoLevel, "<134>", false},
+		{LOG_LOCAL7, TraceLevel, "<191>", true},
+	} {
+		w := &SyslogWriter{
+			Network:  "udp",
+			Address:  conn.LocalAddr().String(),
+			Facility: c.Facility,
+			RFC5424:  c.RFC5424,
+		}
+		_, err = wlprintf(w, c.Level, `{"message":"hello facility"}`+"\n")
+		if err != nil {
+			t.Fatalf("write syslog writer error: %+v", err)
+		}
+		w.Close()
+
+		var data [1024]byte
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(data[:])
+		if err != nil {
+			t.Fatalf("read syslog message error: %+v", err)
+		}
+		if msg := string(data[:n]); !strings.HasPrefix(msg, c.Prefix) {
+			t.Errorf("syslog message %q should have prefix %q", msg, c.Prefix)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// testingTB is the subset of testing.TB used by TestWriter, it keeps the testing package
+// out of the programs which import this package.
+type testingTB interface {
+	Helper()
+	Log(args ...interface{})
+	Cleanup(func())
+}
+
+// TestWriter returns a Writer which logs the entries to t.Log in the format of ConsoleWriter,
+// prefixed with the file and line of the logging call. The entries written after the test
+// has finished are dropped, instead of panicking in t.Log, e.g.
+//
+//	logger := log.Logger{Level: log.TraceLevel, Writer: log.TestWriter(t)}
+func TestWriter(t testingTB) Writer {
+	w := &testWriter{t: t}
+	w.console.Writer = &w.buf
+	t.Cleanup(func() {
+		w.mu.Lock()
+		w.done = true
+		w.mu.Unlock()
+	})
+	return w
+}
+
+type testWriter struct {
+	mu      sync.Mutex
+	t       testingTB
+	done    bool
+	buf     bb
+	console ConsoleWriter
+}
+
+// WriteEntry implements Writer.
+func (w *testWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.t.Helper()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return len(e.buf), nil
+	}
+
+	w.buf.B = w.buf.B[:0]
+	if file, line := testCaller(); file != "" {
+		w.buf.B = append(w.buf.B, file...)
+		w.buf.B = append(w.buf.B, ':')
+		w.buf.B = strconv.AppendInt(w.buf.B, int64(line), 10)
+		w.buf.B = append(w.buf.B, ": "...)
+	}
+	if _, err = w.console.WriteEntry(e); err != nil {
+		return
+	}
+
+	w.t.Log(strings.TrimRight(b2s(w.buf.B), "\n"))
+	return len(e.buf), nil
+}
+
+var testPackageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// testCaller returns the file and line of the first caller outside this package.
+func testCaller() (file string, line int) {
+	var rpc [32]uintptr
+	frames := runtime.CallersFrames(rpc[:runtime.Callers(3, rpc[:])])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != testPackageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return filepath.Base(frame.File), frame.Line
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+var _ Writer = (*testWriter)(nil)
+
+// CapturedEntry is an entry recorded by CaptureWriter.
+type CapturedEntry struct {
+	// Level is the level of entry.
+	Level Level
+
+	// Line is the json line of entry, with the trailing newline.
+	Line string
+}
+
+// CaptureWriter is a Writer which records the entries in memory for assertions.
+type CaptureWriter struct {
+	mu      sync.Mutex
+	entries []CapturedEntry
+}
+
+// CaptureLogger returns a logger of TraceLevel and the CaptureWriter recording its entries.
+func CaptureLogger() (*Logger, *CaptureWriter) {
+	w := &CaptureWriter{}
+	return &Logger{Level: TraceLevel, Writer: w}, w
+}
+
+// WriteEntry implements Writer.
+func (w *CaptureWriter) WriteEntry(e *Entry) (int, error) {
+	w.mu.Lock()
+	w.entries = append(w.entries, CapturedEntry{Level: e.Level, Line: string(e.buf)})
+	w.mu.Unlock()
+	return len(e.buf), nil
+}
+
+// Entries returns a copy of the recorded entries.
+func (w *CaptureWriter) Entries() []CapturedEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]CapturedEntry(nil), w.entries...)
+}
+
+// Len returns the number of recorded entries.
+func (w *CaptureWriter) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.entries)
+}
+
+// String returns the json lines of recorded entries.
+func (w *CaptureWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var sb strings.Builder
+	for _, entry := range w.entries {
+		sb.WriteString(entry.Line)
+	}
+	return sb.String()
+}
+
+// Reset discards the recorded entries.
+func (w *CaptureWriter) Reset() {
+	w.mu.Lock()
+	w.entries = nil
+	w.mu.Unlock()
+}
+
+var _ Writer = (*CaptureWriter)(nil)
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testWriterTB struct {
+	logs     []string
+	cleanups []func()
+}
+
+func (t *testWriterTB) Helper()                 {}
+func (t *testWriterTB) Log(args ...interface{}) { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *testWriterTB) Cleanup(f func())        { t.cleanups = append(t.cleanups, f) }
+
+func TestTestWriter(t *testing.T) {
+	tb := &testWriterTB{}
+	logger := Logger{Level: InfoLevel, Writer: TestWriter(tb)}
+
+	logger.Info().Str("foo", "bar").Msg("hello test writer")
+	if len(tb.logs) != 1 {
+		t.Fatalf("test writer must log an entry: %q", tb.logs)
+	}
+	if s := tb.logs[0]; !strings.HasPrefix(s, "testwriter_test.go:") || !strings.Contains(s, "INF") || !strings.Contains(s, "hello test writer") || !strings.Contains(s, "foo=bar") || strings.HasSuffix(s, "\n") {
+		t.Errorf("test writer log mismatched: %q", s)
+	}
+
+	for _, f := range tb.cleanups {
+		f()
+	}
+	logger.Info().Msg("after test")
+	if len(tb.logs) != 1 {
+		t.Errorf("test writer must drop entries after test: %q", tb.logs)
+	}
+
+	logger = Logger{Level: InfoLevel, Writer: TestWriter(t)}
+	logger.Info().Msg("hello testing.T")
+}
+
+func TestCaptureLogger(t *testing.T) {
+	logger, w := CaptureLogger()
+
+	logger.Debug().Int("n", 1).Msg("hello")
+	logger.Warn().Msg("world")
+
+	entries := w.Entries()
+	if len(entries) != 2 || w.Len() != 2 {
+		t.Fatalf("capture writer must record 2 entries: %+v", entries)
+	}
+	if entries[0].Level != DebugLevel || !strings.Contains(entries[0].Line, `"n":1,"message":"hello"`) {
+		t.Errorf("capture writer entry mismatched: %+v", entries[0])
+	}
+	if entries[1].Level != WarnLevel || !strings.Contains(w.String(), `"message":"world"`) {
+		t.Errorf("capture writer entry mismatched: %+v", entries[1])
+	}
+
+	w.Reset()
+	if w.Len() != 0 || w.String() != "" {
+		t.Errorf("capture writer must be empty after reset")
+	}
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"net"
 	"net/netip"
@@ -8,12 +10,32 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"time"
 )
 
 // TSVLogger represents an active logging object that generates lines of TSV output to an io.Writer.
 type TSVLogger struct {
 	Separator byte
 	Writer    io.Writer
+
+	// TimeFormat specifies the time format of Time and Now. It uses time.RFC3339 with milliseconds if empty.
+	// If set with `TimeFormatUnix`, `TimeFormatUnixMs`, `TimeFormatUnixWithMs`, times are formated as UNIX timestamp.
+	TimeFormat string
+
+	// TimeUTC specifices that the times of Time and Now should be UTC instead of system local time.
+	TimeUTC bool
+
+	// FloatPrecision specifies the number of digits after the decimal point of floats.
+	// It uses the smallest number of digits necessary if zero, and no digits if negative.
+	FloatPrecision int
+
+	// TrueValue and FalseValue specifies the output of Bool, it uses 1 and 0 if empty.
+	TrueValue  string
+	FalseValue string
+
+	// Escape determines if escapes the backslashes, tabs, newlines and separators of Str, Bytes
+	// and RawJSON with backslashes, as the TSV format of ClickHouse and BigQuery expects.
+	Escape bool
 }
 
 // TSVEntry represents a tsv log entry. It is instanced by one of TSVLogger and finalized by the Msg method.
@@ -21,6 +43,7 @@ type TSVEntry struct {
 	buf []byte
 	w   io.Writer
 	sep byte
+	l   *TSVLogger
 }
 
 var tepool = sync.Pool{
@@ -32,6 +55,7 @@ var tepool = sync.Pool{
 // New starts a new tsv message.
 func (l *TSVLogger) New() (e *TSVEntry) {
 	e = tepool.Get().(*TSVEntry)
+	e.l = l
 	e.sep = l.Separator
 	if l.Writer != nil {
 		e.w = l.Writer
@@ -113,6 +137,34 @@ func (e *TSVEntry) TimestampMS() *TSVEntry {
 	return e
 }
 
+// Now adds the current time formatted by TimeFormat of logger.
+func (e *TSVEntry) Now() *TSVEntry {
+	return e.Time(timeNow())
+}
+
+// Time adds the t formatted by TimeFormat of logger.
+func (e *TSVEntry) Time(t time.Time) *TSVEntry {
+	if e.l.TimeUTC {
+		t = t.UTC()
+	}
+	switch e.l.TimeFormat {
+	case "":
+		e.buf = t.AppendFormat(e.buf, "2006-01-02T15:04:05.000Z07:00")
+	case TimeFormatUnix:
+		e.buf = strconv.AppendInt(e.buf, t.Unix(), 10)
+	case TimeFormatUnixMs:
+		e.buf = strconv.AppendInt(e.buf, t.UnixNano()/1000000, 10)
+	case TimeFormatUnixWithMs:
+		e.buf = strconv.AppendInt(e.buf, t.Unix(), 10)
+		ms := t.Nanosecond() / 1000000
+		e.buf = append(e.buf, '.', byte('0'+ms/100), byte('0'+ms/10%10), byte('0'+ms%10))
+	default:
+		e.buf = t.AppendFormat(e.buf, e.l.TimeFormat)
+	}
+	e.buf = append(e.buf, e.sep)
+	return e
+}
+
 // Caller adds the file:line of to the entry.
 func (e *TSVEntry) Caller(depth int) *TSVEntry {
 	var rpc [1]uintptr
@@ -137,9 +189,16 @@ func (e *TSVEntry) Caller(depth int) *TSVEntry {
 	return e
 }
 
-// Bool append the b as a bool to the entry, the value of output bool is 0 or 1.
+// Bool append the b as a bool to the entry, the value of output bool is 0 or 1,
+// or the TrueValue and FalseValue of logger.
 func (e *TSVEntry) Bool(b bool) *TSVEntry {
-	if b {
+	if b && e.l.TrueValue != "" {
+		e.buf = append(e.buf, e.l.TrueValue...)
+		e.buf = append(e.buf, e.sep)
+	} else if !b && e.l.FalseValue != "" {
+		e.buf = append(e.buf, e.l.FalseValue...)
+		e.buf = append(e.buf, e.sep)
+	} else if b {
 		e.buf = append(e.buf, '1', e.sep)
 	} else {
 		e.buf = append(e.buf, '0', e.sep)
@@ -163,9 +222,16 @@ func (e *TSVEntry) Byte(b byte) *TSVEntry {
 	return e
 }
 
-// Float64 adds a float64 to the entry.
+// Float64 adds a float64 to the entry, with the FloatPrecision of logger.
 func (e *TSVEntry) Float64(f float64) *TSVEntry {
-	e.buf = strconv.AppendFloat(e.buf, f, 'f', -1, 64)
+	prec := e.l.FloatPrecision
+	switch {
+	case prec == 0:
+		prec = -1
+	case prec < 0:
+		prec = 0
+	}
+	e.buf = strconv.AppendFloat(e.buf, f, 'f', prec, 64)
 	e.buf = append(e.buf, e.sep)
 	return e
 }
@@ -231,31 +297,66 @@ func (e *TSVEntry) Uint(i uint) *TSVEntry {
 
 // Str adds a string to the entry.
 func (e *TSVEntry) Str(val string) *TSVEntry {
-	e.buf = append(e.buf, val...)
+	if e.l.Escape {
+		e.escape(val)
+	} else {
+		e.buf = append(e.buf, val...)
+	}
 	e.buf = append(e.buf, e.sep)
 	return e
 }
 
 // Bytes adds a bytes as string to the entry.
 func (e *TSVEntry) Bytes(val []byte) *TSVEntry {
-	e.buf = append(e.buf, val...)
-	e.buf = append(e.buf, e.sep)
-	return e
+	return e.Str(b2s(val))
+}
+
+// RawJSON adds the raw json of nested fields to the entry.
+func (e *TSVEntry) RawJSON(b []byte) *TSVEntry {
+	return e.Str(b2s(b))
+}
+
+// Any adds the json of v to the entry, or the value of v if it is a string.
+func (e *TSVEntry) Any(v interface{}) *TSVEntry {
+	if s, ok := v.(string); ok {
+		return e.Str(s)
+	}
+	b := bbpool.Get().(*bb)
+	b.B = b.B[:0]
+	defer bbpool.Put(b)
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return e.Str(err.Error())
+	}
+	return e.Str(b2s(bytes.TrimRight(b.B, "\n")))
+}
+
+// escape appends the s with the backslashes, tabs, newlines and separators escaped.
+func (e *TSVEntry) escape(s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			e.buf = append(e.buf, '\\', '\\')
+		case '\t':
+			e.buf = append(e.buf, '\\', 't')
+		case '\n':
+			e.buf = append(e.buf, '\\', 'n')
+		case '\r':
+			e.buf = append(e.buf, '\\', 'r')
+		case 0:
+			e.buf = append(e.buf, '\\', '0')
+		case e.sep:
+			e.buf = append(e.buf, '\\', c)
+		default:
+			e.buf = append(e.buf, c)
+		}
+	}
 }
 
 // IPAddr adds IPv4 or IPv6 Address to the entry.
 func (e *TSVEntry) IPAddr(ip net.IP) *TSVEntry {
-	if ip4 := ip.To4(); ip4 != nil {
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[0]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[1]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[2]), 10)
-		e.buf = append(e.buf, '.')
-		e.buf = strconv.AppendInt(e.buf, int64(ip4[3]), 10)
-	} else {
-		e.buf = append(e.buf, ip.String()...)
-	}
+	e.buf = appendIP(e.buf, ip)
 	e.buf = append(e.buf, e.sep)
 	return e
 }
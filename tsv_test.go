@@ -1,9 +1,11 @@
 package log
 
 import (
+	"bytes"
 	"io"
 	"net"
 	"testing"
+	"time"
 )
 
 func TestTSVLogger(t *testing.T) {
@@ -105,3 +107,56 @@ func BenchmarkTSVLogger(b *testing.B) {
 		logger.New().TimestampMS().Str("a tsv message").Msg()
 	}
 }
+
+func TestTSVTypes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := TSVLogger{
+		TimeFormat:     TimeFormatUnixWithMs,
+		TimeUTC:        true,
+		FloatPrecision: 2,
+		TrueValue:      "true",
+		FalseValue:     "false",
+		Escape:         true,
+		Writer:         &buf,
+	}
+
+	logger.New().
+		Time(time.Unix(1562736954, 277000000)).
+		Float64(0.618).
+		Bool(true).
+		Bool(false).
+		Str("a\tb\nc\\d").
+		RawJSON([]byte(`{"a":[1,2]}`)).
+		Any(map[string]int{"n": 1}).
+		Any("x").
+		Msg()
+
+	if got, want := buf.String(), "1562736954.277\t0.62\ttrue\tfalse\ta\\tb\\nc\\\\d\t{\"a\":[1,2]}\t{\"n\":1}\tx\n"; got != want {
+		t.Errorf("TSVLogger output got %q, want %q", got, want)
+	}
+
+	for _, c := range []struct {
+		Format string
+		Prec   int
+		Want   string
+	}{
+		{"", -1, "2019-07-10T05:35:54.277Z\t1\n"},
+		{TimeFormatUnix, 0, "1562736954\t0.618\n"},
+		{TimeFormatUnixMs, 1, "1562736954277\t0.6\n"},
+		{"2006-01-02 15:04:05", 3, "2019-07-10 05:35:54\t0.618\n"},
+	} {
+		buf.Reset()
+		logger := TSVLogger{TimeFormat: c.Format, TimeUTC: true, FloatPrecision: c.Prec, Writer: &buf}
+		logger.New().Time(time.Unix(1562736954, 277000000)).Float64(0.618).Msg()
+		if got := buf.String(); got != c.Want {
+			t.Errorf("TSVLogger TimeFormat=%q got %q, want %q", c.Format, got, c.Want)
+		}
+	}
+
+	buf.Reset()
+	logger = TSVLogger{Separator: ',', Escape: true, Writer: &buf}
+	logger.New().Str("a,b").Bool(true).Msg()
+	if got, want := buf.String(), "a\\,b,1\n"; got != want {
+		t.Errorf("TSVLogger separator escape got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// UringWriter is an Writer that appends the entries to a file via io_uring on Linux, the
+// entries are copied to the registered buffers, and each full buffer is submitted without
+// blocking the logging, so a syscall is made per buffer rather than per entry.
+//
+// It falls back to a FileWriter of Filename elsewhere, or if io_uring is unavailable, e.g.
+// the kernel is older than 5.6 or the syscalls are denied by seccomp. It does not rotate
+// the file, use FileWriter for the rotating.
+type UringWriter struct {
+	// Filename is the file to write logs to.
+	Filename string
+
+	// FileMode represents the file's mode and permission bits. The default mode is 0644.
+	FileMode os.FileMode
+
+	// QueueDepth specifies the number of registered buffers in flight, uses 8 if zero.
+	QueueDepth int
+
+	// BufferSize specifies the size of each registered buffer, uses 64KB if zero.
+	BufferSize int
+
+	// FlushInterval specifies the interval of submitting the partial buffer, uses 1 second
+	// if zero, and submits only on full buffers and Close if negative.
+	FlushInterval time.Duration
+
+	mu       sync.Mutex
+	ring     *uring
+	fallback *FileWriter
+	timer    *time.Timer
+}
+
+// WriteEntry implements Writer.
+func (w *UringWriter) WriteEntry(e *Entry) (n int, err error) {
+	return w.Write(e.buf)
+}
+
+// Write implements io.Writer.
+func (w *UringWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ring == nil && w.fallback == nil {
+		if err = w.open(); err != nil {
+			return
+		}
+	}
+	if w.fallback != nil {
+		return w.fallback.Write(p)
+	}
+
+	n, err = w.ring.write(p)
+
+	if w.timer == nil && w.FlushInterval >= 0 {
+		interval := w.FlushInterval
+		if interval == 0 {
+			interval = time.Second
+		}
+		var timer *time.Timer
+		timer = time.AfterFunc(interval, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.timer != timer || w.ring == nil {
+				return
+			}
+			_ = w.ring.submit()
+			// re-arm after each flush so the later partial buffers are submitted too.
+			timer.Reset(interval)
+		})
+		w.timer = timer
+	}
+
+	return
+}
+
+// Uring reports whether the writer is backed by io_uring rather than the FileWriter fallback.
+func (w *UringWriter) Uring() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ring == nil && w.fallback == nil {
+		if err := w.open(); err != nil {
+			return false
+		}
+	}
+	return w.ring != nil
+}
+
+// open opens the file and sets up io_uring, the errors of opening the file are
+// returned to the caller and retried by the next call.
+func (w *UringWriter) open() (err error) {
+	perm := w.FileMode
+	if perm == 0 {
+		perm = 0644
+	}
+	depth := w.QueueDepth
+	if depth <= 0 {
+		depth = 8
+	}
+	size := w.BufferSize
+	if size <= 0 {
+		size = 64 * 1024
+	}
+
+	file, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return
+	}
+
+	w.ring, err = newUring(file, depth, size)
+	if err != nil {
+		// io_uring is unavailable
+		file.Close()
+		w.ring = nil
+		w.fallback = &FileWriter{Filename: w.Filename, FileMode: w.FileMode}
+		err = nil
+	}
+
+	return
+}
+
+// Flush submits the buffered entries and waits for the completions.
+func (w *UringWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ring == nil {
+		return nil
+	}
+	return w.ring.flush()
+}
+
+// Close implements io.Closer, it flushes the buffered entries and closes the file.
+func (w *UringWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.fallback != nil {
+		err = w.fallback.Close()
+		w.fallback = nil
+	}
+	if w.ring != nil {
+		err = w.ring.close()
+		w.ring = nil
+	}
+	return
+}
+
+var _ Writer = (*UringWriter)(nil)
+var _ io.WriteCloser = (*UringWriter)(nil)
@@ -0,0 +1,303 @@
+//go:build linux && (amd64 || arm64 || 386 || arm || riscv64 || ppc64 || ppc64le || s390x)
+// +build linux
+// +build amd64 arm64 386 arm riscv64 ppc64 ppc64le s390x
+
+package log
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysIoUringSetup    = 425
+	sysIoUringEnter    = 426
+	sysIoUringRegister = 427
+
+	uringOffSQRing = 0
+	uringOffCQRing = 0x8000000
+	uringOffSQEs   = 0x10000000
+
+	uringOpWriteFixed      = 5
+	uringSqeIoDrain        = 1 << 1
+	uringEnterGetEvents    = 1
+	uringRegisterBuffers   = 0
+	uringUnregisterBuffers = 1
+)
+
+// uringParams is struct io_uring_params of linux/io_uring.h.
+type uringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        uringSQOffsets
+	cqOff        uringCQOffsets
+}
+
+// uringSQOffsets is struct io_sqring_offsets of linux/io_uring.h.
+type uringSQOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// uringCQOffsets is struct io_cqring_offsets of linux/io_uring.h.
+type uringCQOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// uringSQE is struct io_uring_sqe of linux/io_uring.h.
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	pad         [2]uint64
+}
+
+// uringCQE is struct io_uring_cqe of linux/io_uring.h.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uring is an io_uring instance which writes the registered buffers to a file, the
+// offsets are tracked by itself and each write drains the previous ones, so that the
+// file never has holes.
+type uring struct {
+	file   *os.File
+	fd     int
+	offset int64
+
+	sqmem, cqmem, sqemem []byte
+	sqHead, sqTail       *uint32
+	sqMask               uint32
+	sqArray              []uint32
+	sqes                 []uringSQE
+	cqHead, cqTail       *uint32
+	cqMask               uint32
+	cqes                 []uringCQE
+
+	bufmem   []byte
+	bufs     [][]byte // the registered buffers
+	lens     []int    // the submitted length of buffers
+	offs     []int64  // the submitted offset of buffers
+	free     []int    // the free buffers
+	cur      int      // the buffer being filled, or -1
+	inflight int
+	pending  int // the queued sqes to be submitted
+}
+
+func newUring(file *os.File, depth, size int) (r *uring, err error) {
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+
+	var params uringParams
+	fd, _, errno := syscall.Syscall(sysIoUringSetup, uintptr(depth), uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	r = &uring{file: file, fd: int(fd), offset: offset, cur: -1}
+	defer func() {
+		if err != nil {
+			r.unmap()
+			syscall.Close(r.fd)
+			r = nil
+		}
+	}()
+
+	r.sqmem, err = syscall.Mmap(r.fd, uringOffSQRing, int(params.sqOff.array+params.sqEntries*4), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		return
+	}
+	r.cqmem, err = syscall.Mmap(r.fd, uringOffCQRing, int(params.cqOff.cqes+params.cqEntries*uint32(unsafe.Sizeof(uringCQE{}))), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		return
+	}
+	r.sqemem, err = syscall.Mmap(r.fd, uringOffSQEs, int(params.sqEntries*uint32(unsafe.Sizeof(uringSQE{}))), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		return
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqmem[params.sqOff.head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqmem[params.sqOff.tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&r.sqmem[params.sqOff.ringMask]))
+	r.sqArray = unsafe.Slice((*uint32)(unsafe.Pointer(&r.sqmem[params.sqOff.array])), params.sqEntries)
+	r.sqes = unsafe.Slice((*uringSQE)(unsafe.Pointer(&r.sqemem[0])), params.sqEntries)
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqmem[params.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqmem[params.cqOff.tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&r.cqmem[params.cqOff.ringMask]))
+	r.cqes = unsafe.Slice((*uringCQE)(unsafe.Pointer(&r.cqmem[params.cqOff.cqes])), params.cqEntries)
+
+	// the buffers are mapped outside of the heap, so they are pinned for the kernel
+	r.bufmem, err = syscall.Mmap(-1, 0, depth*size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return
+	}
+	iovecs := make([]syscall.Iovec, depth)
+	for i := 0; i < depth; i++ {
+		buf := r.bufmem[i*size : (i+1)*size : (i+1)*size]
+		r.bufs = append(r.bufs, buf[:0])
+		r.lens = append(r.lens, 0)
+		r.offs = append(r.offs, 0)
+		r.free = append(r.free, i)
+		iovecs[i].Base = &buf[0]
+		iovecs[i].SetLen(size)
+	}
+	_, _, errno = syscall.Syscall6(sysIoUringRegister, uintptr(r.fd), uringRegisterBuffers, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(depth), 0, 0)
+	if errno != 0 {
+		err = errno
+		return
+	}
+
+	return
+}
+
+func (r *uring) unmap() {
+	for _, mem := range [][]byte{r.sqmem, r.cqmem, r.sqemem, r.bufmem} {
+		if mem != nil {
+			_ = syscall.Munmap(mem)
+		}
+	}
+}
+
+// write copies p to the registered buffers, and submits the full buffers.
+func (r *uring) write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if r.cur < 0 {
+			for len(r.free) == 0 {
+				if err = r.wait(1); err != nil {
+					return
+				}
+			}
+			r.cur, r.free = r.free[len(r.free)-1], r.free[:len(r.free)-1]
+		}
+		buf := r.bufs[r.cur]
+		m := copy(buf[len(buf):cap(buf)], p)
+		r.bufs[r.cur] = buf[:len(buf)+m]
+		n += m
+		p = p[m:]
+		if len(r.bufs[r.cur]) == cap(buf) {
+			if err = r.submit(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// submit queues the buffer being filled and submits the queued sqes.
+func (r *uring) submit() (err error) {
+	if r.cur >= 0 && len(r.bufs[r.cur]) > 0 {
+		buf := r.bufs[r.cur]
+		tail := *r.sqTail
+		if tail-atomic.LoadUint32(r.sqHead) > r.sqMask {
+			// it never happens because the buffers in flight are less than the sq entries
+			return syscall.EBUSY
+		}
+		index := tail & r.sqMask
+		r.sqes[index] = uringSQE{
+			opcode:   uringOpWriteFixed,
+			flags:    uringSqeIoDrain,
+			fd:       int32(r.file.Fd()),
+			off:      uint64(r.offset),
+			addr:     uint64(uintptr(unsafe.Pointer(&buf[0]))),
+			len:      uint32(len(buf)),
+			userData: uint64(r.cur),
+			bufIndex: uint16(r.cur),
+		}
+		r.sqArray[index] = index
+		atomic.StoreUint32(r.sqTail, tail+1)
+		r.lens[r.cur] = len(buf)
+		r.offs[r.cur] = r.offset
+		r.offset += int64(len(buf))
+		r.cur = -1
+		r.inflight++
+		r.pending++
+	}
+	return r.enter(0)
+}
+
+// enter submits the queued sqes, and waits for min completions.
+func (r *uring) enter(min int) error {
+	var flags uintptr
+	if min > 0 {
+		flags = uringEnterGetEvents
+	}
+	for {
+		n, _, errno := syscall.Syscall6(sysIoUringEnter, uintptr(r.fd), uintptr(r.pending), uintptr(min), flags, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return errno
+		}
+		r.pending -= int(n)
+		return nil
+	}
+}
+
+// wait waits for min completions and reaps them.
+func (r *uring) wait(min int) (err error) {
+	if err = r.enter(min); err != nil {
+		return
+	}
+	head := *r.cqHead
+	for tail := atomic.LoadUint32(r.cqTail); head != tail; head++ {
+		cqe := r.cqes[head&r.cqMask]
+		i := int(cqe.userData)
+		if cqe.res < 0 && err == nil {
+			err = syscall.Errno(-cqe.res)
+		} else if int(cqe.res) < r.lens[i] && err == nil {
+			// rewrites the short write synchronously
+			_, err = r.file.WriteAt(r.bufs[i][cqe.res:], r.offs[i]+int64(cqe.res))
+		}
+		r.bufs[i] = r.bufs[i][:0]
+		r.free = append(r.free, i)
+		r.inflight--
+	}
+	atomic.StoreUint32(r.cqHead, head)
+	return
+}
+
+// flush submits the buffer being filled and waits for all completions.
+func (r *uring) flush() (err error) {
+	if err = r.submit(); err != nil {
+		return
+	}
+	for r.inflight > 0 && err == nil {
+		err = r.wait(1)
+	}
+	return
+}
+
+func (r *uring) close() (err error) {
+	err = r.flush()
+	_, _, _ = syscall.Syscall6(sysIoUringRegister, uintptr(r.fd), uringUnregisterBuffers, 0, 0, 0, 0)
+	r.unmap()
+	_ = syscall.Close(r.fd)
+	if err1 := r.file.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return
+}
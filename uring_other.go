@@ -0,0 +1,23 @@
+//go:build !linux || !(amd64 || arm64 || 386 || arm || riscv64 || ppc64 || ppc64le || s390x)
+// +build !linux !amd64,!arm64,!386,!arm,!riscv64,!ppc64,!ppc64le,!s390x
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+type uring struct{}
+
+func newUring(file *os.File, depth, size int) (*uring, error) {
+	return nil, errors.New("log: io_uring is not supported")
+}
+
+func (r *uring) write(p []byte) (int, error) { return 0, nil }
+
+func (r *uring) submit() error { return nil }
+
+func (r *uring) flush() error { return nil }
+
+func (r *uring) close() error { return nil }
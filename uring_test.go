@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUringWriter(t *testing.T) {
+	filename := "file-uring.log"
+	defer os.Remove(filename)
+
+	if err := os.WriteFile(filename, []byte("existing\n"), 0644); err != nil {
+		t.Fatalf("write file error: %+v", err)
+	}
+
+	w := &UringWriter{
+		Filename:      filename,
+		QueueDepth:    4,
+		BufferSize:    256,
+		FlushInterval: -1,
+	}
+	t.Logf("uring writer is backed by io_uring: %v", w.Uring())
+
+	var want bytes.Buffer
+	want.WriteString("existing\n")
+	for i := 0; i < 1000; i++ {
+		line := fmt.Sprintf("hello uring writer %d\n", i)
+		want.WriteString(line)
+		if _, err := wlprintf(w, InfoLevel, "%s", line); err != nil {
+			t.Fatalf("uring writer write error: %+v", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("uring writer close error: %+v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read file error: %+v", err)
+	}
+	if string(data) != want.String() {
+		t.Errorf("uring writer output mismatch, got %d bytes, want %d bytes", len(data), want.Len())
+	}
+}
+
+func TestUringWriterFlushInterval(t *testing.T) {
+	filename := "file-uring-interval.log"
+	defer os.Remove(filename)
+
+	w := &UringWriter{Filename: filename, FlushInterval: 10 * time.Millisecond}
+	defer w.Close()
+
+	_, _ = wlprintf(w, InfoLevel, "hello uring writer\n")
+
+	for i := 0; i < 100; i++ {
+		if data, _ := os.ReadFile(filename); strings.Contains(string(data), "hello uring writer") {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("uring writer must submit the partial buffer periodically")
+}
+
+func TestUringWriterFlushIntervalRearm(t *testing.T) {
+	filename := "file-uring-rearm.log"
+	defer os.Remove(filename)
+
+	w := &UringWriter{Filename: filename, FlushInterval: 10 * time.Millisecond}
+	defer w.Close()
+
+	for _, line := range []string{"hello uring writer first\n", "hello uring writer second\n"} {
+		_, _ = wlprintf(w, InfoLevel, "%s", line)
+		ok := false
+		for i := 0; i < 100 && !ok; i++ {
+			time.Sleep(5 * time.Millisecond)
+			data, _ := os.ReadFile(filename)
+			ok = strings.Contains(string(data), line)
+		}
+		if !ok {
+			t.Fatalf("uring writer must submit the partial buffer periodically: %q", line)
+		}
+	}
+}
+
+func TestUringWriterOpenRetry(t *testing.T) {
+	dirname := "file-uring-retry"
+	filename := dirname + "/main.log"
+	defer os.RemoveAll(dirname)
+
+	w := &UringWriter{Filename: filename, FlushInterval: -1}
+	defer w.Close()
+
+	if w.Uring() {
+		t.Fatalf("uring writer must fail to open %s", filename)
+	}
+
+	if err := os.Mkdir(dirname, 0755); err != nil {
+		t.Fatalf("mkdir error: %+v", err)
+	}
+	if _, err := wlprintf(w, InfoLevel, "hello uring writer\n"); err != nil {
+		t.Fatalf("uring writer must retry to open %s: %+v", filename, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("uring writer close error: %+v", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "hello uring writer\n" {
+		t.Errorf("uring writer output mismatch: %q", data)
+	}
+}
@@ -0,0 +1,333 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// WebSocketWriter is a Writer that broadcasts entries to the connected websocket clients,
+// as a live tail of logs in browser. It is a http.Handler to attach to an existing ServeMux,
+// or serves a small http server by ListenAndServe.
+//
+// The clients receive the entries of Level and above, the "level" query of request and the
+// text messages of client (e.g. "warn") change the level of client. The entries are dropped
+// for the slow clients instead of blocking the logger.
+//
+//	var tail = &log.WebSocketWriter{Level: log.InfoLevel}
+//	http.Handle("/debug/logs", tail)
+//	// in browser: new WebSocket("ws://localhost:8080/debug/logs?level=debug").onmessage = e => console.log(e.data)
+type WebSocketWriter struct {
+	// Level specifies the default level of clients, uses TraceLevel if zero.
+	Level Level
+
+	// BufferSize specifies the pending entries of a client, uses 256 if zero.
+	BufferSize int
+
+	// AllowedOrigins specifies the origins of browsers allowed besides the same origin,
+	// e.g. "https://admin.example.com", and "*" allows any origin.
+	AllowedOrigins []string
+
+	// CheckOrigin specifies an optional function to check the origin of requests instead
+	// of AllowedOrigins. By default, the cross-origin requests of browsers are rejected,
+	// so that the web pages can't read the logs of localhost.
+	CheckOrigin func(req *http.Request) bool
+
+	mu       sync.Mutex
+	clients  map[*wsClient]struct{}
+	listener net.Listener
+	closed   bool
+}
+
+type wsClient struct {
+	conn  net.Conn
+	level uint32
+	ch    chan []byte
+	once  sync.Once
+	done  chan struct{}
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// WriteEntry implements Writer.
+func (w *WebSocketWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var data []byte
+	for c := range w.clients {
+		if levelLess(e.Level, Level(atomic.LoadUint32(&c.level))) {
+			continue
+		}
+		if data == nil {
+			data = append([]byte(nil), bytes.TrimRight(e.buf, "\n")...)
+		}
+		select {
+		case c.ch <- data:
+		default:
+		}
+	}
+	return len(e.buf), nil
+}
+
+// checkOrigin reports whether the origin of request is allowed, the requests without
+// Origin header are not from browsers.
+func (w *WebSocketWriter) checkOrigin(req *http.Request) bool {
+	if w.CheckOrigin != nil {
+		return w.CheckOrigin(req)
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range w.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, req.Host)
+}
+
+// ServeHTTP implements http.Handler, it upgrades the request to websocket.
+func (w *WebSocketWriter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || req.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(rw, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		rw.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(rw, "websocket version unsupported", http.StatusUpgradeRequired)
+		return
+	}
+	if !w.checkOrigin(req) {
+		http.Error(rw, "websocket origin not allowed", http.StatusForbidden)
+		return
+	}
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		http.Error(rw, "websocket upgrade unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	level := w.Level
+	if level == 0 {
+		level = TraceLevel
+	}
+	if s := req.URL.Query().Get("level"); s != "" {
+		if l := ParseLevel(s); l != noLevel {
+			level = l
+		}
+	}
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	if _, err = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		wsAccept(req.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	size := w.BufferSize
+	if size <= 0 {
+		size = 256
+	}
+	c := &wsClient{
+		conn:  conn,
+		level: uint32(level),
+		ch:    make(chan []byte, size),
+		done:  make(chan struct{}),
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		_ = wsWriteFrame(conn, 0x8, []byte{0x03, 0xe9})
+		_ = conn.Close()
+		return
+	}
+	if w.clients == nil {
+		w.clients = make(map[*wsClient]struct{})
+	}
+	w.clients[c] = struct{}{}
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		delete(w.clients, c)
+		w.mu.Unlock()
+		c.close()
+	}()
+
+	go w.read(c, brw.Reader)
+
+	for {
+		select {
+		case data := <-c.ch:
+			if err := wsWriteFrame(conn, 0x1, data); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// read reads the frames of client, it changes the level of client by text messages,
+// and answers the ping and close frames.
+func (w *WebSocketWriter) read(c *wsClient, r *bufio.Reader) {
+	defer c.close()
+	for {
+		opcode, payload, err := wsReadFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case 0x1:
+			if l := ParseLevel(strings.TrimSpace(string(payload))); l != noLevel {
+				atomic.StoreUint32(&c.level, uint32(l))
+			}
+		case 0x8:
+			_ = wsWriteFrame(c.conn, 0x8, payload)
+			return
+		case 0x9:
+			if wsWriteFrame(c.conn, 0xa, payload) != nil {
+				return
+			}
+		}
+	}
+}
+
+// ListenAndServe listens on the TCP network address and serves the websocket clients on any path.
+func (w *WebSocketWriter) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.listener = ln
+	w.mu.Unlock()
+	err = http.Serve(ln, w)
+	if w.isClosed() {
+		err = nil
+	}
+	return err
+}
+
+func (w *WebSocketWriter) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+// Close closes the listener of ListenAndServe and disconnects the clients.
+func (w *WebSocketWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.listener != nil {
+		err = w.listener.Close()
+	}
+	for c := range w.clients {
+		c.close()
+	}
+	return
+}
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// wsWriteFrame writes an unmasked frame of server, the header and payload are written by writev.
+func wsWriteFrame(conn net.Conn, opcode byte, payload []byte) error {
+	var tmp [10]byte
+	header := tmp[:2]
+	header[0] = 0x80 | opcode
+	switch n := uint64(len(payload)); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, byte(n>>8), byte(n))
+	default:
+		header[1] = 127
+		header = append(header, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(conn)
+	return err
+}
+
+// wsReadFrame reads a frame of client, the control frames and text messages are limited to 64KB.
+func wsReadFrame(r *bufio.Reader) (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	opcode = header[0] & 0x0f
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		n = uint64(b[0])<<8 | uint64(b[1])
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		for _, v := range b {
+			n = n<<8 | uint64(v)
+		}
+	}
+	if n > 64*1024 {
+		return 0, nil, errors.New("log: websocket frame too large")
+	}
+	var mask [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i&3]
+		}
+	}
+	return
+}
+
+var _ Writer = (*WebSocketWriter)(nil)
+var _ http.Handler = (*WebSocketWriter)(nil)
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsDial connects to the websocket writer of url path.
+func wsDial(t *testing.T, addr, path string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial error: %+v", err)
+	}
+	_, _ = conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("websocket handshake error: %+v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("websocket handshake mismatch: %d %v", resp.StatusCode, resp.Header)
+	}
+	return conn, br
+}
+
+// wsWriteClientFrame writes a masked frame of client.
+func wsWriteClientFrame(conn net.Conn, opcode byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload)), mask[0], mask[1], mask[2], mask[3]}
+	for i, c := range payload {
+		frame = append(frame, c^mask[i&3])
+	}
+	_, _ = conn.Write(frame)
+}
+
+func wsClients(w *WebSocketWriter) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.clients)
+}
+
+func TestWebSocketWriter(t *testing.T) {
+	w := &WebSocketWriter{}
+	server := httptest.NewServer(w)
+	defer server.Close()
+	defer w.Close()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	info, infor := wsDial(t, addr, "/?level=info")
+	defer info.Close()
+	errc, errr := wsDial(t, addr, "/?level=error")
+	defer errc.Close()
+	for wsClients(w) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	logger := Logger{Writer: w}
+	logger.Debug().Msg("debug message")
+	logger.Info().Msg("info message")
+	logger.Error().Msg("error message")
+
+	_ = info.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_ = errc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, want := range []string{"info message", "error message"} {
+		opcode, payload, err := wsReadFrame(infor)
+		if err != nil || opcode != 0x1 || !strings.Contains(string(payload), want) {
+			t.Errorf("websocket writer info client want %s got %d %s %+v", want, opcode, payload, err)
+		}
+	}
+	if opcode, payload, err := wsReadFrame(errr); err != nil || opcode != 0x1 || !strings.Contains(string(payload), "error message") {
+		t.Errorf("websocket writer error client got %d %s %+v", opcode, payload, err)
+	}
+
+	// changes the level of client, and pings
+	wsWriteClientFrame(errc, 0x1, []byte("debug"))
+	wsWriteClientFrame(errc, 0x9, []byte("ping"))
+	if opcode, payload, err := wsReadFrame(errr); err != nil || opcode != 0xa || string(payload) != "ping" {
+		t.Errorf("websocket writer pong mismatch: %d %s %+v", opcode, payload, err)
+	}
+	logger.Debug().Msg("debug again")
+	if opcode, payload, err := wsReadFrame(errr); err != nil || opcode != 0x1 || !strings.Contains(string(payload), "debug again") {
+		t.Errorf("websocket writer level change mismatch: %d %s %+v", opcode, payload, err)
+	}
+
+	// closes by client
+	wsWriteClientFrame(info, 0x8, []byte{0x03, 0xe8})
+	if opcode, _, err := wsReadFrame(infor); err != nil || opcode != 0x8 {
+		t.Errorf("websocket writer close mismatch: %d %+v", opcode, err)
+	}
+	for wsClients(w) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWebSocketWriterListenAndServe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := &WebSocketWriter{}
+	errc := make(chan error, 1)
+	go func() { errc <- w.ListenAndServe(addr) }()
+
+	for i := 0; i < 100; i++ {
+		if c, err := net.Dial("tcp", addr); err == nil {
+			c.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	conn, br := wsDial(t, addr, "/tail")
+	defer conn.Close()
+	for wsClients(w) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = w.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("websocket writer ListenAndServe error: %+v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := wsReadFrame(br); err == nil {
+		t.Errorf("websocket writer should disconnect clients on close")
+	}
+}
+
+func TestWebSocketWriterBadRequest(t *testing.T) {
+	server := httptest.NewServer(&WebSocketWriter{})
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("http get error: %+v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("websocket writer want 400 got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocketWriterHandshake(t *testing.T) {
+	w := &WebSocketWriter{AllowedOrigins: []string{"https://admin.example.com"}}
+	server := httptest.NewServer(w)
+	defer server.Close()
+
+	cases := []struct {
+		method  string
+		version string
+		origin  string
+		status  int
+	}{
+		{http.MethodPost, "13", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "8", "", http.StatusUpgradeRequired},
+		{http.MethodGet, "13", "https://evil.example.com", http.StatusForbidden},
+		{http.MethodGet, "13", "https://admin.example.com", http.StatusSwitchingProtocols},
+		{http.MethodGet, "13", "http://" + server.Listener.Addr().String(), http.StatusSwitchingProtocols},
+		{http.MethodGet, "13", "", http.StatusSwitchingProtocols},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, server.URL, nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", c.version)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("http request error: %+v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("websocket %s version=%s origin=%q want %d got %d", c.method, c.version, c.origin, c.status, resp.StatusCode)
+		}
+	}
+}